@@ -12,6 +12,7 @@ import (
 
 	"github.com/go-goose/goose/v5/errors"
 	goosehttp "github.com/go-goose/goose/v5/http"
+	"github.com/go-goose/goose/v5/identity"
 )
 
 // Basic returns a basic Cinder client which will handle authorization
@@ -30,6 +31,20 @@ func BasicTLSConfig(endpoint *url.URL, tenantId string, token TokenFn, tlsConfig
 	)
 }
 
+// BasicCredentialsTLS returns a basic Cinder client which will handle
+// authorization of requests, using the client certificate and CA bundle
+// named in creds, and routing to the correct endpoint.
+func BasicCredentialsTLS(endpoint *url.URL, tenantId string, token TokenFn, creds *identity.Credentials) (*Client, error) {
+	tlsConfig, err := creds.TLSConfig()
+	if err != nil {
+		return nil, errors.Newf(err, "cannot configure TLS from credentials")
+	}
+	if tlsConfig == nil {
+		return Basic(endpoint, tenantId, token), nil
+	}
+	return BasicTLSConfig(endpoint, tenantId, token, tlsConfig), nil
+}
+
 // TokenFn represents a function signature which returns the user's
 // authorization token when called.
 type TokenFn func() string
@@ -47,12 +62,14 @@ func SetAuthHeaderFn(token TokenFn, wrappedHandler RequestHandlerFn) RequestHand
 // authentication headers for a given request and provides the
 // client with a tls config.
 func AuthHeaderTSLConfigDoRequestFn(token TokenFn, tlsConfig *tls.Config) RequestHandlerFn {
+	// Share one transport between requests so that connections,
+	// and their TLS handshakes, can be reused.
+	defaultClient := *http.DefaultClient
+	defaultClient.Transport = &http.Transport{
+		TLSClientConfig: tlsConfig,
+	}
 	return func(req *http.Request) (*http.Response, error) {
 		req.Header.Set("X-Auth-Token", token())
-		defaultClient := *http.DefaultClient
-		defaultClient.Transport = &http.Transport{
-			TLSClientConfig: tlsConfig,
-		}
 		return defaultClient.Do(req)
 	}
 }
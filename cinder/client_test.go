@@ -12,6 +12,8 @@ import (
 	"net/url"
 
 	gc "gopkg.in/check.v1"
+
+	"github.com/go-goose/goose/v5/identity"
 )
 
 const (
@@ -833,6 +835,19 @@ func (s *CinderTestSuite) TestGetAvailabilityZones(c *gc.C) {
 	c.Check(zone.State.Available, gc.Equals, true)
 }
 
+func (s *CinderTestSuite) TestBasicCredentialsTLS(c *gc.C) {
+	endpoint, err := url.Parse("https://volume.testing/v2/" + testId)
+	c.Assert(err, gc.IsNil)
+	token := func() string { return testToken }
+
+	client, err := BasicCredentialsTLS(endpoint, testId, token, &identity.Credentials{})
+	c.Assert(err, gc.IsNil)
+	c.Assert(client, gc.NotNil)
+
+	_, err = BasicCredentialsTLS(endpoint, testId, token, &identity.Credentials{ClientCert: "client.crt"})
+	c.Assert(err, gc.ErrorMatches, "cannot configure TLS from credentials\ncaused by: a client certificate and key must be specified together")
+}
+
 func (s *CinderTestSuite) localDo(req *http.Request) (*http.Response, error) {
 	handler, matchedPattern := s.Handler(req)
 	if matchedPattern == "" {
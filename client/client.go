@@ -24,11 +24,13 @@ import (
 const (
 	apiTokens   = "/tokens"
 	apiTokensV3 = "/auth/tokens"
+	apiAuthV1   = "/auth/v1.0"
 
 	// The HTTP request methods.
 	GET    = "GET"
 	POST   = "POST"
 	PUT    = "PUT"
+	PATCH  = "PATCH"
 	DELETE = "DELETE"
 	HEAD   = "HEAD"
 	COPY   = "COPY"
@@ -89,6 +91,11 @@ type AuthenticatingClient interface {
 	// for the specified region.
 	EndpointsForRegion(region string) identity.ServiceURLs
 
+	// Catalog returns the full service catalog returned when the
+	// client authenticated, or nil if the client is not yet
+	// authenticated or the authentication mode has no catalog.
+	Catalog() *identity.Catalog
+
 	// IdentityAuthOptions returns a list of valid auth options
 	// for the given openstack or error if fetching fails.
 	IdentityAuthOptions() (identity.AuthOptions, error)
@@ -175,9 +182,16 @@ type authenticatingClient struct {
 	// Service type to endpoint URLs for each available region
 	regionServiceURLs map[string]identity.ServiceURLs
 
+	// The service catalog returned on authentication.
+	catalog *identity.Catalog
+
 	// Service type to endpoint URLs for the authenticated region
 	serviceURLs identity.ServiceURLs
 
+	// tlsErr holds any error loading the TLS configuration from
+	// creds, which is returned on authentication.
+	tlsErr error
+
 	// API versions available based on service catalogue URL.
 	apiVersionMu                sync.Mutex
 	apiVersionDiscoveryDisabled set.Strings
@@ -188,6 +202,24 @@ func (c *authenticatingClient) EndpointsForRegion(region string) identity.Servic
 	return c.regionServiceURLs[region]
 }
 
+func (c *authenticatingClient) Catalog() *identity.Catalog {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.catalog == nil {
+		return nil
+	}
+	// Return a copy so that callers cannot modify the catalog
+	// the client uses.
+	catalog := &identity.Catalog{
+		Services: make([]identity.CatalogService, len(c.catalog.Services)),
+	}
+	for i, s := range c.catalog.Services {
+		s.Endpoints = append([]identity.CatalogEndpoint(nil), s.Endpoints...)
+		catalog.Services[i] = s
+	}
+	return catalog
+}
+
 var _ AuthenticatingClient = (*authenticatingClient)(nil)
 
 // TODO (stickupkid): The needs some clean up.
@@ -230,31 +262,81 @@ func NewNonValidatingPublicClient(baseURL string, logger logging.CompatLogger, o
 	}
 }
 
-// NewClient creates a new authenticated client.
+// NewClient creates a new authenticated client. Any client certificate
+// or CA bundle named in creds is used for all requests; if they cannot
+// be loaded, the error is reported by Authenticate.
 func NewClient(creds *identity.Credentials, authMethod identity.AuthMode, logger logging.CompatLogger, options ...Option) AuthenticatingClient {
 	opts := newOptions()
 	for _, option := range options {
 		option(opts)
 	}
 
-	return newClient(creds, authMethod, goosehttp.New(
+	if opts.httpClient == nil {
+		opts.httpClient = newOptions().httpClient
+	}
+	httpClient, err := credentialsHTTPClient(creds, opts.httpClient)
+	client := newClient(creds, authMethod, goosehttp.New(
 		goosehttp.WithHeadersFunc(opts.httpHeadersFunc),
-		goosehttp.WithHTTPClient(opts.httpClient),
+		goosehttp.WithHTTPClient(httpClient),
 	), logger)
+	client.tlsErr = err
+	return client
 }
 
 // NewNonValidatingClient creates a new authenticated client that doesn't
-// validate against TLS.
+// validate against TLS. Any client certificate named in creds is still
+// presented to servers.
 func NewNonValidatingClient(creds *identity.Credentials, authMethod identity.AuthMode, logger logging.CompatLogger, options ...Option) AuthenticatingClient {
 	opts := newOptions()
 	for _, option := range options {
 		option(opts)
 	}
 
-	return newClient(creds, authMethod, goosehttp.New(
+	if opts.insecureHTTPClient == nil {
+		opts.insecureHTTPClient = newOptions().insecureHTTPClient
+	}
+	httpClient, err := credentialsHTTPClient(creds, opts.insecureHTTPClient)
+	client := newClient(creds, authMethod, goosehttp.New(
 		goosehttp.WithHeadersFunc(opts.httpHeadersFunc),
-		goosehttp.WithHTTPClient(opts.insecureHTTPClient),
+		goosehttp.WithHTTPClient(httpClient),
 	), logger)
+	client.tlsErr = err
+	return client
+}
+
+// credentialsHTTPClient returns a copy of httpClient whose transport
+// uses the client certificate and CA bundle named in creds. If creds
+// names neither, or they cannot be loaded, httpClient is returned
+// unchanged, along with any error. A TLSTransportConfig transport is
+// rejected, as its TLS configuration can neither be read nor set
+// without changing it for every client sharing it.
+func credentialsHTTPClient(creds *identity.Credentials, httpClient *http.Client) (*http.Client, error) {
+	if !creds.HasTLSConfig() {
+		return httpClient, nil
+	}
+	client := *httpClient
+	switch t := client.Transport.(type) {
+	case nil:
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{}
+		client.Transport = transport
+	case *http.Transport:
+		// Clone the transport, so that a transport shared
+		// with other clients is left untouched.
+		transport := t.Clone()
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		client.Transport = transport
+	case TLSTransportConfig:
+		return httpClient, errors.New("cannot use TLS credentials with client transport type: " + fmt.Sprintf("%T", t))
+	default:
+		return httpClient, errors.New("unexpected client transport type: " + fmt.Sprintf("%T", t))
+	}
+	if err := creds.UpdateTLSConfig(client.Transport.(*http.Transport).TLSClientConfig); err != nil {
+		return httpClient, err
+	}
+	return &client, nil
 }
 
 // TLSTransportConfig allows the setting of a tls.Config onto a given transport.
@@ -263,13 +345,25 @@ type TLSTransportConfig interface {
 }
 
 // NewClientTLSConfig creates a new authenticated client that allows passing
-// in a new TLS config.
+// in a new TLS config. Any client certificate or CA bundle named in creds
+// is added to a copy of config.
 func NewClientTLSConfig(creds *identity.Credentials, authMethod identity.AuthMode, logger logging.CompatLogger, config *tls.Config, options ...Option) (AuthenticatingClient, error) {
 	opts := newOptions()
 	for _, option := range options {
 		option(opts)
 	}
 
+	if creds.HasTLSConfig() {
+		if config == nil {
+			config = &tls.Config{}
+		} else {
+			config = config.Clone()
+		}
+		if err := creds.UpdateTLSConfig(config); err != nil {
+			return nil, err
+		}
+	}
+
 	client := *opts.httpClient
 	if client.Transport == nil {
 		client.Transport = &http.Transport{}
@@ -292,20 +386,29 @@ func NewClientTLSConfig(creds *identity.Credentials, authMethod identity.AuthMod
 
 var defaultRequiredServiceTypes = []string{"compute", "object-store"}
 
-func newClient(creds *identity.Credentials, auth_method identity.AuthMode, httpClient goosehttp.HttpClient, logger logging.CompatLogger) AuthenticatingClient {
+func newClient(creds *identity.Credentials, auth_method identity.AuthMode, httpClient goosehttp.HttpClient, logger logging.CompatLogger) *authenticatingClient {
 	client_creds := *creds
 	if strings.HasSuffix(client_creds.URL, "/") {
 		client_creds.URL = client_creds.URL[:len(client_creds.URL)-1]
 	}
+	requiredServiceTypes := defaultRequiredServiceTypes
 	switch auth_method {
 	case identity.AuthUserPassV3:
 		client_creds.URL = client_creds.URL + apiTokensV3
+	case identity.AuthSwiftV1:
+		// TempAuth serves both /auth/v1.0 and /v1.0, so only
+		// add the path if neither has been given.
+		if !strings.HasSuffix(client_creds.URL, "/v1.0") {
+			client_creds.URL = client_creds.URL + apiAuthV1
+		}
+		// A standalone Swift cluster provides nothing else.
+		requiredServiceTypes = []string{"object-store"}
 	default:
 		client_creds.URL = client_creds.URL + apiTokens
 	}
 	client := authenticatingClient{
 		creds:                &client_creds,
-		requiredServiceTypes: defaultRequiredServiceTypes,
+		requiredServiceTypes: requiredServiceTypes,
 		client: client{
 			logger:     logger,
 			httpClient: httpClient,
@@ -659,6 +762,9 @@ func (c *authenticatingClient) doAuthenticate() error {
 	if c.creds == nil || c.tokenId != "" {
 		return nil
 	}
+	if c.tlsErr != nil {
+		return gooseerrors.Newf(c.tlsErr, "cannot configure TLS from credentials")
+	}
 	if c.authMode == nil {
 		return fmt.Errorf("Authentication method has not been specified")
 	}
@@ -673,6 +779,7 @@ func (c *authenticatingClient) doAuthenticate() error {
 	logger.Debugf("auth details: %+v", authDetails)
 
 	c.regionServiceURLs = authDetails.RegionServiceURLs
+	c.catalog = authDetails.Catalog
 	if err := c.createServiceURLs(); err != nil {
 		return gooseerrors.Newf(err, "cannot create service URLs")
 	}
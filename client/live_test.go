@@ -78,6 +78,12 @@ func (s *LiveTests) TestAuthenticate(c *gc.C) {
 		url, err = cl.MakeServiceURL("object-store", "", nil)
 		c.Check(err, gc.IsNil)
 		c.Check(url, gc.NotNil)
+
+		// Check the full service catalog is retained.
+		catalog := cl.Catalog()
+		c.Assert(catalog, gc.NotNil)
+		c.Check(catalog.ServicesByType("compute"), gc.Not(gc.HasLen), 0)
+		c.Check(catalog.Endpoints("compute", identity.InterfacePublic, ""), gc.Not(gc.HasLen), 0)
 	}
 }
 
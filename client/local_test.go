@@ -169,6 +169,20 @@ func (s *localLiveSuite) TestInexactRegionMatch(c *gc.C) {
 	c.Assert(err, gc.IsNil)
 }
 
+func (s *localLiveSuite) TestCatalogIsACopy(c *gc.C) {
+	if s.authMode == identity.AuthLegacy {
+		c.Skip("legacy authentication has no catalog")
+	}
+	cl := client.NewClient(s.cred, s.authMode, nil)
+	err := cl.Authenticate()
+	c.Assert(err, gc.IsNil)
+	catalog := cl.Catalog()
+	c.Assert(catalog.Services, gc.Not(gc.HasLen), 0)
+	c.Assert(catalog.Services[0].Endpoints, gc.Not(gc.HasLen), 0)
+	catalog.Services[0].Endpoints[0].URL = "http://changed.example.com"
+	c.Assert(cl.Catalog().Services[0].Endpoints[0].URL, gc.Not(gc.Equals), "http://changed.example.com")
+}
+
 type fakeAuthenticator struct {
 	mu        sync.Mutex
 	nrCallers int
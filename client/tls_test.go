@@ -0,0 +1,171 @@
+package client_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"time"
+
+	gc "gopkg.in/check.v1"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/identity"
+	"github.com/go-goose/goose/v5/testservices/identityservice"
+)
+
+// mutualTLSSuite runs an identity service test double behind a
+// server which requires clients to present a certificate.
+type mutualTLSSuite struct {
+	server *httptest.Server
+	cred   *identity.Credentials
+}
+
+var _ = gc.Suite(&mutualTLSSuite{})
+
+func (s *mutualTLSSuite) SetUpSuite(c *gc.C) {
+	dir := c.MkDir()
+	caCert, caKey := createCertificate(c, "ca", nil, nil)
+	clientCert, clientKey := createCertificate(c, "client", caCert, caKey)
+	writePEM(c, filepath.Join(dir, "client.crt"), "CERTIFICATE", clientCert.Raw)
+	keyBytes, err := x509.MarshalECPrivateKey(clientKey)
+	c.Assert(err, gc.IsNil)
+	writePEM(c, filepath.Join(dir, "client.key"), "EC PRIVATE KEY", keyBytes)
+
+	service := identityservice.NewUserPass()
+	service.AddUser("fred", "secret", "tenant", "default")
+	mux := http.NewServeMux()
+	service.SetupHTTP(mux)
+	s.server = httptest.NewUnstartedServer(mux)
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	s.server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}
+	s.server.StartTLS()
+	writePEM(c, filepath.Join(dir, "ca.crt"), "CERTIFICATE", s.server.Certificate().Raw)
+
+	s.cred = &identity.Credentials{
+		URL:        s.server.URL,
+		User:       "fred",
+		Secrets:    "secret",
+		TenantName: "tenant",
+		ClientCert: filepath.Join(dir, "client.crt"),
+		ClientKey:  filepath.Join(dir, "client.key"),
+		CACert:     filepath.Join(dir, "ca.crt"),
+	}
+}
+
+func (s *mutualTLSSuite) TearDownSuite(c *gc.C) {
+	s.server.Close()
+}
+
+func (s *mutualTLSSuite) authenticate(cl client.AuthenticatingClient) error {
+	// Only the identity service is running.
+	cl.SetRequiredServiceTypes(nil)
+	return cl.Authenticate()
+}
+
+func (s *mutualTLSSuite) TestClientPresentsCertificate(c *gc.C) {
+	cl := client.NewClient(s.cred, identity.AuthUserPass, nil)
+	c.Assert(s.authenticate(cl), gc.IsNil)
+}
+
+func (s *mutualTLSSuite) TestNonValidatingClientPresentsCertificate(c *gc.C) {
+	cred := *s.cred
+	cred.CACert = ""
+	cl := client.NewNonValidatingClient(&cred, identity.AuthUserPass, nil)
+	c.Assert(s.authenticate(cl), gc.IsNil)
+}
+
+func (s *mutualTLSSuite) TestClientTLSConfigAddsCertificate(c *gc.C) {
+	cl, err := client.NewClientTLSConfig(s.cred, identity.AuthUserPass, nil, &tls.Config{})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.authenticate(cl), gc.IsNil)
+}
+
+func (s *mutualTLSSuite) TestClientWithoutCertificateRejected(c *gc.C) {
+	cred := *s.cred
+	cred.ClientCert = ""
+	cred.ClientKey = ""
+	cl := client.NewClient(&cred, identity.AuthUserPass, nil)
+	c.Assert(s.authenticate(cl), gc.ErrorMatches, "(.|\n)*tls: .*")
+}
+
+func (s *mutualTLSSuite) TestClientWithNilHTTPClient(c *gc.C) {
+	cl := client.NewClient(s.cred, identity.AuthUserPass, nil, client.WithHTTPClient(nil))
+	c.Assert(s.authenticate(cl), gc.IsNil)
+}
+
+// tlsConfigTransport is a transport which can only have its TLS
+// configuration set.
+type tlsConfigTransport struct {
+	http.RoundTripper
+	config *tls.Config
+}
+
+func (t *tlsConfigTransport) SetTLSConfig(config *tls.Config) {
+	t.config = config
+}
+
+func (s *mutualTLSSuite) TestClientTLSTransportConfigRejected(c *gc.C) {
+	transport := &tlsConfigTransport{RoundTripper: http.DefaultTransport}
+	httpClient := &http.Client{Transport: transport}
+	cl := client.NewClient(s.cred, identity.AuthUserPass, nil, client.WithHTTPClient(httpClient))
+	err := s.authenticate(cl)
+	c.Assert(err, gc.ErrorMatches, "cannot configure TLS from credentials\ncaused by: cannot use TLS credentials with client transport type: \\*client_test.tlsConfigTransport")
+	// The shared transport is left untouched.
+	c.Assert(transport.config, gc.IsNil)
+}
+
+func (s *mutualTLSSuite) TestInvalidCredentialsTLS(c *gc.C) {
+	cred := *s.cred
+	cred.ClientKey = ""
+	cl := client.NewClient(&cred, identity.AuthUserPass, nil)
+	err := s.authenticate(cl)
+	c.Assert(err, gc.ErrorMatches, "cannot configure TLS from credentials\ncaused by: a client certificate and key must be specified together")
+
+	cred = *s.cred
+	cred.CACert = cred.ClientKey
+	_, err = client.NewClientTLSConfig(&cred, identity.AuthUserPass, nil, nil)
+	c.Assert(err, gc.ErrorMatches, `no CA certificates found in ".*"`)
+}
+
+// createCertificate creates a certificate with the given common name,
+// signed by parent, or self-signed if parent is nil.
+func createCertificate(c *gc.C, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, gc.IsNil)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	c.Assert(err, gc.IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, gc.IsNil)
+	return cert, key
+}
+
+func writePEM(c *gc.C, path, blockType string, data []byte) {
+	err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600)
+	c.Assert(err, gc.IsNil)
+}
@@ -0,0 +1,202 @@
+// goose/cloudinit - Go package to build the cloud-init user data given
+// to servers started with nova.RunServer.
+// For the formats understood by cloud-init see
+// https://cloudinit.readthedocs.io/en/latest/explanation/format.html
+
+package cloudinit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// MaxUserDataSize is the largest user data nova accepts, measured
+// after base64 encoding.
+const MaxUserDataSize = 65535
+
+// Content types of the parts of multi-part user data.
+const (
+	ContentTypeCloudConfig = "text/cloud-config"
+	ContentTypeShellScript = "text/x-shellscript"
+	ContentTypeIncludeURL  = "text/x-include-url"
+	ContentTypeBoothook    = "text/cloud-boothook"
+)
+
+// Part is one part of multi-part user data.
+type Part struct {
+	ContentType string
+	Filename    string
+	Content     []byte
+}
+
+// SizeError is returned when user data is larger than nova accepts.
+// Size is the length of the user data once base64 encoded.
+type SizeError struct {
+	Size int
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("user data is %d bytes once encoded, more than the limit of %d", e.Size, MaxUserDataSize)
+}
+
+// Builder assembles multi-part MIME user data. The zero value is an
+// empty Builder ready to use.
+type Builder struct {
+	// Compress sets whether the user data is gzip compressed.
+	// cloud-init detects and decompresses gzip user data itself.
+	Compress bool
+
+	parts []Part
+}
+
+// New returns a new empty Builder.
+func New() *Builder {
+	return &Builder{}
+}
+
+// AddCloudConfig adds cloud-config YAML. Several cloud-config parts
+// are merged by cloud-init.
+func (b *Builder) AddCloudConfig(config string) {
+	b.AddPart(Part{
+		ContentType: ContentTypeCloudConfig,
+		Filename:    b.filename("cloud-config", ".yaml"),
+		Content:     []byte(config),
+	})
+}
+
+// AddShellScript adds a script to run once, late in the first boot.
+// The script must start with an interpreter line, such as "#!/bin/sh".
+// If filename is empty a name is chosen.
+func (b *Builder) AddShellScript(filename, script string) {
+	if filename == "" {
+		filename = b.filename("script", ".sh")
+	}
+	b.AddPart(Part{
+		ContentType: ContentTypeShellScript,
+		Filename:    filename,
+		Content:     []byte(script),
+	})
+}
+
+// AddInclude adds URLs whose content cloud-init fetches and processes
+// as further user data.
+func (b *Builder) AddInclude(urls ...string) {
+	b.AddPart(Part{
+		ContentType: ContentTypeIncludeURL,
+		Filename:    b.filename("include", ".txt"),
+		Content:     []byte(strings.Join(urls, "\n") + "\n"),
+	})
+}
+
+// AddPart adds a part with any content type understood by cloud-init.
+func (b *Builder) AddPart(part Part) {
+	b.parts = append(b.parts, part)
+}
+
+// filename returns a name for the next part, unique within the user
+// data.
+func (b *Builder) filename(prefix, ext string) string {
+	return fmt.Sprintf("part-%03d-%s%s", len(b.parts)+1, prefix, ext)
+}
+
+// Build validates the parts and returns the user data, ready to be
+// used as nova.RunServerOpts.UserData, which is base64 encoded when
+// the request is sent. If the encoded user data is too large for nova
+// a *SizeError is returned; compression may bring it under the limit.
+func (b *Builder) Build() ([]byte, error) {
+	if len(b.parts) == 0 {
+		return nil, fmt.Errorf("no user data parts added")
+	}
+	for i, part := range b.parts {
+		if err := validatePart(part); err != nil {
+			return nil, fmt.Errorf("invalid user data part %d (%s): %v", i+1, part.Filename, err)
+		}
+	}
+	data, err := b.multipart()
+	if err != nil {
+		return nil, err
+	}
+	if b.Compress {
+		if data, err = compress(data); err != nil {
+			return nil, err
+		}
+	}
+	if size := base64.StdEncoding.EncodedLen(len(data)); size > MaxUserDataSize {
+		return nil, &SizeError{Size: size}
+	}
+	return data, nil
+}
+
+// validatePart returns an error if cloud-init would not be able to
+// use the part.
+func validatePart(part Part) error {
+	if part.ContentType == "" {
+		return fmt.Errorf("no content type")
+	}
+	if part.Filename == "" {
+		return fmt.Errorf("no filename")
+	}
+	switch part.ContentType {
+	case ContentTypeShellScript:
+		if !bytes.HasPrefix(part.Content, []byte("#!")) {
+			return fmt.Errorf("shell script does not start with #!")
+		}
+	case ContentTypeIncludeURL:
+		lines := strings.Fields(string(part.Content))
+		if len(lines) == 0 {
+			return fmt.Errorf("no URLs to include")
+		}
+		for _, line := range lines {
+			u, err := url.Parse(line)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return fmt.Errorf("invalid URL %q", line)
+			}
+		}
+	}
+	return nil
+}
+
+// multipart returns the parts as a multipart/mixed MIME message.
+func (b *Builder) multipart() ([]byte, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, part := range b.parts {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Type", fmt.Sprintf("%s; charset=\"utf-8\"", part.ContentType))
+		header.Set("MIME-Version", "1.0")
+		header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", part.Filename))
+		pw, err := w.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pw.Write(part.Content); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%q\r\n", w.Boundary())
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,128 @@
+package cloudinit_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	gc "gopkg.in/check.v1"
+
+	"github.com/go-goose/goose/v5/cloudinit"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type CloudInitSuite struct{}
+
+var _ = gc.Suite(&CloudInitSuite{})
+
+// readParts parses multi-part user data, returning the content of
+// each part keyed by filename and the content types in order.
+func readParts(c *gc.C, data []byte) (map[string]string, []string) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	c.Assert(err, gc.IsNil)
+	c.Assert(msg.Header.Get("MIME-Version"), gc.Equals, "1.0")
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(mediaType, gc.Equals, "multipart/mixed")
+	contents := make(map[string]string)
+	var types []string
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		content, err := ioutil.ReadAll(part)
+		c.Assert(err, gc.IsNil)
+		contentType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		c.Assert(err, gc.IsNil)
+		types = append(types, contentType)
+		contents[part.FileName()] = string(content)
+	}
+	return contents, types
+}
+
+func (s *CloudInitSuite) TestBuild(c *gc.C) {
+	b := cloudinit.New()
+	b.AddCloudConfig("#cloud-config\npackages: [nginx]\n")
+	b.AddShellScript("setup.sh", "#!/bin/sh\necho hello\n")
+	b.AddInclude("https://example.com/a.txt", "https://example.com/b.txt")
+	data, err := b.Build()
+	c.Assert(err, gc.IsNil)
+
+	contents, types := readParts(c, data)
+	c.Assert(types, gc.DeepEquals, []string{
+		cloudinit.ContentTypeCloudConfig,
+		cloudinit.ContentTypeShellScript,
+		cloudinit.ContentTypeIncludeURL,
+	})
+	c.Assert(contents, gc.DeepEquals, map[string]string{
+		"part-001-cloud-config.yaml": "#cloud-config\npackages: [nginx]\n",
+		"setup.sh":                   "#!/bin/sh\necho hello\n",
+		"part-003-include.txt":       "https://example.com/a.txt\nhttps://example.com/b.txt\n",
+	})
+}
+
+func (s *CloudInitSuite) TestBuildCompressed(c *gc.C) {
+	b := &cloudinit.Builder{Compress: true}
+	b.AddShellScript("", "#!/bin/sh\necho hello\n")
+	data, err := b.Build()
+	c.Assert(err, gc.IsNil)
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	c.Assert(err, gc.IsNil)
+	data, err = ioutil.ReadAll(r)
+	c.Assert(err, gc.IsNil)
+	contents, _ := readParts(c, data)
+	c.Assert(contents, gc.DeepEquals, map[string]string{
+		"part-001-script.sh": "#!/bin/sh\necho hello\n",
+	})
+}
+
+func (s *CloudInitSuite) TestBuildTooLarge(c *gc.C) {
+	b := cloudinit.New()
+	b.AddShellScript("big.sh", "#!/bin/sh\n"+strings.Repeat("echo hello\n", 5000))
+	_, err := b.Build()
+	c.Assert(err, gc.FitsTypeOf, &cloudinit.SizeError{})
+	c.Assert(err.(*cloudinit.SizeError).Size > cloudinit.MaxUserDataSize, gc.Equals, true)
+
+	// The repetitive script compresses well under the limit.
+	b.Compress = true
+	_, err = b.Build()
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *CloudInitSuite) TestBuildInvalid(c *gc.C) {
+	_, err := cloudinit.New().Build()
+	c.Assert(err, gc.ErrorMatches, "no user data parts added")
+
+	for _, test := range []struct {
+		add func(b *cloudinit.Builder)
+		err string
+	}{{
+		add: func(b *cloudinit.Builder) { b.AddShellScript("x.sh", "echo hello") },
+		err: `invalid user data part 1 \(x.sh\): shell script does not start with #!`,
+	}, {
+		add: func(b *cloudinit.Builder) { b.AddInclude() },
+		err: `invalid user data part 1 \(part-001-include.txt\): no URLs to include`,
+	}, {
+		add: func(b *cloudinit.Builder) { b.AddInclude("example.com/a.txt") },
+		err: `invalid user data part 1 \(part-001-include.txt\): invalid URL "example.com/a.txt"`,
+	}, {
+		add: func(b *cloudinit.Builder) { b.AddPart(cloudinit.Part{Filename: "x"}) },
+		err: `invalid user data part 1 \(x\): no content type`,
+	}} {
+		b := cloudinit.New()
+		test.add(b)
+		_, err := b.Build()
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
@@ -65,6 +65,12 @@ type ImageMetadata struct {
 	ProjectId    interface{} `json:"project_id"`
 	RAMDiskId    interface{} `json:"ramdisk_id"`
 	OwnerId      interface{} `json:"owner_id"`
+
+	// These are set on images created from servers by nova.
+	ImageType    string `json:"image_type"` // "snapshot" or "backup"
+	InstanceUUID string `json:"instance_uuid"`
+	BaseImageRef string `json:"base_image_ref"`
+	BackupType   string `json:"backup_type"`
 }
 
 // ImageDetail describes extended information about an image.
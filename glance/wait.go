@@ -0,0 +1,93 @@
+// Helpers for waiting on images which are created asynchronously,
+// such as the server snapshots and backups made by nova.
+
+package glance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-goose/goose/v5/errors"
+)
+
+// Statuses of an image, as reported by ImageDetail.Status.
+const (
+	StatusActive  = "ACTIVE"
+	StatusSaving  = "SAVING"
+	StatusError   = "ERROR"
+	StatusDeleted = "DELETED"
+	StatusUnknown = "UNKNOWN"
+)
+
+// WaitOpts defines how WaitForImage polls an image.
+type WaitOpts struct {
+	// Timeout bounds the total time spent waiting. If zero, the wait
+	// is only bounded by the context.
+	Timeout time.Duration
+
+	// Backoff returns the delay before the given poll attempt,
+	// counted from 1. If nil, the delay starts at one second and
+	// doubles on every attempt up to 30 seconds.
+	Backoff func(attempt int) time.Duration
+}
+
+// ImageStatusError is returned when an image being waited on fails to
+// be created, or is deleted.
+type ImageStatusError struct {
+	ImageId string
+	Status  string
+}
+
+func (e *ImageStatusError) Error() string {
+	return fmt.Sprintf("image %s has status %s", e.ImageId, e.Status)
+}
+
+// WaitForImage polls the specified image until it is active, and
+// returns its details. If the image reaches StatusError or
+// StatusDeleted an *ImageStatusError is returned, and if the wait
+// times out the error satisfies errors.IsTimeout.
+func (c *Client) WaitForImage(ctx context.Context, imageId string, opts WaitOpts) (*ImageDetail, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	backoff := opts.Backoff
+	if backoff == nil {
+		backoff = defaultBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		image, err := c.GetImageDetail(imageId)
+		if err != nil {
+			return nil, errors.Newf(err, "failed waiting for image %s to become active", imageId)
+		}
+		switch image.Status {
+		case StatusActive:
+			return image, nil
+		case StatusError, StatusDeleted:
+			return nil, &ImageStatusError{ImageId: imageId, Status: image.Status}
+		}
+		timer := time.NewTimer(backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil, errors.NewTimeoutf(ctx.Err(), "", "timed out waiting for image %s to become active", imageId)
+			}
+			return nil, errors.Newf(ctx.Err(), "stopped waiting for image %s to become active", imageId)
+		case <-timer.C:
+		}
+	}
+}
+
+func defaultBackoff(attempt int) time.Duration {
+	delay := time.Second
+	for i := 1; i < attempt && delay < 30*time.Second; i++ {
+		delay *= 2
+	}
+	if delay > 30*time.Second {
+		delay = 30 * time.Second
+	}
+	return delay
+}
@@ -0,0 +1,120 @@
+package identity
+
+import (
+	"sort"
+)
+
+// Endpoint interfaces, describing who an endpoint is intended for.
+const (
+	InterfacePublic   = "public"
+	InterfaceInternal = "internal"
+	InterfaceAdmin    = "admin"
+)
+
+// CatalogEndpoint describes a single endpoint of a service in the
+// service catalog.
+type CatalogEndpoint struct {
+	Id        string // Empty for identity v2 catalogs which omit endpoint ids.
+	Interface string // One of InterfacePublic, InterfaceInternal or InterfaceAdmin.
+	Region    string
+	URL       string
+}
+
+// CatalogService describes a service in the service catalog.
+type CatalogService struct {
+	Id        string // Empty for identity v2 catalogs.
+	Name      string
+	Type      string
+	Endpoints []CatalogEndpoint
+}
+
+// Catalog is the service catalog returned by the identity service
+// on authentication.
+type Catalog struct {
+	Services []CatalogService
+}
+
+// ServicesByType returns the services of the given type.
+func (c *Catalog) ServicesByType(serviceType string) []CatalogService {
+	var services []CatalogService
+	for _, s := range c.Services {
+		if s.Type == serviceType {
+			services = append(services, s)
+		}
+	}
+	return services
+}
+
+// ServiceTypes returns the sorted, distinct types of the services
+// in the catalog.
+func (c *Catalog) ServiceTypes() []string {
+	seen := make(map[string]bool)
+	var types []string
+	for _, s := range c.Services {
+		if !seen[s.Type] {
+			seen[s.Type] = true
+			types = append(types, s.Type)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Endpoints returns the endpoints of services of the given type
+// which match the given interface and region. An empty
+// serviceType, iface or region matches anything.
+func (c *Catalog) Endpoints(serviceType, iface, region string) []CatalogEndpoint {
+	var endpoints []CatalogEndpoint
+	for _, s := range c.Services {
+		if serviceType != "" && s.Type != serviceType {
+			continue
+		}
+		for _, e := range s.Endpoints {
+			if iface != "" && e.Interface != iface {
+				continue
+			}
+			if region != "" && e.Region != region {
+				continue
+			}
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints
+}
+
+// Regions returns the sorted, distinct regions that have at least
+// one endpoint in the catalog.
+func (c *Catalog) Regions() []string {
+	seen := make(map[string]bool)
+	var regions []string
+	for _, s := range c.Services {
+		for _, e := range s.Endpoints {
+			if !seen[e.Region] {
+				seen[e.Region] = true
+				regions = append(regions, e.Region)
+			}
+		}
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// RegionServiceURLs returns the public URL of each service type,
+// keyed by region, as found in AuthDetails.RegionServiceURLs.
+func (c *Catalog) RegionServiceURLs() map[string]ServiceURLs {
+	rsu := make(map[string]ServiceURLs)
+	for _, s := range c.Services {
+		for _, e := range s.Endpoints {
+			if e.Interface != InterfacePublic {
+				continue
+			}
+			su, ok := rsu[e.Region]
+			if !ok {
+				su = make(ServiceURLs)
+				rsu[e.Region] = su
+			}
+			su[s.Type] = e.URL
+		}
+	}
+	return rsu
+}
@@ -0,0 +1,59 @@
+package identity
+
+import (
+	gc "gopkg.in/check.v1"
+)
+
+type CatalogSuite struct{}
+
+var _ = gc.Suite(&CatalogSuite{})
+
+var testCatalog = &Catalog{Services: []CatalogService{{
+	Id:   "1",
+	Name: "nova",
+	Type: "compute",
+	Endpoints: []CatalogEndpoint{
+		{Id: "e1", Interface: InterfacePublic, Region: "RegionTwo", URL: "http://nova.two"},
+		{Id: "e2", Interface: InterfaceInternal, Region: "RegionTwo", URL: "http://int.nova.two"},
+		{Id: "e3", Interface: InterfacePublic, Region: "RegionOne", URL: "http://nova.one"},
+	},
+}, {
+	Id:   "2",
+	Name: "swift",
+	Type: "object-store",
+	Endpoints: []CatalogEndpoint{
+		{Id: "e4", Interface: InterfaceAdmin, Region: "RegionOne", URL: "http://admin.swift"},
+	},
+}}}
+
+func (s *CatalogSuite) TestServicesByType(c *gc.C) {
+	services := testCatalog.ServicesByType("object-store")
+	c.Assert(services, gc.HasLen, 1)
+	c.Assert(services[0].Name, gc.Equals, "swift")
+	c.Assert(testCatalog.ServicesByType("volume"), gc.HasLen, 0)
+	c.Assert(testCatalog.ServiceTypes(), gc.DeepEquals, []string{"compute", "object-store"})
+}
+
+func (s *CatalogSuite) TestEndpoints(c *gc.C) {
+	ids := func(endpoints []CatalogEndpoint) []string {
+		var ids []string
+		for _, e := range endpoints {
+			ids = append(ids, e.Id)
+		}
+		return ids
+	}
+	c.Assert(ids(testCatalog.Endpoints("compute", InterfacePublic, "")), gc.DeepEquals, []string{"e1", "e3"})
+	c.Assert(ids(testCatalog.Endpoints("", "", "RegionOne")), gc.DeepEquals, []string{"e3", "e4"})
+	c.Assert(ids(testCatalog.Endpoints("compute", InterfaceAdmin, "")), gc.HasLen, 0)
+}
+
+func (s *CatalogSuite) TestRegions(c *gc.C) {
+	c.Assert(testCatalog.Regions(), gc.DeepEquals, []string{"RegionOne", "RegionTwo"})
+}
+
+func (s *CatalogSuite) TestRegionServiceURLs(c *gc.C) {
+	c.Assert(testCatalog.RegionServiceURLs(), gc.DeepEquals, map[string]ServiceURLs{
+		"RegionOne": {"compute": "http://nova.one"},
+		"RegionTwo": {"compute": "http://nova.two"},
+	})
+}
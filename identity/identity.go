@@ -23,6 +23,7 @@ const (
 	AuthUserPass                    // Username + password authentication
 	AuthKeyPair                     // Access/secret key pair authentication
 	AuthUserPassV3                  // Username + password authentication (v3 API)
+	AuthSwiftV1                     // Swift v1 (TempAuth) authentication, for standalone Swift
 )
 
 func (a AuthMode) String() string {
@@ -35,6 +36,8 @@ func (a AuthMode) String() string {
 		return "Username/password Authentication"
 	case AuthUserPassV3:
 		return "Username/password Authentication (Version 3)"
+	case AuthSwiftV1:
+		return "Swift Authentication (Version 1)"
 	}
 	panic(fmt.Errorf("Unknown athentication type: %d", a))
 }
@@ -57,6 +60,8 @@ type AuthDetails struct {
 	UserId            string
 	Domain            string
 	RegionServiceURLs map[string]ServiceURLs // Service type to endpoint URLs for each region
+	Catalog           *Catalog               // The full service catalog, nil for legacy authentication
+	TrustId           string                 // The trust the token is scoped to, if any (keystone v3)
 }
 
 // Credentials defines necessary parameters for authentication.
@@ -72,6 +77,21 @@ type Credentials struct {
 	Domain        string `credentials:"optional"` // The domain for authorization (new in keystone v3)
 	UserDomain    string `credentials:"optional"` // The owning domain for this user (new in keystone v3)
 	ProjectDomain string `credentials:"optional"` // The project domain for authorization (new in keystone v3)
+	TrustId       string `credentials:"optional"` // The trust to scope authorization to (keystone v3)
+
+	// Application credentials may be used in place of User and
+	// Secrets with keystone v3. A credential referred to by name
+	// must be accompanied by its owner's User and UserDomain.
+	ApplicationCredentialId     string `credentials:"optional"`
+	ApplicationCredentialName   string `credentials:"optional"`
+	ApplicationCredentialSecret string `credentials:"optional"`
+
+	// Paths to a PEM encoded client certificate and key to present,
+	// and a CA bundle to verify servers with, for clouds requiring
+	// mutual TLS. See UpdateTLSConfig.
+	ClientCert string `credentials:"optional"`
+	ClientKey  string `credentials:"optional"`
+	CACert     string `credentials:"optional"`
 }
 
 // Authenticator is implemented by each authentication method.
@@ -153,6 +173,37 @@ var (
 	CredEnvDomainName = []string{
 		"OS_DOMAIN_NAME",
 	}
+	// CredEnvTrustId is used for Credentials.TrustId.
+	CredEnvTrustId = []string{
+		"OS_TRUST_ID",
+	}
+	// CredEnvApplicationCredentialId is used for
+	// Credentials.ApplicationCredentialId.
+	CredEnvApplicationCredentialId = []string{
+		"OS_APPLICATION_CREDENTIAL_ID",
+	}
+	// CredEnvApplicationCredentialName is used for
+	// Credentials.ApplicationCredentialName.
+	CredEnvApplicationCredentialName = []string{
+		"OS_APPLICATION_CREDENTIAL_NAME",
+	}
+	// CredEnvApplicationCredentialSecret is used for
+	// Credentials.ApplicationCredentialSecret.
+	CredEnvApplicationCredentialSecret = []string{
+		"OS_APPLICATION_CREDENTIAL_SECRET",
+	}
+	// CredEnvClientCert is used for Credentials.ClientCert.
+	CredEnvClientCert = []string{
+		"OS_CERT",
+	}
+	// CredEnvClientKey is used for Credentials.ClientKey.
+	CredEnvClientKey = []string{
+		"OS_KEY",
+	}
+	// CredEnvCACert is used for Credentials.CACert.
+	CredEnvCACert = []string{
+		"OS_CACERT",
+	}
 )
 
 // CredentialsFromEnv creates and initializes the credentials from the
@@ -168,6 +219,15 @@ func CredentialsFromEnv() (*Credentials, error) {
 		Domain:        getConfig(CredEnvDomainName),
 		UserDomain:    getConfig(CredEnvUserDomainName),
 		ProjectDomain: getConfig(CredEnvProjectDomainName),
+		TrustId:       getConfig(CredEnvTrustId),
+
+		ApplicationCredentialId:     getConfig(CredEnvApplicationCredentialId),
+		ApplicationCredentialName:   getConfig(CredEnvApplicationCredentialName),
+		ApplicationCredentialSecret: getConfig(CredEnvApplicationCredentialSecret),
+
+		ClientCert: getConfig(CredEnvClientCert),
+		ClientKey:  getConfig(CredEnvClientKey),
+		CACert:     getConfig(CredEnvCACert),
 	}
 	defaultDomain := getConfig(CredEnvDefaultDomainName)
 	if defaultDomain != "" {
@@ -191,19 +251,29 @@ func CredentialsFromEnv() (*Credentials, error) {
 }
 
 // CompleteCredentialsFromEnv gets and verifies all the required
-// authentication parameters have values in the environment.
+// authentication parameters have values in the environment. Secrets
+// are not required with an application credential, nor is User if
+// the application credential is given by ID.
 func CompleteCredentialsFromEnv() (cred *Credentials, err error) {
 	cred, err = CredentialsFromEnv()
 	if err != nil {
 		return &Credentials{}, err
 	}
+	// An application credential replaces the secrets of its user,
+	// and the user too when it is referred to by ID.
+	appCred := cred.ApplicationCredentialSecret != "" &&
+		(cred.ApplicationCredentialId != "" || cred.ApplicationCredentialName != "")
 	v := reflect.ValueOf(cred).Elem()
 	t := v.Type()
 	for i := 0; i < v.NumField(); i++ {
 		f := v.Field(i)
+		name := t.Field(i).Name
 		tag := t.Field(i).Tag.Get("credentials")
+		if appCred && (name == "Secrets" || name == "User" && cred.ApplicationCredentialId != "") {
+			tag = "optional"
+		}
 		if f.String() == "" && tag != "optional" {
-			err = fmt.Errorf("required environment variable not set for credentials attribute: %s", t.Field(i).Name)
+			err = fmt.Errorf("required environment variable not set for credentials attribute: %s", name)
 		}
 	}
 	return
@@ -227,6 +297,8 @@ func NewAuthenticator(authMode AuthMode, httpClient goosehttp.HttpClient) Authen
 		return &KeyPair{client: httpClient}
 	case AuthUserPassV3:
 		return &V3UserPass{client: httpClient}
+	case AuthSwiftV1:
+		return &SwiftV1{client: httpClient}
 	}
 }
 
@@ -191,6 +191,42 @@ func (s *CredentialsTestSuite) TestCompleteCredentialsFromEnvProjectID(c *gc.C)
 	c.Check(creds.TenantName, gc.Equals, "")
 }
 
+func (s *CredentialsTestSuite) TestCredentialsFromEnvTrustAndApplicationCredential(c *gc.C) {
+	env := map[string]string{
+		"OS_AUTH_URL":                      "http://auth",
+		"OS_TRUST_ID":                      "trust-id",
+		"OS_APPLICATION_CREDENTIAL_ID":     "app-cred-id",
+		"OS_APPLICATION_CREDENTIAL_NAME":   "app-cred-name",
+		"OS_APPLICATION_CREDENTIAL_SECRET": "app-cred-secret",
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+	creds, err := CredentialsFromEnv()
+	c.Assert(err, gc.IsNil)
+	c.Check(creds.TrustId, gc.Equals, "trust-id")
+	c.Check(creds.ApplicationCredentialId, gc.Equals, "app-cred-id")
+	c.Check(creds.ApplicationCredentialName, gc.Equals, "app-cred-name")
+	c.Check(creds.ApplicationCredentialSecret, gc.Equals, "app-cred-secret")
+}
+
+func (s *CredentialsTestSuite) TestCredentialsFromEnvTLS(c *gc.C) {
+	env := map[string]string{
+		"OS_CERT":   "/path/to/client.crt",
+		"OS_KEY":    "/path/to/client.key",
+		"OS_CACERT": "/path/to/ca.crt",
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+	creds, err := CredentialsFromEnv()
+	c.Assert(err, gc.IsNil)
+	c.Check(creds.ClientCert, gc.Equals, "/path/to/client.crt")
+	c.Check(creds.ClientKey, gc.Equals, "/path/to/client.key")
+	c.Check(creds.CACert, gc.Equals, "/path/to/ca.crt")
+	c.Check(creds.HasTLSConfig(), gc.Equals, true)
+}
+
 // An error is returned if not all required environment variables are set.
 func (s *CredentialsTestSuite) TestCompleteCredentialsFromEnvInvalid(c *gc.C) {
 	env := map[string]string{
@@ -209,6 +245,35 @@ func (s *CredentialsTestSuite) TestCompleteCredentialsFromEnvInvalid(c *gc.C) {
 	c.Assert(err.Error(), gc.Matches, "required environment variable not set.*: Secrets")
 }
 
+func (s *CredentialsTestSuite) TestCompleteCredentialsFromEnvApplicationCredential(c *gc.C) {
+	env := map[string]string{
+		"OS_AUTH_URL":                      "http://auth",
+		"OS_USERNAME":                      "",
+		"OS_PASSWORD":                      "",
+		"OS_REGION_NAME":                   "region",
+		"OS_APPLICATION_CREDENTIAL_ID":     "app-cred-id",
+		"OS_APPLICATION_CREDENTIAL_SECRET": "app-cred-secret",
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+	creds, err := CompleteCredentialsFromEnv()
+	c.Assert(err, gc.IsNil)
+	c.Check(creds.User, gc.Equals, "")
+	c.Check(creds.Secrets, gc.Equals, "")
+	c.Check(creds.ApplicationCredentialId, gc.Equals, "app-cred-id")
+	c.Check(creds.ApplicationCredentialSecret, gc.Equals, "app-cred-secret")
+
+	// A credential referred to by name needs its user.
+	os.Setenv("OS_APPLICATION_CREDENTIAL_ID", "")
+	os.Setenv("OS_APPLICATION_CREDENTIAL_NAME", "app-cred-name")
+	_, err = CompleteCredentialsFromEnv()
+	c.Assert(err, gc.ErrorMatches, "required environment variable not set.*: User")
+	os.Setenv("OS_USERNAME", "test-user")
+	_, err = CompleteCredentialsFromEnv()
+	c.Assert(err, gc.IsNil)
+}
+
 func (s *CredentialsTestSuite) TestCompleteCredentialsFromEnvKeypair(c *gc.C) {
 	env := map[string]string{
 		"OS_AUTH_URL":     "http://auth",
@@ -341,6 +406,14 @@ func (s *NewAuthenticatorSuite) TestLegacyCustomHTTPClient(c *gc.C) {
 	c.Assert(legacyAuth.client, gc.Equals, httpClient)
 }
 
+func (s *NewAuthenticatorSuite) TestSwiftV1CustomHTTPClient(c *gc.C) {
+	httpClient := goosehttp.New()
+	auth := NewAuthenticator(AuthSwiftV1, httpClient)
+	swiftAuth, ok := auth.(*SwiftV1)
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(swiftAuth.client, gc.Equals, httpClient)
+}
+
 func (s *NewAuthenticatorSuite) TestUnknownMode(c *gc.C) {
 	c.Assert(func() { NewAuthenticator(1235, nil) },
 		gc.PanicMatches, "Invalid identity authorisation mode: 1235")
@@ -8,6 +8,7 @@ import (
 )
 
 type endpoint struct {
+	Id          string `json:"id"`
 	AdminURL    string `json:"adminURL"`
 	InternalURL string `json:"internalURL"`
 	PublicURL   string `json:"publicURL"`
@@ -20,6 +21,30 @@ type serviceResponse struct {
 	Endpoints []endpoint
 }
 
+// catalogService converts the v2 service to its catalog form, with
+// one endpoint for each non-empty interface URL.
+func (s serviceResponse) catalogService() CatalogService {
+	service := CatalogService{Name: s.Name, Type: s.Type}
+	for _, e := range s.Endpoints {
+		for _, u := range []struct{ iface, url string }{
+			{InterfacePublic, e.PublicURL},
+			{InterfaceInternal, e.InternalURL},
+			{InterfaceAdmin, e.AdminURL},
+		} {
+			if u.url == "" {
+				continue
+			}
+			service.Endpoints = append(service.Endpoints, CatalogEndpoint{
+				Id:        e.Id,
+				Interface: u.iface,
+				Region:    e.Region,
+				URL:       u.url,
+			})
+		}
+	}
+	return service
+}
+
 type tokenResponse struct {
 	Expires string `json:"expires"`
 	Id      string `json:"id"` // Actual token string
@@ -76,8 +101,10 @@ func keystoneAuth(client goosehttp.HttpClient, auth_data interface{}, URL string
 	details.Token = respToken.Id
 	details.TenantId = respToken.Tenant.Id
 	details.UserId = access.User.Id
+	details.Catalog = &Catalog{}
 	details.RegionServiceURLs = make(map[string]ServiceURLs, len(access.ServiceCatalog))
 	for _, service := range access.ServiceCatalog {
+		details.Catalog.Services = append(details.Catalog.Services, service.catalogService())
 		for i, e := range service.Endpoints {
 			endpointURLs, ok := details.RegionServiceURLs[e.Region]
 			if !ok {
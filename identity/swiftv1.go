@@ -0,0 +1,80 @@
+package identity
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	gooseerrors "github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+// SwiftV1 is an Authenticator for standalone Swift clusters using v1
+// (TempAuth style) authentication. The user is usually given in the
+// "account:user" form, and the secret is the user's key.
+type SwiftV1 struct {
+	client goosehttp.HttpClient
+}
+
+// Auth performs a GET request against creds.URL, normally ending in
+// /auth/v1.0, and returns the token and storage URL from the response.
+// The storage URL is the only service URL, of type "object-store",
+// and is in creds.Region.
+func (s *SwiftV1) Auth(creds *Credentials) (*AuthDetails, error) {
+	if s.client == nil {
+		s.client = goosehttp.New()
+	}
+
+	request, err := http.NewRequest("GET", creds.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("X-Auth-User", creds.User)
+	request.Header.Set("X-Auth-Key", creds.Secrets)
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+	case http.StatusUnauthorized:
+		return nil, gooseerrors.NewUnauthorisedf(nil, "", "invalid Swift credentials for user %q", creds.User)
+	default:
+		content, _ := ioutil.ReadAll(response.Body)
+		return nil, fmt.Errorf("Failed to Authenticate (code %d %s): %s",
+			response.StatusCode, response.Status, content)
+	}
+
+	token := response.Header.Get("X-Auth-Token")
+	if token == "" {
+		// Older TempAuth deployments only send X-Storage-Token.
+		token = response.Header.Get("X-Storage-Token")
+	}
+	if token == "" {
+		return nil, gooseerrors.NewUnauthorisedf(nil, "", "Did not get valid Token from auth request")
+	}
+	storageURL := response.Header.Get("X-Storage-Url")
+	if storageURL == "" {
+		return nil, fmt.Errorf("Did not get valid swift storage URL from auth request")
+	}
+
+	// Swift v1 authentication has no notion of regions, so the
+	// storage URL is placed in the region of the credentials.
+	catalog := &Catalog{Services: []CatalogService{{
+		Name: "swift",
+		Type: "object-store",
+		Endpoints: []CatalogEndpoint{{
+			Interface: InterfacePublic,
+			Region:    creds.Region,
+			URL:       storageURL,
+		}},
+	}}}
+	return &AuthDetails{
+		Token:             token,
+		RegionServiceURLs: catalog.RegionServiceURLs(),
+		Catalog:           catalog,
+	}, nil
+}
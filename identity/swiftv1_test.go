@@ -0,0 +1,77 @@
+package identity
+
+import (
+	gc "gopkg.in/check.v1"
+
+	"github.com/go-goose/goose/v5/errors"
+	"github.com/go-goose/goose/v5/testing/httpsuite"
+	"github.com/go-goose/goose/v5/testservices/identityservice"
+)
+
+type SwiftV1TestSuite struct {
+	httpsuite.HTTPSuite
+}
+
+var _ = gc.Suite(&SwiftV1TestSuite{})
+
+func (s *SwiftV1TestSuite) TestAuthAgainstServer(c *gc.C) {
+	service := identityservice.NewSwiftV1()
+	service.SetupHTTP(s.Mux)
+	userInfo := service.AddUser("account:joe-user", "secrets", "", "")
+	service.SetStorageURL("http://swift.test.invalid/v1/AUTH_account")
+
+	var l Authenticator = &SwiftV1{}
+	creds := Credentials{User: "account:joe-user", URL: s.Server.URL + "/auth/v1.0", Secrets: "secrets"}
+	auth, err := l.Auth(&creds)
+	c.Assert(err, gc.IsNil)
+	c.Assert(auth.Token, gc.Equals, userInfo.Token)
+	c.Assert(auth.RegionServiceURLs, gc.DeepEquals, map[string]ServiceURLs{
+		"": {"object-store": "http://swift.test.invalid/v1/AUTH_account"},
+	})
+	c.Assert(auth.Catalog.ServiceTypes(), gc.DeepEquals, []string{"object-store"})
+}
+
+func (s *SwiftV1TestSuite) TestAuthUsesCredentialsRegion(c *gc.C) {
+	service := identityservice.NewSwiftV1()
+	service.SetupHTTP(s.Mux)
+	service.AddUser("account:joe-user", "secrets", "", "")
+	service.SetStorageURL("http://swift.test.invalid/v1/AUTH_account")
+
+	var l Authenticator = &SwiftV1{}
+	creds := Credentials{
+		User:    "account:joe-user",
+		URL:     s.Server.URL + "/auth/v1.0",
+		Secrets: "secrets",
+		Region:  "RegionOne",
+	}
+	auth, err := l.Auth(&creds)
+	c.Assert(err, gc.IsNil)
+	c.Assert(auth.RegionServiceURLs, gc.DeepEquals, map[string]ServiceURLs{
+		"RegionOne": {"object-store": "http://swift.test.invalid/v1/AUTH_account"},
+	})
+	c.Assert(auth.Catalog.Regions(), gc.DeepEquals, []string{"RegionOne"})
+}
+
+func (s *SwiftV1TestSuite) TestBadAuth(c *gc.C) {
+	service := identityservice.NewSwiftV1()
+	service.SetupHTTP(s.Mux)
+	service.AddUser("account:joe-user", "secrets", "", "")
+
+	var l Authenticator = &SwiftV1{}
+	creds := Credentials{User: "account:joe-user", URL: s.Server.URL + "/auth/v1.0", Secrets: "bad-secrets"}
+	auth, err := l.Auth(&creds)
+	c.Assert(err, gc.ErrorMatches, `invalid Swift credentials for user "account:joe-user"`)
+	c.Assert(errors.IsUnauthorised(err), gc.Equals, true)
+	c.Assert(auth, gc.IsNil)
+}
+
+func (s *SwiftV1TestSuite) TestMissingStorageURL(c *gc.C) {
+	service := identityservice.NewSwiftV1()
+	service.SetupHTTP(s.Mux)
+	service.AddUser("account:joe-user", "secrets", "", "")
+
+	var l Authenticator = &SwiftV1{}
+	creds := Credentials{User: "account:joe-user", URL: s.Server.URL + "/auth/v1.0", Secrets: "secrets"}
+	_, err := l.Auth(&creds)
+	c.Assert(err, gc.ErrorMatches, "Did not get valid swift storage URL from auth request")
+}
@@ -0,0 +1,57 @@
+package identity
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// HasTLSConfig reports whether the credentials specify a client
+// certificate or CA bundle to use when connecting to OpenStack.
+func (c *Credentials) HasTLSConfig() bool {
+	return c.ClientCert != "" || c.ClientKey != "" || c.CACert != ""
+}
+
+// UpdateTLSConfig loads the client certificate, key and CA bundle
+// named by the credentials and adds them to config. The client
+// certificate is appended to config.Certificates, and the CA bundle
+// replaces config.RootCAs.
+func (c *Credentials) UpdateTLSConfig(config *tls.Config) error {
+	if (c.ClientCert == "") != (c.ClientKey == "") {
+		return fmt.Errorf("a client certificate and key must be specified together")
+	}
+	if c.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(c.ClientCert, c.ClientKey)
+		if err != nil {
+			return fmt.Errorf("cannot load client certificate: %v", err)
+		}
+		config.Certificates = append(config.Certificates, cert)
+	}
+	if c.CACert != "" {
+		pem, err := ioutil.ReadFile(c.CACert)
+		if err != nil {
+			return fmt.Errorf("cannot read CA certificates: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no CA certificates found in %q", c.CACert)
+		}
+		config.RootCAs = pool
+	}
+	return nil
+}
+
+// TLSConfig returns a new TLS configuration using the client
+// certificate, key and CA bundle named by the credentials, or nil
+// if the credentials do not specify any.
+func (c *Credentials) TLSConfig() (*tls.Config, error) {
+	if !c.HasTLSConfig() {
+		return nil, nil
+	}
+	config := &tls.Config{}
+	if err := c.UpdateTLSConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
@@ -56,7 +56,7 @@ func (s *UserPassTestSuite) TestRegionMatch(c *gc.C) {
 		Name: "nova",
 		Type: "compute",
 		Endpoints: []identityservice.Endpoint{
-			{PublicURL: "http://nova2", Region: "zone2.RegionOne"},
+			{PublicURL: "http://nova2", AdminURL: "http://admin.nova2", Region: "zone2.RegionOne"},
 		}}
 	service.AddService(identityservice.Service{V2: serviceDef})
 
@@ -76,4 +76,11 @@ func (s *UserPassTestSuite) TestRegionMatch(c *gc.C) {
 	c.Assert(auth.RegionServiceURLs["zone2.RegionOne"]["compute"], gc.Equals, "http://nova2")
 	c.Assert(auth.Token, gc.Equals, userInfo.Token)
 	c.Assert(auth.TenantId, gc.Equals, userInfo.TenantId)
+
+	c.Assert(auth.Catalog, gc.NotNil)
+	c.Assert(auth.Catalog.ServiceTypes(), gc.DeepEquals, []string{"compute", "object-store"})
+	c.Assert(auth.Catalog.Endpoints("compute", "", "zone2.RegionOne"), gc.DeepEquals, []CatalogEndpoint{
+		{Interface: InterfacePublic, Region: "zone2.RegionOne", URL: "http://nova2"},
+		{Interface: InterfaceAdmin, Region: "zone2.RegionOne", URL: "http://admin.nova2"},
+	})
 }
@@ -23,9 +23,10 @@ type v3AuthRequest struct {
 // v3AuthIdentity contains the identity portion of an authentication
 // request.
 type v3AuthIdentity struct {
-	Methods  []string        `json:"methods"`
-	Password *v3AuthPassword `json:"password,omitempty"`
-	Token    *v3AuthToken    `json:"token,omitempty"`
+	Methods               []string                     `json:"methods"`
+	Password              *v3AuthPassword              `json:"password,omitempty"`
+	Token                 *v3AuthToken                 `json:"token,omitempty"`
+	ApplicationCredential *v3AuthApplicationCredential `json:"application_credential,omitempty"`
 }
 
 // v3AuthPassword contains a password authentication request.
@@ -42,6 +43,23 @@ type v3AuthUser struct {
 	Password string        `json:"password"`
 }
 
+// v3AuthApplicationCredential contains an application credential
+// authentication request. An application credential referred to by
+// name must also identify its owning user.
+type v3AuthApplicationCredential struct {
+	ID     string         `json:"id,omitempty"`
+	Name   string         `json:"name,omitempty"`
+	Secret string         `json:"secret"`
+	User   *v3AuthUserRef `json:"user,omitempty"`
+}
+
+// v3AuthUserRef identifies a user without authenticating them.
+type v3AuthUserRef struct {
+	Domain *v3AuthDomain `json:"domain,omitempty"`
+	ID     string        `json:"id,omitempty"`
+	Name   string        `json:"name,omitempty"`
+}
+
 // v3AuthDomain contains a domain definition of an authentication
 // request.
 type v3AuthDomain struct {
@@ -58,6 +76,12 @@ type v3AuthToken struct {
 type v3AuthScope struct {
 	Domain  *v3AuthDomain  `json:"domain,omitempty"`
 	Project *v3AuthProject `json:"project,omitempty"`
+	Trust   *v3AuthTrust   `json:"OS-TRUST:trust,omitempty"`
+}
+
+// v3AuthTrust contains the trust scope for the authentication request.
+type v3AuthTrust struct {
+	ID string `json:"id"`
 }
 
 // v3AuthProject contains the project scope for the authentication
@@ -69,17 +93,23 @@ type v3AuthProject struct {
 }
 
 // V3UserPass is an Authenticator that will perform username + password
-// authentication using the v3 protocol.
+// authentication using the v3 protocol. If the credentials hold an
+// application credential, that is used in place of the username and
+// password.
 type V3UserPass struct {
 	client goosehttp.HttpClient
 }
 
 // Auth performs a v3 username + password authentication request using
-// the values supplied in creds.
+// the values supplied in creds. If creds.TrustId is set, the token is
+// scoped to that trust rather than to a project or domain.
 func (u *V3UserPass) Auth(creds *Credentials) (*AuthDetails, error) {
 	if u.client == nil {
 		u.client = goosehttp.New()
 	}
+	if creds.ApplicationCredentialId != "" || creds.ApplicationCredentialName != "" {
+		return u.applicationCredentialAuth(creds)
+	}
 	userDomain := creds.UserDomain
 	if userDomain == "" {
 		userDomain = "default"
@@ -124,6 +154,51 @@ func (u *V3UserPass) Auth(creds *Credentials) (*AuthDetails, error) {
 		}
 	}
 
+	// A trust determines the project itself, so the trust
+	// scope replaces any project or domain scope.
+	if creds.TrustId != "" {
+		auth.Auth.Scope = &v3AuthScope{
+			Trust: &v3AuthTrust{
+				ID: creds.TrustId,
+			},
+		}
+	}
+
+	return v3KeystoneAuth(u.client, &auth, creds.URL)
+}
+
+// applicationCredentialAuth performs a v3 application credential
+// authentication request. Application credentials are always scoped
+// to the project they were created in, so no scope is requested.
+func (u *V3UserPass) applicationCredentialAuth(creds *Credentials) (*AuthDetails, error) {
+	if creds.TrustId != "" {
+		return nil, fmt.Errorf("application credentials cannot be used to obtain a trust scoped token")
+	}
+	appCred := &v3AuthApplicationCredential{
+		ID:     creds.ApplicationCredentialId,
+		Secret: creds.ApplicationCredentialSecret,
+	}
+	if appCred.ID == "" {
+		userDomain := creds.UserDomain
+		if userDomain == "" {
+			userDomain = "default"
+		}
+		appCred.Name = creds.ApplicationCredentialName
+		appCred.User = &v3AuthUserRef{
+			Domain: &v3AuthDomain{
+				Name: userDomain,
+			},
+			Name: creds.User,
+		}
+	}
+	auth := v3AuthWrapper{
+		Auth: v3AuthRequest{
+			Identity: v3AuthIdentity{
+				Methods:               []string{"application_credential"},
+				ApplicationCredential: appCred,
+			},
+		},
+	}
 	return v3KeystoneAuth(u.client, &auth, creds.URL)
 }
 
@@ -141,6 +216,13 @@ type v3Token struct {
 	Project v3TokenProject   `json:"project"`
 	Domain  v3TokenDomain    `json:"domain"`
 	User    v3TokenUser      `json:"user"`
+	Trust   *v3TokenTrust    `json:"OS-TRUST:trust"`
+}
+
+// v3TokenTrust describes the trust a token is scoped to.
+type v3TokenTrust struct {
+	ID            string `json:"id"`
+	Impersonation bool   `json:"impersonation"`
 }
 
 type v3TokenCatalog struct {
@@ -191,26 +273,31 @@ func v3KeystoneAuth(c goosehttp.HttpClient, v interface{}, url string) (*AuthDet
 	if tok == "" {
 		return nil, gooseerrors.NewUnauthorisedf(nil, "", "empty auth token received.")
 	}
-	rsu := make(map[string]ServiceURLs, len(resp.Token.Catalog))
+	catalog := &Catalog{}
 	for _, s := range resp.Token.Catalog {
+		service := CatalogService{Id: s.ID, Name: s.Name, Type: s.Type}
 		for _, ep := range s.Endpoints {
-			if ep.Interface != "public" {
-				continue
-			}
-			su, ok := rsu[ep.RegionID]
-			if !ok {
-				su = make(ServiceURLs)
-				rsu[ep.RegionID] = su
-			}
-			su[s.Type] = ep.URL
+			service.Endpoints = append(service.Endpoints, CatalogEndpoint{
+				Id:        ep.ID,
+				Interface: ep.Interface,
+				Region:    ep.RegionID,
+				URL:       ep.URL,
+			})
 		}
+		catalog.Services = append(catalog.Services, service)
+	}
+	var trustId string
+	if resp.Token.Trust != nil {
+		trustId = resp.Token.Trust.ID
 	}
 	return &AuthDetails{
+		TrustId:           trustId,
 		Token:             tok,
 		TenantId:          resp.Token.Project.ID,
 		TenantName:        resp.Token.Project.Name,
 		UserId:            resp.Token.User.ID,
 		Domain:            resp.Token.Domain.Name,
-		RegionServiceURLs: rsu,
+		RegionServiceURLs: catalog.RegionServiceURLs(),
+		Catalog:           catalog,
 	}, nil
 }
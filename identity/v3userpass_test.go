@@ -122,6 +122,105 @@ func (s *V3UserPassTestSuite) TestAuthWithCatalog(c *gc.C) {
 	c.Assert(auth.RegionServiceURLs["zone2.RegionOne"]["compute"], gc.Equals, "http://nova2")
 	c.Assert(auth.Token, gc.Equals, userInfo.Token)
 	c.Assert(auth.TenantId, gc.Equals, userInfo.TenantId)
+
+	c.Assert(auth.Catalog, gc.NotNil)
+	c.Assert(auth.Catalog.ServicesByType("compute"), gc.HasLen, 2)
+	c.Assert(auth.Catalog.Regions(), gc.DeepEquals, []string{"RegionOne", "zone1.RegionOne", "zone2.RegionOne"})
+	c.Assert(auth.Catalog.Endpoints("compute", InterfaceInternal, ""), gc.DeepEquals, []CatalogEndpoint{
+		{Interface: InterfaceInternal, Region: "zone2.RegionOne", URL: "http://int.nova2"},
+	})
+}
+
+func (s *V3UserPassTestSuite) TestAuthWithTrust(c *gc.C) {
+	service := identityservice.NewV3UserPass()
+	service.SetupHTTP(s.Mux)
+	trustor := service.AddUser("joe-user", "secrets", "tenant", "default")
+	trustee := service.AddUser("service-user", "service-secrets", "service", "default")
+	trustId, err := service.AddTrust("joe-user", "service-user", "tenant", true)
+	c.Assert(err, gc.IsNil)
+	var l Authenticator = &V3UserPass{}
+	creds := Credentials{
+		User:       "service-user",
+		URL:        s.Server.URL + "/v3/auth/tokens",
+		Secrets:    "service-secrets",
+		TenantName: "service",
+		TrustId:    trustId,
+	}
+	auth, err := l.Auth(&creds)
+	c.Assert(err, gc.IsNil)
+	c.Assert(auth.Token, gc.Equals, trustee.Token)
+	c.Assert(auth.TrustId, gc.Equals, trustId)
+	// The token is scoped to the trustor's project, not the
+	// trustee's own.
+	c.Assert(auth.TenantId, gc.Equals, trustor.TenantId)
+	c.Assert(auth.TenantName, gc.Equals, "tenant")
+}
+
+func (s *V3UserPassTestSuite) TestAuthWithTrustNotTrustee(c *gc.C) {
+	service := identityservice.NewV3UserPass()
+	service.SetupHTTP(s.Mux)
+	service.AddUser("joe-user", "secrets", "tenant", "default")
+	service.AddUser("service-user", "service-secrets", "service", "default")
+	service.AddUser("other-user", "other-secrets", "other", "default")
+	trustId, err := service.AddTrust("joe-user", "service-user", "tenant", false)
+	c.Assert(err, gc.IsNil)
+	var l Authenticator = &V3UserPass{}
+	creds := Credentials{
+		User:    "other-user",
+		URL:     s.Server.URL + "/v3/auth/tokens",
+		Secrets: "other-secrets",
+		TrustId: trustId,
+	}
+	_, err = l.Auth(&creds)
+	c.Assert(err, gc.ErrorMatches, `(?s).*trust .* is not valid for this user.*`)
+}
+
+func (s *V3UserPassTestSuite) TestAuthWithApplicationCredential(c *gc.C) {
+	service := identityservice.NewV3UserPass()
+	service.SetupHTTP(s.Mux)
+	userInfo := service.AddUser("joe-user", "secrets", "tenant", "default")
+	appCredId, err := service.AddApplicationCredential("joe-user", "automation", "app-secret")
+	c.Assert(err, gc.IsNil)
+	var l Authenticator = &V3UserPass{}
+
+	// By ID.
+	creds := Credentials{
+		URL:                         s.Server.URL + "/v3/auth/tokens",
+		ApplicationCredentialId:     appCredId,
+		ApplicationCredentialSecret: "app-secret",
+	}
+	auth, err := l.Auth(&creds)
+	c.Assert(err, gc.IsNil)
+	c.Assert(auth.Token, gc.Equals, userInfo.Token)
+	c.Assert(auth.TenantId, gc.Equals, userInfo.TenantId)
+
+	// By name and owner.
+	creds = Credentials{
+		URL:                         s.Server.URL + "/v3/auth/tokens",
+		User:                        "joe-user",
+		ApplicationCredentialName:   "automation",
+		ApplicationCredentialSecret: "app-secret",
+	}
+	auth, err = l.Auth(&creds)
+	c.Assert(err, gc.IsNil)
+	c.Assert(auth.Token, gc.Equals, userInfo.Token)
+
+	// With the wrong secret.
+	creds.ApplicationCredentialSecret = "wrong"
+	_, err = l.Auth(&creds)
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *V3UserPassTestSuite) TestAuthWithApplicationCredentialAndTrust(c *gc.C) {
+	var l Authenticator = &V3UserPass{}
+	creds := Credentials{
+		URL:                         s.Server.URL + "/v3/auth/tokens",
+		ApplicationCredentialId:     "app-cred-id",
+		ApplicationCredentialSecret: "app-secret",
+		TrustId:                     "trust-id",
+	}
+	_, err := l.Auth(&creds)
+	c.Assert(err, gc.ErrorMatches, "application credentials cannot be used to obtain a trust scoped token")
 }
 
 func (s *V3UserPassTestSuite) TestAuthToDomainwithTenantNameAndTenantID(c *gc.C) {
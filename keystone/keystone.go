@@ -0,0 +1,373 @@
+// goose/keystone - Go package to interact with the OpenStack Identity Service (Keystone) API V3.
+// See documentation at:
+// https://docs.openstack.org/api-ref/identity/v3/index.html
+
+package keystone
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+// API URL parts.
+const (
+	apiDomains         = "domains"
+	apiProjects        = "projects"
+	apiUsers           = "users"
+	apiGroups          = "groups"
+	apiRoles           = "roles"
+	apiRoleAssignments = "role_assignments"
+	apiRegions         = "regions"
+	apiTrusts          = "OS-TRUST/trusts"
+)
+
+// Filter keys.
+const (
+	FilterName     = "name"      // The resource name.
+	FilterDomainId = "domain_id" // The ID of the owning domain.
+	FilterEnabled  = "enabled"   // Whether the resource is enabled ("true" or "false").
+	FilterParentId = "parent_id" // The ID of the parent project.
+	FilterIsDomain = "is_domain" // Whether the project acts as a domain.
+	FilterLimit    = "limit"     // The page size, where the deployment supports it.
+
+	FilterParentRegionId = "parent_region_id" // The ID of the parent region.
+)
+
+// Filter keys for role assignments.
+const (
+	FilterAssignmentUser           = "user.id"
+	FilterAssignmentGroup          = "group.id"
+	FilterAssignmentRole           = "role.id"
+	FilterAssignmentProject        = "scope.project.id"
+	FilterAssignmentDomain         = "scope.domain.id"
+	FilterAssignmentEffective      = "effective"       // Expand group memberships and inheritance.
+	FilterAssignmentIncludeNames   = "include_names"   // Include entity names in the response.
+	FilterAssignmentIncludeSubtree = "include_subtree" // Include assignments on the project's subtree.
+)
+
+// Client provides a means to access the OpenStack Identity Service.
+type Client struct {
+	client client.Client
+}
+
+// New creates a new Client.
+func New(client client.Client) *Client {
+	return &Client{client}
+}
+
+// Filtering helper.
+//
+// Filter builds filtering parameters to be used in a Keystone list query.
+// For example:
+//
+//	filter := NewFilter()
+//	filter.Set(keystone.FilterDomainId, domainId)
+//	filter.Set(keystone.FilterEnabled, "true")
+//	projects, err := client.ListProjects(filter)
+type Filter struct {
+	v url.Values
+}
+
+// NewFilter creates a new Filter.
+func NewFilter() *Filter {
+	return &Filter{make(url.Values)}
+}
+
+// Set sets a value in the filter.
+func (f *Filter) Set(filter, value string) {
+	f.v.Set(filter, value)
+}
+
+// values returns a copy of the filter's query parameters, so that
+// following pagination links does not modify the caller's filter.
+func (f *Filter) values() url.Values {
+	v := make(url.Values)
+	if f != nil {
+		for key, vals := range f.v {
+			v[key] = append([]string(nil), vals...)
+		}
+	}
+	return v
+}
+
+// Links holds the pagination links returned with a list response.
+type Links struct {
+	Self     string `json:"self"`
+	Next     string `json:"next"`
+	Previous string `json:"previous"`
+}
+
+// nextParams returns the query parameters of the page referred to by
+// next, or nil if there are no more pages.
+func nextParams(next string) (url.Values, error) {
+	if next == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(next)
+	if err != nil {
+		return nil, errors.Newf(err, "invalid next link %q", next)
+	}
+	return u.Query(), nil
+}
+
+// list fetches every page of the collection at apiCall. For each page,
+// newPage must return a fresh response value to decode into, along with
+// a function returning the response's pagination links once decoded.
+func (c *Client) list(apiCall string, filter *Filter, newPage func() (interface{}, func() Links)) error {
+	params := filter.values()
+	seen := make(map[string]bool)
+	for {
+		resp, links := newPage()
+		requestData := goosehttp.RequestData{
+			RespValue:      resp,
+			Params:         &params,
+			ExpectedStatus: []int{http.StatusOK},
+		}
+		if err := c.client.SendRequest(client.GET, "identity", "v3", apiCall, &requestData); err != nil {
+			return err
+		}
+		seen[params.Encode()] = true
+		next, err := nextParams(links().Next)
+		if err != nil {
+			return err
+		}
+		// Guard against servers which return a next link
+		// pointing back at a page we have already fetched.
+		if next == nil || seen[next.Encode()] {
+			return nil
+		}
+		params = next
+	}
+}
+
+// Domain describes a Keystone domain.
+type Domain struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// DomainOpts holds the attributes used to create or update a domain.
+// Zero values are not sent.
+type DomainOpts struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Enabled     *bool  `json:"enabled,omitempty"`
+}
+
+// ListDomains lists domains matching the optional filter.
+func (c *Client) ListDomains(filter *Filter) ([]Domain, error) {
+	var domains []Domain
+	err := c.list(apiDomains, filter, func() (interface{}, func() Links) {
+		var resp struct {
+			Domains []Domain `json:"domains"`
+			Links   Links    `json:"links"`
+		}
+		return &resp, func() Links {
+			domains = append(domains, resp.Domains...)
+			return resp.Links
+		}
+	})
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get list of domains")
+	}
+	return domains, nil
+}
+
+// GetDomain returns the domain with the given ID.
+func (c *Client) GetDomain(domainId string) (*Domain, error) {
+	var resp struct {
+		Domain Domain `json:"domain"`
+	}
+	url := fmt.Sprintf("%s/%s", apiDomains, domainId)
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "identity", "v3", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get domain %q", domainId)
+	}
+	return &resp.Domain, nil
+}
+
+// CreateDomain creates a new domain.
+func (c *Client) CreateDomain(opts DomainOpts) (*Domain, error) {
+	var req struct {
+		Domain DomainOpts `json:"domain"`
+	}
+	req.Domain = opts
+	var resp struct {
+		Domain Domain `json:"domain"`
+	}
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusCreated}}
+	err := c.client.SendRequest(client.POST, "identity", "v3", apiDomains, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to create domain %q", opts.Name)
+	}
+	return &resp.Domain, nil
+}
+
+// UpdateDomain updates the given domain.
+func (c *Client) UpdateDomain(domainId string, opts DomainOpts) (*Domain, error) {
+	var req struct {
+		Domain DomainOpts `json:"domain"`
+	}
+	req.Domain = opts
+	var resp struct {
+		Domain Domain `json:"domain"`
+	}
+	url := fmt.Sprintf("%s/%s", apiDomains, domainId)
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusOK}}
+	err := c.client.SendRequest(client.PATCH, "identity", "v3", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to update domain %q", domainId)
+	}
+	return &resp.Domain, nil
+}
+
+// DeleteDomain deletes the given domain. Keystone only allows
+// disabled domains to be deleted.
+func (c *Client) DeleteDomain(domainId string) error {
+	url := fmt.Sprintf("%s/%s", apiDomains, domainId)
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err := c.client.SendRequest(client.DELETE, "identity", "v3", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete domain %q", domainId)
+	}
+	return err
+}
+
+// Project describes a Keystone project.
+type Project struct {
+	Id          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	DomainId    string   `json:"domain_id"`
+	ParentId    string   `json:"parent_id"`
+	IsDomain    bool     `json:"is_domain"`
+	Enabled     bool     `json:"enabled"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// ProjectOpts holds the attributes used to create or update a project.
+// Zero values are not sent. DomainId and ParentId may only be set
+// when creating a project.
+type ProjectOpts struct {
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	DomainId    string   `json:"domain_id,omitempty"`
+	ParentId    string   `json:"parent_id,omitempty"`
+	IsDomain    *bool    `json:"is_domain,omitempty"`
+	Enabled     *bool    `json:"enabled,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// ListProjects lists projects matching the optional filter.
+func (c *Client) ListProjects(filter *Filter) ([]Project, error) {
+	var projects []Project
+	err := c.list(apiProjects, filter, func() (interface{}, func() Links) {
+		var resp struct {
+			Projects []Project `json:"projects"`
+			Links    Links     `json:"links"`
+		}
+		return &resp, func() Links {
+			projects = append(projects, resp.Projects...)
+			return resp.Links
+		}
+	})
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get list of projects")
+	}
+	return projects, nil
+}
+
+// GetProject returns the project with the given ID.
+func (c *Client) GetProject(projectId string) (*Project, error) {
+	var resp struct {
+		Project Project `json:"project"`
+	}
+	url := fmt.Sprintf("%s/%s", apiProjects, projectId)
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "identity", "v3", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get project %q", projectId)
+	}
+	return &resp.Project, nil
+}
+
+// CreateProject creates a new project.
+func (c *Client) CreateProject(opts ProjectOpts) (*Project, error) {
+	var req struct {
+		Project ProjectOpts `json:"project"`
+	}
+	req.Project = opts
+	var resp struct {
+		Project Project `json:"project"`
+	}
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusCreated}}
+	err := c.client.SendRequest(client.POST, "identity", "v3", apiProjects, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to create project %q", opts.Name)
+	}
+	return &resp.Project, nil
+}
+
+// UpdateProject updates the given project.
+func (c *Client) UpdateProject(projectId string, opts ProjectOpts) (*Project, error) {
+	var req struct {
+		Project ProjectOpts `json:"project"`
+	}
+	req.Project = opts
+	var resp struct {
+		Project Project `json:"project"`
+	}
+	url := fmt.Sprintf("%s/%s", apiProjects, projectId)
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusOK}}
+	err := c.client.SendRequest(client.PATCH, "identity", "v3", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to update project %q", projectId)
+	}
+	return &resp.Project, nil
+}
+
+// DeleteProject deletes the given project.
+func (c *Client) DeleteProject(projectId string) error {
+	url := fmt.Sprintf("%s/%s", apiProjects, projectId)
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err := c.client.SendRequest(client.DELETE, "identity", "v3", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete project %q", projectId)
+	}
+	return err
+}
+
+// Region describes a Keystone region. Regions form a hierarchy
+// through ParentRegionId.
+type Region struct {
+	Id             string `json:"id"`
+	Description    string `json:"description"`
+	ParentRegionId string `json:"parent_region_id"`
+}
+
+// ListRegions lists regions matching the optional filter.
+func (c *Client) ListRegions(filter *Filter) ([]Region, error) {
+	var regions []Region
+	err := c.list(apiRegions, filter, func() (interface{}, func() Links) {
+		var resp struct {
+			Regions []Region `json:"regions"`
+			Links   Links    `json:"links"`
+		}
+		return &resp, func() Links {
+			regions = append(regions, resp.Regions...)
+			return resp.Links
+		}
+	})
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get list of regions")
+	}
+	return regions, nil
+}
@@ -0,0 +1,267 @@
+package keystone_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	gc "gopkg.in/check.v1"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	"github.com/go-goose/goose/v5/keystone"
+	"github.com/go-goose/goose/v5/testing/httpsuite"
+	"github.com/go-goose/goose/v5/testservices/identityservice"
+)
+
+func Test(t *testing.T) {
+	gc.TestingT(t)
+}
+
+type KeystoneSuite struct {
+	httpsuite.HTTPSuite
+	keystone *keystone.Client
+}
+
+var _ = gc.Suite(&KeystoneSuite{})
+
+func (s *KeystoneSuite) SetUpTest(c *gc.C) {
+	s.HTTPSuite.SetUpTest(c)
+	s.keystone = keystone.New(client.NewPublicClient(s.Server.URL, nil))
+}
+
+func (s *KeystoneSuite) handle(c *gc.C, method, path string, status int, body string, check func(*http.Request, []byte)) {
+	s.Mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, gc.Equals, method)
+		data, err := ioutil.ReadAll(r.Body)
+		c.Check(err, gc.IsNil)
+		if check != nil {
+			check(r, data)
+		}
+		if body != "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	})
+}
+
+func (s *KeystoneSuite) TestListProjectsFollowsNextLinks(c *gc.C) {
+	var queries []string
+	s.Mux.HandleFunc("/projects", func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("page") == "" {
+			fmt.Fprintf(w, `{"projects": [{"id": "p1", "name": "one", "domain_id": "d1", "enabled": true}],
+				"links": {"next": "%s/projects?domain_id=d1&page=2"}}`, s.Server.URL)
+			return
+		}
+		fmt.Fprint(w, `{"projects": [{"id": "p2", "name": "two", "domain_id": "d1"}], "links": {"next": null}}`)
+	})
+	filter := keystone.NewFilter()
+	filter.Set(keystone.FilterDomainId, "d1")
+	projects, err := s.keystone.ListProjects(filter)
+	c.Assert(err, gc.IsNil)
+	c.Assert(projects, gc.DeepEquals, []keystone.Project{
+		{Id: "p1", Name: "one", DomainId: "d1", Enabled: true},
+		{Id: "p2", Name: "two", DomainId: "d1"},
+	})
+	c.Assert(queries, gc.DeepEquals, []string{"domain_id=d1", "domain_id=d1&page=2"})
+}
+
+func (s *KeystoneSuite) TestListStopsOnRepeatedNextLink(c *gc.C) {
+	count := 0
+	s.Mux.HandleFunc("/roles", func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"roles": [{"id": "r1", "name": "member"}], "links": {"next": "%s/roles"}}`, s.Server.URL)
+	})
+	roles, err := s.keystone.ListRoles(nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(roles, gc.HasLen, 1)
+	c.Assert(count, gc.Equals, 1)
+}
+
+func (s *KeystoneSuite) TestCreateProject(c *gc.C) {
+	s.handle(c, "POST", "/projects", http.StatusCreated,
+		`{"project": {"id": "p1", "name": "team", "domain_id": "d1", "enabled": false}}`,
+		func(r *http.Request, data []byte) {
+			var req map[string]map[string]interface{}
+			c.Assert(json.Unmarshal(data, &req), gc.IsNil)
+			c.Check(req, gc.DeepEquals, map[string]map[string]interface{}{
+				"project": {"name": "team", "domain_id": "d1", "enabled": false},
+			})
+		})
+	enabled := false
+	project, err := s.keystone.CreateProject(keystone.ProjectOpts{Name: "team", DomainId: "d1", Enabled: &enabled})
+	c.Assert(err, gc.IsNil)
+	c.Assert(project, gc.DeepEquals, &keystone.Project{Id: "p1", Name: "team", DomainId: "d1"})
+}
+
+func (s *KeystoneSuite) TestUpdateDomain(c *gc.C) {
+	s.handle(c, "PATCH", "/domains/d1", http.StatusOK,
+		`{"domain": {"id": "d1", "name": "dom", "description": "new"}}`,
+		func(r *http.Request, data []byte) {
+			c.Check(string(data), gc.Equals, `{"domain":{"description":"new"}}`)
+		})
+	domain, err := s.keystone.UpdateDomain("d1", keystone.DomainOpts{Description: "new"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(domain.Description, gc.Equals, "new")
+}
+
+func (s *KeystoneSuite) TestDeleteUserNotFound(c *gc.C) {
+	s.handle(c, "DELETE", "/users/u1", http.StatusNotFound, `{"error": {"code": 404}}`, nil)
+	err := s.keystone.DeleteUser("u1")
+	c.Assert(err, gc.ErrorMatches, `(?s)failed to delete user "u1".*`)
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+}
+
+func (s *KeystoneSuite) TestListRegions(c *gc.C) {
+	identity := identityservice.NewV3UserPass()
+	identity.AddService(identityservice.Service{V3: identityservice.V3Service{
+		Name:      "nova",
+		Type:      "compute",
+		Endpoints: identityservice.NewV3Endpoints("", "", "http://compute.example.com", "region-b"),
+	}})
+	identity.AddService(identityservice.Service{V3: identityservice.V3Service{
+		Name:      "neutron",
+		Type:      "network",
+		Endpoints: identityservice.NewV3Endpoints("", "", "http://network.example.com", "region-a"),
+	}})
+	identity.AddService(identityservice.Service{V3: identityservice.V3Service{
+		Name:      "nova",
+		Type:      "compute",
+		Endpoints: identityservice.NewV3Endpoints("", "", "http://compute.example.com", "region-a"),
+	}})
+	identity.SetupHTTP(s.Mux)
+
+	regions, err := keystone.New(client.NewPublicClient(s.Server.URL+"/v3", nil)).ListRegions(nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(regions, gc.DeepEquals, []keystone.Region{{Id: "region-a"}, {Id: "region-b"}})
+}
+
+func (s *KeystoneSuite) TestGroupMembership(c *gc.C) {
+	members := map[string]bool{}
+	s.Mux.HandleFunc("/groups/g1/users/u1", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT":
+			members["u1"] = true
+		case "DELETE":
+			delete(members, "u1")
+		case "HEAD":
+			if !members["u1"] {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	ok, err := s.keystone.IsUserInGroup("g1", "u1")
+	c.Assert(err, gc.IsNil)
+	c.Assert(ok, gc.Equals, false)
+
+	c.Assert(s.keystone.AddUserToGroup("g1", "u1"), gc.IsNil)
+	ok, err = s.keystone.IsUserInGroup("g1", "u1")
+	c.Assert(err, gc.IsNil)
+	c.Assert(ok, gc.Equals, true)
+
+	c.Assert(s.keystone.RemoveUserFromGroup("g1", "u1"), gc.IsNil)
+	c.Assert(members, gc.HasLen, 0)
+}
+
+func (s *KeystoneSuite) TestGrantAndRevokeRole(c *gc.C) {
+	var calls []string
+	record := func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+	s.Mux.HandleFunc("/projects/p1/users/u1/roles/r1", record)
+	s.Mux.HandleFunc("/domains/d1/groups/g1/roles/r1", record)
+
+	c.Assert(s.keystone.GrantRole(keystone.RoleGrant{RoleId: "r1", UserId: "u1", ProjectId: "p1"}), gc.IsNil)
+	c.Assert(s.keystone.RevokeRole(keystone.RoleGrant{RoleId: "r1", GroupId: "g1", DomainId: "d1"}), gc.IsNil)
+	c.Assert(calls, gc.DeepEquals, []string{
+		"PUT /projects/p1/users/u1/roles/r1",
+		"DELETE /domains/d1/groups/g1/roles/r1",
+	})
+}
+
+func (s *KeystoneSuite) TestGrantRoleInvalid(c *gc.C) {
+	err := s.keystone.GrantRole(keystone.RoleGrant{RoleId: "r1", UserId: "u1", GroupId: "g1", ProjectId: "p1"})
+	c.Assert(err, gc.ErrorMatches, `(?s)failed to grant role.*exactly one of user id and group id`)
+	err = s.keystone.GrantRole(keystone.RoleGrant{RoleId: "r1", UserId: "u1"})
+	c.Assert(err, gc.ErrorMatches, `(?s)failed to grant role.*exactly one of project id and domain id`)
+}
+
+func (s *KeystoneSuite) TestListRoleAssignments(c *gc.C) {
+	s.handle(c, "GET", "/role_assignments", http.StatusOK, `{"role_assignments": [
+		{"role": {"id": "r1", "name": "admin"}, "user": {"id": "u1", "name": "bob", "domain": {"id": "d1"}},
+		 "scope": {"project": {"id": "p1", "name": "team"}}},
+		{"role": {"id": "r2"}, "group": {"id": "g1"}, "scope": {"domain": {"id": "d1"}}}
+	], "links": {"next": null}}`, func(r *http.Request, data []byte) {
+		c.Check(r.URL.Query().Get(keystone.FilterAssignmentProject), gc.Equals, "p1")
+		_, ok := r.URL.Query()[keystone.FilterAssignmentIncludeNames]
+		c.Check(ok, gc.Equals, true)
+	})
+	filter := keystone.NewFilter()
+	filter.Set(keystone.FilterAssignmentProject, "p1")
+	filter.Set(keystone.FilterAssignmentIncludeNames, "")
+	assignments, err := s.keystone.ListRoleAssignments(filter)
+	c.Assert(err, gc.IsNil)
+	c.Assert(assignments, gc.DeepEquals, []keystone.RoleAssignment{{
+		Role:  keystone.EntityRef{Id: "r1", Name: "admin"},
+		User:  &keystone.EntityRef{Id: "u1", Name: "bob", Domain: &keystone.EntityRef{Id: "d1"}},
+		Scope: keystone.RoleAssignmentScope{Project: &keystone.EntityRef{Id: "p1", Name: "team"}},
+	}, {
+		Role:  keystone.EntityRef{Id: "r2"},
+		Group: &keystone.EntityRef{Id: "g1"},
+		Scope: keystone.RoleAssignmentScope{Domain: &keystone.EntityRef{Id: "d1"}},
+	}})
+}
+
+func (s *KeystoneSuite) TestCreateTrust(c *gc.C) {
+	s.handle(c, "POST", "/OS-TRUST/trusts", http.StatusCreated, `{"trust": {
+		"id": "t1", "trustor_user_id": "u1", "trustee_user_id": "u2", "project_id": "p1",
+		"impersonation": true, "roles": [{"id": "r1", "name": "member"}], "expires_at": null,
+		"remaining_uses": null}}`,
+		func(r *http.Request, data []byte) {
+			c.Check(string(data), gc.Equals,
+				`{"trust":{"trustor_user_id":"u1","trustee_user_id":"u2","project_id":"p1",`+
+					`"impersonation":true,"roles":[{"name":"member"}]}}`)
+		})
+	trust, err := s.keystone.CreateTrust(keystone.TrustOpts{
+		TrustorUserId: "u1",
+		TrusteeUserId: "u2",
+		ProjectId:     "p1",
+		Impersonation: true,
+		Roles:         []keystone.RoleRef{{Name: "member"}},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(trust, gc.DeepEquals, &keystone.Trust{
+		Id:            "t1",
+		TrustorUserId: "u1",
+		TrusteeUserId: "u2",
+		ProjectId:     "p1",
+		Impersonation: true,
+		Roles:         []keystone.RoleRef{{Id: "r1", Name: "member"}},
+	})
+}
+
+func (s *KeystoneSuite) TestListAndDeleteTrusts(c *gc.C) {
+	s.handle(c, "GET", "/OS-TRUST/trusts", http.StatusOK,
+		`{"trusts": [{"id": "t1", "trustee_user_id": "u2"}], "links": {"next": null}}`,
+		func(r *http.Request, data []byte) {
+			c.Check(r.URL.Query().Get(keystone.FilterTrusteeUserId), gc.Equals, "u2")
+		})
+	s.handle(c, "DELETE", "/OS-TRUST/trusts/t1", http.StatusNoContent, "", nil)
+
+	filter := keystone.NewFilter()
+	filter.Set(keystone.FilterTrusteeUserId, "u2")
+	trusts, err := s.keystone.ListTrusts(filter)
+	c.Assert(err, gc.IsNil)
+	c.Assert(trusts, gc.HasLen, 1)
+	c.Assert(trusts[0].Id, gc.Equals, "t1")
+	c.Assert(s.keystone.DeleteTrust("t1"), gc.IsNil)
+}
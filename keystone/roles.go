@@ -0,0 +1,178 @@
+package keystone
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+// Role describes a Keystone role.
+type Role struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	DomainId    string `json:"domain_id"`
+}
+
+// ListRoles lists roles matching the optional filter.
+func (c *Client) ListRoles(filter *Filter) ([]Role, error) {
+	var roles []Role
+	err := c.list(apiRoles, filter, func() (interface{}, func() Links) {
+		var resp struct {
+			Roles []Role `json:"roles"`
+			Links Links  `json:"links"`
+		}
+		return &resp, func() Links {
+			roles = append(roles, resp.Roles...)
+			return resp.Links
+		}
+	})
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get list of roles")
+	}
+	return roles, nil
+}
+
+// GetRole returns the role with the given ID.
+func (c *Client) GetRole(roleId string) (*Role, error) {
+	var resp struct {
+		Role Role `json:"role"`
+	}
+	url := fmt.Sprintf("%s/%s", apiRoles, roleId)
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "identity", "v3", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get role %q", roleId)
+	}
+	return &resp.Role, nil
+}
+
+// RoleGrant identifies a role granted to an actor on a target.
+// Exactly one of UserId and GroupId, and exactly one of ProjectId
+// and DomainId, must be set.
+type RoleGrant struct {
+	RoleId    string
+	UserId    string
+	GroupId   string
+	ProjectId string
+	DomainId  string
+}
+
+// url returns the API path of the grant, such as
+// projects/{project_id}/users/{user_id}/roles/{role_id}.
+func (g RoleGrant) url() (string, error) {
+	if g.RoleId == "" {
+		return "", fmt.Errorf("role grant requires a role id")
+	}
+	var target, actor string
+	switch {
+	case g.ProjectId != "" && g.DomainId == "":
+		target = fmt.Sprintf("%s/%s", apiProjects, g.ProjectId)
+	case g.DomainId != "" && g.ProjectId == "":
+		target = fmt.Sprintf("%s/%s", apiDomains, g.DomainId)
+	default:
+		return "", fmt.Errorf("role grant requires exactly one of project id and domain id")
+	}
+	switch {
+	case g.UserId != "" && g.GroupId == "":
+		actor = fmt.Sprintf("%s/%s", apiUsers, g.UserId)
+	case g.GroupId != "" && g.UserId == "":
+		actor = fmt.Sprintf("%s/%s", apiGroups, g.GroupId)
+	default:
+		return "", fmt.Errorf("role grant requires exactly one of user id and group id")
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", target, actor, apiRoles, g.RoleId), nil
+}
+
+// GrantRole assigns a role to a user or group on a project or domain.
+func (c *Client) GrantRole(grant RoleGrant) error {
+	url, err := grant.url()
+	if err != nil {
+		return errors.Newf(err, "failed to grant role")
+	}
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err = c.client.SendRequest(client.PUT, "identity", "v3", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to grant role %q", grant.RoleId)
+	}
+	return err
+}
+
+// RevokeRole removes a role assignment from a user or group on a
+// project or domain.
+func (c *Client) RevokeRole(grant RoleGrant) error {
+	url, err := grant.url()
+	if err != nil {
+		return errors.Newf(err, "failed to revoke role")
+	}
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err = c.client.SendRequest(client.DELETE, "identity", "v3", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to revoke role %q", grant.RoleId)
+	}
+	return err
+}
+
+// HasRole reports whether the role has been directly assigned to the
+// user or group on the project or domain.
+func (c *Client) HasRole(grant RoleGrant) (bool, error) {
+	url, err := grant.url()
+	if err != nil {
+		return false, errors.Newf(err, "failed to check role")
+	}
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err = c.client.SendRequest(client.HEAD, "identity", "v3", url, &requestData)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Newf(err, "failed to check role %q", grant.RoleId)
+	}
+	return true, nil
+}
+
+// EntityRef refers to a Keystone entity within a role assignment.
+// Name and Domain are only populated when the assignments are
+// listed with FilterAssignmentIncludeNames.
+type EntityRef struct {
+	Id     string     `json:"id"`
+	Name   string     `json:"name,omitempty"`
+	Domain *EntityRef `json:"domain,omitempty"`
+}
+
+// RoleAssignmentScope holds the target of a role assignment.
+type RoleAssignmentScope struct {
+	Project *EntityRef `json:"project,omitempty"`
+	Domain  *EntityRef `json:"domain,omitempty"`
+}
+
+// RoleAssignment describes a role assigned to a user or group.
+type RoleAssignment struct {
+	Role  EntityRef           `json:"role"`
+	User  *EntityRef          `json:"user,omitempty"`
+	Group *EntityRef          `json:"group,omitempty"`
+	Scope RoleAssignmentScope `json:"scope"`
+}
+
+// ListRoleAssignments lists role assignments matching the optional
+// filter. See the FilterAssignment* keys.
+func (c *Client) ListRoleAssignments(filter *Filter) ([]RoleAssignment, error) {
+	var assignments []RoleAssignment
+	err := c.list(apiRoleAssignments, filter, func() (interface{}, func() Links) {
+		var resp struct {
+			RoleAssignments []RoleAssignment `json:"role_assignments"`
+			Links           Links            `json:"links"`
+		}
+		return &resp, func() Links {
+			assignments = append(assignments, resp.RoleAssignments...)
+			return resp.Links
+		}
+	})
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get list of role assignments")
+	}
+	return assignments, nil
+}
@@ -0,0 +1,118 @@
+package keystone
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+// Filter keys for trusts.
+const (
+	FilterTrustorUserId = "trustor_user_id"
+	FilterTrusteeUserId = "trustee_user_id"
+)
+
+// RoleRef refers to a role by ID or by name.
+type RoleRef struct {
+	Id   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// Trust describes a Keystone trust (OS-TRUST), through which a trustor
+// delegates some of their roles on a project to a trustee. The trustee
+// may then authenticate with identity.Credentials.TrustId set to obtain
+// a token scoped to the trust.
+type Trust struct {
+	Id                 string     `json:"id"`
+	TrustorUserId      string     `json:"trustor_user_id"`
+	TrusteeUserId      string     `json:"trustee_user_id"`
+	ProjectId          string     `json:"project_id"`
+	Impersonation      bool       `json:"impersonation"`
+	Roles              []RoleRef  `json:"roles"`
+	ExpiresAt          *time.Time `json:"expires_at"`
+	RemainingUses      *int       `json:"remaining_uses"`
+	AllowRedelegation  bool       `json:"allow_redelegation"`
+	RedelegationCount  int        `json:"redelegation_count"`
+	RedelegatedTrustId string     `json:"redelegated_trust_id"`
+}
+
+// TrustOpts holds the attributes used to create a trust. The
+// trustor must be the user the client is authenticated as.
+type TrustOpts struct {
+	TrustorUserId     string     `json:"trustor_user_id"`
+	TrusteeUserId     string     `json:"trustee_user_id"`
+	ProjectId         string     `json:"project_id,omitempty"`
+	Impersonation     bool       `json:"impersonation"`
+	Roles             []RoleRef  `json:"roles,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	RemainingUses     *int       `json:"remaining_uses,omitempty"`
+	AllowRedelegation bool       `json:"allow_redelegation,omitempty"`
+}
+
+// ListTrusts lists trusts matching the optional filter. Without a
+// FilterTrustorUserId or FilterTrusteeUserId filter, Keystone only
+// lists all trusts for administrators.
+func (c *Client) ListTrusts(filter *Filter) ([]Trust, error) {
+	var trusts []Trust
+	err := c.list(apiTrusts, filter, func() (interface{}, func() Links) {
+		var resp struct {
+			Trusts []Trust `json:"trusts"`
+			Links  Links   `json:"links"`
+		}
+		return &resp, func() Links {
+			trusts = append(trusts, resp.Trusts...)
+			return resp.Links
+		}
+	})
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get list of trusts")
+	}
+	return trusts, nil
+}
+
+// GetTrust returns the trust with the given ID.
+func (c *Client) GetTrust(trustId string) (*Trust, error) {
+	var resp struct {
+		Trust Trust `json:"trust"`
+	}
+	url := fmt.Sprintf("%s/%s", apiTrusts, trustId)
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "identity", "v3", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get trust %q", trustId)
+	}
+	return &resp.Trust, nil
+}
+
+// CreateTrust creates a new trust.
+func (c *Client) CreateTrust(opts TrustOpts) (*Trust, error) {
+	var req struct {
+		Trust TrustOpts `json:"trust"`
+	}
+	req.Trust = opts
+	var resp struct {
+		Trust Trust `json:"trust"`
+	}
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusCreated}}
+	err := c.client.SendRequest(client.POST, "identity", "v3", apiTrusts, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to create trust for trustee %q", opts.TrusteeUserId)
+	}
+	return &resp.Trust, nil
+}
+
+// DeleteTrust deletes the given trust, revoking any tokens scoped
+// to it.
+func (c *Client) DeleteTrust(trustId string) error {
+	url := fmt.Sprintf("%s/%s", apiTrusts, trustId)
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err := c.client.SendRequest(client.DELETE, "identity", "v3", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete trust %q", trustId)
+	}
+	return err
+}
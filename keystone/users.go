@@ -0,0 +1,294 @@
+package keystone
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+// User describes a Keystone user.
+type User struct {
+	Id                string `json:"id"`
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	Email             string `json:"email"`
+	DomainId          string `json:"domain_id"`
+	DefaultProjectId  string `json:"default_project_id"`
+	Enabled           bool   `json:"enabled"`
+	PasswordExpiresAt string `json:"password_expires_at"`
+}
+
+// UserOpts holds the attributes used to create or update a user.
+// Zero values are not sent.
+type UserOpts struct {
+	Name             string `json:"name,omitempty"`
+	Description      string `json:"description,omitempty"`
+	Email            string `json:"email,omitempty"`
+	DomainId         string `json:"domain_id,omitempty"`
+	DefaultProjectId string `json:"default_project_id,omitempty"`
+	Password         string `json:"password,omitempty"`
+	Enabled          *bool  `json:"enabled,omitempty"`
+}
+
+// ListUsers lists users matching the optional filter.
+func (c *Client) ListUsers(filter *Filter) ([]User, error) {
+	users, err := c.listUsers(apiUsers, filter)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get list of users")
+	}
+	return users, nil
+}
+
+func (c *Client) listUsers(apiCall string, filter *Filter) ([]User, error) {
+	var users []User
+	err := c.list(apiCall, filter, func() (interface{}, func() Links) {
+		var resp struct {
+			Users []User `json:"users"`
+			Links Links  `json:"links"`
+		}
+		return &resp, func() Links {
+			users = append(users, resp.Users...)
+			return resp.Links
+		}
+	})
+	return users, err
+}
+
+// GetUser returns the user with the given ID.
+func (c *Client) GetUser(userId string) (*User, error) {
+	var resp struct {
+		User User `json:"user"`
+	}
+	url := fmt.Sprintf("%s/%s", apiUsers, userId)
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "identity", "v3", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get user %q", userId)
+	}
+	return &resp.User, nil
+}
+
+// CreateUser creates a new user.
+func (c *Client) CreateUser(opts UserOpts) (*User, error) {
+	var req struct {
+		User UserOpts `json:"user"`
+	}
+	req.User = opts
+	var resp struct {
+		User User `json:"user"`
+	}
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusCreated}}
+	err := c.client.SendRequest(client.POST, "identity", "v3", apiUsers, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to create user %q", opts.Name)
+	}
+	return &resp.User, nil
+}
+
+// UpdateUser updates the given user.
+func (c *Client) UpdateUser(userId string, opts UserOpts) (*User, error) {
+	var req struct {
+		User UserOpts `json:"user"`
+	}
+	req.User = opts
+	var resp struct {
+		User User `json:"user"`
+	}
+	url := fmt.Sprintf("%s/%s", apiUsers, userId)
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusOK}}
+	err := c.client.SendRequest(client.PATCH, "identity", "v3", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to update user %q", userId)
+	}
+	return &resp.User, nil
+}
+
+// DeleteUser deletes the given user.
+func (c *Client) DeleteUser(userId string) error {
+	url := fmt.Sprintf("%s/%s", apiUsers, userId)
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err := c.client.SendRequest(client.DELETE, "identity", "v3", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete user %q", userId)
+	}
+	return err
+}
+
+// ListUserProjects lists the projects the given user has access to.
+func (c *Client) ListUserProjects(userId string) ([]Project, error) {
+	var projects []Project
+	url := fmt.Sprintf("%s/%s/%s", apiUsers, userId, apiProjects)
+	err := c.list(url, nil, func() (interface{}, func() Links) {
+		var resp struct {
+			Projects []Project `json:"projects"`
+			Links    Links     `json:"links"`
+		}
+		return &resp, func() Links {
+			projects = append(projects, resp.Projects...)
+			return resp.Links
+		}
+	})
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get list of projects for user %q", userId)
+	}
+	return projects, nil
+}
+
+// ListUserGroups lists the groups the given user belongs to.
+func (c *Client) ListUserGroups(userId string) ([]Group, error) {
+	url := fmt.Sprintf("%s/%s/%s", apiUsers, userId, apiGroups)
+	groups, err := c.listGroups(url, nil)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get list of groups for user %q", userId)
+	}
+	return groups, nil
+}
+
+// Group describes a Keystone group.
+type Group struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	DomainId    string `json:"domain_id"`
+}
+
+// GroupOpts holds the attributes used to create or update a group.
+// Zero values are not sent.
+type GroupOpts struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	DomainId    string `json:"domain_id,omitempty"`
+}
+
+// ListGroups lists groups matching the optional filter.
+func (c *Client) ListGroups(filter *Filter) ([]Group, error) {
+	groups, err := c.listGroups(apiGroups, filter)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get list of groups")
+	}
+	return groups, nil
+}
+
+func (c *Client) listGroups(apiCall string, filter *Filter) ([]Group, error) {
+	var groups []Group
+	err := c.list(apiCall, filter, func() (interface{}, func() Links) {
+		var resp struct {
+			Groups []Group `json:"groups"`
+			Links  Links   `json:"links"`
+		}
+		return &resp, func() Links {
+			groups = append(groups, resp.Groups...)
+			return resp.Links
+		}
+	})
+	return groups, err
+}
+
+// GetGroup returns the group with the given ID.
+func (c *Client) GetGroup(groupId string) (*Group, error) {
+	var resp struct {
+		Group Group `json:"group"`
+	}
+	url := fmt.Sprintf("%s/%s", apiGroups, groupId)
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "identity", "v3", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get group %q", groupId)
+	}
+	return &resp.Group, nil
+}
+
+// CreateGroup creates a new group.
+func (c *Client) CreateGroup(opts GroupOpts) (*Group, error) {
+	var req struct {
+		Group GroupOpts `json:"group"`
+	}
+	req.Group = opts
+	var resp struct {
+		Group Group `json:"group"`
+	}
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusCreated}}
+	err := c.client.SendRequest(client.POST, "identity", "v3", apiGroups, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to create group %q", opts.Name)
+	}
+	return &resp.Group, nil
+}
+
+// UpdateGroup updates the given group.
+func (c *Client) UpdateGroup(groupId string, opts GroupOpts) (*Group, error) {
+	var req struct {
+		Group GroupOpts `json:"group"`
+	}
+	req.Group = opts
+	var resp struct {
+		Group Group `json:"group"`
+	}
+	url := fmt.Sprintf("%s/%s", apiGroups, groupId)
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusOK}}
+	err := c.client.SendRequest(client.PATCH, "identity", "v3", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to update group %q", groupId)
+	}
+	return &resp.Group, nil
+}
+
+// DeleteGroup deletes the given group.
+func (c *Client) DeleteGroup(groupId string) error {
+	url := fmt.Sprintf("%s/%s", apiGroups, groupId)
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err := c.client.SendRequest(client.DELETE, "identity", "v3", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete group %q", groupId)
+	}
+	return err
+}
+
+// ListGroupUsers lists the members of the given group.
+func (c *Client) ListGroupUsers(groupId string, filter *Filter) ([]User, error) {
+	url := fmt.Sprintf("%s/%s/%s", apiGroups, groupId, apiUsers)
+	users, err := c.listUsers(url, filter)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get list of users in group %q", groupId)
+	}
+	return users, nil
+}
+
+// AddUserToGroup adds the user to the group.
+func (c *Client) AddUserToGroup(groupId, userId string) error {
+	url := fmt.Sprintf("%s/%s/%s/%s", apiGroups, groupId, apiUsers, userId)
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err := c.client.SendRequest(client.PUT, "identity", "v3", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to add user %q to group %q", userId, groupId)
+	}
+	return err
+}
+
+// RemoveUserFromGroup removes the user from the group.
+func (c *Client) RemoveUserFromGroup(groupId, userId string) error {
+	url := fmt.Sprintf("%s/%s/%s/%s", apiGroups, groupId, apiUsers, userId)
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err := c.client.SendRequest(client.DELETE, "identity", "v3", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to remove user %q from group %q", userId, groupId)
+	}
+	return err
+}
+
+// IsUserInGroup reports whether the user is a member of the group.
+func (c *Client) IsUserInGroup(groupId, userId string) (bool, error) {
+	url := fmt.Sprintf("%s/%s/%s/%s", apiGroups, groupId, apiUsers, userId)
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err := c.client.SendRequest(client.HEAD, "identity", "v3", url, &requestData)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Newf(err, "failed to check membership of user %q in group %q", userId, groupId)
+	}
+	return true, nil
+}
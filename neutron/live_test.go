@@ -187,6 +187,121 @@ func (s *LiveTests) TestSubnetsV2(c *gc.C) {
 	c.Check(foundSubnet.Name, gc.Equals, firstSubnet.Name)
 }
 
+func (s *LiveTests) TestNetworkCRUDV2(c *gc.C) {
+	network, err := s.neutron.CreateNetworkV2(neutron.NetworkOptsV2{Name: "goose-test-network"})
+	c.Assert(err, gc.IsNil)
+	defer s.neutron.DeleteNetworkV2(network.Id)
+	c.Check(network.Id, gc.Not(gc.Equals), "")
+	c.Check(network.Name, gc.Equals, "goose-test-network")
+	c.Check(network.AdminStateUp, gc.Equals, true)
+
+	network, err = s.neutron.UpdateNetworkV2(network.Id, neutron.NetworkOptsV2{Name: "goose-test-network-renamed"})
+	c.Assert(err, gc.IsNil)
+	c.Check(network.Name, gc.Equals, "goose-test-network-renamed")
+
+	err = s.neutron.DeleteNetworkV2(network.Id)
+	c.Assert(err, gc.IsNil)
+	_, err = s.neutron.GetNetworkV2(network.Id)
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *LiveTests) TestSubnetCRUDV2(c *gc.C) {
+	network, err := s.neutron.CreateNetworkV2(neutron.NetworkOptsV2{Name: "goose-test-subnet-network"})
+	c.Assert(err, gc.IsNil)
+	defer s.neutron.DeleteNetworkV2(network.Id)
+
+	subnet, err := s.neutron.CreateSubnetV2(neutron.SubnetOptsV2{
+		NetworkId:      network.Id,
+		Name:           "goose-test-subnet",
+		IPVersion:      4,
+		Cidr:           "10.250.0.0/24",
+		DNSNameservers: []string{"8.8.8.8"},
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.neutron.DeleteSubnetV2(subnet.Id)
+	c.Check(subnet.NetworkId, gc.Equals, network.Id)
+	c.Check(subnet.Cidr, gc.Equals, "10.250.0.0/24")
+	c.Check(subnet.GatewayIP, gc.Equals, "10.250.0.1")
+	c.Check(subnet.DNSNameservers, gc.DeepEquals, []string{"8.8.8.8"})
+
+	subnet, err = s.neutron.UpdateSubnetV2(subnet.Id, neutron.SubnetOptsV2{
+		DisableGateway:  true,
+		AllocationPools: []neutron.AllocationPoolV2{{Start: "10.250.0.10", End: "10.250.0.20"}},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Check(subnet.GatewayIP, gc.Equals, "")
+	c.Check(subnet.Pools(), gc.DeepEquals, []neutron.AllocationPoolV2{{Start: "10.250.0.10", End: "10.250.0.20"}})
+
+	err = s.neutron.DeleteSubnetV2(subnet.Id)
+	c.Assert(err, gc.IsNil)
+	_, err = s.neutron.GetSubnetV2(subnet.Id)
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *LiveTests) TestRoutersV2(c *gc.C) {
+	filter := neutron.NewFilter()
+	filter.Set(neutron.FilterRouterExternal, "true")
+	externalNetworks, err := s.neutron.ListNetworksV2(filter)
+	c.Assert(err, gc.IsNil)
+	if len(externalNetworks) < 1 {
+		c.Skip("an external network is necessary for this test")
+	}
+	network, err := s.neutron.CreateNetworkV2(neutron.NetworkOptsV2{Name: "goose-test-router-network"})
+	c.Assert(err, gc.IsNil)
+	defer s.neutron.DeleteNetworkV2(network.Id)
+	subnet, err := s.neutron.CreateSubnetV2(neutron.SubnetOptsV2{
+		NetworkId: network.Id,
+		IPVersion: 4,
+		Cidr:      "10.251.0.0/24",
+	})
+	c.Assert(err, gc.IsNil)
+
+	router, err := s.neutron.CreateRouterV2(neutron.RouterOptsV2{
+		Name:                "goose-test-router",
+		ExternalGatewayInfo: &neutron.ExternalGatewayInfoV2{NetworkId: externalNetworks[0].Id},
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.neutron.DeleteRouterV2(router.Id)
+	c.Check(router.Name, gc.Equals, "goose-test-router")
+	c.Assert(router.ExternalGatewayInfo, gc.NotNil)
+	c.Check(router.ExternalGatewayInfo.NetworkId, gc.Equals, externalNetworks[0].Id)
+
+	iface, err := s.neutron.AddRouterInterfaceV2(router.Id, neutron.RouterInterfaceOptsV2{SubnetId: subnet.Id})
+	c.Assert(err, gc.IsNil)
+	c.Check(iface.SubnetId, gc.Equals, subnet.Id)
+	port, err := s.neutron.PortByIdV2(iface.PortId)
+	c.Assert(err, gc.IsNil)
+	c.Check(port.DeviceOwner, gc.Equals, neutron.DeviceOwnerRouterInterface)
+
+	route := neutron.HostRouteV2{Destination: "192.168.250.0/24", NextHop: "10.251.0.10"}
+	router, err = s.neutron.AddRouterRoutesV2(router.Id, []neutron.HostRouteV2{route})
+	c.Assert(err, gc.IsNil)
+	c.Check(router.Routes, gc.DeepEquals, []neutron.HostRouteV2{route})
+	router, err = s.neutron.RemoveRouterRoutesV2(router.Id, []neutron.HostRouteV2{route})
+	c.Assert(err, gc.IsNil)
+	c.Check(router.Routes, gc.HasLen, 0)
+	router, err = s.neutron.UpdateRouterV2(router.Id, neutron.RouterOptsV2{Routes: &[]neutron.HostRouteV2{route}})
+	c.Assert(err, gc.IsNil)
+	c.Check(router.Routes, gc.DeepEquals, []neutron.HostRouteV2{route})
+	router, err = s.neutron.UpdateRouterV2(router.Id, neutron.RouterOptsV2{Routes: &[]neutron.HostRouteV2{}})
+	c.Assert(err, gc.IsNil)
+	c.Check(router.Routes, gc.HasLen, 0)
+
+	router, err = s.neutron.ClearRouterGatewayV2(router.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(router.ExternalGatewayInfo, gc.IsNil)
+	router, err = s.neutron.UpdateRouterV2(router.Id, neutron.RouterOptsV2{Name: "goose-test-router-renamed"})
+	c.Assert(err, gc.IsNil)
+	c.Check(router.Name, gc.Equals, "goose-test-router-renamed")
+
+	_, err = s.neutron.RemoveRouterInterfaceV2(router.Id, neutron.RouterInterfaceOptsV2{SubnetId: subnet.Id})
+	c.Assert(err, gc.IsNil)
+	err = s.neutron.DeleteRouterV2(router.Id)
+	c.Assert(err, gc.IsNil)
+	_, err = s.neutron.GetRouterV2(router.Id)
+	c.Assert(err, gc.NotNil)
+}
+
 func (s *LiveTests) deleteSecurityGroup(id string, c *gc.C) {
 	err := s.neutron.DeleteSecurityGroupV2(id)
 	c.Assert(err, gc.IsNil)
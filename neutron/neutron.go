@@ -5,6 +5,7 @@
 package neutron
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -18,6 +19,7 @@ const (
 	ApiFloatingIPsV2        = "floatingips"
 	ApiNetworksV2           = "networks"
 	ApiPortsV2              = "ports"
+	ApiRoutersV2            = "routers"
 	ApiSubnetsV2            = "subnets"
 	ApiSecurityGroupsV2     = "security-groups"
 	ApiSecurityGroupRulesV2 = "security-group-rules"
@@ -51,11 +53,51 @@ const (
 type NetworkV2 struct {
 	Id                  string   `json:"id"` // UUID of the resource
 	Name                string   // User-provided name for the network range
+	Description         string   `json:"description"`
 	SubnetIds           []string `json:"subnets"`         // an array of subnet UUIDs
 	External            bool     `json:"router:external"` // is this network connected to an external router
 	AvailabilityZones   []string `json:"availability_zones"`
 	TenantId            string   `json:"tenant_id"`
 	PortSecurityEnabled *bool    `json:"port_security_enabled"`
+	AdminStateUp        bool     `json:"admin_state_up"`
+	Shared              bool     `json:"shared"`
+	Status              string   `json:"status"`
+	MTU                 int      `json:"mtu"`
+
+	// The provider attributes describe the physical network the
+	// network is implemented on. They are only reported to
+	// administrators.
+	NetworkType     string `json:"provider:network_type"`
+	PhysicalNetwork string `json:"provider:physical_network"`
+	SegmentationId  int    `json:"provider:segmentation_id"`
+}
+
+// Provider network types, for NetworkV2.NetworkType.
+const (
+	NetworkTypeFlat   = "flat"
+	NetworkTypeVLAN   = "vlan"
+	NetworkTypeVXLAN  = "vxlan"
+	NetworkTypeGRE    = "gre"
+	NetworkTypeGeneve = "geneve"
+	NetworkTypeLocal  = "local"
+)
+
+// NetworkOptsV2 defines the arguments for CreateNetworkV2() and
+// UpdateNetworkV2(). When updating, empty fields are left unchanged.
+// The provider attributes can only be set by administrators, and only
+// when creating.
+type NetworkOptsV2 struct {
+	Name                string `json:"name,omitempty"`
+	Description         string `json:"description,omitempty"`
+	AdminStateUp        *bool  `json:"admin_state_up,omitempty"`        // Optional, true if nil when creating
+	Shared              *bool  `json:"shared,omitempty"`                // Optional
+	External            *bool  `json:"router:external,omitempty"`       // Optional
+	PortSecurityEnabled *bool  `json:"port_security_enabled,omitempty"` // Optional
+	MTU                 int    `json:"mtu,omitempty"`                   // Optional, the largest possible if zero
+
+	NetworkType     string `json:"provider:network_type,omitempty"`     // Optional, one of the NetworkType* constants
+	PhysicalNetwork string `json:"provider:physical_network,omitempty"` // Required for flat and VLAN networks
+	SegmentationId  int    `json:"provider:segmentation_id,omitempty"`  // Optional, the VLAN ID or tunnel ID
 }
 
 // SubnetV2 contains details about a labeled subnet
@@ -63,9 +105,108 @@ type SubnetV2 struct {
 	Id              string        `json:"id"`         // UUID of the resource
 	NetworkId       string        `json:"network_id"` // UUID of the related network
 	Name            string        `json:"name"`       // User-provided name for the subnet
-	Cidr            string        `json:"cidr"`       // IP range covered by the subnet
-	AllocationPools []interface{} `json:"allocation_pools"`
+	Description     string        `json:"description"`
+	Cidr            string        `json:"cidr"` // IP range covered by the subnet
+	IPVersion       int           `json:"ip_version"`
+	AllocationPools []interface{} `json:"allocation_pools"` // See Pools
 	TenantId        string        `json:"tenant_id"`
+	GatewayIP       string        `json:"gateway_ip"` // Empty if the subnet has no gateway
+	EnableDHCP      bool          `json:"enable_dhcp"`
+	DNSNameservers  []string      `json:"dns_nameservers"`
+	HostRoutes      []HostRouteV2 `json:"host_routes"`
+	IPv6AddressMode string        `json:"ipv6_address_mode"`
+	IPv6RAMode      string        `json:"ipv6_ra_mode"`
+	SubnetPoolId    string        `json:"subnetpool_id"`
+}
+
+// Pools returns the allocation pools of the subnet.
+//
+// TODO: AllocationPools should be a []AllocationPoolV2, but we don't
+// want to break compatibility at this time.
+func (s *SubnetV2) Pools() []AllocationPoolV2 {
+	var pools []AllocationPoolV2
+	data, err := json.Marshal(s.AllocationPools)
+	if err != nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, &pools); err != nil {
+		return nil
+	}
+	return pools
+}
+
+// AllocationPoolV2 is a range of addresses of a subnet, from Start to
+// End inclusive, which are allocated to ports.
+type AllocationPoolV2 struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// HostRouteV2 is a route given to the hosts of a subnet by DHCP.
+type HostRouteV2 struct {
+	Destination string `json:"destination"` // A CIDR
+	NextHop     string `json:"nexthop"`
+}
+
+// Address modes and router advertisement modes of IPv6 subnets, for
+// SubnetV2.IPv6AddressMode and SubnetV2.IPv6RAMode.
+const (
+	IPv6ModeSLAAC           = "slaac"
+	IPv6ModeDHCPv6Stateful  = "dhcpv6-stateful"
+	IPv6ModeDHCPv6Stateless = "dhcpv6-stateless"
+)
+
+// SubnetOptsV2 defines the arguments for CreateSubnetV2() and
+// UpdateSubnetV2(). When updating, empty fields are left unchanged,
+// and only the name, description, gateway, DHCP, DNS nameservers, host
+// routes and allocation pools can be changed.
+type SubnetOptsV2 struct {
+	NetworkId   string `json:"network_id,omitempty"` // Required when creating
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	IPVersion   int    `json:"ip_version,omitempty"` // Required when creating, 4 or 6
+
+	// Cidr is the range of addresses of the subnet. It is required
+	// when creating, unless SubnetPoolId is set, in which case a range
+	// of PrefixLength bits is allocated from the subnet pool.
+	Cidr         string `json:"cidr,omitempty"`
+	SubnetPoolId string `json:"subnetpool_id,omitempty"`
+	PrefixLength int    `json:"prefixlen,omitempty"` // Optional, the pool's default if zero
+
+	// GatewayIP is the address of the gateway of the subnet. If it is
+	// empty the first address of the subnet is used, unless
+	// DisableGateway is set.
+	GatewayIP      string `json:"-"`
+	DisableGateway bool   `json:"-"`
+
+	EnableDHCP      *bool              `json:"enable_dhcp,omitempty"` // Optional, true if nil when creating
+	DNSNameservers  []string           `json:"dns_nameservers,omitempty"`
+	HostRoutes      []HostRouteV2      `json:"host_routes,omitempty"`
+	AllocationPools []AllocationPoolV2 `json:"allocation_pools,omitempty"`  // Optional, all the subnet but the gateway if empty
+	IPv6AddressMode string             `json:"ipv6_address_mode,omitempty"` // Optional, one of the IPv6Mode* constants
+	IPv6RAMode      string             `json:"ipv6_ra_mode,omitempty"`      // Optional, one of the IPv6Mode* constants
+}
+
+// subnetRequestV2 is the body of a request creating or updating a
+// subnet. The gateway IP is null to disable the gateway.
+type subnetRequestV2 struct {
+	SubnetOptsV2
+	GatewayIP json.RawMessage `json:"gateway_ip,omitempty"`
+}
+
+func newSubnetRequestV2(opts SubnetOptsV2) (subnetRequestV2, error) {
+	req := subnetRequestV2{SubnetOptsV2: opts}
+	switch {
+	case opts.DisableGateway:
+		req.GatewayIP = json.RawMessage("null")
+	case opts.GatewayIP != "":
+		gatewayIP, err := json.Marshal(opts.GatewayIP)
+		if err != nil {
+			return req, err
+		}
+		req.GatewayIP = gatewayIP
+	}
+	return req, nil
 }
 
 // Client provides a means to access the OpenStack Network Service.
@@ -171,6 +312,121 @@ func (c *Client) GetSubnetV2(subnetID string) (*SubnetV2, error) {
 	return &resp.Subnet, nil
 }
 
+// CreateNetworkV2 creates a network.
+func (c *Client) CreateNetworkV2(opts NetworkOptsV2) (*NetworkV2, error) {
+	req := struct {
+		Network NetworkOptsV2 `json:"network"`
+	}{opts}
+	var resp struct {
+		Network NetworkV2 `json:"network"`
+	}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ExpectedStatus: []int{http.StatusCreated},
+	}
+	err := c.client.SendRequest(client.POST, "network", "v2.0", ApiNetworksV2, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to create network %q", opts.Name)
+	}
+	return &resp.Network, nil
+}
+
+// UpdateNetworkV2 updates the specified network.
+func (c *Client) UpdateNetworkV2(netID string, opts NetworkOptsV2) (*NetworkV2, error) {
+	req := struct {
+		Network NetworkOptsV2 `json:"network"`
+	}{opts}
+	var resp struct {
+		Network NetworkV2 `json:"network"`
+	}
+	url := fmt.Sprintf("%s/%s", ApiNetworksV2, netID)
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	err := c.client.SendRequest(client.PUT, "network", "v2.0", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to update network with id: %s", netID)
+	}
+	return &resp.Network, nil
+}
+
+// DeleteNetworkV2 deletes the specified network and its subnets. No
+// ports other than those of the network's DHCP agents may be left on
+// the network.
+func (c *Client) DeleteNetworkV2(netID string) error {
+	url := fmt.Sprintf("%s/%s", ApiNetworksV2, netID)
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err := c.client.SendRequest(client.DELETE, "network", "v2.0", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete network with id: %s", netID)
+	}
+	return err
+}
+
+// CreateSubnetV2 creates a subnet on a network.
+func (c *Client) CreateSubnetV2(opts SubnetOptsV2) (*SubnetV2, error) {
+	subnet, err := newSubnetRequestV2(opts)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to create subnet %q", opts.Name)
+	}
+	req := struct {
+		Subnet subnetRequestV2 `json:"subnet"`
+	}{subnet}
+	var resp struct {
+		Subnet SubnetV2 `json:"subnet"`
+	}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ExpectedStatus: []int{http.StatusCreated},
+	}
+	err = c.client.SendRequest(client.POST, "network", "v2.0", ApiSubnetsV2, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to create subnet %q on network with id: %s", opts.Name, opts.NetworkId)
+	}
+	return &resp.Subnet, nil
+}
+
+// UpdateSubnetV2 updates the specified subnet.
+func (c *Client) UpdateSubnetV2(subnetID string, opts SubnetOptsV2) (*SubnetV2, error) {
+	subnet, err := newSubnetRequestV2(opts)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to update subnet with id: %s", subnetID)
+	}
+	req := struct {
+		Subnet subnetRequestV2 `json:"subnet"`
+	}{subnet}
+	var resp struct {
+		Subnet SubnetV2 `json:"subnet"`
+	}
+	url := fmt.Sprintf("%s/%s", ApiSubnetsV2, subnetID)
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	err = c.client.SendRequest(client.PUT, "network", "v2.0", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to update subnet with id: %s", subnetID)
+	}
+	return &resp.Subnet, nil
+}
+
+// DeleteSubnetV2 deletes the specified subnet. No port may have an
+// address allocated from it.
+func (c *Client) DeleteSubnetV2(subnetID string) error {
+	url := fmt.Sprintf("%s/%s", ApiSubnetsV2, subnetID)
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err := c.client.SendRequest(client.DELETE, "network", "v2.0", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete subnet with id: %s", subnetID)
+	}
+	return err
+}
+
 // FloatingIPV2 contains details about a floating ip
 type FloatingIPV2 struct {
 	// FixedIP holds the private IP address of the machine (when assigned)
@@ -178,6 +434,9 @@ type FloatingIPV2 struct {
 	Id                string `json:"id"`
 	IP                string `json:"floating_ip_address"`
 	FloatingNetworkId string `json:"floating_network_id"`
+	// PortId holds the ID of the port the floating IP is associated
+	// with, if any.
+	PortId string `json:"port_id,omitempty"`
 }
 
 // ListFloatingIPsV2 lists floating IP addresses associated with the tenant or account.
@@ -0,0 +1,282 @@
+// goose/neutron/routers - Go package to interact with the routers of
+// the OpenStack Network Service (Neutron) API. Routers forward traffic
+// between their interfaces on tenant subnets, and to an external
+// network through their gateway.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/network/v2/#routers-routers>
+
+package neutron
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+// Device owners of the ports neutron creates for routers, as reported
+// by PortV2.DeviceOwner.
+const (
+	DeviceOwnerRouterInterface = "network:router_interface"
+	DeviceOwnerRouterGateway   = "network:router_gateway"
+)
+
+// RouterV2 describes a router. ExternalGatewayInfo is nil if the
+// router has no gateway. Routes are the extra routes of the router,
+// whose next hops are on the subnets of its interfaces.
+type RouterV2 struct {
+	Id                  string                 `json:"id"`
+	Name                string                 `json:"name"`
+	Description         string                 `json:"description"`
+	TenantId            string                 `json:"tenant_id"`
+	AdminStateUp        bool                   `json:"admin_state_up"`
+	Status              string                 `json:"status"`
+	ExternalGatewayInfo *ExternalGatewayInfoV2 `json:"external_gateway_info"`
+	Routes              []HostRouteV2          `json:"routes"`
+}
+
+// ExternalGatewayInfoV2 describes the gateway of a router on an
+// external network. EnableSNAT defaults to true, so that servers
+// without floating IPs can reach the external network. The addresses
+// of the gateway are chosen by neutron if ExternalFixedIPs is empty.
+type ExternalGatewayInfoV2 struct {
+	NetworkId        string           `json:"network_id"`
+	EnableSNAT       *bool            `json:"enable_snat,omitempty"`
+	ExternalFixedIPs []PortFixedIPsV2 `json:"external_fixed_ips,omitempty"`
+}
+
+// RouterOptsV2 defines the arguments for CreateRouterV2() and
+// UpdateRouterV2(). When updating, empty fields are left unchanged;
+// use ClearRouterGatewayV2 to remove the gateway. Routes, if not nil,
+// replace all the extra routes of the router, so a pointer to an
+// empty slice removes them all.
+type RouterOptsV2 struct {
+	Name                string                 `json:"name,omitempty"`
+	Description         string                 `json:"description,omitempty"`
+	AdminStateUp        *bool                  `json:"admin_state_up,omitempty"`
+	ExternalGatewayInfo *ExternalGatewayInfoV2 `json:"external_gateway_info,omitempty"`
+	Routes              *[]HostRouteV2         `json:"routes,omitempty"`
+}
+
+// RouterInterfaceOptsV2 identifies the interface added to or removed
+// from a router, by exactly one of a subnet or a port. An interface
+// added by subnet has the gateway IP address of the subnet.
+type RouterInterfaceOptsV2 struct {
+	SubnetId string `json:"subnet_id,omitempty"`
+	PortId   string `json:"port_id,omitempty"`
+}
+
+// RouterInterfaceV2 describes an interface of a router, which is the
+// port with the given ID.
+type RouterInterfaceV2 struct {
+	Id        string   `json:"id"`
+	TenantId  string   `json:"tenant_id"`
+	NetworkId string   `json:"network_id"`
+	PortId    string   `json:"port_id"`
+	SubnetId  string   `json:"subnet_id"`
+	SubnetIds []string `json:"subnet_ids"`
+}
+
+// ListRoutersV2 lists the routers matching the given filter.
+// Zero or one Filters accepted, any more will be ignored.
+func (c *Client) ListRoutersV2(filter ...*Filter) ([]RouterV2, error) {
+	var resp struct {
+		Routers []RouterV2 `json:"routers"`
+	}
+	var params *url.Values
+	if len(filter) > 0 {
+		params = &filter[0].v
+	}
+	requestData := goosehttp.RequestData{RespValue: &resp, Params: params}
+	err := c.client.SendRequest(client.GET, "network", "v2.0", ApiRoutersV2, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to list routers")
+	}
+	return resp.Routers, nil
+}
+
+// GetRouterV2 returns the router with the given ID.
+func (c *Client) GetRouterV2(routerId string) (*RouterV2, error) {
+	var resp struct {
+		Router RouterV2 `json:"router"`
+	}
+	url := fmt.Sprintf("%s/%s", ApiRoutersV2, routerId)
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "network", "v2.0", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get router with id: %s", routerId)
+	}
+	return &resp.Router, nil
+}
+
+// CreateRouterV2 creates a router, with a gateway on an external
+// network if opts.ExternalGatewayInfo is set.
+func (c *Client) CreateRouterV2(opts RouterOptsV2) (*RouterV2, error) {
+	req := struct {
+		Router RouterOptsV2 `json:"router"`
+	}{opts}
+	var resp struct {
+		Router RouterV2 `json:"router"`
+	}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ExpectedStatus: []int{http.StatusCreated},
+	}
+	err := c.client.SendRequest(client.POST, "network", "v2.0", ApiRoutersV2, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to create router %q", opts.Name)
+	}
+	return &resp.Router, nil
+}
+
+// UpdateRouterV2 changes the attributes of the router with the given
+// ID. Routes, if given, replace all the extra routes of the router.
+func (c *Client) UpdateRouterV2(routerId string, opts RouterOptsV2) (*RouterV2, error) {
+	router, err := c.updateRouter(routerId, opts)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to update router with id: %s", routerId)
+	}
+	return router, nil
+}
+
+// SetRouterGatewayV2 sets the gateway of the router with the given ID,
+// replacing any existing gateway.
+func (c *Client) SetRouterGatewayV2(routerId string, info ExternalGatewayInfoV2) (*RouterV2, error) {
+	req := struct {
+		ExternalGatewayInfo ExternalGatewayInfoV2 `json:"external_gateway_info"`
+	}{info}
+	router, err := c.updateRouter(routerId, req)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to set gateway on network %s of router with id: %s", info.NetworkId, routerId)
+	}
+	return router, nil
+}
+
+// ClearRouterGatewayV2 removes the gateway of the router with the
+// given ID. The gateway cannot be removed while floating IPs use it.
+func (c *Client) ClearRouterGatewayV2(routerId string) (*RouterV2, error) {
+	req := struct {
+		ExternalGatewayInfo *ExternalGatewayInfoV2 `json:"external_gateway_info"`
+	}{}
+	router, err := c.updateRouter(routerId, req)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to clear gateway of router with id: %s", routerId)
+	}
+	return router, nil
+}
+
+func (c *Client) updateRouter(routerId string, opts interface{}) (*RouterV2, error) {
+	req := struct {
+		Router interface{} `json:"router"`
+	}{opts}
+	var resp struct {
+		Router RouterV2 `json:"router"`
+	}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	url := fmt.Sprintf("%s/%s", ApiRoutersV2, routerId)
+	if err := c.client.SendRequest(client.PUT, "network", "v2.0", url, &requestData); err != nil {
+		return nil, err
+	}
+	return &resp.Router, nil
+}
+
+// DeleteRouterV2 deletes the router with the given ID, and its
+// gateway. The interfaces of the router must be removed first.
+func (c *Client) DeleteRouterV2(routerId string) error {
+	url := fmt.Sprintf("%s/%s", ApiRoutersV2, routerId)
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err := c.client.SendRequest(client.DELETE, "network", "v2.0", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete router with id: %s", routerId)
+	}
+	return err
+}
+
+// AddRouterInterfaceV2 adds an interface to the router with the given
+// ID, on the subnet or port given in opts.
+func (c *Client) AddRouterInterfaceV2(routerId string, opts RouterInterfaceOptsV2) (*RouterInterfaceV2, error) {
+	iface, err := c.routerInterfaceAction(routerId, "add_router_interface", opts)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to add interface %+v to router with id: %s", opts, routerId)
+	}
+	return iface, nil
+}
+
+// RemoveRouterInterfaceV2 removes the interface on the subnet or port
+// given in opts from the router with the given ID, deleting its port.
+// An interface cannot be removed while floating IPs or extra routes
+// use it.
+func (c *Client) RemoveRouterInterfaceV2(routerId string, opts RouterInterfaceOptsV2) (*RouterInterfaceV2, error) {
+	iface, err := c.routerInterfaceAction(routerId, "remove_router_interface", opts)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to remove interface %+v from router with id: %s", opts, routerId)
+	}
+	return iface, nil
+}
+
+func (c *Client) routerInterfaceAction(routerId, action string, opts RouterInterfaceOptsV2) (*RouterInterfaceV2, error) {
+	var resp RouterInterfaceV2
+	requestData := goosehttp.RequestData{
+		ReqValue:       opts,
+		RespValue:      &resp,
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	url := fmt.Sprintf("%s/%s/%s", ApiRoutersV2, routerId, action)
+	if err := c.client.SendRequest(client.PUT, "network", "v2.0", url, &requestData); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AddRouterRoutesV2 adds extra routes to the router with the given ID,
+// keeping its existing routes.
+func (c *Client) AddRouterRoutesV2(routerId string, routes []HostRouteV2) (*RouterV2, error) {
+	router, err := c.routerRoutesAction(routerId, "add_extraroutes", routes)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to add routes to router with id: %s", routerId)
+	}
+	return router, nil
+}
+
+// RemoveRouterRoutesV2 removes extra routes from the router with the
+// given ID, keeping its other routes.
+func (c *Client) RemoveRouterRoutesV2(routerId string, routes []HostRouteV2) (*RouterV2, error) {
+	router, err := c.routerRoutesAction(routerId, "remove_extraroutes", routes)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to remove routes from router with id: %s", routerId)
+	}
+	return router, nil
+}
+
+func (c *Client) routerRoutesAction(routerId, action string, routes []HostRouteV2) (*RouterV2, error) {
+	if routes == nil {
+		routes = []HostRouteV2{}
+	}
+	var req struct {
+		Router struct {
+			Routes []HostRouteV2 `json:"routes"`
+		} `json:"router"`
+	}
+	req.Router.Routes = routes
+	var resp struct {
+		Router RouterV2 `json:"router"`
+	}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	url := fmt.Sprintf("%s/%s/%s", ApiRoutersV2, routerId, action)
+	if err := c.client.SendRequest(client.PUT, "network", "v2.0", url, &requestData); err != nil {
+		return nil, err
+	}
+	return &resp.Router, nil
+}
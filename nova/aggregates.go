@@ -0,0 +1,232 @@
+// Nova api calls for managing host aggregates, which group compute
+// hosts and are used to define availability zones. These calls are
+// restricted to administrators by default.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#host-aggregates-os-aggregates>
+
+package nova
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+const (
+	apiAggregates = "os-aggregates"
+
+	// aggregateMicroversion is the compute API microversion adding
+	// the UUID of aggregates. It is requested by all the aggregate
+	// calls.
+	aggregateMicroversion = "2.41"
+)
+
+// AggregateAvailabilityZoneKey is the metadata key holding the
+// availability zone of an aggregate.
+const AggregateAvailabilityZoneKey = "availability_zone"
+
+// Aggregate describes a host aggregate. AvailabilityZone is empty if
+// the aggregate does not define one.
+type Aggregate struct {
+	Id               int               `json:"id"`
+	UUID             string            `json:"uuid"`
+	Name             string            `json:"name"`
+	AvailabilityZone string            `json:"availability_zone"`
+	Hosts            []string          `json:"hosts"`
+	Metadata         map[string]string `json:"metadata"`
+	CreatedAt        string            `json:"created_at"`
+	UpdatedAt        string            `json:"updated_at"`
+}
+
+// AggregateOpts defines the arguments for CreateAggregate() and
+// UpdateAggregate(). When updating, empty fields are left unchanged.
+type AggregateOpts struct {
+	Name             string `json:"name,omitempty"`              // Required when creating
+	AvailabilityZone string `json:"availability_zone,omitempty"` // Optional
+}
+
+// ListAggregates lists all the host aggregates.
+func (c *Client) ListAggregates() ([]Aggregate, error) {
+	var resp struct {
+		Aggregates []Aggregate `json:"aggregates"`
+	}
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(aggregateMicroversion),
+	}
+	err := c.client.SendRequest(client.GET, "compute", "v2", apiAggregates, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to list aggregates")
+	}
+	return resp.Aggregates, nil
+}
+
+// GetAggregate returns the host aggregate with the given ID.
+func (c *Client) GetAggregate(aggregateId int) (*Aggregate, error) {
+	var resp struct {
+		Aggregate Aggregate `json:"aggregate"`
+	}
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(aggregateMicroversion),
+	}
+	url := fmt.Sprintf("%s/%d", apiAggregates, aggregateId)
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get aggregate with id: %d", aggregateId)
+	}
+	return &resp.Aggregate, nil
+}
+
+// CreateAggregate creates a host aggregate, and defines an
+// availability zone if opts.AvailabilityZone is set.
+func (c *Client) CreateAggregate(opts AggregateOpts) (*Aggregate, error) {
+	req := struct {
+		Aggregate AggregateOpts `json:"aggregate"`
+	}{opts}
+	var resp struct {
+		Aggregate Aggregate `json:"aggregate"`
+	}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ReqHeaders:     microversionHeaders(aggregateMicroversion),
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	err := c.client.SendRequest(client.POST, "compute", "v2", apiAggregates, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to create aggregate %q", opts.Name)
+	}
+	return &resp.Aggregate, nil
+}
+
+// UpdateAggregate changes the name or availability zone of the host
+// aggregate with the given ID.
+func (c *Client) UpdateAggregate(aggregateId int, opts AggregateOpts) (*Aggregate, error) {
+	req := struct {
+		Aggregate AggregateOpts `json:"aggregate"`
+	}{opts}
+	var resp struct {
+		Aggregate Aggregate `json:"aggregate"`
+	}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ReqHeaders:     microversionHeaders(aggregateMicroversion),
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	url := fmt.Sprintf("%s/%d", apiAggregates, aggregateId)
+	err := c.client.SendRequest(client.PUT, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to update aggregate with id: %d", aggregateId)
+	}
+	return &resp.Aggregate, nil
+}
+
+// DeleteAggregate deletes the host aggregate with the given ID, which
+// must have no hosts.
+func (c *Client) DeleteAggregate(aggregateId int) error {
+	requestData := goosehttp.RequestData{
+		ReqHeaders:     microversionHeaders(aggregateMicroversion),
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	url := fmt.Sprintf("%s/%d", apiAggregates, aggregateId)
+	err := c.client.SendRequest(client.DELETE, "compute", "v2", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete aggregate with id: %d", aggregateId)
+	}
+	return err
+}
+
+// AddAggregateHost adds a compute host to the host aggregate with the
+// given ID. A host may only be in one availability zone.
+func (c *Client) AddAggregateHost(aggregateId int, host string) (*Aggregate, error) {
+	req := struct {
+		AddHost struct {
+			Host string `json:"host"`
+		} `json:"add_host"`
+	}{}
+	req.AddHost.Host = host
+	aggregate, err := c.aggregateAction(aggregateId, req)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to add host %s to aggregate with id: %d", host, aggregateId)
+	}
+	return aggregate, nil
+}
+
+// RemoveAggregateHost removes a compute host from the host aggregate
+// with the given ID.
+func (c *Client) RemoveAggregateHost(aggregateId int, host string) (*Aggregate, error) {
+	req := struct {
+		RemoveHost struct {
+			Host string `json:"host"`
+		} `json:"remove_host"`
+	}{}
+	req.RemoveHost.Host = host
+	aggregate, err := c.aggregateAction(aggregateId, req)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to remove host %s from aggregate with id: %d", host, aggregateId)
+	}
+	return aggregate, nil
+}
+
+// SetAggregateMetadata sets metadata items on the host aggregate with
+// the given ID, keeping any other existing items.
+func (c *Client) SetAggregateMetadata(aggregateId int, metadata map[string]string) (*Aggregate, error) {
+	items := make(map[string]*string)
+	for k, v := range metadata {
+		v := v
+		items[k] = &v
+	}
+	aggregate, err := c.setAggregateMetadata(aggregateId, items)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to set metadata %v on aggregate with id: %d", metadata, aggregateId)
+	}
+	return aggregate, nil
+}
+
+// DeleteAggregateMetadata deletes the given metadata items from the
+// host aggregate with the given ID.
+func (c *Client) DeleteAggregateMetadata(aggregateId int, keys ...string) (*Aggregate, error) {
+	items := make(map[string]*string)
+	for _, k := range keys {
+		items[k] = nil
+	}
+	aggregate, err := c.setAggregateMetadata(aggregateId, items)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to delete metadata %v from aggregate with id: %d", keys, aggregateId)
+	}
+	return aggregate, nil
+}
+
+// setAggregateMetadata changes the metadata of an aggregate. Items
+// with a nil value are deleted.
+func (c *Client) setAggregateMetadata(aggregateId int, items map[string]*string) (*Aggregate, error) {
+	req := struct {
+		SetMetadata struct {
+			Metadata map[string]*string `json:"metadata"`
+		} `json:"set_metadata"`
+	}{}
+	req.SetMetadata.Metadata = items
+	return c.aggregateAction(aggregateId, req)
+}
+
+func (c *Client) aggregateAction(aggregateId int, req interface{}) (*Aggregate, error) {
+	var resp struct {
+		Aggregate Aggregate `json:"aggregate"`
+	}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ReqHeaders:     microversionHeaders(aggregateMicroversion),
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	url := fmt.Sprintf("%s/%d/action", apiAggregates, aggregateId)
+	if err := c.client.SendRequest(client.POST, "compute", "v2", url, &requestData); err != nil {
+		return nil, err
+	}
+	return &resp.Aggregate, nil
+}
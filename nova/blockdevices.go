@@ -0,0 +1,229 @@
+// Helpers for building the block device mappings of servers booted
+// from volumes or created with several disks.
+// See documentation at:
+// <https://docs.openstack.org/nova/latest/user/block-device-mapping.html>
+
+package nova
+
+import (
+	"fmt"
+
+	"github.com/go-goose/goose/v5/cinder"
+	"github.com/go-goose/goose/v5/errors"
+)
+
+// volumeTypeMicroversion is the compute API microversion allowing the
+// volume type of block devices to be chosen.
+const volumeTypeMicroversion = "2.67"
+
+// Sources of the content of a block device.
+const (
+	BlockDeviceSourceImage    = "image"
+	BlockDeviceSourceVolume   = "volume"
+	BlockDeviceSourceSnapshot = "snapshot"
+	BlockDeviceSourceBlank    = "blank"
+)
+
+// Destinations of a block device, either a cinder volume or local
+// storage on the compute host.
+const (
+	BlockDeviceDestinationVolume = "volume"
+	BlockDeviceDestinationLocal  = "local"
+)
+
+// GuestFormatSwap is the guest format of a swap device.
+const GuestFormatSwap = "swap"
+
+// VolumeOpts defines optional arguments for the volumes added by a
+// BlockDeviceBuilder.
+type VolumeOpts struct {
+	// VolumeType is the name or ID of the cinder volume type of a new
+	// volume. It requires microversion 2.67, which RunServer sends
+	// when a volume type is set. See ResolveVolumeTypes.
+	VolumeType string
+
+	// DeleteOnTermination sets whether the volume is deleted with
+	// the server.
+	DeleteOnTermination bool
+
+	// DeviceName is the device the volume is attached as, such as
+	// "/dev/vdb". Hypervisors may not honour it.
+	DeviceName string
+
+	// Tag is a device role tag for the volume.
+	Tag string
+}
+
+// VolumeTypeLister lists the volume types known to cinder. It is
+// implemented by *cinder.Client.
+type VolumeTypeLister interface {
+	GetVolumeTypes() (*cinder.GetVolumeTypesResults, error)
+}
+
+// BlockDeviceBuilder builds the BlockDeviceMappings of RunServerOpts.
+// At most one boot device may be added; without one the server must
+// be booted from RunServerOpts.ImageId, and the other devices are
+// added to it. Errors are reported by Build. The zero value is an
+// empty BlockDeviceBuilder ready to use.
+type BlockDeviceBuilder struct {
+	mappings []BlockDeviceMapping
+	err      error
+}
+
+// BootFromImage boots the server from a new volume of sizeGB
+// gigabytes, created from the given image.
+func (b *BlockDeviceBuilder) BootFromImage(imageId string, sizeGB int, opts VolumeOpts) {
+	b.addVolume(0, BlockDeviceSourceImage, imageId, sizeGB, opts)
+}
+
+// BootFromVolume boots the server from an existing bootable volume.
+func (b *BlockDeviceBuilder) BootFromVolume(volumeId string, opts VolumeOpts) {
+	b.addVolume(0, BlockDeviceSourceVolume, volumeId, 0, opts)
+}
+
+// BootFromSnapshot boots the server from a new volume created from
+// the given volume snapshot. If sizeGB is 0 the volume is the size of
+// the snapshot.
+func (b *BlockDeviceBuilder) BootFromSnapshot(snapshotId string, sizeGB int, opts VolumeOpts) {
+	b.addVolume(0, BlockDeviceSourceSnapshot, snapshotId, sizeGB, opts)
+}
+
+// AddVolume attaches an existing volume to the server.
+func (b *BlockDeviceBuilder) AddVolume(volumeId string, opts VolumeOpts) {
+	b.addVolume(-1, BlockDeviceSourceVolume, volumeId, 0, opts)
+}
+
+// AddBlankVolume attaches a new, empty volume of sizeGB gigabytes to
+// the server.
+func (b *BlockDeviceBuilder) AddBlankVolume(sizeGB int, opts VolumeOpts) {
+	b.addVolume(-1, BlockDeviceSourceBlank, "", sizeGB, opts)
+}
+
+// AddEphemeral adds a local disk of sizeGB gigabytes, deleted with the
+// server. If guestFormat is not empty, such as "ext4", the disk is
+// formatted.
+func (b *BlockDeviceBuilder) AddEphemeral(sizeGB int, guestFormat string) {
+	if guestFormat == GuestFormatSwap {
+		b.setErr(fmt.Errorf("use AddSwap to add a swap device"))
+	}
+	b.addLocal(sizeGB, guestFormat)
+}
+
+// AddSwap adds a local swap disk of sizeGB gigabytes. A server can
+// have only one swap device.
+func (b *BlockDeviceBuilder) AddSwap(sizeGB int) {
+	for _, m := range b.mappings {
+		if m.GuestFormat == GuestFormatSwap {
+			b.setErr(fmt.Errorf("more than one swap device"))
+		}
+	}
+	b.addLocal(sizeGB, GuestFormatSwap)
+}
+
+func (b *BlockDeviceBuilder) addVolume(bootIndex int, sourceType, uuid string, sizeGB int, opts VolumeOpts) {
+	switch {
+	case bootIndex == 0 && b.hasBootDevice():
+		b.setErr(fmt.Errorf("more than one boot device"))
+	case sourceType != BlockDeviceSourceBlank && uuid == "":
+		b.setErr(fmt.Errorf("no %s ID given", sourceType))
+	case sizeGB < 0:
+		b.setErr(fmt.Errorf("invalid volume size %d", sizeGB))
+	case sizeGB == 0 && (sourceType == BlockDeviceSourceImage || sourceType == BlockDeviceSourceBlank):
+		b.setErr(fmt.Errorf("new %s volumes need a size", sourceType))
+	}
+	b.mappings = append(b.mappings, BlockDeviceMapping{
+		BootIndex:           bootIndex,
+		UUID:                uuid,
+		SourceType:          sourceType,
+		DestinationType:     BlockDeviceDestinationVolume,
+		VolumeSize:          sizeGB,
+		VolumeType:          opts.VolumeType,
+		DeleteOnTermination: opts.DeleteOnTermination,
+		DeviceName:          opts.DeviceName,
+		Tag:                 opts.Tag,
+	})
+}
+
+func (b *BlockDeviceBuilder) addLocal(sizeGB int, guestFormat string) {
+	if sizeGB <= 0 {
+		b.setErr(fmt.Errorf("invalid local disk size %d", sizeGB))
+	}
+	b.mappings = append(b.mappings, BlockDeviceMapping{
+		BootIndex:           -1,
+		SourceType:          BlockDeviceSourceBlank,
+		DestinationType:     BlockDeviceDestinationLocal,
+		VolumeSize:          sizeGB,
+		GuestFormat:         guestFormat,
+		DeleteOnTermination: true,
+	})
+}
+
+func (b *BlockDeviceBuilder) hasBootDevice() bool {
+	for _, m := range b.mappings {
+		if m.BootIndex == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// setErr records the first error found while building.
+func (b *BlockDeviceBuilder) setErr(err error) {
+	if b.err == nil {
+		b.err = errors.Newf(err, "invalid block device mapping %d", len(b.mappings))
+	}
+}
+
+// ResolveVolumeTypes checks the volume types of the volumes added so
+// far exist in cinder, and replaces each name with the ID of the type.
+func (b *BlockDeviceBuilder) ResolveVolumeTypes(lister VolumeTypeLister) error {
+	var types []cinder.VolumeType
+	for i, m := range b.mappings {
+		if m.VolumeType == "" {
+			continue
+		}
+		if types == nil {
+			resp, err := lister.GetVolumeTypes()
+			if err != nil {
+				return errors.Newf(err, "failed to get volume types")
+			}
+			types = resp.VolumeTypes
+		}
+		id := ""
+		for _, t := range types {
+			if t.ID == m.VolumeType || t.Name == m.VolumeType {
+				id = t.ID
+				break
+			}
+		}
+		if id == "" {
+			return errors.NewNotFoundf(nil, "", "volume type %q not found", m.VolumeType)
+		}
+		b.mappings[i].VolumeType = id
+	}
+	return nil
+}
+
+// Build returns the block device mappings, or the first error found
+// while adding them.
+func (b *BlockDeviceBuilder) Build() ([]BlockDeviceMapping, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.mappings) == 0 {
+		return nil, fmt.Errorf("no block devices added")
+	}
+	mappings := make([]BlockDeviceMapping, len(b.mappings))
+	copy(mappings, b.mappings)
+	return mappings, nil
+}
+
+// hasVolumeType reports whether any of the mappings sets a volume type.
+func hasVolumeType(mappings []BlockDeviceMapping) bool {
+	for _, m := range mappings {
+		if m.VolumeType != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package nova_test
+
+import (
+	"fmt"
+
+	gc "gopkg.in/check.v1"
+
+	"github.com/go-goose/goose/v5/cinder"
+	"github.com/go-goose/goose/v5/errors"
+	"github.com/go-goose/goose/v5/nova"
+)
+
+type BlockDeviceSuite struct {
+}
+
+var _ = gc.Suite(&BlockDeviceSuite{})
+
+func (s *BlockDeviceSuite) TestBuild(c *gc.C) {
+	var b nova.BlockDeviceBuilder
+	b.BootFromImage("image-id", 20, nova.VolumeOpts{DeleteOnTermination: true})
+	b.AddVolume("volume-id", nova.VolumeOpts{DeviceName: "/dev/vdb"})
+	b.AddBlankVolume(10, nova.VolumeOpts{Tag: "data"})
+	b.AddEphemeral(5, "ext4")
+	b.AddSwap(1)
+	mappings, err := b.Build()
+	c.Assert(err, gc.IsNil)
+	c.Assert(mappings, gc.DeepEquals, []nova.BlockDeviceMapping{{
+		BootIndex:           0,
+		UUID:                "image-id",
+		SourceType:          "image",
+		DestinationType:     "volume",
+		VolumeSize:          20,
+		DeleteOnTermination: true,
+	}, {
+		BootIndex:       -1,
+		UUID:            "volume-id",
+		SourceType:      "volume",
+		DestinationType: "volume",
+		DeviceName:      "/dev/vdb",
+	}, {
+		BootIndex:       -1,
+		SourceType:      "blank",
+		DestinationType: "volume",
+		VolumeSize:      10,
+		Tag:             "data",
+	}, {
+		BootIndex:           -1,
+		SourceType:          "blank",
+		DestinationType:     "local",
+		VolumeSize:          5,
+		GuestFormat:         "ext4",
+		DeleteOnTermination: true,
+	}, {
+		BootIndex:           -1,
+		SourceType:          "blank",
+		DestinationType:     "local",
+		VolumeSize:          1,
+		GuestFormat:         "swap",
+		DeleteOnTermination: true,
+	}})
+}
+
+func (s *BlockDeviceSuite) TestBuildInvalid(c *gc.C) {
+	for i, test := range []struct {
+		build func(b *nova.BlockDeviceBuilder)
+		err   string
+	}{{
+		build: func(b *nova.BlockDeviceBuilder) {},
+		err:   "no block devices added",
+	}, {
+		build: func(b *nova.BlockDeviceBuilder) {
+			b.BootFromVolume("volume-id", nova.VolumeOpts{})
+			b.BootFromSnapshot("snapshot-id", 0, nova.VolumeOpts{})
+		},
+		err: "invalid block device mapping 1\ncaused by: more than one boot device",
+	}, {
+		build: func(b *nova.BlockDeviceBuilder) {
+			b.BootFromImage("image-id", 0, nova.VolumeOpts{})
+		},
+		err: "invalid block device mapping 0\ncaused by: new image volumes need a size",
+	}, {
+		build: func(b *nova.BlockDeviceBuilder) {
+			b.AddVolume("", nova.VolumeOpts{})
+		},
+		err: "invalid block device mapping 0\ncaused by: no volume ID given",
+	}, {
+		build: func(b *nova.BlockDeviceBuilder) {
+			b.AddBlankVolume(-1, nova.VolumeOpts{})
+		},
+		err: "invalid block device mapping 0\ncaused by: invalid volume size -1",
+	}, {
+		build: func(b *nova.BlockDeviceBuilder) {
+			b.AddSwap(1)
+			b.AddSwap(1)
+		},
+		err: "invalid block device mapping 1\ncaused by: more than one swap device",
+	}, {
+		build: func(b *nova.BlockDeviceBuilder) {
+			b.AddEphemeral(1, "swap")
+		},
+		err: "invalid block device mapping 0\ncaused by: use AddSwap to add a swap device",
+	}, {
+		build: func(b *nova.BlockDeviceBuilder) {
+			b.AddEphemeral(0, "")
+		},
+		err: "invalid block device mapping 0\ncaused by: invalid local disk size 0",
+	}} {
+		c.Logf("test %d", i)
+		var b nova.BlockDeviceBuilder
+		test.build(&b)
+		_, err := b.Build()
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+}
+
+type fakeVolumeTypes struct {
+	types []cinder.VolumeType
+	calls int
+}
+
+func (f *fakeVolumeTypes) GetVolumeTypes() (*cinder.GetVolumeTypesResults, error) {
+	f.calls++
+	if f.types == nil {
+		return nil, fmt.Errorf("cinder unavailable")
+	}
+	return &cinder.GetVolumeTypesResults{VolumeTypes: f.types}, nil
+}
+
+func (s *BlockDeviceSuite) TestResolveVolumeTypes(c *gc.C) {
+	lister := &fakeVolumeTypes{types: []cinder.VolumeType{
+		{ID: "ssd-id", Name: "ssd"},
+		{ID: "hdd-id", Name: "hdd"},
+	}}
+	var b nova.BlockDeviceBuilder
+	b.BootFromImage("image-id", 20, nova.VolumeOpts{VolumeType: "ssd"})
+	b.AddBlankVolume(100, nova.VolumeOpts{VolumeType: "hdd-id"})
+	b.AddSwap(1)
+	c.Assert(b.ResolveVolumeTypes(lister), gc.IsNil)
+	c.Assert(lister.calls, gc.Equals, 1)
+	mappings, err := b.Build()
+	c.Assert(err, gc.IsNil)
+	c.Assert(mappings[0].VolumeType, gc.Equals, "ssd-id")
+	c.Assert(mappings[1].VolumeType, gc.Equals, "hdd-id")
+	c.Assert(mappings[2].VolumeType, gc.Equals, "")
+
+	b.AddBlankVolume(100, nova.VolumeOpts{VolumeType: "nvme"})
+	err = b.ResolveVolumeTypes(lister)
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+	c.Assert(err, gc.ErrorMatches, `volume type "nvme" not found`)
+
+	err = b.ResolveVolumeTypes(&fakeVolumeTypes{})
+	c.Assert(err, gc.ErrorMatches, "failed to get volume types\ncaused by: cinder unavailable")
+}
@@ -0,0 +1,98 @@
+// Nova api calls for accessing the consoles of a server.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#show-console-output-os-getconsoleoutput-action>
+// <https://docs.openstack.org/api-ref/compute/#server-consoles>
+
+package nova
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+const (
+	apiRemoteConsoles = "remote-consoles"
+
+	// remoteConsoleMicroversion is the compute API microversion
+	// introducing remote-consoles.
+	remoteConsoleMicroversion = "2.6"
+)
+
+// Remote console protocols.
+const (
+	ConsoleProtocolVNC    = "vnc"
+	ConsoleProtocolSpice  = "spice"
+	ConsoleProtocolRDP    = "rdp"
+	ConsoleProtocolSerial = "serial"
+	ConsoleProtocolMKS    = "mks"
+)
+
+// Remote console types. Each is only valid with one protocol.
+const (
+	ConsoleTypeNoVNC      = "novnc"       // ConsoleProtocolVNC
+	ConsoleTypeXVPVNC     = "xvpvnc"      // ConsoleProtocolVNC
+	ConsoleTypeSpiceHTML5 = "spice-html5" // ConsoleProtocolSpice
+	ConsoleTypeRDPHTML5   = "rdp-html5"   // ConsoleProtocolRDP
+	ConsoleTypeSerial     = "serial"      // ConsoleProtocolSerial
+	ConsoleTypeWebMKS     = "webmks"      // ConsoleProtocolMKS
+)
+
+// RemoteConsole describes a remote console of a server.
+type RemoteConsole struct {
+	Protocol string `json:"protocol"`
+	Type     string `json:"type"`
+	URL      string `json:"url"`
+}
+
+// GetConsoleOutput returns the console log of the specified server.
+// If length is positive only the last length lines are returned.
+func (c *Client) GetConsoleOutput(serverId string, length int) (string, error) {
+	var req struct {
+		GetConsoleOutput struct {
+			Length *int `json:"length"`
+		} `json:"os-getConsoleOutput"`
+	}
+	if length > 0 {
+		req.GetConsoleOutput.Length = &length
+	}
+	var resp struct {
+		Output string `json:"output"`
+	}
+	err := c.serverAction(serverId, req, &resp, http.StatusOK)
+	if err != nil {
+		return "", errors.Newf(err, "failed to get console output of server with id: %s", serverId)
+	}
+	return resp.Output, nil
+}
+
+// GetRemoteConsole returns a remote console of the specified server,
+// using the given protocol and console type.
+func (c *Client) GetRemoteConsole(serverId, protocol, consoleType string) (*RemoteConsole, error) {
+	var req struct {
+		RemoteConsole struct {
+			Protocol string `json:"protocol"`
+			Type     string `json:"type"`
+		} `json:"remote_console"`
+	}
+	req.RemoteConsole.Protocol = protocol
+	req.RemoteConsole.Type = consoleType
+	var resp struct {
+		RemoteConsole RemoteConsole `json:"remote_console"`
+	}
+	url := fmt.Sprintf("%s/%s/%s", apiServers, serverId, apiRemoteConsoles)
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ReqHeaders:     microversionHeaders(remoteConsoleMicroversion),
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	err := c.client.SendRequest(client.POST, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get %s %s console of server with id: %s", protocol, consoleType, serverId)
+	}
+	return &resp.RemoteConsole, nil
+}
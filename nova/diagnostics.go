@@ -0,0 +1,103 @@
+// Nova api calls for the diagnostics of a server, reported by its
+// hypervisor. These calls are restricted to administrators by
+// default.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#servers-diagnostics-servers-diagnostics>
+
+package nova
+
+import (
+	"fmt"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+// diagnosticsMicroversion is the compute API microversion reporting
+// diagnostics in the same format whatever the hypervisor.
+const diagnosticsMicroversion = "2.48"
+
+// States of a server as reported by the hypervisor, in
+// ServerDiagnostics.State.
+const (
+	DiagnosticsStatePending   = "pending"
+	DiagnosticsStateRunning   = "running"
+	DiagnosticsStatePaused    = "paused"
+	DiagnosticsStateShutdown  = "shutdown"
+	DiagnosticsStateCrashed   = "crashed"
+	DiagnosticsStateSuspended = "suspended"
+)
+
+// DiagnosticsMemory holds the memory of a server in MB.
+type DiagnosticsMemory struct {
+	Maximum int `json:"maximum"`
+	Used    int `json:"used"`
+}
+
+// DiagnosticsCPU holds the time in nanoseconds a virtual CPU has run
+// for, and its utilisation as a percentage.
+type DiagnosticsCPU struct {
+	Id          int   `json:"id"`
+	Time        int64 `json:"time"`
+	Utilisation int   `json:"utilisation"`
+}
+
+// DiagnosticsNIC holds the traffic counters of a network interface.
+type DiagnosticsNIC struct {
+	MACAddress string `json:"mac_address"`
+	RxOctets   int64  `json:"rx_octets"`
+	RxErrors   int64  `json:"rx_errors"`
+	RxDrop     int64  `json:"rx_drop"`
+	RxPackets  int64  `json:"rx_packets"`
+	RxRate     int64  `json:"rx_rate"`
+	TxOctets   int64  `json:"tx_octets"`
+	TxErrors   int64  `json:"tx_errors"`
+	TxDrop     int64  `json:"tx_drop"`
+	TxPackets  int64  `json:"tx_packets"`
+	TxRate     int64  `json:"tx_rate"`
+}
+
+// DiagnosticsDisk holds the I/O counters of a disk.
+type DiagnosticsDisk struct {
+	ReadBytes     int64 `json:"read_bytes"`
+	ReadRequests  int64 `json:"read_requests"`
+	WriteBytes    int64 `json:"write_bytes"`
+	WriteRequests int64 `json:"write_requests"`
+	ErrorsCount   int64 `json:"errors_count"`
+}
+
+// ServerDiagnostics describes the state and resource usage of a
+// server, as seen by its hypervisor. Uptime is in seconds. Counters a
+// hypervisor does not report are zero.
+type ServerDiagnostics struct {
+	State         string            `json:"state"`
+	Driver        string            `json:"driver"`
+	Hypervisor    string            `json:"hypervisor"`
+	HypervisorOS  string            `json:"hypervisor_os"`
+	Uptime        int               `json:"uptime"`
+	ConfigDrive   bool              `json:"config_drive"`
+	NumCPUs       int               `json:"num_cpus"`
+	NumNICs       int               `json:"num_nics"`
+	NumDisks      int               `json:"num_disks"`
+	MemoryDetails DiagnosticsMemory `json:"memory_details"`
+	CPUDetails    []DiagnosticsCPU  `json:"cpu_details"`
+	NICDetails    []DiagnosticsNIC  `json:"nic_details"`
+	DiskDetails   []DiagnosticsDisk `json:"disk_details"`
+}
+
+// GetServerDiagnostics returns the diagnostics of the specified
+// server.
+func (c *Client) GetServerDiagnostics(serverId string) (*ServerDiagnostics, error) {
+	var resp ServerDiagnostics
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(diagnosticsMicroversion),
+	}
+	url := fmt.Sprintf("%s/%s/diagnostics", apiServers, serverId)
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get diagnostics of server with id: %s", serverId)
+	}
+	return &resp, nil
+}
@@ -3,3 +3,5 @@ package nova
 func UseNumericIds(val bool) {
 	useNumericIds = val
 }
+
+var NewRunServerRequest = newRunServerRequest
@@ -0,0 +1,227 @@
+// Nova api calls for administering flavors, their extra specs, and
+// which projects may use private flavors.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#flavors>
+// <https://docs.openstack.org/api-ref/compute/#flavors-extra-specs-flavors-flavor-id-os-extra-specs>
+// <https://docs.openstack.org/api-ref/compute/#flavors-access-flavors-os-flavor-access>
+
+package nova
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+const (
+	apiFlavorExtraSpecs = "os-extra_specs"
+	apiFlavorAccess     = "os-flavor-access"
+)
+
+// FlavorOpts defines required and optional arguments for
+// CreateFlavor().
+type FlavorOpts struct {
+	Name       string  `json:"name"`                                // Required
+	RAM        int     `json:"ram"`                                 // Required, in MB
+	VCPUs      int     `json:"vcpus"`                               // Required
+	Disk       int     `json:"disk"`                                // Required, in GB
+	Id         string  `json:"id,omitempty"`                        // Optional, generated if empty
+	Swap       int     `json:"swap,omitempty"`                      // Optional, in MB
+	Ephemeral  int     `json:"OS-FLV-EXT-DATA:ephemeral,omitempty"` // Optional, in GB
+	RxTxFactor float64 `json:"rxtx_factor,omitempty"`               // Optional
+
+	// IsPublic defines whether all projects may use the flavor. If
+	// nil the flavor is public.
+	IsPublic *bool `json:"os-flavor-access:is_public,omitempty"`
+}
+
+// FlavorAccess records that a project may use a private flavor.
+type FlavorAccess struct {
+	FlavorId string `json:"flavor_id"`
+	TenantId string `json:"tenant_id"`
+}
+
+// GetFlavor returns the flavor with the given ID.
+func (c *Client) GetFlavor(flavorId string) (*FlavorDetail, error) {
+	var resp struct {
+		Flavor FlavorDetail `json:"flavor"`
+	}
+	url := fmt.Sprintf("%s/%s", apiFlavors, flavorId)
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get flavor with id: %s", flavorId)
+	}
+	return &resp.Flavor, nil
+}
+
+// FindFlavor returns the flavor whose ID or, failing that, name is
+// nameOrId. It is an error if several flavors have the name.
+func (c *Client) FindFlavor(nameOrId string) (*FlavorDetail, error) {
+	flavor, err := c.GetFlavor(nameOrId)
+	if err == nil {
+		return flavor, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	flavors, err := c.ListFlavorsDetail()
+	if err != nil {
+		return nil, err
+	}
+	var found *FlavorDetail
+	for i := range flavors {
+		if flavors[i].Name != nameOrId {
+			continue
+		}
+		if found != nil {
+			return nil, errors.NewDuplicateValuef(nil, nameOrId, "more than one flavor is named %q", nameOrId)
+		}
+		found = &flavors[i]
+	}
+	if found == nil {
+		return nil, errors.NewNotFoundf(nil, nameOrId, "no flavor with id or name %q", nameOrId)
+	}
+	return found, nil
+}
+
+// CreateFlavor creates a new flavor. This is usually restricted to
+// administrators.
+func (c *Client) CreateFlavor(opts FlavorOpts) (*FlavorDetail, error) {
+	var req struct {
+		Flavor FlavorOpts `json:"flavor"`
+	}
+	req.Flavor = opts
+	var resp struct {
+		Flavor FlavorDetail `json:"flavor"`
+	}
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusOK}}
+	err := c.client.SendRequest(client.POST, "compute", "v2", apiFlavors, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to create flavor %q", opts.Name)
+	}
+	return &resp.Flavor, nil
+}
+
+// DeleteFlavor deletes the flavor with the given ID. Existing servers
+// using the flavor are not affected.
+func (c *Client) DeleteFlavor(flavorId string) error {
+	url := fmt.Sprintf("%s/%s", apiFlavors, flavorId)
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusAccepted}}
+	err := c.client.SendRequest(client.DELETE, "compute", "v2", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete flavor with id: %s", flavorId)
+	}
+	return err
+}
+
+// GetFlavorExtraSpecs returns the extra specs of the given flavor.
+func (c *Client) GetFlavorExtraSpecs(flavorId string) (map[string]string, error) {
+	var resp struct {
+		ExtraSpecs map[string]string `json:"extra_specs"`
+	}
+	url := fmt.Sprintf("%s/%s/%s", apiFlavors, flavorId, apiFlavorExtraSpecs)
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get extra specs of flavor with id: %s", flavorId)
+	}
+	return resp.ExtraSpecs, nil
+}
+
+// GetFlavorExtraSpec returns the value of a single extra spec of the
+// given flavor.
+func (c *Client) GetFlavorExtraSpec(flavorId, key string) (string, error) {
+	var resp map[string]string
+	url := fmt.Sprintf("%s/%s/%s/%s", apiFlavors, flavorId, apiFlavorExtraSpecs, key)
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return "", errors.Newf(err, "failed to get extra spec %q of flavor with id: %s", key, flavorId)
+	}
+	return resp[key], nil
+}
+
+// SetFlavorExtraSpecs creates or updates extra specs of the given
+// flavor. Extra specs not in specs are left unchanged.
+func (c *Client) SetFlavorExtraSpecs(flavorId string, specs map[string]string) (map[string]string, error) {
+	var req struct {
+		ExtraSpecs map[string]string `json:"extra_specs"`
+	}
+	req.ExtraSpecs = specs
+	var resp struct {
+		ExtraSpecs map[string]string `json:"extra_specs"`
+	}
+	url := fmt.Sprintf("%s/%s/%s", apiFlavors, flavorId, apiFlavorExtraSpecs)
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusOK}}
+	err := c.client.SendRequest(client.POST, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to set extra specs of flavor with id: %s", flavorId)
+	}
+	return resp.ExtraSpecs, nil
+}
+
+// UnsetFlavorExtraSpec deletes an extra spec of the given flavor.
+func (c *Client) UnsetFlavorExtraSpec(flavorId, key string) error {
+	url := fmt.Sprintf("%s/%s/%s/%s", apiFlavors, flavorId, apiFlavorExtraSpecs, key)
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusOK}}
+	err := c.client.SendRequest(client.DELETE, "compute", "v2", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to unset extra spec %q of flavor with id: %s", key, flavorId)
+	}
+	return err
+}
+
+// ListFlavorAccess lists the projects which may use the given private
+// flavor.
+func (c *Client) ListFlavorAccess(flavorId string) ([]FlavorAccess, error) {
+	var resp struct {
+		FlavorAccess []FlavorAccess `json:"flavor_access"`
+	}
+	url := fmt.Sprintf("%s/%s/%s", apiFlavors, flavorId, apiFlavorAccess)
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get access list of flavor with id: %s", flavorId)
+	}
+	return resp.FlavorAccess, nil
+}
+
+// AddFlavorAccess allows the given project to use the given private
+// flavor, and returns the updated access list.
+func (c *Client) AddFlavorAccess(flavorId, tenantId string) ([]FlavorAccess, error) {
+	access, err := c.flavorAccessAction(flavorId, "addTenantAccess", tenantId)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to add access to flavor with id: %s for tenant: %s", flavorId, tenantId)
+	}
+	return access, nil
+}
+
+// RemoveFlavorAccess stops the given project using the given private
+// flavor, and returns the updated access list.
+func (c *Client) RemoveFlavorAccess(flavorId, tenantId string) ([]FlavorAccess, error) {
+	access, err := c.flavorAccessAction(flavorId, "removeTenantAccess", tenantId)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to remove access to flavor with id: %s for tenant: %s", flavorId, tenantId)
+	}
+	return access, nil
+}
+
+func (c *Client) flavorAccessAction(flavorId, action, tenantId string) ([]FlavorAccess, error) {
+	req := map[string]interface{}{
+		action: map[string]string{"tenant": tenantId},
+	}
+	var resp struct {
+		FlavorAccess []FlavorAccess `json:"flavor_access"`
+	}
+	url := fmt.Sprintf("%s/%s/action", apiFlavors, flavorId)
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusOK}}
+	err := c.client.SendRequest(client.POST, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, err
+	}
+	return resp.FlavorAccess, nil
+}
@@ -0,0 +1,135 @@
+// Nova api calls for listing the hypervisors of the compute hosts,
+// with their capacity and usage. These calls are restricted to
+// administrators by default.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#hypervisors-os-hypervisors>
+
+package nova
+
+import (
+	"fmt"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+const (
+	apiHypervisors = "os-hypervisors"
+
+	// hostAdminMicroversion is the compute API microversion using
+	// UUIDs to identify hypervisors and services. It is requested by
+	// all the hypervisor and service calls.
+	hostAdminMicroversion = "2.53"
+)
+
+// States of a hypervisor or service, as reported by their State.
+const (
+	HostStateUp   = "up"
+	HostStateDown = "down"
+)
+
+// Statuses of a hypervisor or service, as reported by their Status.
+const (
+	HostStatusEnabled  = "enabled"
+	HostStatusDisabled = "disabled"
+)
+
+// HypervisorService describes the nova-compute service managing a
+// hypervisor.
+type HypervisorService struct {
+	Id             string `json:"id"`
+	Host           string `json:"host"`
+	DisabledReason string `json:"disabled_reason"`
+}
+
+// Hypervisor describes a hypervisor, with its capacity and the
+// resources used by the servers running on it. Memory is in MB and
+// disk in GB.
+type Hypervisor struct {
+	Id                 string            `json:"id"`
+	HypervisorHostname string            `json:"hypervisor_hostname"`
+	HypervisorType     string            `json:"hypervisor_type"`
+	HypervisorVersion  int               `json:"hypervisor_version"`
+	HostIP             string            `json:"host_ip"`
+	State              string            `json:"state"`
+	Status             string            `json:"status"`
+	Service            HypervisorService `json:"service"`
+	VCPUs              int               `json:"vcpus"`
+	VCPUsUsed          int               `json:"vcpus_used"`
+	MemoryMB           int               `json:"memory_mb"`
+	MemoryMBUsed       int               `json:"memory_mb_used"`
+	FreeRAMMB          int               `json:"free_ram_mb"`
+	LocalGB            int               `json:"local_gb"`
+	LocalGBUsed        int               `json:"local_gb_used"`
+	FreeDiskGB         int               `json:"free_disk_gb"`
+	RunningVMs         int               `json:"running_vms"`
+	CurrentWorkload    int               `json:"current_workload"`
+}
+
+// HypervisorStatistics holds the capacity and usage of all the
+// hypervisors added together.
+type HypervisorStatistics struct {
+	Count        int `json:"count"`
+	VCPUs        int `json:"vcpus"`
+	VCPUsUsed    int `json:"vcpus_used"`
+	MemoryMB     int `json:"memory_mb"`
+	MemoryMBUsed int `json:"memory_mb_used"`
+	FreeRAMMB    int `json:"free_ram_mb"`
+	LocalGB      int `json:"local_gb"`
+	LocalGBUsed  int `json:"local_gb_used"`
+	FreeDiskGB   int `json:"free_disk_gb"`
+	RunningVMs   int `json:"running_vms"`
+}
+
+// ListHypervisors lists all the hypervisors, with their usage.
+func (c *Client) ListHypervisors() ([]Hypervisor, error) {
+	var resp struct {
+		Hypervisors []Hypervisor `json:"hypervisors"`
+	}
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(hostAdminMicroversion),
+	}
+	url := fmt.Sprintf("%s/detail", apiHypervisors)
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to list hypervisors")
+	}
+	return resp.Hypervisors, nil
+}
+
+// GetHypervisor returns the hypervisor with the given ID.
+func (c *Client) GetHypervisor(hypervisorId string) (*Hypervisor, error) {
+	var resp struct {
+		Hypervisor Hypervisor `json:"hypervisor"`
+	}
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(hostAdminMicroversion),
+	}
+	url := fmt.Sprintf("%s/%s", apiHypervisors, hypervisorId)
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get hypervisor with id: %s", hypervisorId)
+	}
+	return &resp.Hypervisor, nil
+}
+
+// GetHypervisorStatistics returns the capacity and usage of all the
+// hypervisors added together.
+func (c *Client) GetHypervisorStatistics() (*HypervisorStatistics, error) {
+	var resp struct {
+		Statistics HypervisorStatistics `json:"hypervisor_statistics"`
+	}
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(hostAdminMicroversion),
+	}
+	url := fmt.Sprintf("%s/statistics", apiHypervisors)
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get hypervisor statistics")
+	}
+	return &resp.Statistics, nil
+}
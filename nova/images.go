@@ -0,0 +1,103 @@
+// Nova api calls for creating images of a server, either as one-off
+// snapshots or as rotated backups. The images are created
+// asynchronously by glance, see glance.Client.WaitForImage.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#create-image-createimage-action>
+// <https://docs.openstack.org/api-ref/compute/#create-server-back-up-createbackup-action>
+
+package nova
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+// Backup types used by BackupServer.
+const (
+	BackupDaily  = "daily"
+	BackupWeekly = "weekly"
+)
+
+// CreateImageOpts defines required and optional arguments for
+// CreateServerImage().
+type CreateImageOpts struct {
+	Name     string            `json:"name"`               // Required
+	Metadata map[string]string `json:"metadata,omitempty"` // Optional
+}
+
+// BackupOpts defines required and optional arguments for
+// BackupServer().
+type BackupOpts struct {
+	Name       string `json:"name"`        // Required
+	BackupType string `json:"backup_type"` // Required, e.g. BackupDaily
+
+	// Rotation is the number of backups of BackupType to keep for
+	// the server. Older backups are deleted. Required.
+	Rotation int `json:"rotation"`
+
+	Metadata map[string]string `json:"metadata,omitempty"` // Optional
+}
+
+// CreateServerImage creates a snapshot image of the given server, and
+// returns the ID of the new image.
+func (c *Client) CreateServerImage(serverId string, opts CreateImageOpts) (string, error) {
+	req := struct {
+		CreateImage CreateImageOpts `json:"createImage"`
+	}{opts}
+	imageId, err := c.imageAction(serverId, req)
+	if err == nil && imageId == "" {
+		err = fmt.Errorf("no image id returned")
+	}
+	if err != nil {
+		return "", errors.Newf(err, "failed to create image %q of server with id: %s", opts.Name, serverId)
+	}
+	return imageId, nil
+}
+
+// BackupServer creates a backup image of the given server, deleting
+// the oldest backups of the same type beyond opts.Rotation, and
+// returns the ID of the new image. The ID is empty if opts.Rotation
+// is 0, as no backup is kept.
+func (c *Client) BackupServer(serverId string, opts BackupOpts) (string, error) {
+	req := struct {
+		CreateBackup BackupOpts `json:"createBackup"`
+	}{opts}
+	imageId, err := c.imageAction(serverId, req)
+	if err != nil {
+		return "", errors.Newf(err, "failed to create backup %q of server with id: %s", opts.Name, serverId)
+	}
+	return imageId, nil
+}
+
+// imageAction sends an action creating an image of the given server,
+// and returns the ID of the image. The ID is in the response body
+// from microversion 2.45, and in the Location header before it.
+func (c *Client) imageAction(serverId string, req interface{}) (string, error) {
+	var resp struct {
+		ImageId string `json:"image_id"`
+	}
+	actionURL := fmt.Sprintf("%s/%s/action", apiServers, serverId)
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusAccepted}}
+	err := c.client.SendRequest(client.POST, "compute", "v2", actionURL, &requestData)
+	if err != nil {
+		return "", err
+	}
+	if resp.ImageId != "" {
+		return resp.ImageId, nil
+	}
+	location := requestData.RespHeaders.Get("Location")
+	if location == "" {
+		return "", nil
+	}
+	imageURL, err := url.Parse(location)
+	if err != nil {
+		return "", errors.Newf(err, "invalid image location %q", location)
+	}
+	return path.Base(imageURL.Path), nil
+}
@@ -0,0 +1,123 @@
+// Nova api calls for the action and event history of a server.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#servers-actions-servers-os-instance-actions>
+
+package nova
+
+import (
+	"fmt"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+const (
+	apiInstanceActions = "os-instance-actions"
+
+	// instanceActionsMicroversion is the compute API microversion
+	// allowing the actions of deleted servers to be shown.
+	instanceActionsMicroversion = "2.21"
+)
+
+// Names of the actions recorded against a server.
+const (
+	ActionCreate          = "create"
+	ActionDelete          = "delete"
+	ActionReboot          = "reboot"
+	ActionStop            = "stop"
+	ActionStart           = "start"
+	ActionPause           = "pause"
+	ActionUnpause         = "unpause"
+	ActionSuspend         = "suspend"
+	ActionResume          = "resume"
+	ActionShelve          = "shelve"
+	ActionUnshelve        = "unshelve"
+	ActionRebuild         = "rebuild"
+	ActionResize          = "resize"
+	ActionConfirmResize   = "confirmResize"
+	ActionRevertResize    = "revertResize"
+	ActionAttachVolume    = "attach_volume"
+	ActionDetachVolume    = "detach_volume"
+	ActionAttachInterface = "attach_interface"
+	ActionDetachInterface = "detach_interface"
+	ActionCreateImage     = "createImage"
+	ActionCreateBackup    = "createBackup"
+	ActionLiveMigration   = "live-migration"
+	ActionMigrate         = "migrate"
+	ActionEvacuate        = "evacuate"
+	ActionRescue          = "rescue"
+	ActionUnrescue        = "unrescue"
+
+	ActionLiveMigrationForceComplete = "live_migration_force_complete"
+)
+
+// Results of an instance action event.
+const (
+	EventResultSuccess = "Success"
+	EventResultError   = "Error"
+)
+
+// InstanceActionEvent describes one step taken by nova to carry out
+// an instance action. Traceback, Host and HostId are only shown to
+// administrators.
+type InstanceActionEvent struct {
+	Event      string `json:"event"`
+	StartTime  string `json:"start_time"`
+	FinishTime string `json:"finish_time"`
+	Result     string `json:"result"`
+	Traceback  string `json:"traceback,omitempty"`
+	Host       string `json:"host,omitempty"`
+	HostId     string `json:"hostId,omitempty"`
+	Details    string `json:"details,omitempty"`
+}
+
+// InstanceAction describes a request made against a server. Message
+// is set when the action failed. Events are only returned by
+// GetInstanceAction.
+type InstanceAction struct {
+	Action       string                `json:"action"`
+	InstanceUUID string                `json:"instance_uuid"`
+	Message      string                `json:"message"`
+	ProjectId    string                `json:"project_id"`
+	RequestId    string                `json:"request_id"`
+	StartTime    string                `json:"start_time"`
+	UserId       string                `json:"user_id"`
+	Events       []InstanceActionEvent `json:"events,omitempty"`
+}
+
+// ListInstanceActions lists the actions made against the given server,
+// most recent first. The actions of deleted servers are included.
+func (c *Client) ListInstanceActions(serverId string) ([]InstanceAction, error) {
+	var resp struct {
+		InstanceActions []InstanceAction `json:"instanceActions"`
+	}
+	url := fmt.Sprintf("%s/%s/%s", apiServers, serverId, apiInstanceActions)
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(instanceActionsMicroversion),
+	}
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get actions of server with id: %s", serverId)
+	}
+	return resp.InstanceActions, nil
+}
+
+// GetInstanceAction returns the action with the given request ID made
+// against the given server, along with its events.
+func (c *Client) GetInstanceAction(serverId, requestId string) (*InstanceAction, error) {
+	var resp struct {
+		InstanceAction InstanceAction `json:"instanceAction"`
+	}
+	url := fmt.Sprintf("%s/%s/%s/%s", apiServers, serverId, apiInstanceActions, requestId)
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(instanceActionsMicroversion),
+	}
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get action %s of server with id: %s", requestId, serverId)
+	}
+	return &resp.InstanceAction, nil
+}
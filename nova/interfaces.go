@@ -0,0 +1,104 @@
+// Nova api calls for attaching network interfaces to, and detaching
+// them from, a running server.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#port-interfaces-servers-os-interface>
+
+package nova
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+// interfaceTagMicroversion is the compute API microversion allowing
+// attached interfaces to be tagged.
+const interfaceTagMicroversion = "2.49"
+
+// AttachInterfaceOpts defines the arguments for AttachOSInterface().
+// One of NetworkId or PortId is required.
+type AttachInterfaceOpts struct {
+	// NetworkId is the network to create a new port on.
+	NetworkId string
+
+	// PortId is an existing, unused port to attach.
+	PortId string
+
+	// FixedIP is the address to give the new port on NetworkId. If
+	// empty an address is allocated.
+	FixedIP string
+
+	// Tag is a device role tag for the interface, shown to the
+	// server through the metadata service.
+	Tag string
+}
+
+// AttachOSInterface attaches a network interface to the given server,
+// and returns the interface.
+func (c *Client) AttachOSInterface(serverId string, opts AttachInterfaceOpts) (*OSInterface, error) {
+	type fixedIP struct {
+		IPAddress string `json:"ip_address"`
+	}
+	var req struct {
+		InterfaceAttachment struct {
+			NetId    string    `json:"net_id,omitempty"`
+			PortId   string    `json:"port_id,omitempty"`
+			FixedIPs []fixedIP `json:"fixed_ips,omitempty"`
+			Tag      string    `json:"tag,omitempty"`
+		} `json:"interfaceAttachment"`
+	}
+	req.InterfaceAttachment.NetId = opts.NetworkId
+	req.InterfaceAttachment.PortId = opts.PortId
+	if opts.FixedIP != "" {
+		req.InterfaceAttachment.FixedIPs = []fixedIP{{opts.FixedIP}}
+	}
+	req.InterfaceAttachment.Tag = opts.Tag
+	var resp struct {
+		InterfaceAttachment OSInterface `json:"interfaceAttachment"`
+	}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	if opts.Tag != "" {
+		requestData.ReqHeaders = microversionHeaders(interfaceTagMicroversion)
+	}
+	url := fmt.Sprintf("%s/%s/%s", apiServers, serverId, apiOSInterface)
+	err := c.client.SendRequest(client.POST, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to attach interface to server with id: %s", serverId)
+	}
+	return &resp.InterfaceAttachment, nil
+}
+
+// GetOSInterface returns the interface of the given server with the
+// given port ID.
+func (c *Client) GetOSInterface(serverId, portId string) (*OSInterface, error) {
+	var resp struct {
+		InterfaceAttachment OSInterface `json:"interfaceAttachment"`
+	}
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	url := fmt.Sprintf("%s/%s/%s/%s", apiServers, serverId, apiOSInterface, portId)
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get interface %s of server with id: %s", portId, serverId)
+	}
+	return &resp.InterfaceAttachment, nil
+}
+
+// DetachOSInterface detaches the interface with the given port ID from
+// the given server. A port created by AttachOSInterface is deleted,
+// while an existing port which was attached is kept.
+func (c *Client) DetachOSInterface(serverId, portId string) error {
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusAccepted}}
+	url := fmt.Sprintf("%s/%s/%s/%s", apiServers, serverId, apiOSInterface, portId)
+	err := c.client.SendRequest(client.DELETE, "compute", "v2", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to detach interface %s from server with id: %s", portId, serverId)
+	}
+	return err
+}
@@ -123,7 +123,12 @@ func (entity Entity) MarshalJSON() ([]byte, error) {
 type jsonFlavorDetail FlavorDetail
 
 func (flavorDetail *FlavorDetail) UnmarshalJSON(b []byte) error {
-	var jfd jsonFlavorDetail = jsonFlavorDetail(*flavorDetail)
+	// Clouds older than microversion 2.75 report no swap as "".
+	var jfd struct {
+		jsonFlavorDetail
+		Swap interface{} `json:"swap"`
+	}
+	jfd.jsonFlavorDetail = jsonFlavorDetail(*flavorDetail)
 	var err error
 	if err = json.Unmarshal(b, &jfd); err != nil {
 		return err
@@ -131,7 +136,17 @@ func (flavorDetail *FlavorDetail) UnmarshalJSON(b []byte) error {
 	if jfd.Id, err = getIdAsString(b, idTag); err != nil {
 		return err
 	}
-	*flavorDetail = FlavorDetail(jfd)
+	switch swap := jfd.Swap.(type) {
+	case float64:
+		jfd.jsonFlavorDetail.Swap = int(swap)
+	case string:
+		if swap != "" {
+			if jfd.jsonFlavorDetail.Swap, err = strconv.Atoi(swap); err != nil {
+				return fmt.Errorf("invalid flavor swap %q", swap)
+			}
+		}
+	}
+	*flavorDetail = FlavorDetail(jfd.jsonFlavorDetail)
 	return nil
 }
 
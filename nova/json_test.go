@@ -40,6 +40,21 @@ func (s *JsonSuite) TestMarshallFlavorDetailLargeIntId(c *gc.C) {
 	s.assertMarshallRoundtrip(c, &fd, &unmarshalled)
 }
 
+func (s *JsonSuite) TestUnmarshallFlavorDetailSwap(c *gc.C) {
+	for data, swap := range map[string]int{
+		`{"id": 1, "name": "test", "swap": ""}`:    0,
+		`{"id": 1, "name": "test", "swap": 512}`:   512,
+		`{"id": 1, "name": "test", "swap": "512"}`: 512,
+		`{"id": 1, "name": "test"}`:                0,
+	} {
+		var fd nova.FlavorDetail
+		err := json.Unmarshal([]byte(data), &fd)
+		c.Assert(err, gc.IsNil)
+		c.Check(fd.Id, gc.Equals, "1")
+		c.Check(fd.Swap, gc.Equals, swap)
+	}
+}
+
 func (s *JsonSuite) TestMarshallServerDetailLargeIntId(c *gc.C) {
 	fd := nova.Entity{Id: "2000000", Name: "test"}
 	im := nova.Entity{Id: "2000000", Name: "test"}
@@ -92,3 +107,31 @@ func (s *JsonSuite) TestUnmarshallServerDetailImage(c *gc.C) {
 	}
 	c.Assert(image, gc.DeepEquals, expected)
 }
+
+func (s *JsonSuite) TestMarshallRunServerRequestNetworks(c *gc.C) {
+	networks := func(opts nova.RunServerOpts) interface{} {
+		data, err := json.Marshal(nova.NewRunServerRequest(opts))
+		c.Assert(err, gc.IsNil)
+		var req struct {
+			Server map[string]interface{} `json:"server"`
+		}
+		err = json.Unmarshal(data, &req)
+		c.Assert(err, gc.IsNil)
+		value, ok := req.Server["networks"]
+		c.Assert(ok, gc.Equals, true)
+		return value
+	}
+	opts := nova.RunServerOpts{Name: "test", FlavorId: "1", ImageId: "1"}
+	c.Check(networks(opts), gc.IsNil)
+
+	// Microversions from 2.37 require networks.
+	tagged := opts
+	tagged.Tags = []string{"web"}
+	c.Check(networks(tagged), gc.Equals, "auto")
+	typed := opts
+	typed.BlockDeviceMappings = []nova.BlockDeviceMapping{{BootIndex: 0, SourceType: "image", DestinationType: "volume", VolumeType: "ssd"}}
+	c.Check(networks(typed), gc.Equals, "auto")
+
+	tagged.Networks = []nova.ServerNetworks{{NetworkId: "net"}}
+	c.Check(networks(tagged), gc.DeepEquals, []interface{}{map[string]interface{}{"uuid": "net"}})
+}
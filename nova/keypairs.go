@@ -0,0 +1,148 @@
+// Nova api calls for managing the keypairs used to access servers.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#keypairs-keypairs>
+
+package nova
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+const (
+	apiKeyPairs = "os-keypairs"
+
+	// keyPairTypeMicroversion is the compute API microversion
+	// introducing keypair types. It is requested by all the
+	// keypair calls.
+	keyPairTypeMicroversion = "2.2"
+)
+
+// Keypair types. Clouds older than microversion 2.2 treat every
+// keypair as KeyTypeSSH.
+const (
+	KeyTypeSSH  = "ssh"
+	KeyTypeX509 = "x509"
+)
+
+// KeyPair describes a keypair which can be used with RunServer.
+type KeyPair struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	PublicKey   string `json:"public_key"`
+	Fingerprint string `json:"fingerprint"`
+	UserId      string `json:"user_id,omitempty"`
+	Created     string `json:"created_at,omitempty"`
+
+	// PrivateKey is only returned by CreateKeyPair, when the
+	// keypair is generated by the cloud. It cannot be retrieved
+	// later.
+	PrivateKey string `json:"private_key,omitempty"`
+}
+
+// ListKeyPairs lists the keypairs of the current user.
+func (c *Client) ListKeyPairs() ([]KeyPair, error) {
+	var resp struct {
+		KeyPairs []struct {
+			KeyPair KeyPair `json:"keypair"`
+		} `json:"keypairs"`
+	}
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(keyPairTypeMicroversion),
+	}
+	err := c.client.SendRequest(client.GET, "compute", "v2", apiKeyPairs, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get list of keypairs")
+	}
+	keyPairs := make([]KeyPair, len(resp.KeyPairs))
+	for i, kp := range resp.KeyPairs {
+		keyPairs[i] = kp.KeyPair
+	}
+	return keyPairs, nil
+}
+
+// GetKeyPair returns the keypair with the given name.
+func (c *Client) GetKeyPair(name string) (*KeyPair, error) {
+	var resp struct {
+		KeyPair KeyPair `json:"keypair"`
+	}
+	url := fmt.Sprintf("%s/%s", apiKeyPairs, name)
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(keyPairTypeMicroversion),
+	}
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get keypair %q", name)
+	}
+	return &resp.KeyPair, nil
+}
+
+// CreateKeyPair has the cloud generate a new keypair with the given
+// name and type, which may be empty for the default. The returned
+// keypair holds the private key.
+func (c *Client) CreateKeyPair(name, keyType string) (*KeyPair, error) {
+	keyPair, err := c.createKeyPair(KeyPair{Name: name, Type: keyType})
+	if err != nil {
+		return nil, errors.Newf(err, "failed to create keypair %q", name)
+	}
+	return keyPair, nil
+}
+
+// ImportKeyPair creates a keypair with the given name and type, which
+// may be empty for the default, from an existing public key.
+func (c *Client) ImportKeyPair(name, publicKey, keyType string) (*KeyPair, error) {
+	keyPair, err := c.createKeyPair(KeyPair{Name: name, PublicKey: publicKey, Type: keyType})
+	if err != nil {
+		return nil, errors.Newf(err, "failed to import keypair %q", name)
+	}
+	return keyPair, nil
+}
+
+func (c *Client) createKeyPair(keyPair KeyPair) (*KeyPair, error) {
+	var req struct {
+		KeyPair struct {
+			Name      string `json:"name"`
+			Type      string `json:"type,omitempty"`
+			PublicKey string `json:"public_key,omitempty"`
+		} `json:"keypair"`
+	}
+	req.KeyPair.Name = keyPair.Name
+	req.KeyPair.Type = keyPair.Type
+	req.KeyPair.PublicKey = keyPair.PublicKey
+	var resp struct {
+		KeyPair KeyPair `json:"keypair"`
+	}
+	requestData := goosehttp.RequestData{
+		ReqValue:   req,
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(keyPairTypeMicroversion),
+		// Microversion 2.2 changed the response from 200 to 201.
+		ExpectedStatus: []int{http.StatusOK, http.StatusCreated},
+	}
+	err := c.client.SendRequest(client.POST, "compute", "v2", apiKeyPairs, &requestData)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.KeyPair, nil
+}
+
+// DeleteKeyPair deletes the keypair with the given name.
+func (c *Client) DeleteKeyPair(name string) error {
+	url := fmt.Sprintf("%s/%s", apiKeyPairs, name)
+	requestData := goosehttp.RequestData{
+		ReqHeaders: microversionHeaders(keyPairTypeMicroversion),
+		// Microversion 2.2 changed the response from 202 to 204.
+		ExpectedStatus: []int{http.StatusAccepted, http.StatusNoContent},
+	}
+	err := c.client.SendRequest(client.DELETE, "compute", "v2", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete keypair %q", name)
+	}
+	return err
+}
@@ -0,0 +1,187 @@
+// Nova api calls for changing the power and lifecycle state of a
+// server. Each of them is a server action, the new state is reached
+// asynchronously and can be observed through the server status.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#servers-run-an-action-servers-action>
+
+package nova
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+// Reboot types used by RebootServer.
+const (
+	RebootSoft = "SOFT" // Ask the guest operating system to restart.
+	RebootHard = "HARD" // Power cycle the server.
+)
+
+// serverAction sends the given action request to the specified server.
+func (c *Client) serverAction(serverId string, req interface{}, resp interface{}, expectedStatus int) error {
+	url := fmt.Sprintf("%s/%s/action", apiServers, serverId)
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: resp, ExpectedStatus: []int{expectedStatus}}
+	return c.client.SendRequest(client.POST, "compute", "v2", url, &requestData)
+}
+
+// simpleServerAction sends an action which takes no arguments, such
+// as {"pause": null}, to the specified server.
+func (c *Client) simpleServerAction(serverId, action string, expectedStatus int) error {
+	err := c.serverAction(serverId, map[string]interface{}{action: nil}, nil, expectedStatus)
+	if err != nil {
+		err = errors.Newf(err, "failed to %s server with id: %s", action, serverId)
+	}
+	return err
+}
+
+// RebootServer reboots the specified server. The rebootType is one
+// of RebootSoft or RebootHard.
+func (c *Client) RebootServer(serverId, rebootType string) error {
+	var req struct {
+		Reboot struct {
+			Type string `json:"type"`
+		} `json:"reboot"`
+	}
+	req.Reboot.Type = rebootType
+	err := c.serverAction(serverId, req, nil, http.StatusAccepted)
+	if err != nil {
+		err = errors.Newf(err, "failed to reboot (%s) server with id: %s", rebootType, serverId)
+	}
+	return err
+}
+
+// StopServer powers off the specified server.
+func (c *Client) StopServer(serverId string) error {
+	return c.simpleServerAction(serverId, "os-stop", http.StatusAccepted)
+}
+
+// StartServer powers on the specified server after it has been
+// stopped.
+func (c *Client) StartServer(serverId string) error {
+	return c.simpleServerAction(serverId, "os-start", http.StatusAccepted)
+}
+
+// PauseServer pauses the specified server, keeping its state in
+// memory on the hypervisor.
+func (c *Client) PauseServer(serverId string) error {
+	return c.simpleServerAction(serverId, "pause", http.StatusAccepted)
+}
+
+// UnpauseServer resumes the specified paused server.
+func (c *Client) UnpauseServer(serverId string) error {
+	return c.simpleServerAction(serverId, "unpause", http.StatusAccepted)
+}
+
+// SuspendServer suspends the specified server, saving its state to
+// disk.
+func (c *Client) SuspendServer(serverId string) error {
+	return c.simpleServerAction(serverId, "suspend", http.StatusAccepted)
+}
+
+// ResumeServer resumes the specified suspended server.
+func (c *Client) ResumeServer(serverId string) error {
+	return c.simpleServerAction(serverId, "resume", http.StatusAccepted)
+}
+
+// ShelveServer shelves the specified server. Depending on the cloud
+// configuration the server ends up in either StatusShelved or
+// StatusShelvedOffloaded.
+func (c *Client) ShelveServer(serverId string) error {
+	return c.simpleServerAction(serverId, "shelve", http.StatusAccepted)
+}
+
+// UnshelveServer restores the specified shelved server.
+func (c *Client) UnshelveServer(serverId string) error {
+	return c.simpleServerAction(serverId, "unshelve", http.StatusAccepted)
+}
+
+// RebuildServerOpts defines required and optional arguments for
+// RebuildServer().
+type RebuildServerOpts struct {
+	ImageId           string            `json:"imageRef"`                     // Required
+	Name              string            `json:"name,omitempty"`               // Optional
+	AdminPass         string            `json:"adminPass,omitempty"`          // Optional
+	Metadata          map[string]string `json:"metadata,omitempty"`           // Optional
+	PreserveEphemeral bool              `json:"preserve_ephemeral,omitempty"` // Optional
+}
+
+// RebuildServer rebuilds the specified server from the image given in
+// opts, replacing its root disk. The returned details reflect the
+// server as the rebuild starts.
+func (c *Client) RebuildServer(serverId string, opts RebuildServerOpts) (*ServerDetail, error) {
+	var req struct {
+		Rebuild RebuildServerOpts `json:"rebuild"`
+	}
+	req.Rebuild = opts
+	var resp struct {
+		Server ServerDetail `json:"server"`
+	}
+	err := c.serverAction(serverId, req, &resp, http.StatusAccepted)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to rebuild server with id: %s from image: %s", serverId, opts.ImageId)
+	}
+	return &resp.Server, nil
+}
+
+// ResizeServer changes the flavor of the specified server. Once the
+// server reaches StatusVerifyResize the resize must be completed with
+// ConfirmResize, or undone with RevertResize.
+func (c *Client) ResizeServer(serverId, flavorId string) error {
+	var req struct {
+		Resize struct {
+			FlavorId string `json:"flavorRef"`
+		} `json:"resize"`
+	}
+	req.Resize.FlavorId = flavorId
+	err := c.serverAction(serverId, req, nil, http.StatusAccepted)
+	if err != nil {
+		err = errors.Newf(err, "failed to resize server with id: %s to flavor: %s", serverId, flavorId)
+	}
+	return err
+}
+
+// ConfirmResize completes a pending resize of the specified server.
+func (c *Client) ConfirmResize(serverId string) error {
+	return c.simpleServerAction(serverId, "confirmResize", http.StatusNoContent)
+}
+
+// RevertResize returns the specified server to its flavor before a
+// pending resize.
+func (c *Client) RevertResize(serverId string) error {
+	return c.simpleServerAction(serverId, "revertResize", http.StatusAccepted)
+}
+
+// RescueServerOpts defines the optional arguments for RescueServer().
+type RescueServerOpts struct {
+	AdminPass string `json:"adminPass,omitempty"`        // Optional, generated if empty
+	ImageId   string `json:"rescue_image_ref,omitempty"` // Optional, the image of the server if empty
+}
+
+// RescueServer boots the specified server from a rescue image, with
+// its own root disk attached as a secondary disk so it can be
+// repaired. It returns the admin password of the rescue system. Once
+// repaired, the server is booted from its own disk again with
+// UnrescueServer.
+func (c *Client) RescueServer(serverId string, opts RescueServerOpts) (string, error) {
+	req := struct {
+		Rescue RescueServerOpts `json:"rescue"`
+	}{opts}
+	var resp struct {
+		AdminPass string `json:"adminPass"`
+	}
+	err := c.serverAction(serverId, req, &resp, http.StatusOK)
+	if err != nil {
+		return "", errors.Newf(err, "failed to rescue server with id: %s", serverId)
+	}
+	return resp.AdminPass, nil
+}
+
+// UnrescueServer boots the specified server, which is in rescue
+// mode, from its own root disk again.
+func (c *Client) UnrescueServer(serverId string) error {
+	return c.simpleServerAction(serverId, "unrescue", http.StatusAccepted)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"time"
 
@@ -696,6 +697,48 @@ func (s *LiveTests) TestSetServerMetadata(c *gc.C) {
 	c.Assert(server.Metadata["k2"], gc.Equals, "v2.replacement")
 }
 
+func (s *LiveTests) TestServerMetadataItems(c *gc.C) {
+	entity, err := s.nova.RunServer(nova.RunServerOpts{
+		Name:             "inst-metadata-items",
+		FlavorId:         s.testFlavorId,
+		ImageId:          s.testImageId,
+		AvailabilityZone: s.testAvailabilityZone,
+		Networks: []nova.ServerNetworks{{
+			NetworkId: s.testNetwork,
+		}},
+		Metadata: map[string]string{"stale": "yes"},
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(entity.Id)
+	s.waitTestServerCompleteBuilding(c, entity.Id)
+
+	err = s.nova.SetServerMetadataItem(entity.Id, "leader", "unit/0")
+	c.Assert(err, gc.IsNil)
+	value, err := s.nova.GetServerMetadataItem(entity.Id, "leader")
+	c.Assert(err, gc.IsNil)
+	c.Assert(value, gc.Equals, "unit/0")
+
+	err = s.nova.DeleteServerMetadataItem(entity.Id, "stale")
+	c.Assert(err, gc.IsNil)
+	_, err = s.nova.GetServerMetadataItem(entity.Id, "stale")
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+	err = s.nova.DeleteServerMetadataItem(entity.Id, "stale")
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+
+	metadata, err := s.nova.GetServerMetadata(entity.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(metadata["leader"], gc.Equals, "unit/0")
+	_, ok := metadata["stale"]
+	c.Assert(ok, gc.Equals, false)
+
+	replaced, err := s.nova.ReplaceServerMetadata(entity.Id, map[string]string{"k1": "v1"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(replaced, gc.DeepEquals, map[string]string{"k1": "v1"})
+	metadata, err = s.nova.GetServerMetadata(entity.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(metadata, gc.DeepEquals, map[string]string{"k1": "v1"})
+}
+
 func (s *LiveTests) TestCreateServerBlockDeviceMappingLocal(c *gc.C) {
 	entity, err := s.nova.RunServer(nova.RunServerOpts{
 		Name:             "inst-block-device-mapping-local",
@@ -750,6 +793,30 @@ func (s *LiveTests) TestCreateServerBlockDeviceMappingVolume(c *gc.C) {
 	c.Assert(server.Status, gc.Equals, nova.StatusActive)
 }
 
+func (s *LiveTests) TestCreateServerBlockDeviceBuilder(c *gc.C) {
+	var b nova.BlockDeviceBuilder
+	b.BootFromImage(s.testImageId, 20, nova.VolumeOpts{DeleteOnTermination: true})
+	b.AddBlankVolume(1, nova.VolumeOpts{DeleteOnTermination: true})
+	mappings, err := b.Build()
+	c.Assert(err, gc.IsNil)
+	entity, err := s.nova.RunServer(nova.RunServerOpts{
+		Name:             "inst-block-device-builder",
+		FlavorId:         s.testFlavorId,
+		AvailabilityZone: s.testAvailabilityZone,
+		Networks: []nova.ServerNetworks{{
+			NetworkId: s.testNetwork,
+		}},
+		BlockDeviceMappings: mappings,
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(entity.Id)
+	s.waitTestServerCompleteBuilding(c, entity.Id)
+
+	server, err := s.nova.GetServer(entity.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(server.Status, gc.Equals, nova.StatusActive)
+}
+
 func (s *LiveTests) TestServerOSInterfaces(c *gc.C) {
 	if s.useNeutronNetworking {
 		c.Skip("Live tests use Neutron, this test will fail")
@@ -779,3 +846,241 @@ func (s *LiveTests) TestServerOSInterfaces(c *gc.C) {
 	_, err = s.nova.ListOSInterfaces(entity.Id)
 	c.Assert(err, gc.IsNil)
 }
+
+func (s *LiveTests) TestAttachDetachOSInterface(c *gc.C) {
+	attached, err := s.nova.AttachOSInterface(s.testServer.Id, nova.AttachInterfaceOpts{
+		NetworkId: s.testNetwork,
+	})
+	c.Assert(err, gc.IsNil)
+	c.Check(attached.NetID, gc.Equals, s.testNetwork)
+	c.Check(attached.PortID, gc.Not(gc.Equals), "")
+	c.Check(attached.FixedIPs, gc.Not(gc.HasLen), 0)
+
+	osInterface, err := s.nova.GetOSInterface(s.testServer.Id, attached.PortID)
+	c.Assert(err, gc.IsNil)
+	c.Check(osInterface.PortID, gc.Equals, attached.PortID)
+
+	c.Assert(s.nova.DetachOSInterface(s.testServer.Id, attached.PortID), gc.IsNil)
+	interfaces, err := s.nova.ListOSInterfaces(s.testServer.Id)
+	c.Assert(err, gc.IsNil)
+	for _, osInterface := range interfaces {
+		c.Check(osInterface.PortID, gc.Not(gc.Equals), attached.PortID)
+	}
+}
+
+const (
+	testPublicKey            = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOO6wJKmQ2nvQw6/fa8ZtKN9QW9OCnYy+UNOepkvIEpC test"
+	testPublicKeyFingerprint = "f4:cb:13:02:c7:63:83:68:68:40:12:9e:1c:f5:1a:3b"
+)
+
+func (s *LiveTests) TestKeyPairs(c *gc.C) {
+	generated, err := s.nova.CreateKeyPair("test-generated", "")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteKeyPair(generated.Name)
+	c.Check(generated.Name, gc.Equals, "test-generated")
+	c.Check(generated.PublicKey, gc.Not(gc.Equals), "")
+	c.Check(generated.PrivateKey, gc.Matches, "(?s)-----BEGIN .*PRIVATE KEY-----.*")
+	c.Check(generated.Fingerprint, gc.Not(gc.Equals), "")
+
+	imported, err := s.nova.ImportKeyPair("test-imported", testPublicKey, nova.KeyTypeSSH)
+	c.Assert(err, gc.IsNil)
+	c.Check(imported.Fingerprint, gc.Equals, testPublicKeyFingerprint)
+	c.Check(imported.Type, gc.Equals, nova.KeyTypeSSH)
+	c.Check(imported.PrivateKey, gc.Equals, "")
+
+	_, err = s.nova.ImportKeyPair("test-imported", testPublicKey, nova.KeyTypeSSH)
+	c.Assert(err, gc.ErrorMatches, `failed to import keypair "test-imported"(.|\n)*already exists(.|\n)*`)
+
+	keyPair, err := s.nova.GetKeyPair("test-imported")
+	c.Assert(err, gc.IsNil)
+	c.Check(keyPair.PublicKey, gc.Equals, testPublicKey)
+	c.Check(keyPair.Fingerprint, gc.Equals, testPublicKeyFingerprint)
+
+	keyPairs, err := s.nova.ListKeyPairs()
+	c.Assert(err, gc.IsNil)
+	names := make(map[string]string)
+	for _, kp := range keyPairs {
+		names[kp.Name] = kp.Fingerprint
+	}
+	c.Check(names["test-generated"], gc.Equals, generated.Fingerprint)
+	c.Check(names["test-imported"], gc.Equals, testPublicKeyFingerprint)
+
+	c.Assert(s.nova.DeleteKeyPair("test-imported"), gc.IsNil)
+	_, err = s.nova.GetKeyPair("test-imported")
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+}
+
+func (s *LiveTests) TestRunServerWithKeyPair(c *gc.C) {
+	keyPair, err := s.nova.ImportKeyPair("test-server-key", testPublicKey, "")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteKeyPair(keyPair.Name)
+
+	entity, err := s.nova.RunServer(nova.RunServerOpts{
+		Name:             "inst-keypair",
+		FlavorId:         s.testFlavorId,
+		ImageId:          s.testImageId,
+		AvailabilityZone: s.testAvailabilityZone,
+		Networks: []nova.ServerNetworks{{
+			NetworkId: s.testNetwork,
+		}},
+		KeyName: keyPair.Name,
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(entity.Id)
+
+	server, err := s.nova.GetServer(entity.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(server.KeyName, gc.Equals, keyPair.Name)
+}
+
+func (s *LiveTests) TestServerGroups(c *gc.C) {
+	group, err := s.nova.CreateServerGroup(nova.ServerGroupOpts{
+		Name:   "test-group",
+		Policy: nova.ServerGroupAntiAffinity,
+		Rules:  &nova.ServerGroupRules{MaxServerPerHost: 2},
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServerGroup(group.Id)
+	c.Check(group.Name, gc.Equals, "test-group")
+	c.Check(group.Policy, gc.Equals, nova.ServerGroupAntiAffinity)
+	c.Check(group.Rules, gc.DeepEquals, &nova.ServerGroupRules{MaxServerPerHost: 2})
+	c.Check(group.Members, gc.HasLen, 0)
+
+	entity, err := s.nova.RunServer(nova.RunServerOpts{
+		Name:             "inst-server-group",
+		FlavorId:         s.testFlavorId,
+		ImageId:          s.testImageId,
+		AvailabilityZone: s.testAvailabilityZone,
+		Networks: []nova.ServerNetworks{{
+			NetworkId: s.testNetwork,
+		}},
+		SchedulerHints: &nova.SchedulerHints{Group: group.Id},
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(entity.Id)
+
+	group, err = s.nova.GetServerGroup(group.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(group.Members, gc.DeepEquals, []string{entity.Id})
+
+	groups, err := s.nova.ListServerGroups()
+	c.Assert(err, gc.IsNil)
+	found := false
+	for _, g := range groups {
+		found = found || g.Id == group.Id
+	}
+	c.Check(found, gc.Equals, true)
+
+	c.Assert(s.nova.DeleteServerGroup(group.Id), gc.IsNil)
+	_, err = s.nova.GetServerGroup(group.Id)
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+}
+
+func (s *LiveTests) TestLimitsAndQuotas(c *gc.C) {
+	limits, err := s.nova.GetLimits()
+	c.Assert(err, gc.IsNil)
+	c.Check(limits.Absolute.MaxTotalInstances, gc.Not(gc.Equals), 0)
+	// At least the test server is running.
+	c.Check(limits.Absolute.TotalInstancesUsed >= 1, gc.Equals, true)
+
+	quotas, err := s.nova.GetQuotaSet(s.tenantId)
+	c.Assert(err, gc.IsNil)
+	c.Check(quotas.Id, gc.Equals, s.tenantId)
+	c.Check(quotas.Instances, gc.Equals, limits.Absolute.MaxTotalInstances)
+	c.Check(quotas.Cores, gc.Equals, limits.Absolute.MaxTotalCores)
+	c.Check(quotas.RAM, gc.Equals, limits.Absolute.MaxTotalRAMSize)
+	c.Check(quotas.KeyPairs, gc.Equals, limits.Absolute.MaxTotalKeypairs)
+	c.Check(quotas.ServerGroups, gc.Equals, limits.Absolute.MaxServerGroups)
+
+	defaults, err := s.nova.GetDefaultQuotaSet(s.tenantId)
+	c.Assert(err, gc.IsNil)
+	c.Check(defaults.Instances, gc.Not(gc.Equals), 0)
+}
+
+func (s *LiveTests) TestGetAndFindFlavor(c *gc.C) {
+	flavor, err := s.nova.GetFlavor(s.testFlavorId)
+	c.Assert(err, gc.IsNil)
+	c.Check(flavor.Id, gc.Equals, s.testFlavorId)
+	c.Check(flavor.Name, gc.Equals, s.testFlavor)
+
+	flavor, err = s.nova.FindFlavor(s.testFlavor)
+	c.Assert(err, gc.IsNil)
+	c.Check(flavor.Id, gc.Equals, s.testFlavorId)
+	flavor, err = s.nova.FindFlavor(s.testFlavorId)
+	c.Assert(err, gc.IsNil)
+	c.Check(flavor.Name, gc.Equals, s.testFlavor)
+
+	_, err = s.nova.FindFlavor("no-such-flavor")
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+}
+
+func (s *LiveTests) TestServerTags(c *gc.C) {
+	instance, err := s.createInstance("test-server-tags")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	tags, err := s.nova.ReplaceServerTags(instance.Id, []string{"cluster-a", "web"})
+	c.Assert(err, gc.IsNil)
+	sort.Strings(tags)
+	c.Check(tags, gc.DeepEquals, []string{"cluster-a", "web"})
+	c.Assert(s.nova.AddServerTag(instance.Id, "db"), gc.IsNil)
+	// Adding an existing tag is not an error.
+	c.Assert(s.nova.AddServerTag(instance.Id, "db"), gc.IsNil)
+
+	ok, err := s.nova.HasServerTag(instance.Id, "db")
+	c.Assert(err, gc.IsNil)
+	c.Check(ok, gc.Equals, true)
+	c.Assert(s.nova.DeleteServerTag(instance.Id, "db"), gc.IsNil)
+	ok, err = s.nova.HasServerTag(instance.Id, "db")
+	c.Assert(err, gc.IsNil)
+	c.Check(ok, gc.Equals, false)
+
+	filter := nova.NewFilter()
+	filter.Set(nova.FilterTags, "cluster-a,web")
+	servers, err := s.nova.ListServersDetail(filter)
+	c.Assert(err, gc.IsNil)
+	c.Assert(servers, gc.HasLen, 1)
+	c.Check(servers[0].Id, gc.Equals, instance.Id)
+	sort.Strings(servers[0].Tags)
+	c.Check(servers[0].Tags, gc.DeepEquals, []string{"cluster-a", "web"})
+
+	c.Assert(s.nova.DeleteAllServerTags(instance.Id), gc.IsNil)
+	tags, err = s.nova.ListServerTags(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(tags, gc.HasLen, 0)
+}
+
+func (s *LiveTests) TestInstanceActions(c *gc.C) {
+	instance, err := s.createInstance("test-instance-actions")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	actions, err := s.nova.ListInstanceActions(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(actions, gc.HasLen, 1)
+	c.Check(actions[0].Action, gc.Equals, nova.ActionCreate)
+	c.Check(actions[0].RequestId, gc.Not(gc.Equals), "")
+
+	action, err := s.nova.GetInstanceAction(instance.Id, actions[0].RequestId)
+	c.Assert(err, gc.IsNil)
+	c.Check(action.Action, gc.Equals, nova.ActionCreate)
+	c.Check(action.RequestId, gc.Equals, actions[0].RequestId)
+	c.Check(action.Events, gc.Not(gc.HasLen), 0)
+}
+
+func (s *LiveTests) TestUpdateServerDescription(c *gc.C) {
+	instance, err := s.createInstance("test-server-description")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	description := "web frontend"
+	server, err := s.nova.UpdateServer(instance.Id, nova.UpdateServerOpts{Description: &description})
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Description, gc.Equals, description)
+	c.Check(server.Name, gc.Equals, "test-server-description")
+
+	name := "test-server-renamed"
+	server, err = s.nova.UpdateServer(instance.Id, nova.UpdateServerOpts{Name: &name})
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Name, gc.Equals, name)
+}
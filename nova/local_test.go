@@ -2,15 +2,25 @@ package nova_test
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	gc "gopkg.in/check.v1"
 
 	"github.com/go-goose/goose/v5/client"
 	"github.com/go-goose/goose/v5/errors"
+	"github.com/go-goose/goose/v5/glance"
 	goosehttp "github.com/go-goose/goose/v5/http"
 	"github.com/go-goose/goose/v5/identity"
 	"github.com/go-goose/goose/v5/nova"
@@ -322,3 +332,1412 @@ func (s *localLiveSuite) TestVolumeAttachments(c *gc.C) {
 	c.Assert(err, gc.NotNil)
 	c.Assert(errors.IsNotFound(err), gc.Equals, true)
 }
+
+func (s *localLiveSuite) assertServerStatus(c *gc.C, serverId, status string) {
+	server, err := s.nova.GetServer(serverId)
+	c.Assert(err, gc.IsNil)
+	c.Assert(server.Status, gc.Equals, status)
+}
+
+func (s *localLiveSuite) TestServerLifecycleActions(c *gc.C) {
+	instance, err := s.createInstance("test-lifecycle")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	actions := []struct {
+		action func(string) error
+		status string
+	}{
+		{s.nova.StopServer, nova.StatusShutoff},
+		{s.nova.StartServer, nova.StatusActive},
+		{s.nova.PauseServer, nova.StatusPaused},
+		{s.nova.UnpauseServer, nova.StatusActive},
+		{s.nova.SuspendServer, nova.StatusSuspended},
+		{s.nova.ResumeServer, nova.StatusActive},
+		{s.nova.ShelveServer, nova.StatusShelvedOffloaded},
+		{s.nova.UnshelveServer, nova.StatusActive},
+	}
+	for _, t := range actions {
+		c.Assert(t.action(instance.Id), gc.IsNil)
+		s.assertServerStatus(c, instance.Id, t.status)
+	}
+}
+
+func (s *localLiveSuite) TestRebootServer(c *gc.C) {
+	instance, err := s.createInstance("test-reboot")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	c.Assert(s.nova.PauseServer(instance.Id), gc.IsNil)
+	err = s.nova.RebootServer(instance.Id, nova.RebootSoft)
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*Cannot 'reboot' instance .* while it is in status PAUSED(.|\n)*")
+	c.Assert(s.nova.RebootServer(instance.Id, nova.RebootHard), gc.IsNil)
+	s.assertServerStatus(c, instance.Id, nova.StatusActive)
+}
+
+func (s *localLiveSuite) TestServerActionConflict(c *gc.C) {
+	instance, err := s.createInstance("test-conflict")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	err = s.nova.UnpauseServer(instance.Id)
+	c.Assert(err, gc.ErrorMatches, "failed to unpause server with id: .*\ncaused by: (.|\n)*"+
+		"Cannot 'unpause' instance .* while it is in status ACTIVE(.|\n)*")
+	s.assertServerStatus(c, instance.Id, nova.StatusActive)
+}
+
+func (s *localLiveSuite) TestRebuildServer(c *gc.C) {
+	instance, err := s.createInstance("test-rebuild")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	c.Assert(s.nova.StopServer(instance.Id), gc.IsNil)
+	server, err := s.nova.RebuildServer(instance.Id, nova.RebuildServerOpts{
+		ImageId:  "2",
+		Name:     "rebuilt",
+		Metadata: map[string]string{"rebuilt": "yes"},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Image.Id, gc.Equals, "2")
+	c.Check(server.Name, gc.Equals, "rebuilt")
+	c.Check(server.Metadata, gc.DeepEquals, map[string]string{"rebuilt": "yes"})
+	// A stopped server stays stopped after a rebuild.
+	c.Check(server.Status, gc.Equals, nova.StatusShutoff)
+}
+
+func (s *localLiveSuite) TestResizeServer(c *gc.C) {
+	instance, err := s.createInstance("test-resize")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+	flavorId, err := s.findFlavorId("m1.medium")
+	c.Assert(err, gc.IsNil)
+
+	err = s.nova.ResizeServer(instance.Id, s.testFlavorId)
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*When resizing, instances must change flavor!(.|\n)*")
+
+	// Revert a resize.
+	c.Assert(s.nova.ResizeServer(instance.Id, flavorId), gc.IsNil)
+	server, err := s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Status, gc.Equals, nova.StatusVerifyResize)
+	c.Check(server.Flavor.Id, gc.Equals, flavorId)
+	c.Assert(s.nova.RevertResize(instance.Id), gc.IsNil)
+	server, err = s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Status, gc.Equals, nova.StatusActive)
+	c.Check(server.Flavor.Id, gc.Equals, s.testFlavorId)
+
+	// Confirm a resize.
+	c.Assert(s.nova.ResizeServer(instance.Id, flavorId), gc.IsNil)
+	c.Assert(s.nova.ConfirmResize(instance.Id), gc.IsNil)
+	server, err = s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Status, gc.Equals, nova.StatusActive)
+	c.Check(server.Flavor.Id, gc.Equals, flavorId)
+
+	err = s.nova.ConfirmResize(instance.Id)
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*Cannot 'confirmResize' instance .* while it is in status ACTIVE(.|\n)*")
+}
+
+// noWait polls without any delay.
+func noWait(int) time.Duration {
+	return 0
+}
+
+// hostId returns the HostId nova reports for the given server when
+// it is on the named host.
+func hostId(server *nova.ServerDetail, host string) string {
+	sum := sha256.Sum224([]byte(server.TenantId + host))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *localLiveSuite) TestWaitForServer(c *gc.C) {
+	instance, err := s.createInstance("test-wait")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	c.Assert(s.nova.StopServer(instance.Id), gc.IsNil)
+	server, err := s.nova.WaitForServer(context.Background(), instance.Id, nova.WaitOpts{
+		Statuses: []string{nova.StatusShutoff},
+		Backoff:  noWait,
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(server.Status, gc.Equals, nova.StatusShutoff)
+}
+
+func (s *localLiveSuite) TestWaitForServerFault(c *gc.C) {
+	s.openstack.Nova.SetAZForNoValidHosts(nova.AvailabilityZone{
+		Name:  "az-novalid",
+		State: nova.AvailabilityZoneState{Available: true},
+	})
+	instance, err := s.runServerAvailabilityZone("az-novalid")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	_, err = s.nova.WaitForServer(context.Background(), instance.Id, nova.WaitOpts{
+		Statuses: []string{nova.StatusActive},
+		Backoff:  noWait,
+	})
+	c.Assert(err, gc.ErrorMatches, "server .* has status ERROR: No valid host was found.* \\(code 500\\)")
+	statusErr, ok := err.(*nova.ServerStatusError)
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(statusErr.ServerId, gc.Equals, instance.Id)
+	c.Assert(statusErr.Fault, gc.NotNil)
+	c.Assert(statusErr.Fault.Code, gc.Equals, 500)
+}
+
+func (s *localLiveSuite) TestWaitForServerFailureStatus(c *gc.C) {
+	s.openstack.Nova.SetAZForNoValidHosts(nova.AvailabilityZone{
+		Name:  "az-novalid",
+		State: nova.AvailabilityZoneState{Available: true},
+	})
+	instance, err := s.runServerAvailabilityZone("az-novalid")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	server, err := s.nova.WaitForServer(context.Background(), instance.Id, nova.WaitOpts{
+		Statuses: []string{nova.StatusError},
+		Backoff:  noWait,
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(server.Status, gc.Equals, nova.StatusError)
+}
+
+func (s *localLiveSuite) TestWaitForServerTimeout(c *gc.C) {
+	instance, err := s.createInstance("test-wait-timeout")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+	s.openstack.Nova.SetServerStatus(nova.StatusBuild)
+	defer s.openstack.Nova.SetServerStatus("")
+
+	_, err = s.nova.WaitForServer(context.Background(), instance.Id, nova.WaitOpts{
+		Statuses: []string{nova.StatusActive},
+		Timeout:  10 * time.Millisecond,
+		Backoff:  nova.ExponentialBackoff(time.Millisecond, 5*time.Millisecond),
+	})
+	c.Assert(err, gc.ErrorMatches, "timed out waiting for server .* to reach status ACTIVE(.|\n)*")
+	c.Assert(errors.IsTimeout(err), gc.Equals, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.nova.WaitForServer(ctx, instance.Id, nova.WaitOpts{
+		Statuses: []string{nova.StatusActive},
+	})
+	c.Assert(err, gc.ErrorMatches, "stopped waiting for server .*\ncaused by: context canceled")
+	c.Assert(errors.IsTimeout(err), gc.Equals, false)
+}
+
+func (s *localLiveSuite) TestWaitForServerDeleted(c *gc.C) {
+	instance, err := s.createInstance("test-wait-deleted")
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.nova.DeleteServer(instance.Id), gc.IsNil)
+	err = s.nova.WaitForServerDeleted(context.Background(), instance.Id, nova.WaitOpts{Backoff: noWait})
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *localLiveSuite) TestExponentialBackoff(c *gc.C) {
+	backoff := nova.ExponentialBackoff(time.Second, 5*time.Second)
+	var delays []time.Duration
+	for attempt := 1; attempt <= 5; attempt++ {
+		delays = append(delays, backoff(attempt))
+	}
+	c.Assert(delays, gc.DeepEquals, []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second,
+	})
+}
+
+func (s *localLiveSuite) TestRunServerUnknownKeyPair(c *gc.C) {
+	_, err := s.nova.RunServer(nova.RunServerOpts{
+		Name:     "inst-no-keypair",
+		FlavorId: s.testFlavorId,
+		ImageId:  s.testImageId,
+		Networks: []nova.ServerNetworks{{NetworkId: s.testNetwork}},
+		KeyName:  "no-such-key",
+	})
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*Invalid key_name provided.(.|\n)*")
+}
+
+func (s *localLiveSuite) TestImportKeyPairInvalid(c *gc.C) {
+	_, err := s.nova.ImportKeyPair("test-invalid", "not a key", "")
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*Keypair data is invalid: failed to generate fingerprint(.|\n)*")
+	_, err = s.nova.ImportKeyPair("test-invalid", testPublicKey, "pgp")
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*Keypair data is invalid: invalid keypair type(.|\n)*")
+}
+
+func (s *localLiveSuite) runServerInGroup(c *gc.C, name, groupId string) *nova.ServerDetail {
+	entity, err := s.nova.RunServer(nova.RunServerOpts{
+		Name:           name,
+		FlavorId:       s.testFlavorId,
+		ImageId:        s.testImageId,
+		Networks:       []nova.ServerNetworks{{NetworkId: s.testNetwork}},
+		SchedulerHints: &nova.SchedulerHints{Group: groupId},
+	})
+	c.Assert(err, gc.IsNil)
+	server, err := s.nova.GetServer(entity.Id)
+	c.Assert(err, gc.IsNil)
+	return server
+}
+
+func (s *localLiveSuite) TestServerGroupAntiAffinity(c *gc.C) {
+	s.openstack.Nova.SetHosts("host1", "host2")
+	defer s.openstack.Nova.SetHosts()
+	group, err := s.nova.CreateServerGroup(nova.ServerGroupOpts{
+		Name:   "test-anti-affinity",
+		Policy: nova.ServerGroupAntiAffinity,
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServerGroup(group.Id)
+
+	hosts := make(map[string]bool)
+	for _, name := range []string{"anti1", "anti2"} {
+		server := s.runServerInGroup(c, name, group.Id)
+		defer s.nova.DeleteServer(server.Id)
+		c.Assert(server.Status, gc.Equals, nova.StatusActive)
+		hosts[server.HostId] = true
+	}
+	c.Assert(hosts, gc.HasLen, 2)
+
+	// There is no host left for a third server.
+	server := s.runServerInGroup(c, "anti3", group.Id)
+	defer s.nova.DeleteServer(server.Id)
+	_, err = s.nova.WaitForServer(context.Background(), server.Id, nova.WaitOpts{
+		Statuses: []string{nova.StatusActive},
+		Backoff:  noWait,
+	})
+	c.Assert(err, gc.ErrorMatches, "server .* has status ERROR: No valid host was found.*")
+
+	group, err = s.nova.GetServerGroup(group.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(group.Members, gc.HasLen, 2)
+}
+
+func (s *localLiveSuite) TestServerGroupMaxServerPerHost(c *gc.C) {
+	s.openstack.Nova.SetHosts("host1", "host2")
+	defer s.openstack.Nova.SetHosts()
+	group, err := s.nova.CreateServerGroup(nova.ServerGroupOpts{
+		Name:   "test-max-per-host",
+		Policy: nova.ServerGroupAntiAffinity,
+		Rules:  &nova.ServerGroupRules{MaxServerPerHost: 2},
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServerGroup(group.Id)
+
+	for i := 0; i < 4; i++ {
+		server := s.runServerInGroup(c, fmt.Sprintf("max%d", i), group.Id)
+		defer s.nova.DeleteServer(server.Id)
+		c.Assert(server.Status, gc.Equals, nova.StatusActive)
+	}
+	server := s.runServerInGroup(c, "max4", group.Id)
+	defer s.nova.DeleteServer(server.Id)
+	c.Assert(server.Status, gc.Equals, nova.StatusError)
+}
+
+func (s *localLiveSuite) TestServerGroupAffinity(c *gc.C) {
+	s.openstack.Nova.SetHosts("host1", "host2")
+	defer s.openstack.Nova.SetHosts()
+	group, err := s.nova.CreateServerGroup(nova.ServerGroupOpts{
+		Name:   "test-affinity",
+		Policy: nova.ServerGroupAffinity,
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServerGroup(group.Id)
+
+	first := s.runServerInGroup(c, "aff1", group.Id)
+	defer s.nova.DeleteServer(first.Id)
+	second := s.runServerInGroup(c, "aff2", group.Id)
+	defer s.nova.DeleteServer(second.Id)
+	c.Assert(second.HostId, gc.Equals, first.HostId)
+}
+
+func (s *localLiveSuite) TestCreateServerGroupInvalid(c *gc.C) {
+	_, err := s.nova.CreateServerGroup(nova.ServerGroupOpts{
+		Name:   "test-invalid",
+		Policy: nova.ServerGroupAffinity,
+		Rules:  &nova.ServerGroupRules{MaxServerPerHost: 2},
+	})
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*rules are only allowed with the anti-affinity policy(.|\n)*")
+	_, err = s.nova.CreateServerGroup(nova.ServerGroupOpts{Name: "test-invalid", Policy: "scattered"})
+	c.Assert(err, gc.ErrorMatches, `(.|\n)*invalid policy "scattered"(.|\n)*`)
+}
+
+func (s *localLiveSuite) TestGetConsoleOutput(c *gc.C) {
+	instance, err := s.createInstance("test-console")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+	s.openstack.Nova.SetConsoleOutput(instance.Id, "booting\nmounting root\nkernel panic\n")
+
+	output, err := s.nova.GetConsoleOutput(instance.Id, 0)
+	c.Assert(err, gc.IsNil)
+	c.Assert(output, gc.Equals, "booting\nmounting root\nkernel panic\n")
+	output, err = s.nova.GetConsoleOutput(instance.Id, 2)
+	c.Assert(err, gc.IsNil)
+	c.Assert(output, gc.Equals, "mounting root\nkernel panic\n")
+
+	_, err = s.nova.GetConsoleOutput("no-such-server", 0)
+	c.Assert(err, gc.ErrorMatches, "failed to get console output of server with id: no-such-server(.|\n)*")
+}
+
+func (s *localLiveSuite) TestGetRemoteConsole(c *gc.C) {
+	instance, err := s.createInstance("test-remote-console")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	console, err := s.nova.GetRemoteConsole(instance.Id, nova.ConsoleProtocolVNC, nova.ConsoleTypeNoVNC)
+	c.Assert(err, gc.IsNil)
+	c.Check(console.Protocol, gc.Equals, nova.ConsoleProtocolVNC)
+	c.Check(console.Type, gc.Equals, nova.ConsoleTypeNoVNC)
+	c.Check(console.URL, gc.Matches, "http://.*/console/novnc\\?token=.+")
+
+	_, err = s.nova.GetRemoteConsole(instance.Id, nova.ConsoleProtocolSerial, nova.ConsoleTypeNoVNC)
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*Invalid console type novnc for protocol serial(.|\n)*")
+}
+
+// setQuotas changes the quotas of the double, and returns a function
+// restoring the defaults.
+func (s *localLiveSuite) setQuotas(opts nova.QuotaSetOpts) func() {
+	s.openstack.Nova.SetQuotas(opts)
+	return s.openstack.Nova.ResetQuotas
+}
+
+func (s *localLiveSuite) TestRunServerQuotaExceeded(c *gc.C) {
+	limits, err := s.nova.GetLimits()
+	c.Assert(err, gc.IsNil)
+	used := limits.Absolute.TotalInstancesUsed
+	defer s.setQuotas(nova.QuotaSetOpts{Instances: &used})()
+
+	_, err = s.createInstance("test-over-quota")
+	c.Assert(errors.IsForbidden(err), gc.Equals, true)
+	c.Assert(err, gc.ErrorMatches, fmt.Sprintf(
+		"(.|\n)*Quota exceeded for instances: Requested 1, but already used %d of %d instances(.|\n)*", used, used))
+
+	unlimited := nova.Unlimited
+	s.openstack.Nova.SetQuotas(nova.QuotaSetOpts{
+		Instances: &unlimited,
+		RAM:       &limits.Absolute.TotalRAMUsed,
+	})
+	_, err = s.createInstance("test-over-quota")
+	c.Assert(errors.IsForbidden(err), gc.Equals, true)
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*Quota exceeded for ram: Requested [0-9]+, but already used(.|\n)*")
+}
+
+func (s *localLiveSuite) TestServerGroupMembersQuotaExceeded(c *gc.C) {
+	one := 1
+	defer s.setQuotas(nova.QuotaSetOpts{ServerGroupMembers: &one})()
+	group, err := s.nova.CreateServerGroup(nova.ServerGroupOpts{
+		Name:   "test-members-quota",
+		Policy: nova.ServerGroupSoftAffinity,
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServerGroup(group.Id)
+	server := s.runServerInGroup(c, "inst-members-quota", group.Id)
+	defer s.nova.DeleteServer(server.Id)
+
+	_, err = s.nova.RunServer(nova.RunServerOpts{
+		Name:           "inst-members-quota",
+		FlavorId:       s.testFlavorId,
+		ImageId:        s.testImageId,
+		Networks:       []nova.ServerNetworks{{NetworkId: s.testNetwork}},
+		SchedulerHints: &nova.SchedulerHints{Group: group.Id},
+	})
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*Quota exceeded, too many servers in group(.|\n)*")
+}
+
+func (s *localLiveSuite) TestKeyPairAndServerGroupQuotaExceeded(c *gc.C) {
+	zero := 0
+	defer s.setQuotas(nova.QuotaSetOpts{KeyPairs: &zero, ServerGroups: &zero})()
+	_, err := s.nova.ImportKeyPair("test-over-quota", testPublicKey, "")
+	c.Assert(errors.IsForbidden(err), gc.Equals, true)
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*Quota exceeded, too many key pairs.(.|\n)*")
+	_, err = s.nova.CreateServerGroup(nova.ServerGroupOpts{
+		Name:   "test-over-quota",
+		Policy: nova.ServerGroupAffinity,
+	})
+	c.Assert(errors.IsForbidden(err), gc.Equals, true)
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*Quota exceeded, too many server groups.(.|\n)*")
+}
+
+func (s *localLiveSuite) TestUpdateQuotaSet(c *gc.C) {
+	defer s.openstack.Nova.ResetQuotas()
+	limits, err := s.nova.GetLimits()
+	c.Assert(err, gc.IsNil)
+	used := limits.Absolute.TotalInstancesUsed
+
+	instances, cores := used+5, nova.Unlimited
+	quotas, err := s.nova.UpdateQuotaSet(s.tenantId, nova.QuotaSetOpts{
+		Instances: &instances,
+		Cores:     &cores,
+	})
+	c.Assert(err, gc.IsNil)
+	c.Check(quotas.Instances, gc.Equals, instances)
+	c.Check(quotas.Cores, gc.Equals, nova.Unlimited)
+	c.Check(quotas.RAM, gc.Equals, limits.Absolute.MaxTotalRAMSize)
+
+	limits, err = s.nova.GetLimits()
+	c.Assert(err, gc.IsNil)
+	c.Check(limits.Absolute.MaxTotalInstances, gc.Equals, instances)
+	c.Check(limits.Absolute.MaxTotalCores, gc.Equals, nova.Unlimited)
+
+	// Quotas cannot be lowered below the usage unless forced.
+	instances = used - 1
+	_, err = s.nova.UpdateQuotaSet(s.tenantId, nova.QuotaSetOpts{Instances: &instances})
+	c.Assert(err, gc.ErrorMatches, fmt.Sprintf(
+		"(.|\n)*Quota limit %d for instances must be greater than or equal to already used and reserved %d(.|\n)*", instances, used))
+	quotas, err = s.nova.UpdateQuotaSet(s.tenantId, nova.QuotaSetOpts{Instances: &instances, Force: true})
+	c.Assert(err, gc.IsNil)
+	c.Check(quotas.Instances, gc.Equals, instances)
+}
+
+func (s *localLiveSuite) TestCreateDeleteFlavor(c *gc.C) {
+	private := false
+	flavor, err := s.nova.CreateFlavor(nova.FlavorOpts{
+		Name:      "test-flavor",
+		RAM:       1024,
+		VCPUs:     2,
+		Disk:      20,
+		Swap:      512,
+		Ephemeral: 10,
+		IsPublic:  &private,
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteFlavor(flavor.Id)
+	c.Check(flavor.Name, gc.Equals, "test-flavor")
+	c.Check(flavor.Swap, gc.Equals, 512)
+	c.Check(flavor.Ephemeral, gc.Equals, 10)
+	c.Check(flavor.IsPublic, gc.Equals, false)
+	c.Check(flavor.RxTxFactor, gc.Equals, 1.0)
+
+	found, err := s.nova.FindFlavor("test-flavor")
+	c.Assert(err, gc.IsNil)
+	c.Check(found, gc.DeepEquals, flavor)
+
+	_, err = s.nova.CreateFlavor(nova.FlavorOpts{Name: "test-flavor", RAM: 1, VCPUs: 1})
+	c.Assert(errors.IsDuplicateValue(err), gc.Equals, true)
+	_, err = s.nova.CreateFlavor(nova.FlavorOpts{Name: "test-no-vcpus", RAM: 1})
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*vcpus must be at least 1(.|\n)*")
+
+	c.Assert(s.nova.DeleteFlavor(flavor.Id), gc.IsNil)
+	_, err = s.nova.GetFlavor(flavor.Id)
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+	err = s.nova.DeleteFlavor(flavor.Id)
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+}
+
+func (s *localLiveSuite) TestFlavorExtraSpecs(c *gc.C) {
+	flavor, err := s.nova.CreateFlavor(nova.FlavorOpts{Name: "test-extra-specs", RAM: 512, VCPUs: 1})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteFlavor(flavor.Id)
+
+	specs, err := s.nova.GetFlavorExtraSpecs(flavor.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(specs, gc.HasLen, 0)
+
+	specs, err = s.nova.SetFlavorExtraSpecs(flavor.Id, map[string]string{
+		"hw:cpu_policy":    "dedicated",
+		"hw:mem_page_size": "large",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Check(specs, gc.HasLen, 2)
+	_, err = s.nova.SetFlavorExtraSpecs(flavor.Id, map[string]string{"hw:cpu_policy": "shared"})
+	c.Assert(err, gc.IsNil)
+	value, err := s.nova.GetFlavorExtraSpec(flavor.Id, "hw:cpu_policy")
+	c.Assert(err, gc.IsNil)
+	c.Check(value, gc.Equals, "shared")
+
+	c.Assert(s.nova.UnsetFlavorExtraSpec(flavor.Id, "hw:cpu_policy"), gc.IsNil)
+	specs, err = s.nova.GetFlavorExtraSpecs(flavor.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(specs, gc.DeepEquals, map[string]string{"hw:mem_page_size": "large"})
+	_, err = s.nova.GetFlavorExtraSpec(flavor.Id, "hw:cpu_policy")
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+	err = s.nova.UnsetFlavorExtraSpec(flavor.Id, "hw:cpu_policy")
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+}
+
+func (s *localLiveSuite) TestFlavorAccess(c *gc.C) {
+	private := false
+	flavor, err := s.nova.CreateFlavor(nova.FlavorOpts{Name: "test-access", RAM: 512, VCPUs: 1, IsPublic: &private})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteFlavor(flavor.Id)
+
+	access, err := s.nova.AddFlavorAccess(flavor.Id, "tenant-b")
+	c.Assert(err, gc.IsNil)
+	c.Check(access, gc.DeepEquals, []nova.FlavorAccess{{FlavorId: flavor.Id, TenantId: "tenant-b"}})
+	_, err = s.nova.AddFlavorAccess(flavor.Id, "tenant-a")
+	c.Assert(err, gc.IsNil)
+	_, err = s.nova.AddFlavorAccess(flavor.Id, "tenant-a")
+	c.Assert(errors.IsDuplicateValue(err), gc.Equals, true)
+
+	access, err = s.nova.ListFlavorAccess(flavor.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(access, gc.DeepEquals, []nova.FlavorAccess{
+		{FlavorId: flavor.Id, TenantId: "tenant-a"},
+		{FlavorId: flavor.Id, TenantId: "tenant-b"},
+	})
+
+	access, err = s.nova.RemoveFlavorAccess(flavor.Id, "tenant-b")
+	c.Assert(err, gc.IsNil)
+	c.Check(access, gc.DeepEquals, []nova.FlavorAccess{{FlavorId: flavor.Id, TenantId: "tenant-a"}})
+	_, err = s.nova.RemoveFlavorAccess(flavor.Id, "tenant-b")
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+
+	// Public flavors have no access list.
+	_, err = s.nova.AddFlavorAccess(s.testFlavorId, "tenant-a")
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*Can not add access to a public flavor(.|\n)*")
+	_, err = s.nova.ListFlavorAccess(s.testFlavorId)
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+}
+
+func (s *localLiveSuite) TestRunServerWithTagsAndDescription(c *gc.C) {
+	entity, err := s.nova.RunServer(nova.RunServerOpts{
+		Name:        "inst-tags",
+		FlavorId:    s.testFlavorId,
+		ImageId:     s.testImageId,
+		Networks:    []nova.ServerNetworks{{NetworkId: s.testNetwork}},
+		Description: "tagged server",
+		Tags:        []string{"web", "cluster-a", "web"},
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(entity.Id)
+	server, err := s.nova.GetServer(entity.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Description, gc.Equals, "tagged server")
+	c.Check(server.Tags, gc.DeepEquals, []string{"cluster-a", "web"})
+
+	// Tags need microversion 2.52, which requires networks, so a
+	// network is allocated if none are given.
+	entity, err = s.nova.RunServer(nova.RunServerOpts{
+		Name:     "inst-tags-no-networks",
+		FlavorId: s.testFlavorId,
+		ImageId:  s.testImageId,
+		Tags:     []string{"web"},
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(entity.Id)
+
+	_, err = s.nova.RunServer(nova.RunServerOpts{
+		Name:     "inst-bad-tags",
+		FlavorId: s.testFlavorId,
+		ImageId:  s.testImageId,
+		Tags:     []string{"a/b"},
+	})
+	c.Assert(err, gc.ErrorMatches, "(.|\n)*must not contain '/' or ','(.|\n)*")
+}
+
+func (s *localLiveSuite) TestServerTagFilters(c *gc.C) {
+	var ids []string
+	for _, tags := range [][]string{{"a", "b"}, {"b", "c"}, {"c"}} {
+		instance, err := s.createInstance("test-tag-filters")
+		c.Assert(err, gc.IsNil)
+		defer s.nova.DeleteServer(instance.Id)
+		_, err = s.nova.ReplaceServerTags(instance.Id, tags)
+		c.Assert(err, gc.IsNil)
+		ids = append(ids, instance.Id)
+	}
+	for i, test := range []struct {
+		key, value string
+		expected   []string
+	}{
+		{nova.FilterTags, "b", []string{ids[0], ids[1]}},
+		{nova.FilterTags, "a,b", []string{ids[0]}},
+		{nova.FilterTagsAny, "a,c", ids},
+		{nova.FilterNotTags, "b,c", []string{ids[0], ids[2]}},
+		{nova.FilterNotTagsAny, "a,b", []string{ids[2]}},
+	} {
+		c.Logf("test %d: %s=%s", i, test.key, test.value)
+		filter := nova.NewFilter()
+		filter.Set(nova.FilterServer, "test-tag-filters")
+		filter.Set(test.key, test.value)
+		servers, err := s.nova.ListServers(filter)
+		c.Assert(err, gc.IsNil)
+		var found []string
+		for _, server := range servers {
+			found = append(found, server.Id)
+		}
+		sort.Strings(found)
+		sort.Strings(test.expected)
+		c.Check(found, gc.DeepEquals, test.expected)
+	}
+}
+
+func (s *localLiveSuite) TestServerTagNotFound(c *gc.C) {
+	instance, err := s.createInstance("test-tag-not-found")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+	err = s.nova.DeleteServerTag(instance.Id, "missing")
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+	_, err = s.nova.HasServerTag("no-such-server", "missing")
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+}
+
+func (s *localLiveSuite) TestInstanceActionsRecorded(c *gc.C) {
+	instance, err := s.createInstance("test-actions-recorded")
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.nova.RebootServer(instance.Id, nova.RebootSoft), gc.IsNil)
+	c.Assert(s.nova.DeleteServer(instance.Id), gc.IsNil)
+
+	// The actions of deleted servers are still shown.
+	actions, err := s.nova.ListInstanceActions(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(actions, gc.HasLen, 3)
+	c.Check(actions[0].Action, gc.Equals, nova.ActionDelete)
+	c.Check(actions[1].Action, gc.Equals, nova.ActionReboot)
+	c.Check(actions[2].Action, gc.Equals, nova.ActionCreate)
+
+	action, err := s.nova.GetInstanceAction(instance.Id, actions[1].RequestId)
+	c.Assert(err, gc.IsNil)
+	c.Check(action.Message, gc.Equals, "")
+	c.Assert(action.Events, gc.HasLen, 1)
+	c.Check(action.Events[0].Event, gc.Equals, "compute_reboot_instance")
+	c.Check(action.Events[0].Result, gc.Equals, nova.EventResultSuccess)
+
+	_, err = s.nova.GetInstanceAction(instance.Id, "req-missing")
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+	_, err = s.nova.ListInstanceActions("no-such-server")
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+}
+
+func (s *localLiveSuite) TestInstanceActionsRecordFailure(c *gc.C) {
+	s.openstack.Nova.SetAZForNoValidHosts(nova.AvailabilityZone{
+		Name:  "az-actions-novalid",
+		State: nova.AvailabilityZoneState{Available: true},
+	})
+	instance, err := s.runServerAvailabilityZone("az-actions-novalid")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	actions, err := s.nova.ListInstanceActions(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(actions, gc.HasLen, 1)
+	c.Check(actions[0].Message, gc.Equals, "Error")
+
+	action, err := s.nova.GetInstanceAction(instance.Id, actions[0].RequestId)
+	c.Assert(err, gc.IsNil)
+	c.Assert(action.Events, gc.HasLen, 1)
+	c.Check(action.Events[0].Event, gc.Equals, "conductor_schedule_and_build_instances")
+	c.Check(action.Events[0].Result, gc.Equals, nova.EventResultError)
+	c.Check(action.Events[0].Traceback, gc.Matches, "(?s)Traceback.*No valid host was found.*")
+}
+
+func (s *localLiveSuite) TestCreateServerImage(c *gc.C) {
+	instance, err := s.createInstance("test-create-image")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+	server, err := s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+
+	imageId, err := s.nova.CreateServerImage(instance.Id, nova.CreateImageOpts{
+		Name:     "golden",
+		Metadata: map[string]string{"pipeline": "golden-image"},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(imageId, gc.Not(gc.Equals), "")
+
+	glanceClient := glance.New(s.client)
+	image, err := glanceClient.WaitForImage(context.Background(), imageId, glance.WaitOpts{Backoff: noWait})
+	c.Assert(err, gc.IsNil)
+	c.Check(image.Name, gc.Equals, "golden")
+	c.Check(image.Status, gc.Equals, glance.StatusActive)
+	c.Check(image.Metadata.ImageType, gc.Equals, "snapshot")
+	c.Check(image.Metadata.InstanceUUID, gc.Equals, server.UUID)
+
+	actions, err := s.nova.ListInstanceActions(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(actions[0].Action, gc.Equals, nova.ActionCreateImage)
+}
+
+func (s *localLiveSuite) TestCreateServerImageConflict(c *gc.C) {
+	instance, err := s.createInstance("test-create-image-conflict")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+	c.Assert(s.nova.ShelveServer(instance.Id), gc.IsNil)
+	_, err = s.nova.CreateServerImage(instance.Id, nova.CreateImageOpts{Name: "shelved"})
+	c.Assert(err, gc.ErrorMatches, "(?s)failed to create image \"shelved\" of server .*SHELVED_OFFLOADED.*")
+}
+
+func (s *localLiveSuite) TestBackupServerRotation(c *gc.C) {
+	instance, err := s.createInstance("test-backup-rotation")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	var backups []string
+	for i := 0; i < 3; i++ {
+		imageId, err := s.nova.BackupServer(instance.Id, nova.BackupOpts{
+			Name:       fmt.Sprintf("backup-%d", i),
+			BackupType: nova.BackupDaily,
+			Rotation:   2,
+		})
+		c.Assert(err, gc.IsNil)
+		backups = append(backups, imageId)
+	}
+	// A weekly backup does not rotate the daily ones.
+	_, err = s.nova.BackupServer(instance.Id, nova.BackupOpts{
+		Name:       "weekly",
+		BackupType: nova.BackupWeekly,
+		Rotation:   1,
+	})
+	c.Assert(err, gc.IsNil)
+
+	glanceClient := glance.New(s.client)
+	_, err = glanceClient.GetImageDetail(backups[0])
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+	for _, imageId := range backups[1:] {
+		image, err := glanceClient.GetImageDetail(imageId)
+		c.Assert(err, gc.IsNil)
+		c.Check(image.Metadata.ImageType, gc.Equals, "backup")
+		c.Check(image.Metadata.BackupType, gc.Equals, nova.BackupDaily)
+	}
+
+	// With no rotation no backup is kept.
+	imageId, err := s.nova.BackupServer(instance.Id, nova.BackupOpts{
+		Name:       "none",
+		BackupType: nova.BackupDaily,
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(imageId, gc.Equals, "")
+	images, err := glanceClient.ListImagesDetail()
+	c.Assert(err, gc.IsNil)
+	c.Assert(images, gc.HasLen, 1)
+	c.Assert(images[0].Name, gc.Equals, "weekly")
+}
+
+func (s *localLiveSuite) TestWaitForImage(c *gc.C) {
+	instance, err := s.createInstance("test-wait-for-image")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+	defer s.openstack.Nova.SetImageStatus("")
+	glanceClient := glance.New(s.client)
+
+	s.openstack.Nova.SetImageStatus(glance.StatusSaving)
+	imageId, err := s.nova.CreateServerImage(instance.Id, nova.CreateImageOpts{Name: "saving"})
+	c.Assert(err, gc.IsNil)
+	_, err = glanceClient.WaitForImage(context.Background(), imageId, glance.WaitOpts{
+		Timeout: 10 * time.Millisecond,
+		Backoff: func(int) time.Duration { return time.Millisecond },
+	})
+	c.Assert(errors.IsTimeout(err), gc.Equals, true)
+
+	s.openstack.Nova.SetImageStatus(glance.StatusError)
+	imageId, err = s.nova.CreateServerImage(instance.Id, nova.CreateImageOpts{Name: "failed"})
+	c.Assert(err, gc.IsNil)
+	_, err = glanceClient.WaitForImage(context.Background(), imageId, glance.WaitOpts{Backoff: noWait})
+	c.Assert(err, gc.ErrorMatches, "image .* has status ERROR")
+	_, ok := err.(*glance.ImageStatusError)
+	c.Assert(ok, gc.Equals, true)
+}
+
+func (s *localLiveSuite) TestAttachDetachOSInterfaceAddresses(c *gc.C) {
+	instance, err := s.createInstance("test-attach-by-network")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	attached, err := s.nova.AttachOSInterface(instance.Id, nova.AttachInterfaceOpts{
+		NetworkId: s.testNetwork,
+		FixedIP:   "10.20.0.5",
+		Tag:       "management",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Check(attached.IPAddress, gc.Equals, "10.20.0.5")
+	c.Check(attached.Tag, gc.Equals, "management")
+	server, err := s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Addresses["net"], gc.DeepEquals, []nova.IPAddress{{Version: 4, Address: "10.20.0.5", Type: "fixed"}})
+
+	// The address cannot be used twice.
+	_, err = s.nova.AttachOSInterface(instance.Id, nova.AttachInterfaceOpts{
+		NetworkId: s.testNetwork,
+		FixedIP:   "10.20.0.5",
+	})
+	c.Assert(err, gc.ErrorMatches, "(?s).*Fixed IP address 10.20.0.5 is already in use.*")
+
+	c.Assert(s.nova.DetachOSInterface(instance.Id, attached.PortID), gc.IsNil)
+	server, err = s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Addresses["net"], gc.HasLen, 0)
+	err = s.nova.DetachOSInterface(instance.Id, attached.PortID)
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+
+	actions, err := s.nova.ListInstanceActions(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(actions[0].Action, gc.Equals, nova.ActionDetachInterface)
+	c.Check(actions[1].Action, gc.Equals, nova.ActionAttachInterface)
+}
+
+func (s *localLiveSuite) TestServerMetadataErrors(c *gc.C) {
+	instance, err := s.createInstance("test-metadata-errors")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	longKey := strings.Repeat("k", nova.MaxMetadataKeyLength+1)
+	longValue := strings.Repeat("v", nova.MaxMetadataValueLength+1)
+	for _, test := range []struct {
+		key, value, reason string
+	}{
+		{"", "v", nova.MetadataKeyBlank},
+		{longKey, "v", nova.MetadataKeyTooLong},
+		{"k", longValue, nova.MetadataValueTooLong},
+	} {
+		err := s.nova.SetServerMetadataItem(instance.Id, test.key, test.value)
+		c.Assert(err, gc.FitsTypeOf, &nova.MetadataError{})
+		c.Check(err.(*nova.MetadataError).Reason, gc.Equals, test.reason)
+		c.Check(err.(*nova.MetadataError).Key, gc.Equals, test.key)
+		_, err = s.nova.ReplaceServerMetadata(instance.Id, map[string]string{test.key: test.value})
+		c.Assert(err, gc.FitsTypeOf, &nova.MetadataError{})
+		err = s.nova.SetServerMetadata(instance.Id, map[string]string{test.key: test.value})
+		c.Assert(err, gc.FitsTypeOf, &nova.MetadataError{})
+	}
+
+	two := 2
+	defer s.setQuotas(nova.QuotaSetOpts{MetadataItems: &two})()
+	_, err = s.nova.ReplaceServerMetadata(instance.Id, map[string]string{"k1": "v1", "k2": "v2"})
+	c.Assert(err, gc.IsNil)
+	err = s.nova.SetServerMetadataItem(instance.Id, "k3", "v3")
+	c.Assert(err, gc.FitsTypeOf, &nova.MetadataError{})
+	c.Check(err.(*nova.MetadataError).Reason, gc.Equals, nova.MetadataLimitExceeded)
+	c.Check(err, gc.ErrorMatches, "(?s).*Maximum number of metadata items exceeds 2.*")
+
+	// Replacing an existing item does not count against the quota.
+	err = s.nova.SetServerMetadataItem(instance.Id, "k2", "v2.replacement")
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.nova.DeleteServerMetadataItem(instance.Id, "k1"), gc.IsNil)
+	c.Assert(s.nova.SetServerMetadataItem(instance.Id, "k3", "v3"), gc.IsNil)
+	metadata, err := s.nova.GetServerMetadata(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(metadata, gc.DeepEquals, map[string]string{"k2": "v2.replacement", "k3": "v3"})
+
+	// Other forbidden requests are not quota errors.
+	cleanup := s.openstack.Nova.RegisterControlPoint("setServerMetadataItem", func(sc hook.ServiceControl, args ...interface{}) error {
+		return testservices.NewForbiddenRateLimitError()
+	})
+	err = s.nova.SetServerMetadataItem(instance.Id, "k4", "v4")
+	cleanup()
+	c.Assert(errors.IsForbidden(err), gc.Equals, true)
+	c.Assert(err, gc.Not(gc.FitsTypeOf), &nova.MetadataError{})
+}
+
+func (s *localLiveSuite) TestServerMetadataEscapedKeys(c *gc.C) {
+	instance, err := s.createInstance("test-metadata-escaped-keys")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	for _, key := range []string{"a/b", "100%", "os-interface"} {
+		c.Assert(s.nova.SetServerMetadataItem(instance.Id, key, "v"), gc.IsNil)
+		value, err := s.nova.GetServerMetadataItem(instance.Id, key)
+		c.Assert(err, gc.IsNil)
+		c.Check(value, gc.Equals, "v")
+		c.Assert(s.nova.DeleteServerMetadataItem(instance.Id, key), gc.IsNil)
+	}
+}
+
+func (s *localLiveSuite) TestRunServerBlockDevices(c *gc.C) {
+	var b nova.BlockDeviceBuilder
+	b.BootFromImage(s.testImageId, 20, nova.VolumeOpts{VolumeType: "ssd", DeleteOnTermination: true})
+	b.AddBlankVolume(10, nova.VolumeOpts{})
+	b.AddSwap(1)
+	mappings, err := b.Build()
+	c.Assert(err, gc.IsNil)
+	// The volume type needs microversion 2.67, which also requires
+	// networks, so a network is allocated.
+	entity, err := s.nova.RunServer(nova.RunServerOpts{
+		Name:                "inst-block-devices",
+		FlavorId:            s.testFlavorId,
+		BlockDeviceMappings: mappings,
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(entity.Id)
+
+	for _, test := range []struct {
+		mappings []nova.BlockDeviceMapping
+		err      string
+	}{{
+		mappings: []nova.BlockDeviceMapping{{BootIndex: -1, SourceType: "blank", DestinationType: "volume", VolumeSize: 1}},
+		err:      "Boot sequence for the instance and image/block device mapping combination is not valid.",
+	}, {
+		mappings: []nova.BlockDeviceMapping{{BootIndex: 0, SourceType: "volume", DestinationType: "local", UUID: "volume-id"}},
+		err:      "Mapping volume to local is not supported.",
+	}, {
+		mappings: []nova.BlockDeviceMapping{{BootIndex: 0, SourceType: "image", DestinationType: "volume", UUID: s.testImageId}},
+		err:      "Images with destination_type 'volume' need to have a non-zero size specified",
+	}, {
+		mappings: []nova.BlockDeviceMapping{{BootIndex: 0, SourceType: "snapshot", DestinationType: "volume"}},
+		err:      "Missing device UUID.",
+	}} {
+		_, err := s.nova.RunServer(nova.RunServerOpts{
+			Name:                "inst-bad-block-devices",
+			FlavorId:            s.testFlavorId,
+			BlockDeviceMappings: test.mappings,
+		})
+		c.Check(err, gc.ErrorMatches, "(?s).*Block Device Mapping is Invalid: "+regexp.QuoteMeta(test.err)+".*")
+	}
+}
+
+func (s *localLiveSuite) TestHypervisorsAndServices(c *gc.C) {
+	s.openstack.Nova.SetHosts("host1", "host2")
+	defer s.openstack.Nova.SetHosts()
+	instance, err := s.createInstance("test-hypervisors")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+	flavor, err := s.nova.GetFlavor(s.testFlavorId)
+	c.Assert(err, gc.IsNil)
+
+	hypervisors, err := s.nova.ListHypervisors()
+	c.Assert(err, gc.IsNil)
+	c.Assert(hypervisors, gc.HasLen, 2)
+	c.Check(hypervisors[0].HypervisorHostname, gc.Equals, "host1")
+	c.Check(hypervisors[0].RunningVMs, gc.Equals, 1)
+	c.Check(hypervisors[0].VCPUsUsed, gc.Equals, flavor.VCPUs)
+	c.Check(hypervisors[0].MemoryMBUsed, gc.Equals, flavor.RAM)
+	c.Check(hypervisors[0].FreeRAMMB, gc.Equals, hypervisors[0].MemoryMB-flavor.RAM)
+	c.Check(hypervisors[1].RunningVMs, gc.Equals, 0)
+	hypervisor, err := s.nova.GetHypervisor(hypervisors[1].Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(hypervisor.HypervisorHostname, gc.Equals, "host2")
+	stats, err := s.nova.GetHypervisorStatistics()
+	c.Assert(err, gc.IsNil)
+	c.Check(stats.Count, gc.Equals, 2)
+	c.Check(stats.RunningVMs, gc.Equals, 1)
+	c.Check(stats.VCPUs, gc.Equals, hypervisors[0].VCPUs+hypervisors[1].VCPUs)
+
+	services, err := s.nova.ListServices(nova.ListServicesOpts{Binary: nova.BinaryCompute, Host: "host1"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(services, gc.HasLen, 1)
+	c.Check(services[0].Status, gc.Equals, nova.HostStatusEnabled)
+	c.Check(services[0].Id, gc.Equals, hypervisors[0].Service.Id)
+
+	// New servers are not scheduled on the host of a disabled service.
+	service, err := s.nova.DisableService(services[0].Id, "maintenance")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.EnableService(service.Id)
+	c.Check(service.Status, gc.Equals, nova.HostStatusDisabled)
+	c.Check(service.DisabledReason, gc.Equals, "maintenance")
+	second, err := s.createInstance("test-hypervisors-disabled")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(second.Id)
+	server, err := s.nova.GetServer(second.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.HostId, gc.Equals, hostId(server, "host2"))
+
+	service, err = s.nova.EnableService(service.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(service.Status, gc.Equals, nova.HostStatusEnabled)
+	c.Check(service.DisabledReason, gc.Equals, "")
+
+	_, err = s.nova.GetHypervisor("no-such-hypervisor")
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+	_, err = s.nova.DisableService("no-such-service", "")
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+}
+
+func (s *localLiveSuite) TestAggregateAvailabilityZones(c *gc.C) {
+	s.openstack.Nova.SetAvailabilityZones()
+	s.openstack.Nova.SetHosts("host1", "host2", "host3")
+	defer s.openstack.Nova.SetHosts()
+
+	aggregate, err := s.nova.CreateAggregate(nova.AggregateOpts{Name: "rack-a", AvailabilityZone: "zone-a"})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteAggregate(aggregate.Id)
+	c.Check(aggregate.AvailabilityZone, gc.Equals, "zone-a")
+	c.Check(aggregate.Metadata, gc.DeepEquals, map[string]string{"availability_zone": "zone-a"})
+	_, err = s.nova.CreateAggregate(nova.AggregateOpts{Name: "rack-a"})
+	c.Assert(errors.IsDuplicateValue(err), gc.Equals, true)
+
+	aggregate, err = s.nova.AddAggregateHost(aggregate.Id, "host2")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.RemoveAggregateHost(aggregate.Id, "host2")
+	c.Check(aggregate.Hosts, gc.DeepEquals, []string{"host2"})
+	_, err = s.nova.AddAggregateHost(aggregate.Id, "no-such-host")
+	c.Assert(errors.IsNotFound(err), gc.Equals, true)
+
+	// The zones are derived from the aggregates, with the hosts
+	// outside them in the default zone.
+	zones, err := s.nova.ListAvailabilityZones()
+	c.Assert(err, gc.IsNil)
+	c.Assert(zones, gc.DeepEquals, []nova.AvailabilityZone{
+		{Name: "nova", State: nova.AvailabilityZoneState{Available: true}},
+		{Name: "zone-a", State: nova.AvailabilityZoneState{Available: true}},
+	})
+	inst, err := s.runServerAvailabilityZone("zone-a")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(inst.Id)
+	server, err := s.nova.GetServer(inst.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.HostId, gc.Equals, hostId(server, "host2"))
+	c.Check(server.AvailabilityZone, gc.Equals, "zone-a")
+
+	// A host can only be in one availability zone.
+	other, err := s.nova.CreateAggregate(nova.AggregateOpts{Name: "rack-b", AvailabilityZone: "zone-b"})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteAggregate(other.Id)
+	_, err = s.nova.AddAggregateHost(other.Id, "host2")
+	c.Assert(err, gc.ErrorMatches, "(?s).*One or more hosts already in availability zone\\(s\\) \\[zone-a\\].*")
+
+	// Aggregates with hosts cannot be deleted.
+	err = s.nova.DeleteAggregate(aggregate.Id)
+	c.Assert(err, gc.ErrorMatches, "(?s).*Host aggregate is not empty.*")
+
+	aggregate, err = s.nova.SetAggregateMetadata(aggregate.Id, map[string]string{"ssd": "true"})
+	c.Assert(err, gc.IsNil)
+	c.Check(aggregate.Metadata, gc.DeepEquals, map[string]string{"availability_zone": "zone-a", "ssd": "true"})
+	aggregate, err = s.nova.DeleteAggregateMetadata(aggregate.Id, "ssd")
+	c.Assert(err, gc.IsNil)
+	c.Check(aggregate.Metadata, gc.DeepEquals, map[string]string{"availability_zone": "zone-a"})
+	aggregate, err = s.nova.UpdateAggregate(aggregate.Id, nova.AggregateOpts{Name: "rack-a1"})
+	c.Assert(err, gc.IsNil)
+	c.Check(aggregate.Name, gc.Equals, "rack-a1")
+
+	aggregates, err := s.nova.ListAggregates()
+	c.Assert(err, gc.IsNil)
+	c.Assert(aggregates, gc.HasLen, 2)
+	c.Check(aggregates[0].Name, gc.Equals, "rack-a1")
+	c.Check(aggregates[1].Name, gc.Equals, "rack-b")
+}
+
+func (s *localLiveSuite) TestLiveMigrateServer(c *gc.C) {
+	s.openstack.Nova.SetHosts("host1", "host2", "host3")
+	defer s.openstack.Nova.SetHosts()
+	instance, err := s.createInstance("test-live-migrate")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+	server, err := s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(server.HostId, gc.Equals, hostId(server, "host1"))
+
+	err = s.nova.LiveMigrateServer(instance.Id, nova.LiveMigrateOpts{})
+	c.Assert(err, gc.IsNil)
+	server, err = s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Status, gc.Equals, nova.StatusActive)
+	c.Check(server.HostId, gc.Equals, hostId(server, "host2"))
+
+	// Held migrations can be watched and forced to complete.
+	s.openstack.Nova.SetLiveMigrationsHeld(true)
+	defer s.openstack.Nova.SetLiveMigrationsHeld(false)
+	// The double's hosts do not share storage, so disks are copied
+	// unless block migration is turned off.
+	err = s.nova.LiveMigrateServer(instance.Id, nova.LiveMigrateOpts{Host: "host3"})
+	c.Assert(err, gc.IsNil)
+	server, err = s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Status, gc.Equals, nova.StatusMigrating)
+	migrations, err := s.nova.ListServerMigrations(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(migrations, gc.HasLen, 1)
+	c.Check(migrations[0].Status, gc.Equals, nova.MigrationStatusRunning)
+	c.Check(migrations[0].SourceCompute, gc.Equals, "host2")
+	c.Check(migrations[0].DestCompute, gc.Equals, "host3")
+	c.Check(migrations[0].DiskTotalBytes > 0, gc.Equals, true)
+	c.Check(migrations[0].MemoryRemainingBytes, gc.Equals, migrations[0].MemoryTotalBytes-migrations[0].MemoryProcessedBytes)
+	migration, err := s.nova.GetServerMigration(instance.Id, migrations[0].Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(migration.UUID, gc.Equals, migrations[0].UUID)
+
+	err = s.nova.ForceCompleteMigration(instance.Id, migrations[0].Id)
+	c.Assert(err, gc.IsNil)
+	server, err = s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Status, gc.Equals, nova.StatusActive)
+	c.Check(server.HostId, gc.Equals, hostId(server, "host3"))
+	migrations, err = s.nova.ListServerMigrations(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(migrations, gc.HasLen, 0)
+	_, err = s.nova.GetServerMigration(instance.Id, migration.Id)
+	c.Check(errors.IsNotFound(err), gc.Equals, true)
+
+	all, err := s.nova.ListMigrations(nova.ListMigrationsOpts{
+		InstanceUUID:  server.UUID,
+		MigrationType: nova.MigrationTypeLive,
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(all, gc.HasLen, 2)
+	c.Check(all[0].Status, gc.Equals, nova.MigrationStatusCompleted)
+	c.Check(all[0].DestCompute, gc.Equals, "host3")
+	c.Check(all[1].DestCompute, gc.Equals, "host2")
+
+	actions, err := s.nova.ListInstanceActions(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(actions[0].Action, gc.Equals, nova.ActionLiveMigrationForceComplete)
+	c.Check(actions[1].Action, gc.Equals, nova.ActionLiveMigration)
+
+	err = s.nova.LiveMigrateServer(instance.Id, nova.LiveMigrateOpts{Host: "host3"})
+	c.Check(err, gc.ErrorMatches, "(.|\n)*Unable to migrate instance .* to current host \\(host3\\).*")
+	err = s.nova.LiveMigrateServer(instance.Id, nova.LiveMigrateOpts{Host: "no-such-host"})
+	c.Check(errors.IsNotFound(err), gc.Equals, true)
+}
+
+func (s *localLiveSuite) TestMigrateServer(c *gc.C) {
+	s.openstack.Nova.SetHosts("host1", "host2")
+	defer s.openstack.Nova.SetHosts()
+	instance, err := s.createInstance("test-migrate")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	err = s.nova.MigrateServer(instance.Id, "host2")
+	c.Assert(err, gc.IsNil)
+	server, err := s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Status, gc.Equals, nova.StatusVerifyResize)
+	c.Check(server.HostId, gc.Equals, hostId(server, "host2"))
+	err = s.nova.RevertResize(instance.Id)
+	c.Assert(err, gc.IsNil)
+	server, err = s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Status, gc.Equals, nova.StatusActive)
+	c.Check(server.HostId, gc.Equals, hostId(server, "host1"))
+
+	err = s.nova.MigrateServer(instance.Id, "")
+	c.Assert(err, gc.IsNil)
+	err = s.nova.ConfirmResize(instance.Id)
+	c.Assert(err, gc.IsNil)
+	server, err = s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.HostId, gc.Equals, hostId(server, "host2"))
+
+	migrations, err := s.nova.ListMigrations(nova.ListMigrationsOpts{InstanceUUID: server.UUID})
+	c.Assert(err, gc.IsNil)
+	c.Assert(migrations, gc.HasLen, 2)
+	c.Check(migrations[0].MigrationType, gc.Equals, nova.MigrationTypeCold)
+	c.Check(migrations[0].Status, gc.Equals, nova.MigrationStatusConfirmed)
+	c.Check(migrations[1].Status, gc.Equals, nova.MigrationStatusReverted)
+	migrations, err = s.nova.ListMigrations(nova.ListMigrationsOpts{
+		InstanceUUID: server.UUID,
+		Status:       nova.MigrationStatusConfirmed,
+	})
+	c.Assert(err, gc.IsNil)
+	c.Check(migrations, gc.HasLen, 1)
+
+	// No other host is left to migrate to.
+	services, err := s.nova.ListServices(nova.ListServicesOpts{Host: "host1"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.nova.DisableService(services[0].Id, "")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.EnableService(services[0].Id)
+	err = s.nova.MigrateServer(instance.Id, "")
+	c.Check(err, gc.ErrorMatches, "(.|\n)*No valid host was found.*")
+}
+
+func (s *localLiveSuite) TestEvacuateServer(c *gc.C) {
+	s.openstack.Nova.SetHosts("host1", "host2")
+	defer s.openstack.Nova.SetHosts()
+	instance, err := s.createInstance("test-evacuate")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	err = s.nova.EvacuateServer(instance.Id, nova.EvacuateOpts{})
+	c.Check(err, gc.ErrorMatches, "(.|\n)*Compute service of host1 is still in use.*")
+
+	services, err := s.nova.ListServices(nova.ListServicesOpts{Host: "host1"})
+	c.Assert(err, gc.IsNil)
+	service, err := s.nova.ForceDownService(services[0].Id, true)
+	c.Assert(err, gc.IsNil)
+	defer s.nova.ForceDownService(service.Id, false)
+	c.Check(service.ForcedDown, gc.Equals, true)
+	c.Check(service.State, gc.Equals, nova.HostStateDown)
+	c.Check(service.Status, gc.Equals, nova.HostStatusEnabled)
+
+	err = s.nova.EvacuateServer(instance.Id, nova.EvacuateOpts{AdminPass: "secret"})
+	c.Assert(err, gc.IsNil)
+	server, err := s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Status, gc.Equals, nova.StatusActive)
+	c.Check(server.HostId, gc.Equals, hostId(server, "host2"))
+	migrations, err := s.nova.ListMigrations(nova.ListMigrationsOpts{
+		Host:          "host1",
+		MigrationType: nova.MigrationTypeEvacuation,
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(migrations, gc.HasLen, 1)
+	c.Check(migrations[0].InstanceUUID, gc.Equals, server.UUID)
+	c.Check(migrations[0].Status, gc.Equals, nova.MigrationStatusDone)
+
+	// New servers are not scheduled on a host which is down.
+	second, err := s.createInstance("test-evacuate-down")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(second.Id)
+	server, err = s.nova.GetServer(second.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.HostId, gc.Equals, hostId(server, "host2"))
+
+	service, err = s.nova.ForceDownService(service.Id, false)
+	c.Assert(err, gc.IsNil)
+	c.Check(service.State, gc.Equals, nova.HostStateUp)
+}
+
+func (s *localLiveSuite) TestTenantUsage(c *gc.C) {
+	start := time.Now().Add(-time.Hour)
+	first, err := s.createInstance("test-usage-1")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(first.Id)
+	second, err := s.createInstance("test-usage-2")
+	c.Assert(err, gc.IsNil)
+	err = s.nova.DeleteServer(second.Id)
+	c.Assert(err, gc.IsNil)
+	server, err := s.nova.GetServer(first.Id)
+	c.Assert(err, gc.IsNil)
+	flavor, err := s.nova.GetFlavor(s.testFlavorId)
+	c.Assert(err, gc.IsNil)
+
+	// A limit of one server per page needs several requests, whose
+	// results are merged.
+	usages, err := s.nova.ListTenantUsages(nova.TenantUsageOpts{Start: start, Detailed: true, Limit: 1})
+	c.Assert(err, gc.IsNil)
+	var tenantUsage *nova.TenantUsage
+	for i := range usages {
+		if usages[i].TenantId == server.TenantId {
+			tenantUsage = &usages[i]
+		}
+	}
+	c.Assert(tenantUsage, gc.NotNil)
+	serverUsages := make(map[string]nova.ServerUsage)
+	for _, usage := range tenantUsage.ServerUsages {
+		serverUsages[usage.InstanceId] = usage
+	}
+	c.Assert(serverUsages[first.Id].Name, gc.Equals, "test-usage-1")
+	c.Check(serverUsages[first.Id].State, gc.Equals, "active")
+	c.Check(serverUsages[first.Id].VCPUs, gc.Equals, flavor.VCPUs)
+	c.Check(serverUsages[first.Id].MemoryMB, gc.Equals, flavor.RAM)
+	c.Check(serverUsages[second.Id].State, gc.Equals, "terminated")
+	c.Check(serverUsages[second.Id].EndedAt, gc.Not(gc.Equals), "")
+	var vcpuHours float64
+	for _, usage := range tenantUsage.ServerUsages {
+		vcpuHours += usage.VCPUHours()
+	}
+	c.Check(tenantUsage.TotalVCPUsUsage-vcpuHours < 1e-9, gc.Equals, true)
+
+	usage, err := s.nova.GetTenantUsage(server.TenantId, nova.TenantUsageOpts{Start: start, Limit: 1})
+	c.Assert(err, gc.IsNil)
+	c.Check(usage.TenantId, gc.Equals, server.TenantId)
+	c.Check(usage.ServerUsages, gc.HasLen, len(tenantUsage.ServerUsages))
+
+	usage, err = s.nova.GetTenantUsage("no-such-tenant", nova.TenantUsageOpts{Start: start})
+	c.Assert(err, gc.IsNil)
+	c.Check(usage.TenantId, gc.Equals, "no-such-tenant")
+	c.Check(usage.ServerUsages, gc.HasLen, 0)
+
+	_, err = s.nova.ListTenantUsages(nova.TenantUsageOpts{Start: time.Now().Add(time.Hour)})
+	c.Check(err, gc.ErrorMatches, "(.|\n)*The start time cannot occur after the end time.*")
+}
+
+func (s *localLiveSuite) TestRescueServer(c *gc.C) {
+	instance, err := s.createInstance("test-rescue")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+
+	password, err := s.nova.RescueServer(instance.Id, nova.RescueServerOpts{ImageId: s.testImageId})
+	c.Assert(err, gc.IsNil)
+	c.Check(password, gc.Not(gc.Equals), "")
+	server, err := s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Status, gc.Equals, nova.StatusRescue)
+
+	// A rescued server cannot be rescued again.
+	_, err = s.nova.RescueServer(instance.Id, nova.RescueServerOpts{})
+	c.Check(err, gc.ErrorMatches, "(.|\n)*Cannot 'rescue' instance.*")
+
+	err = s.nova.UnrescueServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	server, err = s.nova.GetServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(server.Status, gc.Equals, nova.StatusActive)
+	err = s.nova.UnrescueServer(instance.Id)
+	c.Check(err, gc.ErrorMatches, "(.|\n)*Cannot 'unrescue' instance.*")
+
+	password, err = s.nova.RescueServer(instance.Id, nova.RescueServerOpts{AdminPass: "secret"})
+	c.Assert(err, gc.IsNil)
+	c.Check(password, gc.Equals, "secret")
+
+	actions, err := s.nova.ListInstanceActions(instance.Id)
+	c.Assert(err, gc.IsNil)
+	var names []string
+	for _, action := range actions {
+		names = append(names, action.Action)
+	}
+	sort.Strings(names)
+	c.Check(names, gc.DeepEquals, []string{nova.ActionCreate, nova.ActionRescue, nova.ActionRescue, nova.ActionUnrescue})
+}
+
+func (s *localLiveSuite) TestServerPassword(c *gc.C) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	c.Assert(err, gc.IsNil)
+	_, err = s.nova.ImportKeyPair("test-password-key", sshRSAPublicKey(key), nova.KeyTypeSSH)
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteKeyPair("test-password-key")
+	entity, err := s.nova.RunServer(nova.RunServerOpts{
+		Name:     "test-password",
+		FlavorId: s.testFlavorId,
+		ImageId:  s.testImageId,
+		KeyName:  "test-password-key",
+		Networks: []nova.ServerNetworks{{NetworkId: s.testNetwork}},
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(entity.Id)
+
+	// No password is reported until the guest sets one.
+	encrypted, err := s.nova.GetServerPassword(entity.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(encrypted, gc.Equals, "")
+
+	err = s.openstack.Nova.SetServerPassword(entity.Id, "secret")
+	c.Assert(err, gc.IsNil)
+	encrypted, err = s.nova.GetServerPassword(entity.Id)
+	c.Assert(err, gc.IsNil)
+	password, err := nova.DecryptServerPassword(encrypted, key)
+	c.Assert(err, gc.IsNil)
+	c.Check(password, gc.Equals, "secret")
+
+	err = s.nova.ClearServerPassword(entity.Id)
+	c.Assert(err, gc.IsNil)
+	encrypted, err = s.nova.GetServerPassword(entity.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(encrypted, gc.Equals, "")
+
+	_, err = s.nova.GetServerPassword("no-such-server")
+	c.Check(errors.IsNotFound(err), gc.Equals, true)
+}
+
+func (s *localLiveSuite) TestServerPasswordWithGeneratedKeyPair(c *gc.C) {
+	keyPair, err := s.nova.CreateKeyPair("test-generated-password-key", "")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteKeyPair(keyPair.Name)
+	c.Check(keyPair.PublicKey, gc.Matches, "ssh-rsa .*")
+	key, err := nova.ParseRSAPrivateKey([]byte(keyPair.PrivateKey))
+	c.Assert(err, gc.IsNil)
+	c.Check(key.N.BitLen(), gc.Equals, 2048)
+
+	entity, err := s.nova.RunServer(nova.RunServerOpts{
+		Name:     "test-generated-password",
+		FlavorId: s.testFlavorId,
+		ImageId:  s.testImageId,
+		KeyName:  keyPair.Name,
+		Networks: []nova.ServerNetworks{{NetworkId: s.testNetwork}},
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(entity.Id)
+	err = s.openstack.Nova.SetServerPassword(entity.Id, "secret")
+	c.Assert(err, gc.IsNil)
+	encrypted, err := s.nova.GetServerPassword(entity.Id)
+	c.Assert(err, gc.IsNil)
+	password, err := nova.DecryptServerPassword(encrypted, key)
+	c.Assert(err, gc.IsNil)
+	c.Check(password, gc.Equals, "secret")
+}
+
+func (s *localLiveSuite) TestServerDiagnostics(c *gc.C) {
+	instance, err := s.createInstance("test-diagnostics")
+	c.Assert(err, gc.IsNil)
+	defer s.nova.DeleteServer(instance.Id)
+	flavor, err := s.nova.GetFlavor(s.testFlavorId)
+	c.Assert(err, gc.IsNil)
+
+	diagnostics, err := s.nova.GetServerDiagnostics(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(diagnostics.State, gc.Equals, nova.DiagnosticsStateRunning)
+	c.Check(diagnostics.NumCPUs, gc.Equals, flavor.VCPUs)
+	c.Check(diagnostics.CPUDetails, gc.HasLen, flavor.VCPUs)
+	c.Check(diagnostics.MemoryDetails.Maximum, gc.Equals, flavor.RAM)
+	c.Check(diagnostics.NumNICs, gc.Equals, len(diagnostics.NICDetails))
+
+	err = s.nova.StopServer(instance.Id)
+	c.Assert(err, gc.IsNil)
+	diagnostics, err = s.nova.GetServerDiagnostics(instance.Id)
+	c.Assert(err, gc.IsNil)
+	c.Check(diagnostics.State, gc.Equals, nova.DiagnosticsStateShutdown)
+}
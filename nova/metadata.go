@@ -0,0 +1,177 @@
+// Nova api calls for reading and changing the metadata of a server,
+// as a whole or one item at a time.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#server-metadata-servers-metadata>
+
+package nova
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+// Limits nova applies to server metadata. The number of items is
+// limited by the metadata_items quota of the project.
+const (
+	MaxMetadataKeyLength   = 255
+	MaxMetadataValueLength = 255
+)
+
+// Reasons server metadata is rejected, as reported by MetadataError.
+const (
+	MetadataKeyBlank      = "key is blank"
+	MetadataKeyTooLong    = "key is longer than 255 characters"
+	MetadataValueTooLong  = "value is longer than 255 characters"
+	MetadataLimitExceeded = "too many metadata items"
+)
+
+// MetadataError is returned when server metadata is rejected, either
+// by the checks made before a request is sent or, when the metadata
+// items quota is exceeded, by nova. Key is empty if the error is not
+// about a single key. Err holds the error returned by nova, if any.
+type MetadataError struct {
+	ServerId string
+	Key      string
+	Reason   string
+	Err      error
+}
+
+func (e *MetadataError) Error() string {
+	msg := fmt.Sprintf("invalid metadata for server %s: ", e.ServerId)
+	if e.Key != "" {
+		msg += fmt.Sprintf("key %q: ", e.Key)
+	}
+	msg += e.Reason
+	if e.Err != nil {
+		msg += fmt.Sprintf("\ncaused by: %v", e.Err)
+	}
+	return msg
+}
+
+// validateMetadata returns a *MetadataError if nova would reject the
+// given metadata items.
+func validateMetadata(serverId string, metadata map[string]string) error {
+	for key, value := range metadata {
+		reason := ""
+		switch {
+		case key == "":
+			reason = MetadataKeyBlank
+		case len(key) > MaxMetadataKeyLength:
+			reason = MetadataKeyTooLong
+		case len(value) > MaxMetadataValueLength:
+			reason = MetadataValueTooLong
+		default:
+			continue
+		}
+		return &MetadataError{ServerId: serverId, Key: key, Reason: reason}
+	}
+	return nil
+}
+
+// metadataLimitMessage starts the fault message nova returns when a
+// request exceeds the metadata items quota.
+const metadataLimitMessage = "Maximum number of metadata items exceeds"
+
+// metadataError returns the error for a failed request changing the
+// metadata of a server. Nova rejects requests exceeding the metadata
+// items quota as forbidden, but other requests, such as those denied
+// by policy, may be forbidden too.
+func metadataError(serverId string, err error, format string, args ...interface{}) error {
+	if errors.IsForbidden(err) && strings.Contains(err.Error(), metadataLimitMessage) {
+		return &MetadataError{ServerId: serverId, Reason: MetadataLimitExceeded, Err: err}
+	}
+	return errors.Newf(err, format, args...)
+}
+
+func serverMetadataURL(serverId string) string {
+	return fmt.Sprintf("%s/%s/metadata", apiServers, serverId)
+}
+
+func serverMetadataItemURL(serverId, key string) string {
+	return fmt.Sprintf("%s/%s", serverMetadataURL(serverId), url.PathEscape(key))
+}
+
+// GetServerMetadata returns all the metadata of the given server.
+func (c *Client) GetServerMetadata(serverId string) (map[string]string, error) {
+	var resp struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "compute", "v2", serverMetadataURL(serverId), &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get metadata of server with id: %s", serverId)
+	}
+	return resp.Metadata, nil
+}
+
+// GetServerMetadataItem returns the value of a single metadata item
+// of the given server.
+func (c *Client) GetServerMetadataItem(serverId, key string) (string, error) {
+	var resp struct {
+		Meta map[string]string `json:"meta"`
+	}
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "compute", "v2", serverMetadataItemURL(serverId, key), &requestData)
+	if err != nil {
+		return "", errors.Newf(err, "failed to get metadata item %q of server with id: %s", key, serverId)
+	}
+	return resp.Meta[key], nil
+}
+
+// ReplaceServerMetadata replaces all the metadata of the given server,
+// and returns the new metadata.
+func (c *Client) ReplaceServerMetadata(serverId string, metadata map[string]string) (map[string]string, error) {
+	if err := validateMetadata(serverId, metadata); err != nil {
+		return nil, err
+	}
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	req := struct {
+		Metadata map[string]string `json:"metadata"`
+	}{metadata}
+	var resp struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusOK}}
+	err := c.client.SendRequest(client.PUT, "compute", "v2", serverMetadataURL(serverId), &requestData)
+	if err != nil {
+		return nil, metadataError(serverId, err, "failed to replace metadata of server with id: %s", serverId)
+	}
+	return resp.Metadata, nil
+}
+
+// SetServerMetadataItem creates or updates a single metadata item of
+// the given server.
+func (c *Client) SetServerMetadataItem(serverId, key, value string) error {
+	item := map[string]string{key: value}
+	if err := validateMetadata(serverId, item); err != nil {
+		return err
+	}
+	req := struct {
+		Meta map[string]string `json:"meta"`
+	}{item}
+	requestData := goosehttp.RequestData{ReqValue: req, ExpectedStatus: []int{http.StatusOK}}
+	err := c.client.SendRequest(client.PUT, "compute", "v2", serverMetadataItemURL(serverId, key), &requestData)
+	if err != nil {
+		return metadataError(serverId, err, "failed to set metadata item %q of server with id: %s", key, serverId)
+	}
+	return nil
+}
+
+// DeleteServerMetadataItem deletes a single metadata item of the given
+// server.
+func (c *Client) DeleteServerMetadataItem(serverId, key string) error {
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	err := c.client.SendRequest(client.DELETE, "compute", "v2", serverMetadataItemURL(serverId, key), &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete metadata item %q of server with id: %s", key, serverId)
+	}
+	return err
+}
@@ -0,0 +1,278 @@
+// Nova api calls for moving servers between compute hosts, by live
+// migration, cold migration or evacuation, and for listing the
+// migrations. These calls are restricted to administrators by default.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#migrations-os-migrations>
+// <https://docs.openstack.org/api-ref/compute/#server-migrations-servers-server-id-migrations>
+
+package nova
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+const (
+	apiMigrations = "os-migrations"
+
+	// migrationMicroversion is the compute API microversion adding
+	// the UUID of migrations and allowing a cold migration to be sent
+	// to a given host. It is requested by all the migration calls.
+	migrationMicroversion = "2.59"
+)
+
+// Types of migration, as reported by Migration.MigrationType.
+const (
+	MigrationTypeLive       = "live-migration"
+	MigrationTypeCold       = "migration"
+	MigrationTypeResize     = "resize"
+	MigrationTypeEvacuation = "evacuation"
+)
+
+// Statuses of a migration. Live migrations go through the queued,
+// preparing and running statuses, ending as completed, failed or
+// cancelled. Cold migrations and resizes are finished once the server
+// reaches StatusVerifyResize, then confirmed or reverted. Evacuations
+// end as done or failed.
+const (
+	MigrationStatusQueued    = "queued"
+	MigrationStatusPreparing = "preparing"
+	MigrationStatusRunning   = "running"
+	MigrationStatusCompleted = "completed"
+	MigrationStatusFinished  = "finished"
+	MigrationStatusConfirmed = "confirmed"
+	MigrationStatusReverted  = "reverted"
+	MigrationStatusDone      = "done"
+	MigrationStatusFailed    = "failed"
+	MigrationStatusCancelled = "cancelled"
+	MigrationStatusError     = "error"
+)
+
+// Migration describes a move of a server between compute hosts, as
+// listed by ListMigrations.
+type Migration struct {
+	Id            int    `json:"id"`
+	UUID          string `json:"uuid"`
+	InstanceUUID  string `json:"instance_uuid"`
+	MigrationType string `json:"migration_type"`
+	Status        string `json:"status"`
+	SourceCompute string `json:"source_compute"`
+	SourceNode    string `json:"source_node"`
+	DestCompute   string `json:"dest_compute"`
+	DestNode      string `json:"dest_node"`
+	DestHost      string `json:"dest_host"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
+
+// ServerMigration describes an in-progress live migration of a server,
+// with the progress made copying its memory and disks.
+type ServerMigration struct {
+	Id                   int    `json:"id"`
+	UUID                 string `json:"uuid"`
+	ServerUUID           string `json:"server_uuid"`
+	Status               string `json:"status"`
+	SourceCompute        string `json:"source_compute"`
+	SourceNode           string `json:"source_node"`
+	DestCompute          string `json:"dest_compute"`
+	DestNode             string `json:"dest_node"`
+	DestHost             string `json:"dest_host"`
+	MemoryTotalBytes     int64  `json:"memory_total_bytes"`
+	MemoryProcessedBytes int64  `json:"memory_processed_bytes"`
+	MemoryRemainingBytes int64  `json:"memory_remaining_bytes"`
+	DiskTotalBytes       int64  `json:"disk_total_bytes"`
+	DiskProcessedBytes   int64  `json:"disk_processed_bytes"`
+	DiskRemainingBytes   int64  `json:"disk_remaining_bytes"`
+	CreatedAt            string `json:"created_at"`
+	UpdatedAt            string `json:"updated_at"`
+}
+
+// ListMigrationsOpts filters the migrations returned by
+// ListMigrations.
+type ListMigrationsOpts struct {
+	Host          string // Optional, the source or destination host
+	Status        string // Optional, one of the MigrationStatus* constants
+	InstanceUUID  string // Optional
+	MigrationType string // Optional, one of the MigrationType* constants
+}
+
+// LiveMigrateOpts defines the arguments for LiveMigrateServer().
+type LiveMigrateOpts struct {
+	// Host is the compute host to move the server to. If it is
+	// empty the scheduler chooses a host.
+	Host string
+
+	// BlockMigration sets whether the disks of the server are copied
+	// to the destination host. If it is nil nova decides, depending on
+	// whether the hosts share storage.
+	BlockMigration *bool
+}
+
+// LiveMigrateServer moves the specified server to another compute
+// host while it keeps running. The server has StatusMigrating until
+// the migration is over; ListServerMigrations shows its progress.
+func (c *Client) LiveMigrateServer(serverId string, opts LiveMigrateOpts) error {
+	var req struct {
+		LiveMigrate struct {
+			Host           *string     `json:"host"`
+			BlockMigration interface{} `json:"block_migration"`
+		} `json:"os-migrateLive"`
+	}
+	if opts.Host != "" {
+		req.LiveMigrate.Host = &opts.Host
+	}
+	req.LiveMigrate.BlockMigration = "auto"
+	if opts.BlockMigration != nil {
+		req.LiveMigrate.BlockMigration = *opts.BlockMigration
+	}
+	err := c.migrationAction(serverId, req)
+	if err != nil {
+		err = errors.Newf(err, "failed to live migrate server with id: %s", serverId)
+	}
+	return err
+}
+
+// MigrateServer moves the specified server to another compute host by
+// cold migration, restarting it there. If host is empty the scheduler
+// chooses a host. Once the server reaches StatusVerifyResize the
+// migration must be completed with ConfirmResize, or undone with
+// RevertResize.
+func (c *Client) MigrateServer(serverId, host string) error {
+	var req struct {
+		Migrate struct {
+			Host string `json:"host,omitempty"`
+		} `json:"migrate"`
+	}
+	req.Migrate.Host = host
+	err := c.migrationAction(serverId, req)
+	if err != nil {
+		err = errors.Newf(err, "failed to migrate server with id: %s", serverId)
+	}
+	return err
+}
+
+// EvacuateOpts defines the optional arguments for EvacuateServer().
+type EvacuateOpts struct {
+	Host      string `json:"host,omitempty"`      // Optional, chosen by the scheduler if empty
+	AdminPass string `json:"adminPass,omitempty"` // Optional
+}
+
+// EvacuateServer rebuilds the specified server on another compute
+// host. The nova-compute service of its current host must be down,
+// see ForceDownService.
+func (c *Client) EvacuateServer(serverId string, opts EvacuateOpts) error {
+	req := struct {
+		Evacuate EvacuateOpts `json:"evacuate"`
+	}{opts}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		ReqHeaders:     microversionHeaders(migrationMicroversion),
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	url := fmt.Sprintf("%s/%s/action", apiServers, serverId)
+	err := c.client.SendRequest(client.POST, "compute", "v2", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to evacuate server with id: %s", serverId)
+	}
+	return err
+}
+
+func (c *Client) migrationAction(serverId string, req interface{}) error {
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		ReqHeaders:     microversionHeaders(migrationMicroversion),
+		ExpectedStatus: []int{http.StatusAccepted},
+	}
+	url := fmt.Sprintf("%s/%s/action", apiServers, serverId)
+	return c.client.SendRequest(client.POST, "compute", "v2", url, &requestData)
+}
+
+// ForceCompleteMigration forces the in-progress live migration with
+// the given ID of the specified server to complete, by pausing the
+// server until its memory has been copied.
+func (c *Client) ForceCompleteMigration(serverId string, migrationId int) error {
+	req := map[string]interface{}{"force_complete": nil}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		ReqHeaders:     microversionHeaders(migrationMicroversion),
+		ExpectedStatus: []int{http.StatusAccepted},
+	}
+	url := fmt.Sprintf("%s/%s/migrations/%d/action", apiServers, serverId, migrationId)
+	err := c.client.SendRequest(client.POST, "compute", "v2", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to force complete migration %d of server with id: %s", migrationId, serverId)
+	}
+	return err
+}
+
+// ListMigrations lists the in-progress and past migrations of all
+// servers matching opts.
+func (c *Client) ListMigrations(opts ListMigrationsOpts) ([]Migration, error) {
+	var resp struct {
+		Migrations []Migration `json:"migrations"`
+	}
+	params := make(url.Values)
+	if opts.Host != "" {
+		params.Set("host", opts.Host)
+	}
+	if opts.Status != "" {
+		params.Set("status", opts.Status)
+	}
+	if opts.InstanceUUID != "" {
+		params.Set("instance_uuid", opts.InstanceUUID)
+	}
+	if opts.MigrationType != "" {
+		params.Set("migration_type", opts.MigrationType)
+	}
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		Params:     &params,
+		ReqHeaders: microversionHeaders(migrationMicroversion),
+	}
+	err := c.client.SendRequest(client.GET, "compute", "v2", apiMigrations, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to list migrations")
+	}
+	return resp.Migrations, nil
+}
+
+// ListServerMigrations lists the in-progress live migrations of the
+// specified server.
+func (c *Client) ListServerMigrations(serverId string) ([]ServerMigration, error) {
+	var resp struct {
+		Migrations []ServerMigration `json:"migrations"`
+	}
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(migrationMicroversion),
+	}
+	url := fmt.Sprintf("%s/%s/migrations", apiServers, serverId)
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to list migrations of server with id: %s", serverId)
+	}
+	return resp.Migrations, nil
+}
+
+// GetServerMigration returns the in-progress live migration with the
+// given ID of the specified server.
+func (c *Client) GetServerMigration(serverId string, migrationId int) (*ServerMigration, error) {
+	var resp struct {
+		Migration ServerMigration `json:"migration"`
+	}
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(migrationMicroversion),
+	}
+	url := fmt.Sprintf("%s/%s/migrations/%d", apiServers, serverId, migrationId)
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get migration %d of server with id: %s", migrationId, serverId)
+	}
+	return &resp.Migration, nil
+}
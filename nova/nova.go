@@ -27,21 +27,25 @@ const (
 
 // Server status values.
 const (
-	StatusActive        = "ACTIVE"          // The server is active.
-	StatusBuild         = "BUILD"           // The server has not finished the original build process.
-	StatusBuildSpawning = "BUILD(spawning)" // The server has not finished the original build process but networking works (HP Cloud specific)
-	StatusDeleted       = "DELETED"         // The server is deleted.
-	StatusError         = "ERROR"           // The server is in error.
-	StatusHardReboot    = "HARD_REBOOT"     // The server is hard rebooting.
-	StatusPassword      = "PASSWORD"        // The password is being reset on the server.
-	StatusReboot        = "REBOOT"          // The server is in a soft reboot state.
-	StatusRebuild       = "REBUILD"         // The server is currently being rebuilt from an image.
-	StatusRescue        = "RESCUE"          // The server is in rescue mode.
-	StatusResize        = "RESIZE"          // Server is performing the differential copy of data that changed during its initial copy.
-	StatusShutoff       = "SHUTOFF"         // The virtual machine (VM) was powered down by the user, but not through the OpenStack Compute API.
-	StatusSuspended     = "SUSPENDED"       // The server is suspended, either by request or necessity.
-	StatusUnknown       = "UNKNOWN"         // The state of the server is unknown. Contact your cloud provider.
-	StatusVerifyResize  = "VERIFY_RESIZE"   // System is awaiting confirmation that the server is operational after a move or resize.
+	StatusActive           = "ACTIVE"            // The server is active.
+	StatusBuild            = "BUILD"             // The server has not finished the original build process.
+	StatusBuildSpawning    = "BUILD(spawning)"   // The server has not finished the original build process but networking works (HP Cloud specific)
+	StatusDeleted          = "DELETED"           // The server is deleted.
+	StatusError            = "ERROR"             // The server is in error.
+	StatusHardReboot       = "HARD_REBOOT"       // The server is hard rebooting.
+	StatusMigrating        = "MIGRATING"         // The server is being live migrated to another host.
+	StatusPassword         = "PASSWORD"          // The password is being reset on the server.
+	StatusPaused           = "PAUSED"            // The server is paused, its state is kept in memory.
+	StatusReboot           = "REBOOT"            // The server is in a soft reboot state.
+	StatusRebuild          = "REBUILD"           // The server is currently being rebuilt from an image.
+	StatusRescue           = "RESCUE"            // The server is in rescue mode.
+	StatusResize           = "RESIZE"            // Server is performing the differential copy of data that changed during its initial copy.
+	StatusShelved          = "SHELVED"           // The server is shelved but still on its hypervisor.
+	StatusShelvedOffloaded = "SHELVED_OFFLOADED" // The server is shelved and has been removed from its hypervisor.
+	StatusShutoff          = "SHUTOFF"           // The virtual machine (VM) was powered down by the user, but not through the OpenStack Compute API.
+	StatusSuspended        = "SUSPENDED"         // The server is suspended, either by request or necessity.
+	StatusUnknown          = "UNKNOWN"           // The state of the server is unknown. Contact your cloud provider.
+	StatusVerifyResize     = "VERIFY_RESIZE"     // System is awaiting confirmation that the server is operational after a move or resize.
 )
 
 // Filter keys.
@@ -53,6 +57,14 @@ const (
 	FilterMarker       = "marker"        // The ID of the last item in the previous list.
 	FilterLimit        = "limit"         // The page size.
 	FilterChangesSince = "changes-since" // The changes-since time. The list contains servers that have been deleted since the changes-since time.
+
+	// The tag filters take a comma separated list of tags, and
+	// require microversion 2.26, which ListServers and
+	// ListServersDetail request when they are used.
+	FilterTags       = "tags"         // Servers with all the tags.
+	FilterTagsAny    = "tags-any"     // Servers with any of the tags.
+	FilterNotTags    = "not-tags"     // Servers without all of the tags.
+	FilterNotTagsAny = "not-tags-any" // Servers without any of the tags.
 )
 
 // Client provides a means to access the OpenStack Compute Service.
@@ -65,6 +77,16 @@ func New(client client.Client) *Client {
 	return &Client{client}
 }
 
+// microversionHeaders returns the request headers asking for the
+// given compute API microversion. Clouds only serving the legacy v2
+// API ignore them.
+func microversionHeaders(version string) http.Header {
+	header := make(http.Header)
+	header.Set("X-OpenStack-Nova-API-Version", version)
+	header.Set("OpenStack-API-Version", "compute "+version)
+	return header
+}
+
 // ----------------------------------------------------------------------------
 // Filtering helper.
 //
@@ -89,6 +111,19 @@ func (f *Filter) Set(filter, value string) {
 	f.v.Set(filter, value)
 }
 
+// headers returns the request headers needed by the filter, if any.
+func (f *Filter) headers() http.Header {
+	if f == nil {
+		return nil
+	}
+	for _, key := range []string{FilterTags, FilterTagsAny, FilterNotTags, FilterNotTagsAny} {
+		if f.v.Get(key) != "" {
+			return microversionHeaders(serverTagsMicroversion)
+		}
+	}
+	return nil
+}
+
 // Link describes a link to a flavor or server.
 type Link struct {
 	Href string
@@ -141,12 +176,21 @@ func (c *Client) ListFlavors() ([]Entity, error) {
 
 // FlavorDetail describes detailed information about a flavor.
 type FlavorDetail struct {
-	Name  string
-	RAM   int    // Available RAM, in MB
-	VCPUs int    // Number of virtual CPU (cores)
-	Disk  int    // Available root partition space, in GB
-	Id    string `json:"-"`
-	Links []Link
+	Name       string
+	RAM        int     // Available RAM, in MB
+	VCPUs      int     // Number of virtual CPU (cores)
+	Disk       int     // Available root partition space, in GB
+	Swap       int     // Swap space, in MB
+	Ephemeral  int     `json:"OS-FLV-EXT-DATA:ephemeral"` // Ephemeral disk space, in GB
+	RxTxFactor float64 `json:"rxtx_factor,omitempty"`
+	IsPublic   bool    `json:"os-flavor-access:is_public"`
+	Disabled   bool    `json:"OS-FLV-DISABLED:disabled,omitempty"`
+	Id         string  `json:"-"`
+	Links      []Link
+
+	// ExtraSpecs is only returned by clouds supporting microversion
+	// 2.61 or later. Use GetFlavorExtraSpecs otherwise.
+	ExtraSpecs map[string]string `json:"extra_specs,omitempty"`
 }
 
 // Allow FlavorDetail slices to be sorted by named attribute.
@@ -189,7 +233,12 @@ func (c *Client) ListServers(filter *Filter) ([]Entity, error) {
 	if filter != nil {
 		params = &filter.v
 	}
-	requestData := goosehttp.RequestData{RespValue: &resp, Params: params, ExpectedStatus: []int{http.StatusOK}}
+	requestData := goosehttp.RequestData{
+		RespValue:      &resp,
+		Params:         params,
+		ReqHeaders:     filter.headers(),
+		ExpectedStatus: []int{http.StatusOK},
+	}
 	err := c.client.SendRequest(client.GET, "compute", "v2", apiServers, &requestData)
 	if err != nil {
 		return nil, errors.Newf(err, "failed to get list of servers")
@@ -261,6 +310,17 @@ type ServerDetail struct {
 	UserId string `json:"user_id"`
 
 	AvailabilityZone string `json:"OS-EXT-AZ:availability_zone"`
+
+	// KeyName holds the name of the keypair the server was
+	// started with, if any.
+	KeyName string `json:"key_name,omitempty"`
+
+	// Description and Tags are only returned by clouds when
+	// microversion 2.19 and 2.26 respectively are requested, as is
+	// done by UpdateServer and by listing servers with tag filters.
+	// Use ListServerTags to get the tags of any server.
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
 }
 
 // ListServersDetail lists all details for available servers.
@@ -272,7 +332,7 @@ func (c *Client) ListServersDetail(filter *Filter) ([]ServerDetail, error) {
 	if filter != nil {
 		params = &filter.v
 	}
-	requestData := goosehttp.RequestData{RespValue: &resp, Params: params}
+	requestData := goosehttp.RequestData{RespValue: &resp, Params: params, ReqHeaders: filter.headers()}
 	err := c.client.SendRequest(client.GET, "compute", "v2", apiServersDetail, &requestData)
 	if err != nil {
 		return nil, errors.Newf(err, "failed to get list of server details")
@@ -332,10 +392,31 @@ type RunServerOpts struct {
 	AvailabilityZone    string               `json:"availability_zone,omitempty"`       // Optional
 	Metadata            map[string]string    `json:"metadata,omitempty"`                // Optional
 	ConfigDrive         bool                 `json:"config_drive,omitempty"`            // Optional
+	KeyName             string               `json:"key_name,omitempty"`                // Optional
 	BlockDeviceMappings []BlockDeviceMapping `json:"block_device_mapping_v2,omitempty"` // Optional
+	SchedulerHints      *SchedulerHints      `json:"-"`                                 // Optional, sent as os:scheduler_hints
+	Description         string               `json:"description,omitempty"`             // Optional, requires microversion 2.19
+	Tags                []string             `json:"tags,omitempty"`                    // Optional, requires microversion 2.52
+}
+
+// headers returns the request headers needed to run a server with
+// the options.
+func (opts RunServerOpts) headers() http.Header {
+	switch {
+	case hasVolumeType(opts.BlockDeviceMappings):
+		return microversionHeaders(volumeTypeMicroversion)
+	case len(opts.Tags) > 0:
+		return microversionHeaders(serverCreateTagsMicroversion)
+	case opts.Description != "":
+		return microversionHeaders(serverDescriptionMicroversion)
+	}
+	return nil
 }
 
 // BlockDeviceMapping defines block devices to be attached to the Server created by RunServer().
+// BootIndex is 0 for the boot device, and -1 for devices which are not
+// bootable. BlockDeviceBuilder builds valid mappings.
+// VolumeType requires microversion 2.67.
 // See: https://developer.openstack.org/api-ref/compute/?expanded=create-server-detail
 type BlockDeviceMapping struct {
 	BootIndex           int    `json:"boot_index"`
@@ -353,17 +434,55 @@ type BlockDeviceMapping struct {
 	Tag                 string `json:"tag,omitempty"`
 }
 
+// requiresNetworks reports whether the microversion needed to run a
+// server with the options, from 2.37, requires networks to be given.
+func (opts RunServerOpts) requiresNetworks() bool {
+	return hasVolumeType(opts.BlockDeviceMappings) || len(opts.Tags) > 0
+}
+
+// runServerBody describes the server run by RunServer. Its Networks
+// shadows RunServerOpts.Networks, so that "auto" can be sent when
+// networks are required but none are given.
+type runServerBody struct {
+	RunServerOpts
+	Networks interface{} `json:"networks"`
+}
+
+// runServerRequest holds the body of the request sent by RunServer.
+type runServerRequest struct {
+	Server         runServerBody   `json:"server"`
+	SchedulerHints *SchedulerHints `json:"os:scheduler_hints,omitempty"`
+}
+
+// newRunServerRequest returns the body of the request running a
+// server with the given options. Without Networks, nova allocates a
+// network for the server if it needs a microversion from 2.37.
+func newRunServerRequest(opts RunServerOpts) runServerRequest {
+	var networks interface{} = opts.Networks
+	if len(opts.Networks) == 0 && opts.requiresNetworks() {
+		networks = "auto"
+	}
+	return runServerRequest{
+		Server:         runServerBody{RunServerOpts: opts, Networks: networks},
+		SchedulerHints: opts.SchedulerHints,
+	}
+}
+
 // RunServer creates a new server, based on the given RunServerOpts.
+// If opts needs microversion 2.37 or later and no Networks are given,
+// nova is asked to allocate a network automatically.
 func (c *Client) RunServer(opts RunServerOpts) (*Entity, error) {
-	var req struct {
-		Server RunServerOpts `json:"server"`
-	}
-	req.Server = opts
+	req := newRunServerRequest(opts)
 	// opts.UserData gets serialized to base64-encoded string automatically
 	var resp struct {
 		Server Entity `json:"server"`
 	}
-	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusAccepted}}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ReqHeaders:     opts.headers(),
+		ExpectedStatus: []int{http.StatusAccepted},
+	}
 	err := c.client.SendRequest(client.POST, "compute", "v2", apiServers, &requestData)
 	if err != nil {
 		return nil, errors.Newf(err, "failed to run a server with %#v", opts)
@@ -393,6 +512,49 @@ func (c *Client) UpdateServerName(serverID, name string) (*Entity, error) {
 	return &resp.Server, nil
 }
 
+// UpdateServerOpts defines the server attributes changed by
+// UpdateServer(). Attributes left nil are not changed.
+type UpdateServerOpts struct {
+	Name *string `json:"name,omitempty"`
+
+	// Description requires microversion 2.19, which is then
+	// requested. An empty description removes it.
+	Description *string `json:"description,omitempty"`
+}
+
+// headers returns the request headers needed to update a server with
+// the options.
+func (opts UpdateServerOpts) headers() http.Header {
+	if opts.Description != nil {
+		return microversionHeaders(serverDescriptionMicroversion)
+	}
+	return nil
+}
+
+// UpdateServer changes the name and description of the given server,
+// and returns its updated details.
+func (c *Client) UpdateServer(serverId string, opts UpdateServerOpts) (*ServerDetail, error) {
+	var req struct {
+		Server UpdateServerOpts `json:"server"`
+	}
+	req.Server = opts
+	var resp struct {
+		Server ServerDetail `json:"server"`
+	}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ReqHeaders:     opts.headers(),
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	url := fmt.Sprintf("%s/%s", apiServers, serverId)
+	err := c.client.SendRequest(client.PUT, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to update server with id: %s", serverId)
+	}
+	return &resp.Server, nil
+}
+
 // AddServerSecurityGroup adds a security group to the specified server.
 func (c *Client) AddServerSecurityGroup(serverId, groupName string) error {
 	var req struct {
@@ -576,6 +738,9 @@ func (c *Client) ListVolumeAttachments(serverId string) ([]VolumeAttachment, err
 // request.
 // See https://developer.openstack.org/api-ref/compute/?expanded=update-metadata-items-detail#update-metadata-items
 func (c *Client) SetServerMetadata(serverId string, metadata map[string]string) error {
+	if err := validateMetadata(serverId, metadata); err != nil {
+		return err
+	}
 	req := struct {
 		Metadata map[string]string `json:"metadata"`
 	}{metadata}
@@ -586,7 +751,7 @@ func (c *Client) SetServerMetadata(serverId string, metadata map[string]string)
 	}
 	err := c.client.SendRequest(client.POST, "compute", "v2", url, &requestData)
 	if err != nil {
-		err = errors.Newf(err, "failed to set metadata %v on server with id: %s", metadata, serverId)
+		err = metadataError(serverId, err, "failed to set metadata %v on server with id: %s", metadata, serverId)
 	}
 	return err
 }
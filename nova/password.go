@@ -0,0 +1,101 @@
+// Nova api calls for the admin password of a server. The password is
+// set by the guest, such as by cloudbase-init on Windows, encrypted
+// with the public key of the key pair the server was started with.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#servers-password-servers-os-server-password>
+
+package nova
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+const apiServerPassword = "os-server-password"
+
+// GetServerPassword returns the encrypted admin password of the
+// specified server, base64 encoded, or an empty string if the guest
+// has not set one. Use DecryptServerPassword to decrypt it.
+func (c *Client) GetServerPassword(serverId string) (string, error) {
+	var resp struct {
+		Password string `json:"password"`
+	}
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	url := fmt.Sprintf("%s/%s/%s", apiServers, serverId, apiServerPassword)
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return "", errors.Newf(err, "failed to get password of server with id: %s", serverId)
+	}
+	return resp.Password, nil
+}
+
+// ClearServerPassword removes the admin password of the specified
+// server from nova. The password of the guest is not changed.
+func (c *Client) ClearServerPassword(serverId string) error {
+	requestData := goosehttp.RequestData{ExpectedStatus: []int{http.StatusNoContent}}
+	url := fmt.Sprintf("%s/%s/%s", apiServers, serverId, apiServerPassword)
+	err := c.client.SendRequest(client.DELETE, "compute", "v2", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to clear password of server with id: %s", serverId)
+	}
+	return err
+}
+
+// DecryptServerPassword decrypts an admin password returned by
+// GetServerPassword, using the private key of the key pair the server
+// was started with.
+func DecryptServerPassword(encrypted string, privateKey *rsa.PrivateKey) (string, error) {
+	if encrypted == "" {
+		return "", fmt.Errorf("no password to decrypt")
+	}
+	// The password may be split over several lines.
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encrypted), ""))
+	if err != nil {
+		return "", errors.Newf(err, "invalid encrypted password")
+	}
+	password, err := rsa.DecryptPKCS1v15(rand.Reader, privateKey, ciphertext)
+	if err != nil {
+		return "", errors.Newf(err, "failed to decrypt password")
+	}
+	return string(password), nil
+}
+
+// ParseRSAPrivateKey parses a PEM encoded RSA private key, in either
+// PKCS #1 ("RSA PRIVATE KEY") or PKCS #8 ("PRIVATE KEY") form. Keys in
+// the OpenSSH format must first be converted, for instance with
+// "ssh-keygen -p -m PEM".
+func ParseRSAPrivateKey(pemData []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded private key found")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Newf(err, "invalid RSA private key")
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Newf(err, "invalid private key")
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("private key is a %T, not an RSA key", key)
+		}
+		return rsaKey, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %q", block.Type)
+}
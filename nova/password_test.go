@@ -0,0 +1,104 @@
+package nova_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+
+	gc "gopkg.in/check.v1"
+
+	"github.com/go-goose/goose/v5/nova"
+)
+
+type PasswordSuite struct {
+	key *rsa.PrivateKey
+}
+
+var _ = gc.Suite(&PasswordSuite{})
+
+func (s *PasswordSuite) SetUpSuite(c *gc.C) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	c.Assert(err, gc.IsNil)
+	s.key = key
+}
+
+// sshRSAPublicKey returns the public key of key in the OpenSSH
+// authorized_keys format.
+func sshRSAPublicKey(key *rsa.PrivateKey) string {
+	var blob []byte
+	e := big.NewInt(int64(key.E)).Bytes()
+	// The modulus is an mpint, so needs a leading zero if its top bit
+	// is set.
+	n := append([]byte{0}, key.N.Bytes()...)
+	for _, part := range [][]byte{[]byte("ssh-rsa"), e, n} {
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(part)))
+		blob = append(append(blob, length[:]...), part...)
+	}
+	return "ssh-rsa " + base64.StdEncoding.EncodeToString(blob) + " test"
+}
+
+func (s *PasswordSuite) encrypt(c *gc.C, password string) string {
+	encrypted, err := rsa.EncryptPKCS1v15(rand.Reader, &s.key.PublicKey, []byte(password))
+	c.Assert(err, gc.IsNil)
+	return base64.StdEncoding.EncodeToString(encrypted)
+}
+
+func (s *PasswordSuite) TestDecryptServerPassword(c *gc.C) {
+	encrypted := s.encrypt(c, "secret")
+	password, err := nova.DecryptServerPassword(encrypted, s.key)
+	c.Assert(err, gc.IsNil)
+	c.Check(password, gc.Equals, "secret")
+
+	// The password may be split over several lines.
+	split := encrypted[:20] + "\n" + encrypted[20:]
+	password, err = nova.DecryptServerPassword(split, s.key)
+	c.Assert(err, gc.IsNil)
+	c.Check(password, gc.Equals, "secret")
+}
+
+func (s *PasswordSuite) TestDecryptServerPasswordErrors(c *gc.C) {
+	_, err := nova.DecryptServerPassword("", s.key)
+	c.Check(err, gc.ErrorMatches, "no password to decrypt")
+	_, err = nova.DecryptServerPassword("not base64!", s.key)
+	c.Check(err, gc.ErrorMatches, "invalid encrypted password\n(.|\n)*")
+
+	other, err := rsa.GenerateKey(rand.Reader, 1024)
+	c.Assert(err, gc.IsNil)
+	_, err = nova.DecryptServerPassword(s.encrypt(c, "secret"), other)
+	c.Check(err, gc.ErrorMatches, "failed to decrypt password\n(.|\n)*")
+}
+
+func (s *PasswordSuite) TestParseRSAPrivateKey(c *gc.C) {
+	pkcs1 := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(s.key),
+	})
+	key, err := nova.ParseRSAPrivateKey(pkcs1)
+	c.Assert(err, gc.IsNil)
+	c.Check(key.Equal(s.key), gc.Equals, true)
+
+	der, err := x509.MarshalPKCS8PrivateKey(s.key)
+	c.Assert(err, gc.IsNil)
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	key, err = nova.ParseRSAPrivateKey(pkcs8)
+	c.Assert(err, gc.IsNil)
+	c.Check(key.Equal(s.key), gc.Equals, true)
+}
+
+func (s *PasswordSuite) TestParseRSAPrivateKeyErrors(c *gc.C) {
+	_, err := nova.ParseRSAPrivateKey([]byte("not a key"))
+	c.Check(err, gc.ErrorMatches, "no PEM encoded private key found")
+
+	openssh := pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: []byte("key")})
+	_, err = nova.ParseRSAPrivateKey(openssh)
+	c.Check(err, gc.ErrorMatches, `unsupported private key type "OPENSSH PRIVATE KEY"`)
+
+	invalid := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("key")})
+	_, err = nova.ParseRSAPrivateKey(invalid)
+	c.Check(err, gc.ErrorMatches, "invalid RSA private key\n(.|\n)*")
+}
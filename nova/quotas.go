@@ -0,0 +1,170 @@
+// Nova api calls for reading the compute limits of a project, and
+// managing its quotas.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#limits-limits>
+// <https://docs.openstack.org/api-ref/compute/#quota-sets-os-quota-sets>
+
+package nova
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+const (
+	apiLimits    = "limits"
+	apiQuotaSets = "os-quota-sets"
+)
+
+// Unlimited is the value of a limit or quota which is not enforced.
+const Unlimited = -1
+
+// AbsoluteLimits holds the limits of the current project, and how
+// much of them is used. Limits of Unlimited are not enforced.
+type AbsoluteLimits struct {
+	MaxTotalCores           int `json:"maxTotalCores"`
+	MaxTotalInstances       int `json:"maxTotalInstances"`
+	MaxTotalRAMSize         int `json:"maxTotalRAMSize"` // In MiB.
+	MaxTotalKeypairs        int `json:"maxTotalKeypairs"`
+	MaxServerGroups         int `json:"maxServerGroups"`
+	MaxServerGroupMembers   int `json:"maxServerGroupMembers"`
+	MaxServerMeta           int `json:"maxServerMeta"`
+	MaxImageMeta            int `json:"maxImageMeta"`
+	MaxPersonality          int `json:"maxPersonality"`
+	MaxPersonalitySize      int `json:"maxPersonalitySize"`
+	TotalCoresUsed          int `json:"totalCoresUsed"`
+	TotalInstancesUsed      int `json:"totalInstancesUsed"`
+	TotalRAMUsed            int `json:"totalRAMUsed"` // In MiB.
+	TotalServerGroupsUsed   int `json:"totalServerGroupsUsed"`
+	MaxSecurityGroups       int `json:"maxSecurityGroups,omitempty"`     // Before microversion 2.36 only.
+	MaxSecurityGroupRules   int `json:"maxSecurityGroupRules,omitempty"` // Before microversion 2.36 only.
+	MaxTotalFloatingIps     int `json:"maxTotalFloatingIps,omitempty"`   // Before microversion 2.36 only.
+	TotalSecurityGroupsUsed int `json:"totalSecurityGroupsUsed,omitempty"`
+	TotalFloatingIpsUsed    int `json:"totalFloatingIpsUsed,omitempty"`
+}
+
+// RateLimitEntry describes a single rate limit of a RateLimit.
+type RateLimitEntry struct {
+	Verb          string `json:"verb"`
+	Value         int    `json:"value"`
+	Remaining     int    `json:"remaining"`
+	Unit          string `json:"unit"`
+	NextAvailable string `json:"next-available"`
+}
+
+// RateLimit describes the rate limits applied to the requests
+// matching a URI. Most clouds enforce rate limits elsewhere, and
+// report none.
+type RateLimit struct {
+	URI   string           `json:"uri"`
+	Regex string           `json:"regex"`
+	Limit []RateLimitEntry `json:"limit"`
+}
+
+// Limits holds the absolute and rate limits of the current project.
+type Limits struct {
+	Absolute AbsoluteLimits `json:"absolute"`
+	Rate     []RateLimit    `json:"rate"`
+}
+
+// GetLimits returns the limits of the current project.
+func (c *Client) GetLimits() (*Limits, error) {
+	var resp struct {
+		Limits Limits `json:"limits"`
+	}
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "compute", "v2", apiLimits, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get limits")
+	}
+	return &resp.Limits, nil
+}
+
+// QuotaSet holds the compute quotas of a project. Quotas of Unlimited
+// are not enforced.
+type QuotaSet struct {
+	Id                       string `json:"id,omitempty"`
+	Cores                    int    `json:"cores"`
+	Instances                int    `json:"instances"`
+	RAM                      int    `json:"ram"` // In MiB.
+	KeyPairs                 int    `json:"key_pairs"`
+	ServerGroups             int    `json:"server_groups"`
+	ServerGroupMembers       int    `json:"server_group_members"`
+	MetadataItems            int    `json:"metadata_items"`
+	InjectedFiles            int    `json:"injected_files"`
+	InjectedFileContentBytes int    `json:"injected_file_content_bytes"`
+	InjectedFilePathBytes    int    `json:"injected_file_path_bytes"`
+}
+
+// QuotaSetOpts defines the quotas changed by UpdateQuotaSet(). Quotas
+// left nil are not changed.
+type QuotaSetOpts struct {
+	Cores                    *int `json:"cores,omitempty"`
+	Instances                *int `json:"instances,omitempty"`
+	RAM                      *int `json:"ram,omitempty"`
+	KeyPairs                 *int `json:"key_pairs,omitempty"`
+	ServerGroups             *int `json:"server_groups,omitempty"`
+	ServerGroupMembers       *int `json:"server_group_members,omitempty"`
+	MetadataItems            *int `json:"metadata_items,omitempty"`
+	InjectedFiles            *int `json:"injected_files,omitempty"`
+	InjectedFileContentBytes *int `json:"injected_file_content_bytes,omitempty"`
+	InjectedFilePathBytes    *int `json:"injected_file_path_bytes,omitempty"`
+
+	// Force allows setting quotas below the current usage.
+	Force bool `json:"force,omitempty"`
+}
+
+func (c *Client) getQuotaSet(url string) (*QuotaSet, error) {
+	var resp struct {
+		QuotaSet QuotaSet `json:"quota_set"`
+	}
+	requestData := goosehttp.RequestData{RespValue: &resp}
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.QuotaSet, nil
+}
+
+// GetQuotaSet returns the compute quotas of the given project.
+func (c *Client) GetQuotaSet(tenantId string) (*QuotaSet, error) {
+	quotas, err := c.getQuotaSet(fmt.Sprintf("%s/%s", apiQuotaSets, tenantId))
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get quotas for tenant: %s", tenantId)
+	}
+	return quotas, nil
+}
+
+// GetDefaultQuotaSet returns the default compute quotas, as they
+// apply to the given project.
+func (c *Client) GetDefaultQuotaSet(tenantId string) (*QuotaSet, error) {
+	quotas, err := c.getQuotaSet(fmt.Sprintf("%s/%s/defaults", apiQuotaSets, tenantId))
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get default quotas for tenant: %s", tenantId)
+	}
+	return quotas, nil
+}
+
+// UpdateQuotaSet changes the compute quotas of the given project, and
+// returns the updated quotas. This is usually restricted to
+// administrators.
+func (c *Client) UpdateQuotaSet(tenantId string, opts QuotaSetOpts) (*QuotaSet, error) {
+	var req struct {
+		QuotaSet QuotaSetOpts `json:"quota_set"`
+	}
+	req.QuotaSet = opts
+	var resp struct {
+		QuotaSet QuotaSet `json:"quota_set"`
+	}
+	url := fmt.Sprintf("%s/%s", apiQuotaSets, tenantId)
+	requestData := goosehttp.RequestData{ReqValue: req, RespValue: &resp, ExpectedStatus: []int{http.StatusOK}}
+	err := c.client.SendRequest(client.PUT, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to update quotas for tenant: %s", tenantId)
+	}
+	return &resp.QuotaSet, nil
+}
@@ -0,0 +1,170 @@
+// Nova api calls for managing server groups, which constrain where
+// the servers in them are scheduled.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#server-groups-os-server-groups>
+
+package nova
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+const (
+	apiServerGroups = "os-server-groups"
+
+	// serverGroupMicroversion is the compute API microversion
+	// introducing a single policy with rules per server group. It
+	// is requested by all the server group calls.
+	serverGroupMicroversion = "2.64"
+)
+
+// Server group policies.
+const (
+	ServerGroupAffinity         = "affinity"           // All servers must share a host.
+	ServerGroupAntiAffinity     = "anti-affinity"      // No two servers may share a host.
+	ServerGroupSoftAffinity     = "soft-affinity"      // Servers should share a host if possible.
+	ServerGroupSoftAntiAffinity = "soft-anti-affinity" // Servers should not share a host if possible.
+)
+
+// ServerGroupRules holds the rules refining a server group policy.
+// They may only be used with ServerGroupAntiAffinity.
+type ServerGroupRules struct {
+	// MaxServerPerHost holds the number of servers in the group
+	// allowed on the same host.
+	MaxServerPerHost int `json:"max_server_per_host,omitempty"`
+}
+
+// ServerGroup describes a server group. Members holds the IDs of the
+// servers in the group.
+type ServerGroup struct {
+	Id        string            `json:"id"`
+	Name      string            `json:"name"`
+	Policy    string            `json:"policy"`
+	Rules     *ServerGroupRules `json:"rules,omitempty"`
+	Members   []string          `json:"members"`
+	ProjectId string            `json:"project_id,omitempty"`
+	UserId    string            `json:"user_id,omitempty"`
+}
+
+// serverGroupResponse also accepts the list of policies returned by
+// clouds older than microversion 2.64.
+type serverGroupResponse struct {
+	ServerGroup
+	Policies []string `json:"policies,omitempty"`
+}
+
+func (resp serverGroupResponse) serverGroup() ServerGroup {
+	group := resp.ServerGroup
+	if group.Policy == "" && len(resp.Policies) > 0 {
+		group.Policy = resp.Policies[0]
+	}
+	return group
+}
+
+// ServerGroupOpts defines required and optional arguments for
+// CreateServerGroup().
+type ServerGroupOpts struct {
+	Name   string            `json:"name"`            // Required
+	Policy string            `json:"policy"`          // Required
+	Rules  *ServerGroupRules `json:"rules,omitempty"` // Optional
+}
+
+// SchedulerHints holds hints to the scheduler about where a server
+// created by RunServer should be placed.
+type SchedulerHints struct {
+	// Group holds the ID of the server group the server is added
+	// to, whose policy is applied.
+	Group string `json:"group,omitempty"`
+
+	// DifferentHost and SameHost hold the IDs of servers which the
+	// server should, or should not, share a host with.
+	DifferentHost []string `json:"different_host,omitempty"`
+	SameHost      []string `json:"same_host,omitempty"`
+
+	// Query holds a JSON query for the JsonFilter scheduler filter.
+	Query string `json:"query,omitempty"`
+
+	// TargetCell holds the cell the server should be created in.
+	TargetCell string `json:"target_cell,omitempty"`
+}
+
+// ListServerGroups lists the server groups of the current project.
+func (c *Client) ListServerGroups() ([]ServerGroup, error) {
+	var resp struct {
+		ServerGroups []serverGroupResponse `json:"server_groups"`
+	}
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(serverGroupMicroversion),
+	}
+	err := c.client.SendRequest(client.GET, "compute", "v2", apiServerGroups, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get list of server groups")
+	}
+	groups := make([]ServerGroup, len(resp.ServerGroups))
+	for i, group := range resp.ServerGroups {
+		groups[i] = group.serverGroup()
+	}
+	return groups, nil
+}
+
+// GetServerGroup returns the server group with the given ID.
+func (c *Client) GetServerGroup(groupId string) (*ServerGroup, error) {
+	var resp struct {
+		ServerGroup serverGroupResponse `json:"server_group"`
+	}
+	url := fmt.Sprintf("%s/%s", apiServerGroups, groupId)
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(serverGroupMicroversion),
+	}
+	err := c.client.SendRequest(client.GET, "compute", "v2", url, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get server group with id: %s", groupId)
+	}
+	group := resp.ServerGroup.serverGroup()
+	return &group, nil
+}
+
+// CreateServerGroup creates a new server group.
+func (c *Client) CreateServerGroup(opts ServerGroupOpts) (*ServerGroup, error) {
+	var req struct {
+		ServerGroup ServerGroupOpts `json:"server_group"`
+	}
+	req.ServerGroup = opts
+	var resp struct {
+		ServerGroup serverGroupResponse `json:"server_group"`
+	}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ReqHeaders:     microversionHeaders(serverGroupMicroversion),
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	err := c.client.SendRequest(client.POST, "compute", "v2", apiServerGroups, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to create server group %q with policy %s", opts.Name, opts.Policy)
+	}
+	group := resp.ServerGroup.serverGroup()
+	return &group, nil
+}
+
+// DeleteServerGroup deletes the server group with the given ID. The
+// servers in the group are not affected.
+func (c *Client) DeleteServerGroup(groupId string) error {
+	url := fmt.Sprintf("%s/%s", apiServerGroups, groupId)
+	requestData := goosehttp.RequestData{
+		ReqHeaders:     microversionHeaders(serverGroupMicroversion),
+		ExpectedStatus: []int{http.StatusNoContent},
+	}
+	err := c.client.SendRequest(client.DELETE, "compute", "v2", url, &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete server group with id: %s", groupId)
+	}
+	return err
+}
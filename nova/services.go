@@ -0,0 +1,126 @@
+// Nova api calls for listing the nova services, such as nova-compute,
+// and for taking compute hosts out of scheduling. These calls are
+// restricted to administrators by default.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#compute-services-os-services>
+
+package nova
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+const apiServices = "os-services"
+
+// BinaryCompute is the binary of the service running on compute
+// hosts.
+const BinaryCompute = "nova-compute"
+
+// ComputeService describes a nova service running on a host. A
+// disabled nova-compute service has no new servers scheduled on its
+// host.
+type ComputeService struct {
+	Id             string `json:"id"`
+	Binary         string `json:"binary"`
+	Host           string `json:"host"`
+	Zone           string `json:"zone"`
+	Status         string `json:"status"`
+	State          string `json:"state"`
+	DisabledReason string `json:"disabled_reason"`
+	ForcedDown     bool   `json:"forced_down"`
+	UpdatedAt      string `json:"updated_at"`
+}
+
+// ListServicesOpts filters the services returned by ListServices.
+type ListServicesOpts struct {
+	Binary string // Optional, e.g. BinaryCompute
+	Host   string // Optional
+}
+
+// ListServices lists the nova services matching opts.
+func (c *Client) ListServices(opts ListServicesOpts) ([]ComputeService, error) {
+	var resp struct {
+		Services []ComputeService `json:"services"`
+	}
+	params := make(url.Values)
+	if opts.Binary != "" {
+		params.Set("binary", opts.Binary)
+	}
+	if opts.Host != "" {
+		params.Set("host", opts.Host)
+	}
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		Params:     &params,
+		ReqHeaders: microversionHeaders(hostAdminMicroversion),
+	}
+	err := c.client.SendRequest(client.GET, "compute", "v2", apiServices, &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to list services")
+	}
+	return resp.Services, nil
+}
+
+// DisableService disables the service with the given ID, recording
+// the reason, which may be empty.
+func (c *Client) DisableService(serviceId, reason string) (*ComputeService, error) {
+	req := struct {
+		Status         string `json:"status"`
+		DisabledReason string `json:"disabled_reason,omitempty"`
+	}{HostStatusDisabled, reason}
+	service, err := c.updateService(serviceId, req)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to disable service with id: %s", serviceId)
+	}
+	return service, nil
+}
+
+// EnableService enables the service with the given ID.
+func (c *Client) EnableService(serviceId string) (*ComputeService, error) {
+	req := struct {
+		Status string `json:"status"`
+	}{HostStatusEnabled}
+	service, err := c.updateService(serviceId, req)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to enable service with id: %s", serviceId)
+	}
+	return service, nil
+}
+
+// ForceDownService marks the service with the given ID as down, or
+// clears the mark, without waiting for nova to notice the service has
+// stopped reporting. Servers can only be evacuated from the host of a
+// nova-compute service which is down.
+func (c *Client) ForceDownService(serviceId string, down bool) (*ComputeService, error) {
+	req := struct {
+		ForcedDown bool `json:"forced_down"`
+	}{down}
+	service, err := c.updateService(serviceId, req)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to set forced down to %t for service with id: %s", down, serviceId)
+	}
+	return service, nil
+}
+
+func (c *Client) updateService(serviceId string, req interface{}) (*ComputeService, error) {
+	var resp struct {
+		Service ComputeService `json:"service"`
+	}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ReqHeaders:     microversionHeaders(hostAdminMicroversion),
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	url := fmt.Sprintf("%s/%s", apiServices, serviceId)
+	if err := c.client.SendRequest(client.PUT, "compute", "v2", url, &requestData); err != nil {
+		return nil, err
+	}
+	return &resp.Service, nil
+}
@@ -0,0 +1,144 @@
+// Nova api calls for managing the tags of a server.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#server-tags-servers-server-id-tags>
+
+package nova
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+const (
+	apiTags = "tags"
+
+	// serverTagsMicroversion is the compute API microversion
+	// introducing server tags. It is requested by all the tag calls.
+	serverTagsMicroversion = "2.26"
+
+	// serverCreateTagsMicroversion is the compute API microversion
+	// allowing tags to be set by RunServer.
+	serverCreateTagsMicroversion = "2.52"
+
+	// serverDescriptionMicroversion is the compute API microversion
+	// introducing server descriptions.
+	serverDescriptionMicroversion = "2.19"
+)
+
+// MaxServerTags is the number of tags a server may have. Tags may be
+// up to 60 characters long, and may not contain '/' or ','.
+const MaxServerTags = 50
+
+func serverTagsURL(serverId string) string {
+	return fmt.Sprintf("%s/%s/%s", apiServers, serverId, apiTags)
+}
+
+func serverTagURL(serverId, tag string) string {
+	return fmt.Sprintf("%s/%s", serverTagsURL(serverId), url.PathEscape(tag))
+}
+
+// ListServerTags returns the tags of the given server.
+func (c *Client) ListServerTags(serverId string) ([]string, error) {
+	var resp struct {
+		Tags []string `json:"tags"`
+	}
+	requestData := goosehttp.RequestData{
+		RespValue:  &resp,
+		ReqHeaders: microversionHeaders(serverTagsMicroversion),
+	}
+	err := c.client.SendRequest(client.GET, "compute", "v2", serverTagsURL(serverId), &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get tags of server with id: %s", serverId)
+	}
+	return resp.Tags, nil
+}
+
+// ReplaceServerTags replaces all the tags of the given server, and
+// returns the new tags.
+func (c *Client) ReplaceServerTags(serverId string, tags []string) ([]string, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+	req := struct {
+		Tags []string `json:"tags"`
+	}{tags}
+	var resp struct {
+		Tags []string `json:"tags"`
+	}
+	requestData := goosehttp.RequestData{
+		ReqValue:       req,
+		RespValue:      &resp,
+		ReqHeaders:     microversionHeaders(serverTagsMicroversion),
+		ExpectedStatus: []int{http.StatusOK},
+	}
+	err := c.client.SendRequest(client.PUT, "compute", "v2", serverTagsURL(serverId), &requestData)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to replace tags of server with id: %s", serverId)
+	}
+	return resp.Tags, nil
+}
+
+// AddServerTag adds a tag to the given server. Adding a tag the
+// server already has is not an error.
+func (c *Client) AddServerTag(serverId, tag string) error {
+	requestData := goosehttp.RequestData{
+		ReqHeaders:     microversionHeaders(serverTagsMicroversion),
+		ExpectedStatus: []int{http.StatusCreated, http.StatusNoContent},
+	}
+	err := c.client.SendRequest(client.PUT, "compute", "v2", serverTagURL(serverId, tag), &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to add tag %q to server with id: %s", tag, serverId)
+	}
+	return err
+}
+
+// HasServerTag reports whether the given server has a tag.
+func (c *Client) HasServerTag(serverId, tag string) (bool, error) {
+	requestData := goosehttp.RequestData{
+		ReqHeaders:     microversionHeaders(serverTagsMicroversion),
+		ExpectedStatus: []int{http.StatusNoContent},
+	}
+	err := c.client.SendRequest(client.GET, "compute", "v2", serverTagURL(serverId, tag), &requestData)
+	if errors.IsNotFound(err) {
+		// The server itself must exist for the tag to be missing.
+		if _, err := c.GetServer(serverId); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Newf(err, "failed to check tag %q of server with id: %s", tag, serverId)
+	}
+	return true, nil
+}
+
+// DeleteServerTag removes a tag from the given server.
+func (c *Client) DeleteServerTag(serverId, tag string) error {
+	requestData := goosehttp.RequestData{
+		ReqHeaders:     microversionHeaders(serverTagsMicroversion),
+		ExpectedStatus: []int{http.StatusNoContent},
+	}
+	err := c.client.SendRequest(client.DELETE, "compute", "v2", serverTagURL(serverId, tag), &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete tag %q of server with id: %s", tag, serverId)
+	}
+	return err
+}
+
+// DeleteAllServerTags removes all the tags of the given server.
+func (c *Client) DeleteAllServerTags(serverId string) error {
+	requestData := goosehttp.RequestData{
+		ReqHeaders:     microversionHeaders(serverTagsMicroversion),
+		ExpectedStatus: []int{http.StatusNoContent},
+	}
+	err := c.client.SendRequest(client.DELETE, "compute", "v2", serverTagsURL(serverId), &requestData)
+	if err != nil {
+		err = errors.Newf(err, "failed to delete tags of server with id: %s", serverId)
+	}
+	return err
+}
@@ -0,0 +1,221 @@
+// Nova api calls for reporting the usage of compute resources by
+// tenants over a period of time. Listing the usage of all tenants is
+// restricted to administrators by default.
+// See documentation at:
+// <https://docs.openstack.org/api-ref/compute/#usage-reports-os-simple-tenant-usage>
+
+package nova
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/errors"
+	goosehttp "github.com/go-goose/goose/v5/http"
+)
+
+const (
+	apiTenantUsage = "os-simple-tenant-usage"
+
+	// tenantUsageMicroversion is the compute API microversion adding
+	// pagination to usage reports. It is requested by all the usage
+	// calls.
+	tenantUsageMicroversion = "2.40"
+)
+
+// tenantUsageTimeFormat is the format of the start and end of the
+// period usage is reported for.
+const tenantUsageTimeFormat = "2006-01-02T15:04:05.000000"
+
+// ServerUsage describes the resources used by a server over the
+// reporting period. Hours is the time the server existed within the
+// period. EndedAt is empty if the server has not been deleted.
+type ServerUsage struct {
+	InstanceId string  `json:"instance_id"`
+	Name       string  `json:"name"`
+	TenantId   string  `json:"tenant_id"`
+	Flavor     string  `json:"flavor"`
+	Hours      float64 `json:"hours"`
+	VCPUs      int     `json:"vcpus"`
+	MemoryMB   int     `json:"memory_mb"`
+	LocalGB    int     `json:"local_gb"`
+	State      string  `json:"state"`
+	StartedAt  string  `json:"started_at"`
+	EndedAt    string  `json:"ended_at"`
+	Uptime     int     `json:"uptime"`
+}
+
+// VCPUHours returns the vCPU-hours used by the server.
+func (u ServerUsage) VCPUHours() float64 {
+	return u.Hours * float64(u.VCPUs)
+}
+
+// MemoryMBHours returns the RAM MB-hours used by the server.
+func (u ServerUsage) MemoryMBHours() float64 {
+	return u.Hours * float64(u.MemoryMB)
+}
+
+// LocalGBHours returns the local disk GB-hours used by the server.
+func (u ServerUsage) LocalGBHours() float64 {
+	return u.Hours * float64(u.LocalGB)
+}
+
+// TenantUsage holds the resources used by the servers of a tenant
+// over the reporting period. The totals are in vCPU-hours, RAM
+// MB-hours and disk GB-hours. ServerUsages is only filled in when
+// detailed usage is requested.
+type TenantUsage struct {
+	TenantId           string        `json:"tenant_id"`
+	Start              string        `json:"start"`
+	Stop               string        `json:"stop"`
+	TotalHours         float64       `json:"total_hours"`
+	TotalVCPUsUsage    float64       `json:"total_vcpus_usage"`
+	TotalMemoryMBUsage float64       `json:"total_memory_mb_usage"`
+	TotalLocalGBUsage  float64       `json:"total_local_gb_usage"`
+	ServerUsages       []ServerUsage `json:"server_usages"`
+}
+
+// TenantUsageOpts defines the arguments for ListTenantUsages() and
+// GetTenantUsage().
+type TenantUsageOpts struct {
+	// Start and End bound the reporting period. If End is zero the
+	// period ends now.
+	Start time.Time
+	End   time.Time
+
+	// Detailed sets whether the usage of each server is listed by
+	// ListTenantUsages. GetTenantUsage always lists it.
+	Detailed bool
+
+	// Limit is the number of servers reported on per request. If it
+	// is zero the cloud's default is used. All the pages are fetched
+	// whatever the limit.
+	Limit int
+}
+
+// ListTenantUsages returns the usage of all tenants over the period
+// given in opts.
+func (c *Client) ListTenantUsages(opts TenantUsageOpts) ([]TenantUsage, error) {
+	usages, err := c.getTenantUsages(apiTenantUsage, opts, opts.Detailed)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to list tenant usages")
+	}
+	return usages, nil
+}
+
+// GetTenantUsage returns the usage of the given tenant over the
+// period given in opts.
+func (c *Client) GetTenantUsage(tenantId string, opts TenantUsageOpts) (*TenantUsage, error) {
+	url := fmt.Sprintf("%s/%s", apiTenantUsage, tenantId)
+	usages, err := c.getTenantUsages(url, opts, true)
+	if err != nil {
+		return nil, errors.Newf(err, "failed to get usage of tenant: %s", tenantId)
+	}
+	if len(usages) == 0 {
+		// Nova reports a tenant without usage as an empty object.
+		return &TenantUsage{TenantId: tenantId}, nil
+	}
+	return &usages[0], nil
+}
+
+// getTenantUsages fetches every page of the usage report at the given
+// URL, following the next links, and merges the pages.
+func (c *Client) getTenantUsages(apiURL string, opts TenantUsageOpts, detailed bool) ([]TenantUsage, error) {
+	end := opts.End
+	if end.IsZero() {
+		end = time.Now()
+	} else if end.Before(opts.Start) {
+		return nil, fmt.Errorf("end %s is before start %s", end.Format(time.RFC3339), opts.Start.Format(time.RFC3339))
+	}
+	params := make(url.Values)
+	params.Set("start", opts.Start.UTC().Format(tenantUsageTimeFormat))
+	params.Set("end", end.UTC().Format(tenantUsageTimeFormat))
+	if detailed {
+		params.Set("detailed", "1")
+	}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	var usages []TenantUsage
+	seen := make(map[string]bool)
+	for {
+		var resp struct {
+			TenantUsages []TenantUsage `json:"tenant_usages"`
+			TenantUsage  *TenantUsage  `json:"tenant_usage"`
+			UsagesLinks  []Link        `json:"tenant_usages_links"`
+			UsageLinks   []Link        `json:"tenant_usage_links"`
+		}
+		requestData := goosehttp.RequestData{
+			RespValue:  &resp,
+			Params:     &params,
+			ReqHeaders: microversionHeaders(tenantUsageMicroversion),
+		}
+		if err := c.client.SendRequest(client.GET, "compute", "v2", apiURL, &requestData); err != nil {
+			return nil, err
+		}
+		page := resp.TenantUsages
+		if resp.TenantUsage != nil && resp.TenantUsage.TenantId != "" {
+			page = append(page, *resp.TenantUsage)
+		}
+		usages = mergeTenantUsages(usages, page)
+		marker, err := nextMarker(append(resp.UsagesLinks, resp.UsageLinks...))
+		if err != nil {
+			return nil, err
+		}
+		if marker == "" {
+			return usages, nil
+		}
+		// Guard against servers which return a next link
+		// pointing back at a page we have already fetched.
+		if seen[marker] {
+			return nil, fmt.Errorf("next link repeats marker %q", marker)
+		}
+		seen[marker] = true
+		params.Set("marker", marker)
+	}
+}
+
+// mergeTenantUsages adds the usages of a page to those of the previous
+// pages. A tenant whose servers span several pages is reported on
+// each of them, with the totals of the servers on that page.
+func mergeTenantUsages(usages, page []TenantUsage) []TenantUsage {
+	for _, p := range page {
+		found := false
+		for i := range usages {
+			u := &usages[i]
+			if u.TenantId != p.TenantId {
+				continue
+			}
+			u.TotalHours += p.TotalHours
+			u.TotalVCPUsUsage += p.TotalVCPUsUsage
+			u.TotalMemoryMBUsage += p.TotalMemoryMBUsage
+			u.TotalLocalGBUsage += p.TotalLocalGBUsage
+			u.ServerUsages = append(u.ServerUsages, p.ServerUsages...)
+			found = true
+			break
+		}
+		if !found {
+			usages = append(usages, p)
+		}
+	}
+	return usages
+}
+
+// nextMarker returns the marker of the next page given in links, or
+// an empty string if there is no next page.
+func nextMarker(links []Link) (string, error) {
+	for _, link := range links {
+		if link.Rel != "next" {
+			continue
+		}
+		u, err := url.Parse(link.Href)
+		if err != nil {
+			return "", errors.Newf(err, "invalid next link %q", link.Href)
+		}
+		return u.Query().Get("marker"), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,49 @@
+package nova_test
+
+import (
+	"encoding/json"
+	"time"
+
+	gc "gopkg.in/check.v1"
+
+	goosehttp "github.com/go-goose/goose/v5/http"
+	"github.com/go-goose/goose/v5/nova"
+)
+
+type UsageSuite struct{}
+
+var _ = gc.Suite(&UsageSuite{})
+
+// pagesClient is a client.Client which answers every request with the
+// same response, counting the requests.
+type pagesClient struct {
+	resp     string
+	requests int
+}
+
+func (p *pagesClient) SendRequest(method, svcType, svcVersion, apiCall string, requestData *goosehttp.RequestData) error {
+	p.requests++
+	return json.Unmarshal([]byte(p.resp), requestData.RespValue)
+}
+
+func (p *pagesClient) MakeServiceURL(serviceType, apiVersion string, parts []string) (string, error) {
+	return "", nil
+}
+
+func (s *UsageSuite) TestTenantUsageRepeatedMarker(c *gc.C) {
+	client := &pagesClient{resp: `{
+		"tenant_usages": [{"tenant_id": "t1"}],
+		"tenant_usages_links": [{"rel": "next", "href": "http://example.com/os-simple-tenant-usage?marker=m1"}]
+	}`}
+	_, err := nova.New(client).ListTenantUsages(nova.TenantUsageOpts{Start: time.Now().Add(-time.Hour)})
+	c.Assert(err, gc.ErrorMatches, "failed to list tenant usages\ncaused by: next link repeats marker \"m1\"")
+	c.Assert(client.requests, gc.Equals, 2)
+}
+
+func (s *UsageSuite) TestTenantUsageEndBeforeStart(c *gc.C) {
+	client := &pagesClient{resp: `{}`}
+	start := time.Now()
+	_, err := nova.New(client).ListTenantUsages(nova.TenantUsageOpts{Start: start, End: start.Add(-time.Hour)})
+	c.Assert(err, gc.ErrorMatches, "failed to list tenant usages\ncaused by: end .* is before start .*")
+	c.Assert(client.requests, gc.Equals, 0)
+}
@@ -0,0 +1,182 @@
+// Helpers for waiting on the asynchronous changes of server status
+// made by RunServer, DeleteServer and the server actions.
+
+package nova
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-goose/goose/v5/errors"
+)
+
+// DefaultFailureStatuses holds the statuses which end a wait with a
+// ServerStatusError when WaitOpts.FailureStatuses is nil.
+var DefaultFailureStatuses = []string{StatusError}
+
+// WaitOpts defines how WaitForServer and WaitForServerDeleted poll a
+// server.
+type WaitOpts struct {
+	// Statuses holds the statuses to wait for. It is required by
+	// WaitForServer, and ignored by WaitForServerDeleted.
+	Statuses []string
+
+	// FailureStatuses holds the statuses which end the wait with a
+	// ServerStatusError. If nil, DefaultFailureStatuses is used.
+	FailureStatuses []string
+
+	// Timeout bounds the total time spent waiting. If zero, the wait
+	// is only bounded by the context.
+	Timeout time.Duration
+
+	// Backoff returns the delay before the given poll attempt,
+	// counted from 1. If nil, ExponentialBackoff(time.Second,
+	// 30*time.Second) is used.
+	Backoff func(attempt int) time.Duration
+}
+
+// ExponentialBackoff returns a backoff function for WaitOpts which
+// starts at initial and doubles on every attempt up to max.
+func ExponentialBackoff(initial, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		delay := initial
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay
+	}
+}
+
+// ServerStatusError is returned when a server being waited on reaches
+// one of the failure statuses. Fault holds the fault reported by the
+// server, if any.
+type ServerStatusError struct {
+	ServerId string
+	Status   string
+	Fault    *ServerFault
+}
+
+func (e *ServerStatusError) Error() string {
+	msg := fmt.Sprintf("server %s has status %s", e.ServerId, e.Status)
+	if e.Fault != nil {
+		msg += fmt.Sprintf(": %s (code %d)", e.Fault.Message, e.Fault.Code)
+		if e.Fault.Details != "" {
+			msg += ": " + e.Fault.Details
+		}
+	}
+	return msg
+}
+
+// WaitForServer polls the specified server until it reaches one of
+// opts.Statuses, and returns its details. If the server reaches one
+// of the failure statuses which is not in opts.Statuses a
+// *ServerStatusError is returned, and if the wait times out the
+// error satisfies errors.IsTimeout.
+func (c *Client) WaitForServer(ctx context.Context, serverId string, opts WaitOpts) (*ServerDetail, error) {
+	if len(opts.Statuses) == 0 {
+		return nil, fmt.Errorf("no statuses to wait for")
+	}
+	var server *ServerDetail
+	waitingFor := fmt.Sprintf("server %s to reach status %s", serverId, strings.Join(opts.Statuses, " or "))
+	err := waitForServer(ctx, waitingFor, opts, func() (bool, error) {
+		var err error
+		server, err = c.GetServer(serverId)
+		if err != nil {
+			return false, err
+		}
+		// A failure status which is waited for is not a failure.
+		if containsStatus(opts.Statuses, server.Status) {
+			return true, nil
+		}
+		return false, serverFailure(serverId, server, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// WaitForServerDeleted polls the specified server until it no longer
+// exists. If the server reaches one of the failure statuses first a
+// *ServerStatusError is returned, and if the wait times out the
+// error satisfies errors.IsTimeout.
+func (c *Client) WaitForServerDeleted(ctx context.Context, serverId string, opts WaitOpts) error {
+	waitingFor := fmt.Sprintf("server %s to be deleted", serverId)
+	return waitForServer(ctx, waitingFor, opts, func() (bool, error) {
+		server, err := c.GetServer(serverId)
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return server.Status == StatusDeleted, serverFailure(serverId, server, opts)
+	})
+}
+
+// waitForServer calls done until it returns true or an error, waiting
+// between calls as given by opts.
+func waitForServer(ctx context.Context, waitingFor string, opts WaitOpts, done func() (bool, error)) error {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	backoff := opts.Backoff
+	if backoff == nil {
+		backoff = ExponentialBackoff(time.Second, 30*time.Second)
+	}
+	for attempt := 1; ; attempt++ {
+		ok, err := done()
+		if _, failed := err.(*ServerStatusError); failed {
+			return err
+		}
+		if err != nil {
+			return errors.Newf(err, "failed waiting for %s", waitingFor)
+		}
+		if ok {
+			return nil
+		}
+		timer := time.NewTimer(backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if ctx.Err() == context.DeadlineExceeded {
+				return errors.NewTimeoutf(ctx.Err(), "", "timed out waiting for %s", waitingFor)
+			}
+			return errors.Newf(ctx.Err(), "stopped waiting for %s", waitingFor)
+		case <-timer.C:
+		}
+	}
+}
+
+// serverFailure returns a *ServerStatusError if the server has one of
+// the failure statuses given by opts.
+func serverFailure(serverId string, server *ServerDetail, opts WaitOpts) error {
+	failureStatuses := opts.FailureStatuses
+	if failureStatuses == nil {
+		failureStatuses = DefaultFailureStatuses
+	}
+	if !containsStatus(failureStatuses, server.Status) {
+		return nil
+	}
+	return &ServerStatusError{
+		ServerId: serverId,
+		Status:   server.Status,
+		Fault:    server.Fault,
+	}
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
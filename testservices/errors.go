@@ -211,3 +211,335 @@ func NewSubnetAlreadyExistsError(id string) *ServerError {
 func NewNoSuchOSInterfaceError(id string) *ServerError {
 	return serverErrorf(404, "No such os interface %q", id)
 }
+
+func NewServerStateConflictError(action, serverId, status string) *ServerError {
+	return serverErrorf(409, "Cannot '%s' instance %s while it is in status %s", action, serverId, status)
+}
+
+func NewResizeSameFlavorError() *ServerError {
+	return serverErrorf(400, "When resizing, instances must change flavor!")
+}
+
+func NewFlavorNotFoundForResizeError(id string) *ServerError {
+	return serverErrorf(400, "Unable to find flavor %q", id)
+}
+
+func NewKeyPairAlreadyExistsError(name string) *ServerError {
+	return serverErrorf(409, "Key pair '%s' already exists.", name)
+}
+
+func NewKeyPairNotFoundError(name string) *ServerError {
+	return serverErrorf(404, "Keypair %s not found", name)
+}
+
+func NewInvalidKeyPairError(reason string) *ServerError {
+	return serverErrorf(400, "Keypair data is invalid: %s", reason)
+}
+
+func NewInvalidKeyNameError() *ServerError {
+	return serverErrorf(400, "Invalid key_name provided.")
+}
+
+func NewServerGroupNotFoundError(id string) *ServerError {
+	return serverErrorf(404, "Instance group %s could not be found.", id)
+}
+
+func NewInvalidServerGroupError(reason string) *ServerError {
+	return serverErrorf(400, "Invalid input for field/attribute server_group: %s", reason)
+}
+
+func NewInvalidRemoteConsoleError(protocol, consoleType string) *ServerError {
+	return serverErrorf(400, "Invalid console type %s for protocol %s", consoleType, protocol)
+}
+
+func NewQuotaExceededError(resource string, requested, used, allowed int) *ServerError {
+	return serverErrorf(403, "Quota exceeded for %s: Requested %d, but already used %d of %d %s", resource, requested, used, allowed, resource)
+}
+
+func NewKeyPairQuotaExceededError() *ServerError {
+	return serverErrorf(403, "Quota exceeded, too many key pairs.")
+}
+
+func NewServerGroupQuotaExceededError() *ServerError {
+	return serverErrorf(403, "Quota exceeded, too many server groups.")
+}
+
+func NewServerGroupMembersQuotaExceededError() *ServerError {
+	return serverErrorf(403, "Quota exceeded, too many servers in group")
+}
+
+func NewInvalidQuotaError(reason string) *ServerError {
+	return serverErrorf(400, "Invalid input for field/attribute quota_set: %s", reason)
+}
+
+func NewQuotaBelowUsageError(resource string, limit, used int) *ServerError {
+	return serverErrorf(400, "Quota limit %d for %s must be greater than or equal to already used and reserved %d.", limit, resource, used)
+}
+
+func NewFlavorNotFoundError(id string) *ServerError {
+	return serverErrorf(404, "Flavor %s could not be found.", id)
+}
+
+func NewFlavorNameExistsError(name string) *ServerError {
+	return serverErrorf(409, "Flavor with name %s already exists.", name)
+}
+
+func NewInvalidFlavorError(reason string) *ServerError {
+	return serverErrorf(400, "Invalid input for field/attribute flavor: %s", reason)
+}
+
+func NewFlavorExtraSpecNotFoundError(id, key string) *ServerError {
+	return serverErrorf(404, "Flavor %s has no extra specs with key %s.", id, key)
+}
+
+func NewPublicFlavorAccessError() *ServerError {
+	return serverErrorf(409, "Can not add access to a public flavor.")
+}
+
+func NewPublicFlavorAccessListError() *ServerError {
+	return serverErrorf(404, "Access list not available for public flavors.")
+}
+
+func NewFlavorAccessExistsError(id, tenantId string) *ServerError {
+	return serverErrorf(409, "Flavor access already exists for flavor %s and project %s combination.", id, tenantId)
+}
+
+func NewFlavorAccessNotFoundError(id, tenantId string) *ServerError {
+	return serverErrorf(404, "Flavor access not found for %s / %s combination.", id, tenantId)
+}
+
+func NewBadRequestError(message string) *ServerError {
+	return serverErrorf(400, "%s", message)
+}
+
+func NewInvalidServerTagError(reason string) *ServerError {
+	return serverErrorf(400, "Invalid input for field/attribute tags: %s", reason)
+}
+
+func NewTooManyServerTagsError(limit int) *ServerError {
+	return serverErrorf(400, "The number of tags exceeded the per-server limit %d", limit)
+}
+
+func NewServerTagNotFoundError(serverId, tag string) *ServerError {
+	return serverErrorf(404, "Instance %s has no tag '%s'", serverId, tag)
+}
+
+func NewUnsupportedMicroversionFieldError(field string) *ServerError {
+	return serverErrorf(400, "Invalid input for field/attribute server. Value: Additional properties are not allowed ('%s' was unexpected)", field)
+}
+
+func NewInstanceActionNotFoundError(requestId, serverId string) *ServerError {
+	return serverErrorf(404, "Action %s on instance %s could not be found", requestId, serverId)
+}
+
+func NewImageNotFoundError(id string) *ServerError {
+	return serverErrorf(404, "Image %s could not be found.", id)
+}
+
+func NewPortInUseError(portId string) *ServerError {
+	return serverErrorf(409, "Port %s is still in use.", portId)
+}
+
+func NewPortNotAttachedError(portId string) *ServerError {
+	return serverErrorf(404, "Port %s is not attached", portId)
+}
+
+func NewFixedIPInUseError(address string) *ServerError {
+	return serverErrorf(400, "Fixed IP address %s is already in use.", address)
+}
+
+func NewMetadataKeyBlankError() *ServerError {
+	return serverErrorf(400, "Metadata property key blank.")
+}
+
+func NewMetadataSizeError(what string, limit int) *ServerError {
+	return serverErrorf(400, "Metadata property %s greater than %d characters.", what, limit)
+}
+
+func NewMetadataLimitExceededError(limit int) *ServerError {
+	return serverErrorf(403, "Maximum number of metadata items exceeds %d", limit)
+}
+
+func NewMetadataItemNotFoundError() *ServerError {
+	return serverErrorf(404, "Metadata item was not found")
+}
+
+func NewInvalidBlockDeviceMappingError(details string) *ServerError {
+	return serverErrorf(400, "Block Device Mapping is Invalid: %s", details)
+}
+
+func NewHypervisorNotFoundError(id string) *ServerError {
+	return serverErrorf(404, "Hypervisor %s could not be found.", id)
+}
+
+func NewServiceNotFoundError(id string) *ServerError {
+	return serverErrorf(404, "Service %s could not be found.", id)
+}
+
+func NewComputeHostNotFoundError(host string) *ServerError {
+	return serverErrorf(404, "Compute host %s could not be found.", host)
+}
+
+func NewAggregateNotFoundError(id int) *ServerError {
+	return serverErrorf(404, "Aggregate %d could not be found.", id)
+}
+
+func NewAggregateNameExistsError(name string) *ServerError {
+	return serverErrorf(409, "Aggregate %s already exists.", name)
+}
+
+func NewAggregateHostExistsError(id int, host string) *ServerError {
+	return serverErrorf(409, "Aggregate %d already has host %s.", id, host)
+}
+
+func NewAggregateHostNotFoundError(id int, host string) *ServerError {
+	return serverErrorf(404, "Aggregate %d has no host %s.", id, host)
+}
+
+func NewAggregateZoneConflictError(id int, zones []string) *ServerError {
+	return serverErrorf(409, "Cannot add host to aggregate %d. Reason: One or more hosts already in availability zone(s) %v.", id, zones)
+}
+
+func NewAggregateNotEmptyError(id int) *ServerError {
+	return serverErrorf(400, "Cannot remove aggregate %d. Reason: Host aggregate is not empty.", id)
+}
+
+func NewNoValidHostError() *ServerError {
+	return serverErrorf(400, "No valid host was found. There are not enough hosts available.")
+}
+
+func NewMigrateToSameHostError(serverId, host string) *ServerError {
+	return serverErrorf(400, "Unable to migrate instance (%s) to current host (%s).", serverId, host)
+}
+
+func NewComputeServiceInUseError(host string) *ServerError {
+	return serverErrorf(400, "Compute service of %s is still in use.", host)
+}
+
+func NewMigrationNotFoundForInstanceError(migrationId int, serverId string) *ServerError {
+	return serverErrorf(404, "Migration %d not found for instance %s", migrationId, serverId)
+}
+
+func NewLiveMigrationNotInProgressError(migrationId int, serverId string) *ServerError {
+	return serverErrorf(404, "In-progress live migration %d is not found for server %s.", migrationId, serverId)
+}
+
+func NewInvalidMigrationStateError(migrationId int, serverId, status, method string) *ServerError {
+	return serverErrorf(400, "Migration %d state of instance %s is %s. Cannot %s while the migration is in this state.", migrationId, serverId, status, method)
+}
+
+func NewMarkerNotFoundError(marker string) *ServerError {
+	return serverErrorf(400, "marker [%s] not found", marker)
+}
+
+func NewNeutronInvalidInputError(reason string) *ServerError {
+	return serverErrorf(400, "Invalid input for operation: %s.", reason)
+}
+
+func NewInvalidAttributeError(attribute, reason string) *ServerError {
+	return serverErrorf(400, "Invalid input for %s. Reason: %s.", attribute, reason)
+}
+
+func NewReadOnlyAttributeError(attribute string) *ServerError {
+	return serverErrorf(400, "Cannot update read-only attribute %s", attribute)
+}
+
+func NewNetworkInUseError(networkId string) *ServerError {
+	return serverErrorf(409, "Unable to complete operation on network %s. There are one or more ports still in use on the network.", networkId)
+}
+
+func NewSubnetInUseError(subnetId string) *ServerError {
+	return serverErrorf(409, "Unable to complete operation on subnet %s: One or more ports have an IP allocation from this subnet.", subnetId)
+}
+
+func NewVlanIdInUseError(vlanId int, physicalNetwork string) *ServerError {
+	return serverErrorf(409, "Unable to create the network. The VLAN %d on physical network %s is in use.", vlanId, physicalNetwork)
+}
+
+func NewTunnelIdInUseError(tunnelId int) *ServerError {
+	return serverErrorf(409, "Unable to create the network. The tunnel ID %d is in use.", tunnelId)
+}
+
+func NewOverlappingSubnetError(cidr, networkId string) *ServerError {
+	return serverErrorf(400, "Invalid input for operation: Requested subnet with cidr: %s for network: %s overlaps with another subnet.", cidr, networkId)
+}
+
+func NewInvalidAllocationPoolError(pool string) *ServerError {
+	return serverErrorf(400, "The allocation pool %s is not valid.", pool)
+}
+
+func NewOutOfBoundsAllocationPoolError(pool, cidr string) *ServerError {
+	return serverErrorf(400, "The allocation pool %s spans beyond the subnet cidr %s.", pool, cidr)
+}
+
+func NewOverlappingAllocationPoolsError(pool1, pool2, cidr string) *ServerError {
+	return serverErrorf(400, "Found overlapping allocation pools: %s and %s for subnet %s.", pool1, pool2, cidr)
+}
+
+func NewGatewayConflictWithAllocationPoolsError(address, pool string) *ServerError {
+	return serverErrorf(409, "Gateway ip %s conflicts with allocation pool %s.", address, pool)
+}
+
+func NewSubnetPoolNotFoundError(id string) *ServerError {
+	return serverErrorf(404, "Subnet pool %s could not be found.", id)
+}
+
+func NewSubnetAllocationError(reason string) *ServerError {
+	return serverErrorf(409, "Failed to allocate subnet: %s.", reason)
+}
+
+func NewRouterNotFoundError(routerId string) *ServerError {
+	return serverErrorf(404, "No such router %q", routerId)
+}
+
+func NewRouterAlreadyExistsError(routerId string) *ServerError {
+	return serverErrorf(409, "A router with id %q already exists", routerId)
+}
+
+func NewBadRouterRequestError(reason string) *ServerError {
+	return serverErrorf(400, "Bad router request: %s.", reason)
+}
+
+func NewRouterInUseError(routerId string) *ServerError {
+	return serverErrorf(409, "Router %s still has ports", routerId)
+}
+
+func NewPortHasDeviceError(portId, networkId, deviceId string) *ServerError {
+	return serverErrorf(409, "Unable to complete operation on port %s for network %s. Port already has an attached device %s.", portId, networkId, deviceId)
+}
+
+func NewIPAddressInUseError(address, networkId string) *ServerError {
+	return serverErrorf(409, "Unable to complete operation for network %s. The IP address %s is in use.", networkId, address)
+}
+
+func NewIPAddressGenerationFailureError(networkId string) *ServerError {
+	return serverErrorf(409, "No more IP addresses available on network %s.", networkId)
+}
+
+func NewRouterInterfaceNotFoundError(routerId, portId string) *ServerError {
+	return serverErrorf(404, "Router %s does not have an interface with id %s", routerId, portId)
+}
+
+func NewRouterInterfaceNotFoundForSubnetError(routerId, subnetId string) *ServerError {
+	return serverErrorf(404, "Router %s has no interface on subnet %s", routerId, subnetId)
+}
+
+func NewRouterInterfaceInUseByRouteError(routerId, subnetId string) *ServerError {
+	return serverErrorf(409, "Router interface for subnet %s on router %s cannot be deleted, as it is required by one or more routes.", subnetId, routerId)
+}
+
+func NewRouterInterfaceInUseByFloatingIPError(routerId, subnetId string) *ServerError {
+	return serverErrorf(409, "Router interface for subnet %s on router %s cannot be deleted, as it is required by one or more floating IPs.", subnetId, routerId)
+}
+
+func NewRouterExternalGatewayInUseByFloatingIPError(routerId, networkId string) *ServerError {
+	return serverErrorf(409, "Gateway cannot be updated for router %s, since a gateway to external network %s is required by one or more floating IPs.", routerId, networkId)
+}
+
+func NewInvalidRoutesError(routes, reason string) *ServerError {
+	return serverErrorf(400, "Invalid format for routes: %s, %s", routes, reason)
+}
+
+func NewExternalNetworkNotReachableError(networkId, subnetId, portId string) *ServerError {
+	return serverErrorf(404, "External network %s is not reachable from subnet %s.  Therefore, cannot associate Port %s with a Floating IP.", networkId, subnetId, portId)
+}
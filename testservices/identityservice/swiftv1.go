@@ -0,0 +1,74 @@
+package identityservice
+
+import (
+	"net/http"
+)
+
+// SwiftV1 is an identity service double implementing the v1 (TempAuth)
+// authentication of standalone Swift clusters. The storage URL it
+// returns is the public URL of the object-store service registered
+// with it.
+type SwiftV1 struct {
+	Users
+	storageURL string
+}
+
+// NewSwiftV1 returns a new SwiftV1 identity service.
+func NewSwiftV1() *SwiftV1 {
+	service := &SwiftV1{}
+	service.users = make(map[string]UserInfo)
+	service.tenants = make(map[string]string)
+	return service
+}
+
+// RegisterServiceProvider records the storage URL of an object-store
+// service provider. Other service types are ignored.
+func (s *SwiftV1) RegisterServiceProvider(name, serviceType string, serviceProvider ServiceProvider) {
+	if serviceType != "object-store" {
+		return
+	}
+	if endpoints := serviceProvider.Endpoints(); len(endpoints) > 0 {
+		s.storageURL = endpoints[0].PublicURL
+	}
+}
+
+func (s *SwiftV1) AddService(service Service) {
+	// NOOP for Swift v1 identity service.
+}
+
+// SetStorageURL sets the storage URL returned on authentication.
+func (s *SwiftV1) SetStorageURL(URL string) {
+	s.storageURL = URL
+}
+
+// SetupHTTP attaches all the needed handlers to provide the HTTP API.
+func (s *SwiftV1) SetupHTTP(mux *http.ServeMux) {
+	mux.Handle("/auth/v1.0", s)
+	mux.Handle("/v1.0", s)
+}
+
+func (s *SwiftV1) Stop() {
+	// NOOP for Swift v1 identity service.
+}
+
+func (s *SwiftV1) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	username := r.Header.Get("X-Auth-User")
+	userInfo, ok := s.users[username]
+	if !ok || r.Header.Get("X-Auth-Key") != userInfo.secret {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if userInfo.Token == "" {
+		userInfo.Token = randomHexToken()
+		s.users[username] = userInfo
+	}
+	header := w.Header()
+	header.Set("X-Auth-Token", userInfo.Token)
+	header.Set("X-Storage-Token", userInfo.Token)
+	header.Set("X-Storage-Url", s.storageURL)
+	w.WriteHeader(http.StatusOK)
+}
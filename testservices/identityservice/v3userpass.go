@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/go-goose/goose/v5/testservices/hook"
@@ -24,6 +25,14 @@ type V3UserPassRequest struct {
 					} `json:"domain"`
 				} `json:"user"`
 			} `json:"password"`
+			ApplicationCredential struct {
+				ID     string `json:"id"`
+				Name   string `json:"name"`
+				Secret string `json:"secret"`
+				User   struct {
+					Name string `json:"name"`
+				} `json:"user"`
+			} `json:"application_credential"`
 		} `json:"identity"`
 		Scope struct {
 			Project struct {
@@ -36,6 +45,9 @@ type V3UserPassRequest struct {
 			Domain struct {
 				Name string `json:"name,omitempty"`
 			} `json:"domain"`
+			Trust struct {
+				ID string `json:"id"`
+			} `json:"OS-TRUST:trust"`
 		} `json:"scope"`
 	} `json:"auth"`
 }
@@ -93,12 +105,36 @@ type V3TokenResponse struct {
 	Catalog []V3Service `json:"catalog,omitempty"`
 	Project *V3Project  `json:"project,omitempty"`
 	Domain  *V3Domain   `json:"domain,omitempty"`
+	Trust   *V3Trust    `json:"OS-TRUST:trust,omitempty"`
 	User    struct {
 		ID   string `json:"id"`
 		Name string `json:"name"`
 	} `json:"user"`
 }
 
+// V3Trust represents a trust, through which a trustor delegates
+// their roles on a project to a trustee.
+type V3Trust struct {
+	ID            string `json:"id"`
+	Impersonation bool   `json:"impersonation"`
+	TrustorUser   struct {
+		ID string `json:"id"`
+	} `json:"trustor_user"`
+	TrusteeUser struct {
+		ID string `json:"id"`
+	} `json:"trustee_user"`
+	project V3Project
+}
+
+// v3ApplicationCredential represents an application credential
+// owned by a user.
+type v3ApplicationCredential struct {
+	id     string
+	name   string
+	secret string
+	user   string
+}
+
 // V3Project represent an openstack project, A project is the base unit of ownership.
 // Resources are owned by a specific project. A project is owned by a specific domain.
 type V3Project struct {
@@ -116,13 +152,17 @@ type V3Domain struct {
 type V3UserPass struct {
 	hook.TestService
 	Users
-	services []V3Service
+	services  []V3Service
+	trusts    map[string]V3Trust
+	appCreds  []v3ApplicationCredential
+	nextTrust int
 }
 
 // NewV3UserPass returns a new V3UserPass
 func NewV3UserPass() *V3UserPass {
 	userpass := &V3UserPass{
 		services: make([]V3Service, 0),
+		trusts:   make(map[string]V3Trust),
 	}
 	userpass.users = make(map[string]UserInfo)
 	userpass.tenants = make(map[string]string)
@@ -145,6 +185,65 @@ func (u *V3UserPass) AddService(service Service) {
 	u.services = append(u.services, service.V3)
 }
 
+// AddTrust adds a trust through which the trustee may obtain tokens
+// scoped to the named project on behalf of the trustor, and returns
+// the trust's ID. Both users must already exist.
+func (u *V3UserPass) AddTrust(trustor, trustee, project string, impersonation bool) (string, error) {
+	trustorInfo, ok := u.users[trustor]
+	if !ok {
+		return "", fmt.Errorf("user %q does not exist", trustor)
+	}
+	trusteeInfo, ok := u.users[trustee]
+	if !ok {
+		return "", fmt.Errorf("user %q does not exist", trustee)
+	}
+	u.nextTrust++
+	trust := V3Trust{
+		ID:            fmt.Sprintf("trust-%d", u.nextTrust),
+		Impersonation: impersonation,
+	}
+	trust.TrustorUser.ID = trustorInfo.Id
+	trust.TrusteeUser.ID = trusteeInfo.Id
+	trust.project.ID, trust.project.Name = u.addTenant(project)
+	u.trusts[trust.ID] = trust
+	return trust.ID, nil
+}
+
+// AddApplicationCredential adds an application credential with the
+// given name and secret for an existing user, and returns its ID.
+func (u *V3UserPass) AddApplicationCredential(user, name, secret string) (string, error) {
+	if _, ok := u.users[user]; !ok {
+		return "", fmt.Errorf("user %q does not exist", user)
+	}
+	id := randomHexToken()
+	u.appCreds = append(u.appCreds, v3ApplicationCredential{
+		id:     id,
+		name:   name,
+		secret: secret,
+		user:   user,
+	})
+	return id, nil
+}
+
+// authenticateApplicationCredential authenticates the owner of the
+// application credential identified by id, or by name and owner.
+func (u *V3UserPass) authenticateApplicationCredential(id, name, user, secret string) (*UserInfo, string) {
+	for _, appCred := range u.appCreds {
+		if id != "" && appCred.id != id {
+			continue
+		}
+		if id == "" && (appCred.name != name || appCred.user != user) {
+			continue
+		}
+		if appCred.secret != secret {
+			return nil, invalidUser
+		}
+		userInfo := u.users[appCred.user]
+		return u.authenticate(appCred.user, userInfo.secret, userInfo.authDomain)
+	}
+	return nil, notAuthorized
+}
+
 // ReturnFailure wraps and returns an error through the http connection.
 func (u *V3UserPass) ReturnFailure(w http.ResponseWriter, status int, message string) {
 	e := ErrorWrapper{
@@ -195,11 +294,24 @@ func (u *V3UserPass) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		u.ReturnFailure(w, http.StatusInternalServerError, err.Error())
 	}
 
-	userInfo, errmsg := u.authenticate(
-		req.Auth.Identity.Password.User.Name,
-		req.Auth.Identity.Password.User.Password,
-		domain,
+	var (
+		userInfo *UserInfo
+		errmsg   string
 	)
+	if appCred := req.Auth.Identity.ApplicationCredential; appCred.Secret != "" {
+		userInfo, errmsg = u.authenticateApplicationCredential(
+			appCred.ID,
+			appCred.Name,
+			appCred.User.Name,
+			appCred.Secret,
+		)
+	} else {
+		userInfo, errmsg = u.authenticate(
+			req.Auth.Identity.Password.User.Name,
+			req.Auth.Identity.Password.User.Password,
+			domain,
+		)
+	}
 	if errmsg != "" {
 		u.ReturnFailure(w, http.StatusUnauthorized, errmsg)
 		return
@@ -222,6 +334,23 @@ func (u *V3UserPass) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			Name: req.Auth.Scope.Domain.Name,
 		}
 	}
+	if req.Auth.Identity.ApplicationCredential.Secret != "" {
+		res.Methods = []string{"application_credential"}
+		res.Project = &V3Project{
+			ID:   userInfo.TenantId,
+			Name: userInfo.TenantName,
+		}
+	}
+	if trustID := req.Auth.Scope.Trust.ID; trustID != "" {
+		trust, ok := u.trusts[trustID]
+		if !ok || trust.TrusteeUser.ID != userInfo.Id {
+			u.ReturnFailure(w, http.StatusForbidden, fmt.Sprintf("trust %s is not valid for this user", trustID))
+			return
+		}
+		project := trust.project
+		res.Project = &project
+		res.Trust = &trust
+	}
 	content, err := json.Marshal(struct {
 		Token *V3TokenResponse `json:"token"`
 	}{
@@ -248,9 +377,53 @@ func (u *V3UserPass) generateV3TokenResponse(userInfo *UserInfo) (*V3TokenRespon
 	return &res, nil
 }
 
+// v3Region represents a region in the response to a region listing.
+type v3Region struct {
+	ID             string  `json:"id"`
+	Description    string  `json:"description"`
+	ParentRegionID *string `json:"parent_region_id"`
+}
+
+// serveRegions lists the distinct regions of the endpoints of the
+// registered services.
+func (u *V3UserPass) serveRegions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "GET" {
+		u.ReturnFailure(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	seen := make(map[string]bool)
+	regions := []v3Region{}
+	for _, service := range u.services {
+		for _, ep := range service.Endpoints {
+			if ep.RegionID == "" || seen[ep.RegionID] {
+				continue
+			}
+			seen[ep.RegionID] = true
+			regions = append(regions, v3Region{ID: ep.RegionID})
+		}
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i].ID < regions[j].ID })
+	var resp struct {
+		Regions []v3Region `json:"regions"`
+		Links   struct {
+			Next *string `json:"next"`
+		} `json:"links"`
+	}
+	resp.Regions = regions
+	content, err := json.Marshal(resp)
+	if err != nil {
+		u.ReturnFailure(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
 // SetupHTTP attaches all the needed handlers to provide the HTTP API.
 func (u *V3UserPass) SetupHTTP(mux *http.ServeMux) {
 	mux.Handle("/v3/auth/tokens", u)
+	mux.HandleFunc("/v3/regions", u.serveRegions)
 }
 
 func (u *V3UserPass) Stop() {
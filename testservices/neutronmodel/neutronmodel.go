@@ -647,6 +647,17 @@ func (n *NeutronModel) AddNetwork(network neutron.NetworkV2) error {
 	return nil
 }
 
+// UpdateNetwork replaces an existing network, given a neutron.NetworkV2.
+func (n *NeutronModel) UpdateNetwork(network neutron.NetworkV2) error {
+	n.rwMu.Lock()
+	defer n.rwMu.Unlock()
+	if _, err := n.Network(network.Id); err != nil {
+		return err
+	}
+	n.networks[network.Id] = network
+	return nil
+}
+
 // RemoveNetwork deletes an existing group.
 func (n *NeutronModel) RemoveNetwork(netId string) error {
 	n.rwMu.Lock()
@@ -683,6 +694,17 @@ func (n *NeutronModel) Port(portId string) (*neutron.PortV2, error) {
 	return &port, nil
 }
 
+// UpdatePort replaces an existing port, given a neutron.PortV2.
+func (n *NeutronModel) UpdatePort(port neutron.PortV2) error {
+	n.rwMu.Lock()
+	defer n.rwMu.Unlock()
+	if _, err := n.Port(port.Id); err != nil {
+		return err
+	}
+	n.ports[port.Id] = port
+	return nil
+}
+
 // AllPorts returns a list of all existing ports, data in
 // neutron.PortV2 form.
 func (n *NeutronModel) AllPorts() []neutron.PortV2 {
@@ -3,6 +3,9 @@
 package neutronservice
 
 import (
+	"fmt"
+	"math/big"
+	"net"
 	"net/url"
 	"strconv"
 	"strings"
@@ -26,6 +29,8 @@ type Neutron struct {
 	floatingIPs  map[string]neutron.FloatingIPV2
 	networks     map[string]neutron.NetworkV2
 	subnets      map[string]neutron.SubnetV2
+	subnetPools  map[string]subnetPool
+	routers      map[string]neutron.RouterV2
 	nextGroupId  int
 	nextRuleId   int
 	nextPortId   int
@@ -103,7 +108,9 @@ func New(hostURL, versionPath, tenantId, region string, identityService, fallbac
 		},
 	}
 	neutronService := &Neutron{
-		subnets: make(map[string]neutron.SubnetV2),
+		subnets:     make(map[string]neutron.SubnetV2),
+		subnetPools: make(map[string]subnetPool),
+		routers:     make(map[string]neutron.RouterV2),
 		ServiceInstance: testservices.ServiceInstance{
 			IdentityService:         identityService,
 			FallbackIdentityService: fallbackIdentity,
@@ -461,3 +468,1367 @@ func (n *Neutron) removeSubnet(subnetId string) error {
 	delete(n.subnets, subnetId)
 	return nil
 }
+
+// defaultMTU is the MTU of networks created without one, and the
+// largest MTU networks may have.
+const defaultMTU = 1500
+
+// segmentationIdRanges holds the range of segmentation IDs of each
+// type of provider network which has them.
+var segmentationIdRanges = map[string][2]int{
+	neutron.NetworkTypeVLAN:   {1, 4094},
+	neutron.NetworkTypeVXLAN:  {1, 1<<24 - 1},
+	neutron.NetworkTypeGRE:    {1, 1<<32 - 1},
+	neutron.NetworkTypeGeneve: {1, 1<<24 - 1},
+}
+
+// createNetwork creates a network as requested through the API.
+func (n *Neutron) createNetwork(opts neutron.NetworkOptsV2) (*neutron.NetworkV2, error) {
+	if err := n.ProcessFunctionHook(n, opts); err != nil {
+		return nil, err
+	}
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	network := neutron.NetworkV2{
+		Id:                id,
+		Name:              opts.Name,
+		Description:       opts.Description,
+		TenantId:          n.TenantId,
+		AvailabilityZones: []string{"nova"},
+		AdminStateUp:      true,
+		Status:            "ACTIVE",
+		MTU:               defaultMTU,
+		SubnetIds:         []string{},
+	}
+	if err := n.setProviderAttributes(&network, opts); err != nil {
+		return nil, err
+	}
+	portSecurity := true
+	network.PortSecurityEnabled = &portSecurity
+	if err := updateNetworkAttributes(&network, opts); err != nil {
+		return nil, err
+	}
+	if err := n.addNetwork(network); err != nil {
+		return nil, err
+	}
+	return &network, nil
+}
+
+// setProviderAttributes validates the provider attributes requested
+// for a new network, allocating a segmentation ID if none is given.
+func (n *Neutron) setProviderAttributes(network *neutron.NetworkV2, opts neutron.NetworkOptsV2) error {
+	if opts.NetworkType == "" {
+		if opts.PhysicalNetwork != "" || opts.SegmentationId != 0 {
+			return testservices.NewNeutronInvalidInputError("network_type required")
+		}
+		return nil
+	}
+	idRange, hasIds := segmentationIdRanges[opts.NetworkType]
+	switch opts.NetworkType {
+	case neutron.NetworkTypeFlat, neutron.NetworkTypeVLAN:
+		if opts.PhysicalNetwork == "" {
+			return testservices.NewNeutronInvalidInputError(fmt.Sprintf("physical_network required for %s provider network", opts.NetworkType))
+		}
+	case neutron.NetworkTypeVXLAN, neutron.NetworkTypeGRE, neutron.NetworkTypeGeneve, neutron.NetworkTypeLocal:
+		if opts.PhysicalNetwork != "" {
+			return testservices.NewNeutronInvalidInputError(fmt.Sprintf("provider:physical_network specified for %s network", opts.NetworkType))
+		}
+	default:
+		return testservices.NewNeutronInvalidInputError(fmt.Sprintf("network_type value '%s' not supported", opts.NetworkType))
+	}
+	if !hasIds {
+		if opts.SegmentationId != 0 {
+			return testservices.NewNeutronInvalidInputError(fmt.Sprintf("segmentation_id not allowed for %s provider network", opts.NetworkType))
+		}
+		network.NetworkType = opts.NetworkType
+		network.PhysicalNetwork = opts.PhysicalNetwork
+		return nil
+	}
+	used := make(map[int]bool)
+	for _, other := range n.neutronModel.AllNetworks() {
+		if other.NetworkType == opts.NetworkType && other.PhysicalNetwork == opts.PhysicalNetwork {
+			used[other.SegmentationId] = true
+		}
+	}
+	segmentationId := opts.SegmentationId
+	if segmentationId == 0 {
+		for id := idRange[0]; id <= idRange[1]; id++ {
+			if !used[id] {
+				segmentationId = id
+				break
+			}
+		}
+	}
+	if segmentationId < idRange[0] || segmentationId > idRange[1] {
+		return testservices.NewNeutronInvalidInputError(fmt.Sprintf("segmentation_id out of range (%d through %d)", idRange[0], idRange[1]))
+	}
+	if used[segmentationId] {
+		if opts.NetworkType == neutron.NetworkTypeVLAN {
+			return testservices.NewVlanIdInUseError(segmentationId, opts.PhysicalNetwork)
+		}
+		return testservices.NewTunnelIdInUseError(segmentationId)
+	}
+	network.NetworkType = opts.NetworkType
+	network.PhysicalNetwork = opts.PhysicalNetwork
+	network.SegmentationId = segmentationId
+	return nil
+}
+
+// updateNetworkAttributes sets the attributes of network given in
+// opts, other than the provider attributes.
+func updateNetworkAttributes(network *neutron.NetworkV2, opts neutron.NetworkOptsV2) error {
+	if opts.MTU != 0 {
+		if opts.MTU < 68 {
+			return testservices.NewInvalidAttributeError("mtu", fmt.Sprintf("'%d' is too small - must be at least '68'", opts.MTU))
+		}
+		if opts.MTU > defaultMTU {
+			return testservices.NewBadRequestError(fmt.Sprintf("Requested MTU is too big, maximum is %d", defaultMTU))
+		}
+		network.MTU = opts.MTU
+	}
+	if opts.Name != "" {
+		network.Name = opts.Name
+	}
+	if opts.Description != "" {
+		network.Description = opts.Description
+	}
+	if opts.AdminStateUp != nil {
+		network.AdminStateUp = *opts.AdminStateUp
+	}
+	if opts.Shared != nil {
+		network.Shared = *opts.Shared
+	}
+	if opts.External != nil {
+		network.External = *opts.External
+	}
+	if opts.PortSecurityEnabled != nil {
+		portSecurity := *opts.PortSecurityEnabled
+		network.PortSecurityEnabled = &portSecurity
+	}
+	return nil
+}
+
+// updateNetwork changes the attributes of an existing network. The
+// provider attributes cannot be changed.
+func (n *Neutron) updateNetwork(networkId string, opts neutron.NetworkOptsV2) (*neutron.NetworkV2, error) {
+	if err := n.ProcessFunctionHook(n, networkId, opts); err != nil {
+		return nil, err
+	}
+	network, err := n.network(networkId)
+	if err != nil {
+		return nil, err
+	}
+	if (opts.NetworkType != "" && opts.NetworkType != network.NetworkType) ||
+		(opts.PhysicalNetwork != "" && opts.PhysicalNetwork != network.PhysicalNetwork) ||
+		(opts.SegmentationId != 0 && opts.SegmentationId != network.SegmentationId) {
+		return nil, testservices.NewNeutronInvalidInputError("Plugin does not support updating provider attributes")
+	}
+	if err := updateNetworkAttributes(network, opts); err != nil {
+		return nil, err
+	}
+	if err := n.neutronModel.UpdateNetwork(*network); err != nil {
+		return nil, err
+	}
+	return network, nil
+}
+
+// deleteNetwork deletes an existing network and its subnets, provided
+// no ports or floating IPs are left on it.
+func (n *Neutron) deleteNetwork(networkId string) error {
+	if err := n.ProcessFunctionHook(n, networkId); err != nil {
+		return err
+	}
+	if _, err := n.network(networkId); err != nil {
+		return err
+	}
+	for _, port := range n.neutronModel.AllPorts() {
+		if port.NetworkId == networkId {
+			return testservices.NewNetworkInUseError(networkId)
+		}
+	}
+	for _, fip := range n.neutronModel.AllFloatingIPs() {
+		if fip.FloatingNetworkId == networkId {
+			return testservices.NewNetworkInUseError(networkId)
+		}
+	}
+	for id, subnet := range n.subnets {
+		if subnet.NetworkId == networkId {
+			delete(n.subnets, id)
+		}
+	}
+	return n.removeNetwork(networkId)
+}
+
+// subnetPool is a set of prefixes subnets can be allocated from.
+type subnetPool struct {
+	prefixes            []*net.IPNet
+	ipVersion           int
+	defaultPrefixLength int
+}
+
+// AddSubnetPool adds a subnet pool with the given ID and prefixes,
+// which must all be of the same IP version. Subnets created from the
+// pool without a prefix length have defaultPrefixLength.
+//
+// Note: this is implemented as a public method rather than as
+// an HTTP API as goose has no API to manage subnet pools, which are
+// usually created by administrators.
+func (n *Neutron) AddSubnetPool(id string, prefixes []string, defaultPrefixLength int) error {
+	var pool subnetPool
+	for _, prefix := range prefixes {
+		ipNet, version, err := parseSubnetCIDR(prefix)
+		if err != nil {
+			return err
+		}
+		if pool.ipVersion != 0 && pool.ipVersion != version {
+			return fmt.Errorf("subnet pool %s mixes IPv4 and IPv6 prefixes", id)
+		}
+		pool.ipVersion = version
+		pool.prefixes = append(pool.prefixes, ipNet)
+	}
+	if len(pool.prefixes) == 0 {
+		return fmt.Errorf("subnet pool %s has no prefixes", id)
+	}
+	pool.defaultPrefixLength = defaultPrefixLength
+	n.subnetPools[id] = pool
+	return nil
+}
+
+// allocateSubnet returns the CIDR of a new subnet from the subnet pool
+// with the given ID. If cidr is given, it must be free in the pool;
+// otherwise the first free range with the given prefix length is
+// allocated.
+func (n *Neutron) allocateSubnet(poolId string, ipVersion int, cidr string, prefixLength int) (string, error) {
+	pool, ok := n.subnetPools[poolId]
+	if !ok {
+		return "", testservices.NewSubnetPoolNotFoundError(poolId)
+	}
+	if ipVersion != pool.ipVersion {
+		return "", testservices.NewNeutronInvalidInputError(fmt.Sprintf("ip_version %d does not match the subnet pool %s", ipVersion, poolId))
+	}
+	var used []ipRange
+	for _, subnet := range n.subnets {
+		if subnet.SubnetPoolId != poolId {
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(subnet.Cidr); err == nil {
+			used = append(used, cidrRange(ipNet))
+		}
+	}
+	overlapsUsed := func(r ipRange) *ipRange {
+		for i := range used {
+			if used[i].overlaps(r) {
+				return &used[i]
+			}
+		}
+		return nil
+	}
+	if cidr != "" {
+		ipNet, _, err := parseSubnetCIDR(cidr)
+		if err != nil {
+			return "", err
+		}
+		requested := cidrRange(ipNet)
+		for _, prefix := range pool.prefixes {
+			if cidrRange(prefix).contains(requested) && overlapsUsed(requested) == nil {
+				return ipNet.String(), nil
+			}
+		}
+		return "", testservices.NewSubnetAllocationError("Cannot allocate requested subnet from the available set of prefixes")
+	}
+	if prefixLength == 0 {
+		prefixLength = pool.defaultPrefixLength
+	}
+	bits := 8 * net.IPv4len
+	if ipVersion == 6 {
+		bits = 8 * net.IPv6len
+	}
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-prefixLength))
+	for _, prefix := range pool.prefixes {
+		ones, _ := prefix.Mask.Size()
+		if prefixLength < ones || prefixLength > bits {
+			continue
+		}
+		available := cidrRange(prefix)
+		start := new(big.Int).Set(available.start)
+		for start.Cmp(available.end) <= 0 {
+			candidate := ipRange{start, new(big.Int).Sub(new(big.Int).Add(start, size), big.NewInt(1))}
+			blocker := overlapsUsed(candidate)
+			if blocker == nil {
+				return fmt.Sprintf("%s/%d", intToIP(start, ipVersion), prefixLength), nil
+			}
+			// Skip to the first aligned range after the allocated
+			// subnet.
+			next := new(big.Int).Add(blocker.end, big.NewInt(1))
+			next.Add(next, new(big.Int).Sub(size, big.NewInt(1)))
+			start = next.Sub(next, new(big.Int).Mod(next, size))
+		}
+	}
+	return "", testservices.NewSubnetAllocationError(fmt.Sprintf("Insufficient prefix space to allocate subnet size /%d", prefixLength))
+}
+
+// createSubnet creates a subnet as requested through the API,
+// validating its CIDR and addresses.
+func (n *Neutron) createSubnet(opts neutron.SubnetOptsV2) (*neutron.SubnetV2, error) {
+	if err := n.ProcessFunctionHook(n, opts); err != nil {
+		return nil, err
+	}
+	if opts.NetworkId == "" {
+		return nil, testservices.NewInvalidAttributeError("network_id", "'' is not a valid UUID")
+	}
+	network, err := n.network(opts.NetworkId)
+	if err != nil {
+		return nil, err
+	}
+	if opts.IPVersion != 4 && opts.IPVersion != 6 {
+		return nil, testservices.NewInvalidAttributeError("ip_version", fmt.Sprintf("%d is not in [4, 6]", opts.IPVersion))
+	}
+	cidr := opts.Cidr
+	switch {
+	case opts.SubnetPoolId != "":
+		if cidr, err = n.allocateSubnet(opts.SubnetPoolId, opts.IPVersion, cidr, opts.PrefixLength); err != nil {
+			return nil, err
+		}
+	case cidr == "":
+		return nil, testservices.NewBadRequestError("Bad subnets request: a subnetpool must be specified in the absence of a cidr.")
+	}
+	ipNet, version, err := parseSubnetCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if version != opts.IPVersion {
+		return nil, testservices.NewNeutronInvalidInputError(fmt.Sprintf("Cidr %s does not match ip_version %d", cidr, opts.IPVersion))
+	}
+	for _, other := range n.subnets {
+		if other.NetworkId != network.Id {
+			continue
+		}
+		if _, otherNet, err := net.ParseCIDR(other.Cidr); err == nil && cidrRange(otherNet).overlaps(cidrRange(ipNet)) {
+			return nil, testservices.NewOverlappingSubnetError(ipNet.String(), network.Id)
+		}
+	}
+	enableDHCP := opts.EnableDHCP == nil || *opts.EnableDHCP
+	if err := validateIPv6Modes(ipNet, version, opts, enableDHCP); err != nil {
+		return nil, err
+	}
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	subnet := neutron.SubnetV2{
+		Id:              id,
+		NetworkId:       network.Id,
+		Name:            opts.Name,
+		Description:     opts.Description,
+		Cidr:            ipNet.String(),
+		IPVersion:       version,
+		EnableDHCP:      enableDHCP,
+		DNSNameservers:  []string{},
+		HostRoutes:      []neutron.HostRouteV2{},
+		IPv6AddressMode: opts.IPv6AddressMode,
+		IPv6RAMode:      opts.IPv6RAMode,
+		SubnetPoolId:    opts.SubnetPoolId,
+	}
+	if !opts.DisableGateway && opts.GatewayIP == "" {
+		// The gateway is the first address of the subnet by default.
+		opts.GatewayIP = intToIP(new(big.Int).Add(cidrRange(ipNet).start, big.NewInt(1)), version).String()
+	}
+	if err := configureSubnet(&subnet, ipNet, opts, true); err != nil {
+		return nil, err
+	}
+	if err := n.addSubnet(subnet); err != nil {
+		return nil, err
+	}
+	network.SubnetIds = append(network.SubnetIds, subnet.Id)
+	if err := n.neutronModel.UpdateNetwork(*network); err != nil {
+		return nil, err
+	}
+	return n.subnet(subnet.Id)
+}
+
+// validateIPv6Modes checks the IPv6 address and router advertisement
+// modes requested for a new subnet.
+func validateIPv6Modes(ipNet *net.IPNet, version int, opts neutron.SubnetOptsV2, enableDHCP bool) error {
+	if opts.IPv6AddressMode == "" && opts.IPv6RAMode == "" {
+		return nil
+	}
+	if version != 6 {
+		return testservices.NewNeutronInvalidInputError("ipv6_ra_mode or ipv6_address_mode cannot be set when ip_version is 4")
+	}
+	for attribute, mode := range map[string]string{"ipv6_address_mode": opts.IPv6AddressMode, "ipv6_ra_mode": opts.IPv6RAMode} {
+		switch mode {
+		case "", neutron.IPv6ModeSLAAC, neutron.IPv6ModeDHCPv6Stateful, neutron.IPv6ModeDHCPv6Stateless:
+		default:
+			return testservices.NewInvalidAttributeError(attribute, fmt.Sprintf("'%s' is not in ['dhcpv6-stateful', 'dhcpv6-stateless', 'slaac']", mode))
+		}
+	}
+	if opts.IPv6AddressMode != "" && opts.IPv6RAMode != "" && opts.IPv6AddressMode != opts.IPv6RAMode {
+		return testservices.NewNeutronInvalidInputError(fmt.Sprintf(
+			"ipv6_ra_mode set to '%s' with ipv6_address_mode set to '%s' is not valid. If both attributes are set, they must be the same value",
+			opts.IPv6RAMode, opts.IPv6AddressMode))
+	}
+	if !enableDHCP {
+		return testservices.NewNeutronInvalidInputError("ipv6_ra_mode or ipv6_address_mode cannot be set when enable_dhcp is set to False")
+	}
+	if ones, _ := ipNet.Mask.Size(); ones != 64 &&
+		(opts.IPv6AddressMode == neutron.IPv6ModeSLAAC || opts.IPv6AddressMode == neutron.IPv6ModeDHCPv6Stateless) {
+		return testservices.NewNeutronInvalidInputError(fmt.Sprintf(
+			"Invalid CIDR %s for IPv6 address mode. OpenStack uses the EUI-64 address format, which requires the prefix to be /64", ipNet))
+	}
+	return nil
+}
+
+// maxDNSNameservers is the largest number of DNS nameservers a subnet
+// may have.
+const maxDNSNameservers = 5
+
+// configureSubnet validates and sets the gateway, allocation pools,
+// DNS nameservers and host routes of subnet given in opts. When
+// creating, the allocation pools default to all the addresses of the
+// subnet but the gateway.
+func configureSubnet(subnet *neutron.SubnetV2, ipNet *net.IPNet, opts neutron.SubnetOptsV2, creating bool) error {
+	version := subnet.IPVersion
+	subnetRange := cidrRange(ipNet)
+	switch {
+	case opts.DisableGateway:
+		subnet.GatewayIP = ""
+	case opts.GatewayIP != "":
+		ip, err := parseAddress(opts.GatewayIP, version)
+		if err != nil {
+			return testservices.NewInvalidAttributeError("gateway_ip", err.Error())
+		}
+		subnet.GatewayIP = ip.String()
+	}
+	if len(opts.AllocationPools) > 0 {
+		var ranges []ipRange
+		var pools []interface{}
+		for _, pool := range opts.AllocationPools {
+			name := pool.Start + "-" + pool.End
+			start, err := parseAddress(pool.Start, version)
+			if err != nil {
+				return testservices.NewInvalidAllocationPoolError(name)
+			}
+			end, err := parseAddress(pool.End, version)
+			if err != nil {
+				return testservices.NewInvalidAllocationPoolError(name)
+			}
+			r := ipRange{ipToInt(start), ipToInt(end)}
+			if r.start.Cmp(r.end) > 0 {
+				return testservices.NewInvalidAllocationPoolError(name)
+			}
+			if !subnetRange.contains(r) {
+				return testservices.NewOutOfBoundsAllocationPoolError(name, ipNet.String())
+			}
+			for i, other := range ranges {
+				if other.overlaps(r) {
+					otherPool := opts.AllocationPools[i]
+					return testservices.NewOverlappingAllocationPoolsError(otherPool.Start+"-"+otherPool.End, name, ipNet.String())
+				}
+			}
+			ranges = append(ranges, r)
+			pools = append(pools, neutron.AllocationPoolV2{Start: start.String(), End: end.String()})
+		}
+		subnet.AllocationPools = pools
+	} else if creating {
+		subnet.AllocationPools = defaultAllocationPools(subnetRange, subnet.GatewayIP, version)
+	}
+	if subnet.GatewayIP != "" {
+		gateway := ipToInt(net.ParseIP(subnet.GatewayIP))
+		for _, pool := range subnet.Pools() {
+			r := ipRange{ipToInt(net.ParseIP(pool.Start)), ipToInt(net.ParseIP(pool.End))}
+			if r.contains(ipRange{gateway, gateway}) {
+				return testservices.NewGatewayConflictWithAllocationPoolsError(subnet.GatewayIP, pool.Start+"-"+pool.End)
+			}
+		}
+	}
+	if len(opts.DNSNameservers) > 0 {
+		if len(opts.DNSNameservers) > maxDNSNameservers {
+			return testservices.NewBadRequestError(fmt.Sprintf(
+				"Unable to complete operation for %s. The number of DNS nameservers exceeds the limit %d.", subnet.Id, maxDNSNameservers))
+		}
+		seen := make(map[string]bool)
+		var nameservers []string
+		for _, nameserver := range opts.DNSNameservers {
+			if net.ParseIP(nameserver) == nil {
+				return testservices.NewInvalidAttributeError("dns_nameservers", fmt.Sprintf("'%s' is not a valid nameserver", nameserver))
+			}
+			if seen[nameserver] {
+				return testservices.NewInvalidAttributeError("dns_nameservers", fmt.Sprintf("Duplicate nameserver '%s'", nameserver))
+			}
+			seen[nameserver] = true
+			nameservers = append(nameservers, nameserver)
+		}
+		subnet.DNSNameservers = nameservers
+	}
+	if len(opts.HostRoutes) > 0 {
+		var routes []neutron.HostRouteV2
+		for _, route := range opts.HostRoutes {
+			destination, destinationVersion, err := parseSubnetCIDR(route.Destination)
+			if err != nil || destinationVersion != version {
+				return testservices.NewInvalidAttributeError("host_routes", fmt.Sprintf("'%s' is not a valid destination", route.Destination))
+			}
+			nextHop, err := parseAddress(route.NextHop, version)
+			if err != nil {
+				return testservices.NewInvalidAttributeError("host_routes", fmt.Sprintf("'%s' is not a valid nexthop", route.NextHop))
+			}
+			routes = append(routes, neutron.HostRouteV2{Destination: destination.String(), NextHop: nextHop.String()})
+		}
+		subnet.HostRoutes = routes
+	}
+	return nil
+}
+
+// defaultAllocationPools returns the pools of addresses of a subnet
+// allocated to ports by default: all of them but the network address,
+// the gateway and, for IPv4, the broadcast address.
+func defaultAllocationPools(subnetRange ipRange, gatewayIP string, version int) []interface{} {
+	first := new(big.Int).Add(subnetRange.start, big.NewInt(1))
+	last := new(big.Int).Set(subnetRange.end)
+	if version == 4 {
+		last.Sub(last, big.NewInt(1))
+	}
+	ranges := []ipRange{{first, last}}
+	if gatewayIP != "" {
+		gateway := ipToInt(net.ParseIP(gatewayIP))
+		if (ipRange{first, last}).contains(ipRange{gateway, gateway}) {
+			ranges = []ipRange{
+				{first, new(big.Int).Sub(gateway, big.NewInt(1))},
+				{new(big.Int).Add(gateway, big.NewInt(1)), last},
+			}
+		}
+	}
+	pools := []interface{}{}
+	for _, r := range ranges {
+		if r.start.Cmp(r.end) > 0 {
+			continue
+		}
+		pools = append(pools, neutron.AllocationPoolV2{
+			Start: intToIP(r.start, version).String(),
+			End:   intToIP(r.end, version).String(),
+		})
+	}
+	return pools
+}
+
+// updateSubnet changes the attributes of an existing subnet. Only the
+// name, description, gateway, DHCP, DNS nameservers, host routes and
+// allocation pools can be changed.
+func (n *Neutron) updateSubnet(subnetId string, opts neutron.SubnetOptsV2) (*neutron.SubnetV2, error) {
+	if err := n.ProcessFunctionHook(n, subnetId, opts); err != nil {
+		return nil, err
+	}
+	subnet, err := n.subnet(subnetId)
+	if err != nil {
+		return nil, err
+	}
+	readOnly := map[string]bool{
+		"network_id":        opts.NetworkId != "",
+		"ip_version":        opts.IPVersion != 0,
+		"cidr":              opts.Cidr != "",
+		"subnetpool_id":     opts.SubnetPoolId != "",
+		"prefixlen":         opts.PrefixLength != 0,
+		"ipv6_address_mode": opts.IPv6AddressMode != "",
+		"ipv6_ra_mode":      opts.IPv6RAMode != "",
+	}
+	for attribute, set := range readOnly {
+		if set {
+			return nil, testservices.NewReadOnlyAttributeError(attribute)
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(subnet.Cidr)
+	if err != nil {
+		return nil, err
+	}
+	if opts.EnableDHCP != nil {
+		if !*opts.EnableDHCP && (subnet.IPv6AddressMode != "" || subnet.IPv6RAMode != "") {
+			return nil, testservices.NewNeutronInvalidInputError("ipv6_ra_mode or ipv6_address_mode cannot be set when enable_dhcp is set to False")
+		}
+		subnet.EnableDHCP = *opts.EnableDHCP
+	}
+	if opts.Name != "" {
+		subnet.Name = opts.Name
+	}
+	if opts.Description != "" {
+		subnet.Description = opts.Description
+	}
+	if err := configureSubnet(subnet, ipNet, opts, false); err != nil {
+		return nil, err
+	}
+	n.subnets[subnet.Id] = *subnet
+	return subnet, nil
+}
+
+// deleteSubnet deletes an existing subnet, provided no port has an
+// address from it, and removes it from its network.
+func (n *Neutron) deleteSubnet(subnetId string) error {
+	if err := n.ProcessFunctionHook(n, subnetId); err != nil {
+		return err
+	}
+	subnet, err := n.subnet(subnetId)
+	if err != nil {
+		return err
+	}
+	for _, port := range n.neutronModel.AllPorts() {
+		for _, fixedIP := range port.FixedIPs {
+			if fixedIP.SubnetID == subnetId {
+				return testservices.NewSubnetInUseError(subnetId)
+			}
+		}
+	}
+	if err := n.removeSubnet(subnetId); err != nil {
+		return err
+	}
+	network, err := n.neutronModel.Network(subnet.NetworkId)
+	if err != nil {
+		// The subnet's network has already gone.
+		return nil
+	}
+	var subnetIds []string
+	for _, id := range network.SubnetIds {
+		if id != subnetId {
+			subnetIds = append(subnetIds, id)
+		}
+	}
+	network.SubnetIds = subnetIds
+	return n.neutronModel.UpdateNetwork(*network)
+}
+
+// router retrieves an existing router by ID.
+func (n *Neutron) router(routerId string) (*neutron.RouterV2, error) {
+	if err := n.ProcessFunctionHook(n, routerId); err != nil {
+		return nil, err
+	}
+	router, ok := n.routers[routerId]
+	if !ok {
+		return nil, testservices.NewRouterNotFoundError(routerId)
+	}
+	return &router, nil
+}
+
+// allRouters returns a list of all existing routers.
+func (n *Neutron) allRouters() (routers []neutron.RouterV2) {
+	for _, router := range n.routers {
+		routers = append(routers, router)
+	}
+	return routers
+}
+
+// addRouter creates a new router.
+func (n *Neutron) addRouter(router neutron.RouterV2) error {
+	if err := n.ProcessFunctionHook(n, router); err != nil {
+		return err
+	}
+	if _, err := n.router(router.Id); err == nil {
+		return testservices.NewRouterAlreadyExistsError(router.Id)
+	}
+	n.routers[router.Id] = router
+	return nil
+}
+
+// routerPorts returns the ports of a router with the given device
+// owner.
+func (n *Neutron) routerPorts(routerId, deviceOwner string) []neutron.PortV2 {
+	var ports []neutron.PortV2
+	for _, port := range n.neutronModel.AllPorts() {
+		if port.DeviceId == routerId && port.DeviceOwner == deviceOwner {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// createRouter creates a router, setting its gateway if one is given.
+func (n *Neutron) createRouter(opts neutron.RouterOptsV2) (*neutron.RouterV2, error) {
+	if err := n.ProcessFunctionHook(n, opts); err != nil {
+		return nil, err
+	}
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	router := neutron.RouterV2{
+		Id:           id,
+		Name:         opts.Name,
+		Description:  opts.Description,
+		TenantId:     n.TenantId,
+		AdminStateUp: true,
+		Status:       "ACTIVE",
+		Routes:       []neutron.HostRouteV2{},
+	}
+	if opts.AdminStateUp != nil {
+		router.AdminStateUp = *opts.AdminStateUp
+	}
+	// A new router has no interfaces, so no next hop can be reached.
+	if opts.Routes != nil {
+		if err := n.validateRoutes(&router, *opts.Routes); err != nil {
+			return nil, err
+		}
+	}
+	if err := n.addRouter(router); err != nil {
+		return nil, err
+	}
+	if info := opts.ExternalGatewayInfo; info != nil && info.NetworkId != "" {
+		if err := n.setRouterGateway(&router, *info); err != nil {
+			delete(n.routers, router.Id)
+			return nil, err
+		}
+		n.routers[router.Id] = router
+	}
+	return &router, nil
+}
+
+// updateRouter changes the attributes of an existing router. A
+// gateway without a network clears the gateway of the router, and
+// non-nil routes replace its extra routes.
+func (n *Neutron) updateRouter(routerId string, opts neutron.RouterOptsV2) (*neutron.RouterV2, error) {
+	if err := n.ProcessFunctionHook(n, routerId, opts); err != nil {
+		return nil, err
+	}
+	router, err := n.router(routerId)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Routes != nil {
+		if err := n.validateRoutes(router, *opts.Routes); err != nil {
+			return nil, err
+		}
+		router.Routes = append([]neutron.HostRouteV2{}, *opts.Routes...)
+	}
+	if info := opts.ExternalGatewayInfo; info != nil {
+		if info.NetworkId == "" {
+			err = n.clearRouterGateway(router)
+		} else {
+			err = n.setRouterGateway(router, *info)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.Name != "" {
+		router.Name = opts.Name
+	}
+	if opts.Description != "" {
+		router.Description = opts.Description
+	}
+	if opts.AdminStateUp != nil {
+		router.AdminStateUp = *opts.AdminStateUp
+	}
+	n.routers[router.Id] = *router
+	return router, nil
+}
+
+// deleteRouter deletes an existing router and its gateway, provided it
+// has no interfaces left.
+func (n *Neutron) deleteRouter(routerId string) error {
+	if err := n.ProcessFunctionHook(n, routerId); err != nil {
+		return err
+	}
+	router, err := n.router(routerId)
+	if err != nil {
+		return err
+	}
+	if len(n.routerPorts(routerId, neutron.DeviceOwnerRouterInterface)) > 0 {
+		return testservices.NewRouterInUseError(routerId)
+	}
+	if err := n.clearRouterGateway(router); err != nil {
+		return err
+	}
+	delete(n.routers, routerId)
+	return nil
+}
+
+// setRouterGateway sets the gateway of a router on an external
+// network, replacing any existing gateway. The gateway is a port on
+// the external network, with the requested addresses or addresses
+// allocated from its subnets.
+func (n *Neutron) setRouterGateway(router *neutron.RouterV2, info neutron.ExternalGatewayInfoV2) error {
+	network, err := n.network(info.NetworkId)
+	if err != nil {
+		return err
+	}
+	if !network.External {
+		return testservices.NewBadRouterRequestError(fmt.Sprintf("Network %s is not an external network", network.Id))
+	}
+	snat := true
+	if info.EnableSNAT != nil {
+		snat = *info.EnableSNAT
+	}
+	current := router.ExternalGatewayInfo
+	if current != nil && current.NetworkId == network.Id && len(info.ExternalFixedIPs) == 0 {
+		// Only SNAT is changing.
+		current.EnableSNAT = &snat
+		return nil
+	}
+	if err := n.clearRouterGateway(router); err != nil {
+		return err
+	}
+	fixedIPs, err := n.allocateFixedIPs(network, info.ExternalFixedIPs)
+	if err != nil {
+		return err
+	}
+	id, err := newUUID()
+	if err != nil {
+		return err
+	}
+	port := neutron.PortV2{
+		Id:           id,
+		AdminStateUp: true,
+		DeviceId:     router.Id,
+		DeviceOwner:  neutron.DeviceOwnerRouterGateway,
+		FixedIPs:     fixedIPs,
+		NetworkId:    network.Id,
+		Status:       "ACTIVE",
+	}
+	if err := n.addPort(port); err != nil {
+		return err
+	}
+	router.ExternalGatewayInfo = &neutron.ExternalGatewayInfoV2{
+		NetworkId:        network.Id,
+		EnableSNAT:       &snat,
+		ExternalFixedIPs: fixedIPs,
+	}
+	return nil
+}
+
+// clearRouterGateway removes the gateway of a router, provided no
+// floating IP is reached through it.
+func (n *Neutron) clearRouterGateway(router *neutron.RouterV2) error {
+	if router.ExternalGatewayInfo == nil {
+		return nil
+	}
+	networkId := router.ExternalGatewayInfo.NetworkId
+	for _, fip := range n.neutronModel.AllFloatingIPs() {
+		if fip.FloatingNetworkId != networkId || fip.PortId == "" {
+			continue
+		}
+		if r, _ := n.floatingIPRouter(networkId, fip.PortId); r != nil && r.Id == router.Id {
+			return testservices.NewRouterExternalGatewayInUseByFloatingIPError(router.Id, networkId)
+		}
+	}
+	for _, port := range n.routerPorts(router.Id, neutron.DeviceOwnerRouterGateway) {
+		if err := n.removePort(port.Id); err != nil {
+			return err
+		}
+	}
+	router.ExternalGatewayInfo = nil
+	return nil
+}
+
+// addRouterInterface adds an interface to a router, either on a
+// subnet, with the gateway IP address of the subnet, or on an existing
+// port.
+func (n *Neutron) addRouterInterface(routerId string, opts neutron.RouterInterfaceOptsV2) (*neutron.RouterInterfaceV2, error) {
+	if err := n.ProcessFunctionHook(n, routerId, opts); err != nil {
+		return nil, err
+	}
+	router, err := n.router(routerId)
+	if err != nil {
+		return nil, err
+	}
+	var port *neutron.PortV2
+	switch {
+	case opts.SubnetId != "" && opts.PortId != "":
+		return nil, testservices.NewBadRouterRequestError("Cannot specify both subnet-id and port-id")
+	case opts.PortId != "":
+		port, err = n.port(opts.PortId)
+		if err != nil {
+			return nil, err
+		}
+		if port.DeviceId != "" {
+			return nil, testservices.NewPortHasDeviceError(port.Id, port.NetworkId, port.DeviceId)
+		}
+		if len(port.FixedIPs) == 0 {
+			return nil, testservices.NewBadRouterRequestError("Router port must have at least one fixed IP")
+		}
+		for _, fixedIP := range port.FixedIPs {
+			if err := n.checkRouterInterfaceSubnet(router.Id, fixedIP.SubnetID); err != nil {
+				return nil, err
+			}
+		}
+		port.DeviceId = router.Id
+		port.DeviceOwner = neutron.DeviceOwnerRouterInterface
+		if err := n.neutronModel.UpdatePort(*port); err != nil {
+			return nil, err
+		}
+	case opts.SubnetId != "":
+		subnet, err := n.subnet(opts.SubnetId)
+		if err != nil {
+			return nil, err
+		}
+		if subnet.GatewayIP == "" {
+			return nil, testservices.NewBadRouterRequestError("Subnet for router interface must have a gateway IP")
+		}
+		if err := n.checkRouterInterfaceSubnet(router.Id, subnet.Id); err != nil {
+			return nil, err
+		}
+		if n.addressInUse(subnet.GatewayIP) {
+			return nil, testservices.NewIPAddressInUseError(subnet.GatewayIP, subnet.NetworkId)
+		}
+		id, err := newUUID()
+		if err != nil {
+			return nil, err
+		}
+		port = &neutron.PortV2{
+			Id:           id,
+			AdminStateUp: true,
+			DeviceId:     router.Id,
+			DeviceOwner:  neutron.DeviceOwnerRouterInterface,
+			FixedIPs:     []neutron.PortFixedIPsV2{{IPAddress: subnet.GatewayIP, SubnetID: subnet.Id}},
+			NetworkId:    subnet.NetworkId,
+			Status:       "ACTIVE",
+			TenantId:     router.TenantId,
+		}
+		if err := n.addPort(*port); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, testservices.NewBadRouterRequestError("Either subnet_id or port_id must be specified")
+	}
+	return routerInterface(router, port), nil
+}
+
+// checkRouterInterfaceSubnet checks a router can have an interface on
+// the given subnet, which must not overlap the subnets of its other
+// interfaces.
+func (n *Neutron) checkRouterInterfaceSubnet(routerId, subnetId string) error {
+	subnet, err := n.subnet(subnetId)
+	if err != nil {
+		return err
+	}
+	_, ipNet, err := net.ParseCIDR(subnet.Cidr)
+	if err != nil {
+		return err
+	}
+	for _, port := range n.routerPorts(routerId, neutron.DeviceOwnerRouterInterface) {
+		for _, fixedIP := range port.FixedIPs {
+			if fixedIP.SubnetID == subnet.Id {
+				return testservices.NewBadRouterRequestError(fmt.Sprintf("Router already has a port on subnet %s", subnet.Id))
+			}
+			other, err := n.subnet(fixedIP.SubnetID)
+			if err != nil {
+				continue
+			}
+			_, otherNet, err := net.ParseCIDR(other.Cidr)
+			if err != nil {
+				continue
+			}
+			if cidrRange(ipNet).overlaps(cidrRange(otherNet)) {
+				return testservices.NewBadRouterRequestError(fmt.Sprintf("Cidr %s of subnet %s overlaps with cidr %s of subnet %s", subnet.Cidr, subnet.Id, other.Cidr, other.Id))
+			}
+		}
+	}
+	return nil
+}
+
+// removeRouterInterface removes the interface on the given subnet or
+// port from a router, and deletes its port. An interface cannot be
+// removed while the next hop of an extra route, or a port with a
+// floating IP, is on its subnets.
+func (n *Neutron) removeRouterInterface(routerId string, opts neutron.RouterInterfaceOptsV2) (*neutron.RouterInterfaceV2, error) {
+	if err := n.ProcessFunctionHook(n, routerId, opts); err != nil {
+		return nil, err
+	}
+	router, err := n.router(routerId)
+	if err != nil {
+		return nil, err
+	}
+	var port *neutron.PortV2
+	switch {
+	case opts.PortId != "":
+		port, err = n.port(opts.PortId)
+		if err != nil || port.DeviceId != router.Id || port.DeviceOwner != neutron.DeviceOwnerRouterInterface {
+			return nil, testservices.NewRouterInterfaceNotFoundError(router.Id, opts.PortId)
+		}
+		if opts.SubnetId != "" && !portOnSubnet(*port, opts.SubnetId) {
+			return nil, testservices.NewBadRouterRequestError(fmt.Sprintf("Subnet %s is not on port %s", opts.SubnetId, port.Id))
+		}
+	case opts.SubnetId != "":
+		if _, err := n.subnet(opts.SubnetId); err != nil {
+			return nil, err
+		}
+		for _, p := range n.routerPorts(router.Id, neutron.DeviceOwnerRouterInterface) {
+			if portOnSubnet(p, opts.SubnetId) {
+				p := p
+				port = &p
+				break
+			}
+		}
+		if port == nil {
+			return nil, testservices.NewRouterInterfaceNotFoundForSubnetError(router.Id, opts.SubnetId)
+		}
+	default:
+		return nil, testservices.NewBadRouterRequestError("Either subnet_id or port_id must be specified")
+	}
+	for _, fixedIP := range port.FixedIPs {
+		subnet, err := n.subnet(fixedIP.SubnetID)
+		if err != nil {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(subnet.Cidr)
+		if err != nil {
+			continue
+		}
+		for _, route := range router.Routes {
+			if ipNet.Contains(net.ParseIP(route.NextHop)) {
+				return nil, testservices.NewRouterInterfaceInUseByRouteError(router.Id, subnet.Id)
+			}
+		}
+		if gateway := router.ExternalGatewayInfo; gateway != nil {
+			for _, fip := range n.neutronModel.AllFloatingIPs() {
+				if fip.FloatingNetworkId != gateway.NetworkId || fip.PortId == "" {
+					continue
+				}
+				fipPort, err := n.port(fip.PortId)
+				if err == nil && portOnSubnet(*fipPort, subnet.Id) {
+					return nil, testservices.NewRouterInterfaceInUseByFloatingIPError(router.Id, subnet.Id)
+				}
+			}
+		}
+	}
+	if err := n.removePort(port.Id); err != nil {
+		return nil, err
+	}
+	return routerInterface(router, port), nil
+}
+
+// routerInterface describes the interface of a router on a port.
+func routerInterface(router *neutron.RouterV2, port *neutron.PortV2) *neutron.RouterInterfaceV2 {
+	iface := &neutron.RouterInterfaceV2{
+		Id:        router.Id,
+		TenantId:  router.TenantId,
+		NetworkId: port.NetworkId,
+		PortId:    port.Id,
+		SubnetIds: []string{},
+	}
+	for _, fixedIP := range port.FixedIPs {
+		iface.SubnetIds = append(iface.SubnetIds, fixedIP.SubnetID)
+	}
+	if len(iface.SubnetIds) > 0 {
+		iface.SubnetId = iface.SubnetIds[0]
+	}
+	return iface
+}
+
+// portOnSubnet returns whether a port has an address on the given
+// subnet.
+func portOnSubnet(port neutron.PortV2, subnetId string) bool {
+	for _, fixedIP := range port.FixedIPs {
+		if fixedIP.SubnetID == subnetId {
+			return true
+		}
+	}
+	return false
+}
+
+// addRouterRoutes adds extra routes to a router, ignoring those it
+// already has.
+func (n *Neutron) addRouterRoutes(routerId string, routes []neutron.HostRouteV2) (*neutron.RouterV2, error) {
+	if err := n.ProcessFunctionHook(n, routerId, routes); err != nil {
+		return nil, err
+	}
+	router, err := n.router(routerId)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.validateRoutes(router, routes); err != nil {
+		return nil, err
+	}
+	for _, route := range routes {
+		if !hasRoute(router.Routes, route) {
+			router.Routes = append(router.Routes, route)
+		}
+	}
+	n.routers[router.Id] = *router
+	return router, nil
+}
+
+// removeRouterRoutes removes extra routes from a router, ignoring
+// those it does not have.
+func (n *Neutron) removeRouterRoutes(routerId string, routes []neutron.HostRouteV2) (*neutron.RouterV2, error) {
+	if err := n.ProcessFunctionHook(n, routerId, routes); err != nil {
+		return nil, err
+	}
+	router, err := n.router(routerId)
+	if err != nil {
+		return nil, err
+	}
+	kept := []neutron.HostRouteV2{}
+	for _, route := range router.Routes {
+		if !hasRoute(routes, route) {
+			kept = append(kept, route)
+		}
+	}
+	router.Routes = kept
+	n.routers[router.Id] = *router
+	return router, nil
+}
+
+func hasRoute(routes []neutron.HostRouteV2, route neutron.HostRouteV2) bool {
+	for _, r := range routes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRoutes checks the extra routes of a router, whose next hops
+// must be on the subnets of its interfaces, but not the addresses of
+// the interfaces themselves.
+func (n *Neutron) validateRoutes(router *neutron.RouterV2, routes []neutron.HostRouteV2) error {
+	var subnets []*net.IPNet
+	var addresses []net.IP
+	for _, port := range n.routerPorts(router.Id, neutron.DeviceOwnerRouterInterface) {
+		for _, fixedIP := range port.FixedIPs {
+			addresses = append(addresses, net.ParseIP(fixedIP.IPAddress))
+			subnet, err := n.subnet(fixedIP.SubnetID)
+			if err != nil {
+				continue
+			}
+			if _, ipNet, err := net.ParseCIDR(subnet.Cidr); err == nil {
+				subnets = append(subnets, ipNet)
+			}
+		}
+	}
+	for _, route := range routes {
+		formatted := fmt.Sprintf("{'destination': '%s', 'nexthop': '%s'}", route.Destination, route.NextHop)
+		if _, _, err := net.ParseCIDR(route.Destination); err != nil {
+			return testservices.NewInvalidRoutesError(formatted, "the destination is not a valid CIDR")
+		}
+		nextHop := net.ParseIP(route.NextHop)
+		if nextHop == nil {
+			return testservices.NewInvalidRoutesError(formatted, "the nexthop is not a valid IP address")
+		}
+		for _, address := range addresses {
+			if address.Equal(nextHop) {
+				return testservices.NewInvalidRoutesError(formatted, "the nexthop is used by router")
+			}
+		}
+		connected := false
+		for _, ipNet := range subnets {
+			if ipNet.Contains(nextHop) {
+				connected = true
+				break
+			}
+		}
+		if !connected {
+			return testservices.NewInvalidRoutesError(formatted, "the nexthop is not connected with router")
+		}
+	}
+	return nil
+}
+
+// floatingIPRouter returns the router through which a floating IP on
+// the given external network reaches the given port, and the address
+// of the port it is reached on. The port must have an address on a
+// subnet with an interface on a router whose gateway is on the
+// external network.
+func (n *Neutron) floatingIPRouter(networkId, portId string) (*neutron.RouterV2, string) {
+	port, err := n.neutronModel.Port(portId)
+	if err != nil {
+		return nil, ""
+	}
+	for _, router := range n.routers {
+		if router.ExternalGatewayInfo == nil || router.ExternalGatewayInfo.NetworkId != networkId {
+			continue
+		}
+		for _, iface := range n.routerPorts(router.Id, neutron.DeviceOwnerRouterInterface) {
+			for _, fixedIP := range port.FixedIPs {
+				if portOnSubnet(iface, fixedIP.SubnetID) {
+					router := router
+					return &router, fixedIP.IPAddress
+				}
+			}
+		}
+	}
+	return nil, ""
+}
+
+// reachableFixedIP returns the address of the given port a floating IP
+// on the given external network is associated with. As in neutron,
+// the external network must be reachable from the port through a
+// router.
+func (n *Neutron) reachableFixedIP(networkId, portId string) (string, error) {
+	port, err := n.port(portId)
+	if err != nil {
+		return "", err
+	}
+	router, fixedIP := n.floatingIPRouter(networkId, portId)
+	if router == nil {
+		subnetId := ""
+		if len(port.FixedIPs) > 0 {
+			subnetId = port.FixedIPs[0].SubnetID
+		}
+		return "", testservices.NewExternalNetworkNotReachableError(networkId, subnetId, portId)
+	}
+	return fixedIP, nil
+}
+
+// addressInUse returns whether a port has the given address.
+func (n *Neutron) addressInUse(address string) bool {
+	ip := net.ParseIP(address)
+	for _, port := range n.neutronModel.AllPorts() {
+		for _, fixedIP := range port.FixedIPs {
+			if net.ParseIP(fixedIP.IPAddress).Equal(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allocateFixedIPs returns the addresses of a new port on a network.
+// Requested addresses without a subnet are looked up in the subnets of
+// the network, and requested subnets without an address have a free
+// address allocated from their pools. Without requested addresses, an
+// address is allocated from the first subnet with a free address.
+func (n *Neutron) allocateFixedIPs(network *neutron.NetworkV2, requested []neutron.PortFixedIPsV2) ([]neutron.PortFixedIPsV2, error) {
+	if len(requested) == 0 {
+		for _, subnetId := range network.SubnetIds {
+			subnet, err := n.subnet(subnetId)
+			if err != nil {
+				continue
+			}
+			if address := n.freeAddress(subnet); address != "" {
+				return []neutron.PortFixedIPsV2{{IPAddress: address, SubnetID: subnet.Id}}, nil
+			}
+		}
+		return nil, testservices.NewIPAddressGenerationFailureError(network.Id)
+	}
+	var fixedIPs []neutron.PortFixedIPsV2
+	for _, fixedIP := range requested {
+		var subnet *neutron.SubnetV2
+		for _, subnetId := range network.SubnetIds {
+			s, err := n.subnet(subnetId)
+			if err != nil {
+				continue
+			}
+			_, ipNet, err := net.ParseCIDR(s.Cidr)
+			if err != nil {
+				continue
+			}
+			if s.Id == fixedIP.SubnetID || (fixedIP.SubnetID == "" && ipNet.Contains(net.ParseIP(fixedIP.IPAddress))) {
+				subnet = s
+				break
+			}
+		}
+		if subnet == nil {
+			return nil, testservices.NewNeutronInvalidInputError(fmt.Sprintf("Failed to create port on network %s, because fixed_ips included invalid subnet %s", network.Id, fixedIP.SubnetID))
+		}
+		address := fixedIP.IPAddress
+		if address == "" {
+			if address = n.freeAddress(subnet); address == "" {
+				return nil, testservices.NewIPAddressGenerationFailureError(network.Id)
+			}
+		} else {
+			_, ipNet, _ := net.ParseCIDR(subnet.Cidr)
+			if !ipNet.Contains(net.ParseIP(address)) {
+				return nil, testservices.NewInvalidAttributeError("fixed_ips", fmt.Sprintf("IP address %s is not a valid IP for the specified subnet", address))
+			}
+			if n.addressInUse(address) {
+				return nil, testservices.NewIPAddressInUseError(address, network.Id)
+			}
+		}
+		fixedIPs = append(fixedIPs, neutron.PortFixedIPsV2{IPAddress: address, SubnetID: subnet.Id})
+	}
+	return fixedIPs, nil
+}
+
+// freeAddress returns the first address of the allocation pools of a
+// subnet which no port has, or an empty string if there is none.
+func (n *Neutron) freeAddress(subnet *neutron.SubnetV2) string {
+	_, ipNet, err := net.ParseCIDR(subnet.Cidr)
+	if err != nil {
+		return ""
+	}
+	version := 6
+	if ipNet.IP.To4() != nil {
+		version = 4
+	}
+	pools := subnet.Pools()
+	if len(pools) == 0 {
+		subnet := *subnet
+		subnet.AllocationPools = defaultAllocationPools(cidrRange(ipNet), subnet.GatewayIP, version)
+		pools = subnet.Pools()
+	}
+	for _, pool := range pools {
+		start := ipToInt(net.ParseIP(pool.Start))
+		end := ipToInt(net.ParseIP(pool.End))
+		for i := start; i.Cmp(end) <= 0; i = new(big.Int).Add(i, big.NewInt(1)) {
+			address := intToIP(i, version).String()
+			if !n.addressInUse(address) {
+				return address
+			}
+		}
+	}
+	return ""
+}
+
+// parseSubnetCIDR parses the CIDR of a subnet, which must be its
+// network address, returning it with its IP version.
+func parseSubnetCIDR(cidr string) (*net.IPNet, int, error) {
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, 0, testservices.NewInvalidAttributeError("cidr", fmt.Sprintf("'%s' is not a valid IP subnet", cidr))
+	}
+	if !ip.Equal(ipNet.IP) {
+		return nil, 0, testservices.NewInvalidAttributeError("cidr", fmt.Sprintf("'%s' isn't a recognized IP subnet cidr, '%s' is recommended", cidr, ipNet))
+	}
+	if ones, _ := ipNet.Mask.Size(); ones == 0 {
+		return nil, 0, testservices.NewNeutronInvalidInputError(fmt.Sprintf("%s is not allowed as a subnet cidr", cidr))
+	}
+	if ip.To4() != nil {
+		return ipNet, 4, nil
+	}
+	return ipNet, 6, nil
+}
+
+// parseAddress parses an IP address, which must be of the given IP
+// version.
+func parseAddress(address string, version int) (net.IP, error) {
+	ip := net.ParseIP(address)
+	if ip == nil || (ip.To4() != nil) != (version == 4) {
+		return nil, fmt.Errorf("'%s' is not a valid IPv%d address", address, version)
+	}
+	return ip, nil
+}
+
+// ipRange is an inclusive range of IP addresses, as integers.
+type ipRange struct {
+	start, end *big.Int
+}
+
+func (r ipRange) overlaps(other ipRange) bool {
+	return r.start.Cmp(other.end) <= 0 && other.start.Cmp(r.end) <= 0
+}
+
+func (r ipRange) contains(other ipRange) bool {
+	return r.start.Cmp(other.start) <= 0 && other.end.Cmp(r.end) <= 0
+}
+
+// cidrRange returns the range of addresses of a CIDR.
+func cidrRange(ipNet *net.IPNet) ipRange {
+	start := ipToInt(ipNet.IP)
+	ones, bits := ipNet.Mask.Size()
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	return ipRange{start, new(big.Int).Sub(new(big.Int).Add(start, size), big.NewInt(1))}
+}
+
+func ipToInt(ip net.IP) *big.Int {
+	if ip4 := ip.To4(); ip4 != nil {
+		return new(big.Int).SetBytes(ip4)
+	}
+	return new(big.Int).SetBytes(ip.To16())
+}
+
+func intToIP(i *big.Int, version int) net.IP {
+	size := net.IPv4len
+	if version == 6 {
+		size = net.IPv6len
+	}
+	ip := make(net.IP, size)
+	i.FillBytes(ip)
+	return ip
+}
@@ -23,6 +23,7 @@ const (
 	apiFloatingIPsV2        = "/v2.0/" + neutron.ApiFloatingIPsV2
 	apiNetworksV2           = "/v2.0/" + neutron.ApiNetworksV2
 	apiPortsV2              = "/v2.0/" + neutron.ApiPortsV2
+	apiRoutersV2            = "/v2.0/" + neutron.ApiRoutersV2
 	apiSubnetsV2            = "/v2.0/" + neutron.ApiSubnetsV2
 	apiSecurityGroupsV2     = "/v2.0/" + neutron.ApiSecurityGroupsV2
 	apiSecurityGroupRulesV2 = "/v2.0/" + neutron.ApiSecurityGroupRulesV2
@@ -682,10 +683,16 @@ func (n *Neutron) handleFloatingIPs(w http.ResponseWriter, r *http.Request) erro
 		if !extNetwork.External {
 			return errNotFound
 		}
+		var fixedIP string
+		if req.FIP.PortId != "" {
+			if fixedIP, err = n.reachableFixedIP(extNetwork.Id, req.FIP.PortId); err != nil {
+				return err
+			}
+		}
 		n.nextIPId++
 		addr := fmt.Sprintf("10.0.0.%d", n.nextIPId)
 		nextId := strconv.Itoa(n.nextIPId)
-		fip := neutron.FloatingIPV2{FixedIP: "", Id: nextId, IP: addr, FloatingNetworkId: extNetwork.Id}
+		fip := neutron.FloatingIPV2{FixedIP: fixedIP, Id: nextId, IP: addr, FloatingNetworkId: extNetwork.Id, PortId: req.FIP.PortId}
 		err = n.addFloatingIP(fip)
 		if err != nil {
 			return err
@@ -713,10 +720,10 @@ func (n *Neutron) handleFloatingIPs(w http.ResponseWriter, r *http.Request) erro
 
 // handleNetworks handles the v2/networks HTTP API.
 func (n *Neutron) handleNetworks(w http.ResponseWriter, r *http.Request) error {
+	networkId := path.Base(r.URL.Path)
+	apiFunc := path.Base(apiNetworksV2)
 	switch r.Method {
 	case "GET":
-		networkId := path.Base(r.URL.Path)
-		apiFunc := path.Base(apiNetworksV2)
 		if networkId != apiFunc {
 			network, err := n.network(networkId)
 			if err != nil {
@@ -746,17 +753,90 @@ func (n *Neutron) handleNetworks(w http.ResponseWriter, r *http.Request) error {
 			Network []neutron.NetworkV2 `json:"networks"`
 		}{nets}
 		return sendJSON(http.StatusOK, resp, w, r)
+	case "POST":
+		if networkId != apiFunc {
+			return errNotFound
+		}
+		var req struct {
+			Network neutron.NetworkOptsV2 `json:"network"`
+		}
+		if err := readJSONRequest(r, &req); err != nil {
+			return err
+		}
+		network, err := n.createNetwork(req.Network)
+		if err != nil {
+			return err
+		}
+		resp := struct {
+			Network neutron.NetworkV2 `json:"network"`
+		}{*network}
+		return sendJSON(http.StatusCreated, resp, w, r)
+	case "PUT":
+		if networkId == apiFunc {
+			return errNotFound
+		}
+		if _, err := n.network(networkId); err != nil {
+			return errNotFoundJSON
+		}
+		var req struct {
+			Network neutron.NetworkOptsV2 `json:"network"`
+		}
+		if err := readJSONRequest(r, &req); err != nil {
+			return err
+		}
+		network, err := n.updateNetwork(networkId, req.Network)
+		if err != nil {
+			return err
+		}
+		resp := struct {
+			Network neutron.NetworkV2 `json:"network"`
+		}{*network}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "DELETE":
+		if networkId == apiFunc {
+			return errNotFound
+		}
+		if _, err := n.network(networkId); err != nil {
+			return errNotFoundJSON
+		}
+		if err := n.deleteNetwork(networkId); err != nil {
+			return err
+		}
+		writeResponse(w, http.StatusNoContent, nil)
+		return nil
 	default:
 		return errNotFound
 	}
 }
 
-// handleNetworks handles the v2/subnets HTTP API.
+// subnetRequest is the body of a request creating or updating a
+// subnet. A null gateway IP disables the gateway.
+type subnetRequest struct {
+	neutron.SubnetOptsV2
+	GatewayIP json.RawMessage `json:"gateway_ip"`
+}
+
+// opts returns the options requested.
+func (req subnetRequest) opts() (neutron.SubnetOptsV2, error) {
+	opts := req.SubnetOptsV2
+	switch {
+	case len(req.GatewayIP) == 0:
+	case string(req.GatewayIP) == "null":
+		opts.DisableGateway = true
+	default:
+		if err := json.Unmarshal(req.GatewayIP, &opts.GatewayIP); err != nil {
+			return opts, testservices.NewInvalidAttributeError("gateway_ip", fmt.Sprintf("'%s' is not a valid IP address", req.GatewayIP))
+		}
+	}
+	return opts, nil
+}
+
+// handleSubnets handles the v2/subnets HTTP API.
 func (n *Neutron) handleSubnets(w http.ResponseWriter, r *http.Request) error {
+	subnetId := path.Base(r.URL.Path)
+	apiFunc := path.Base(apiSubnetsV2)
 	switch r.Method {
 	case "GET":
-		subnetId := path.Base(r.URL.Path)
-		apiFunc := path.Base(apiSubnetsV2)
 		if subnetId != apiFunc {
 			subnet, err := n.subnet(subnetId)
 			if err != nil {
@@ -775,11 +855,233 @@ func (n *Neutron) handleSubnets(w http.ResponseWriter, r *http.Request) error {
 			Subnets []neutron.SubnetV2 `json:"subnets"`
 		}{subnets}
 		return sendJSON(http.StatusOK, resp, w, r)
+	case "POST", "PUT":
+		if (r.Method == "POST") != (subnetId == apiFunc) {
+			return errNotFound
+		}
+		if r.Method == "PUT" {
+			if _, err := n.subnet(subnetId); err != nil {
+				return errNotFoundJSON
+			}
+		}
+		var req struct {
+			Subnet subnetRequest `json:"subnet"`
+		}
+		if err := readJSONRequest(r, &req); err != nil {
+			return err
+		}
+		opts, err := req.Subnet.opts()
+		if err != nil {
+			return err
+		}
+		var subnet *neutron.SubnetV2
+		status := http.StatusCreated
+		if r.Method == "POST" {
+			subnet, err = n.createSubnet(opts)
+		} else {
+			subnet, err = n.updateSubnet(subnetId, opts)
+			status = http.StatusOK
+		}
+		if err != nil {
+			return err
+		}
+		resp := struct {
+			Subnet neutron.SubnetV2 `json:"subnet"`
+		}{*subnet}
+		return sendJSON(status, resp, w, r)
+	case "DELETE":
+		if subnetId == apiFunc {
+			return errNotFound
+		}
+		if _, err := n.subnet(subnetId); err != nil {
+			return errNotFoundJSON
+		}
+		if err := n.deleteSubnet(subnetId); err != nil {
+			return err
+		}
+		writeResponse(w, http.StatusNoContent, nil)
+		return nil
 	default:
 		return errNotFound
 	}
 }
 
+// routerRequest is the body of a request creating or updating a
+// router. A null gateway clears the gateway of the router.
+type routerRequest struct {
+	neutron.RouterOptsV2
+	ExternalGatewayInfo json.RawMessage `json:"external_gateway_info"`
+}
+
+// opts returns the options requested. A gateway without a network
+// clears the gateway.
+func (req routerRequest) opts() (neutron.RouterOptsV2, error) {
+	opts := req.RouterOptsV2
+	switch {
+	case len(req.ExternalGatewayInfo) == 0:
+	case string(req.ExternalGatewayInfo) == "null":
+		opts.ExternalGatewayInfo = &neutron.ExternalGatewayInfoV2{}
+	default:
+		var info neutron.ExternalGatewayInfoV2
+		if err := json.Unmarshal(req.ExternalGatewayInfo, &info); err != nil {
+			return opts, testservices.NewInvalidAttributeError("external_gateway_info", err.Error())
+		}
+		opts.ExternalGatewayInfo = &info
+	}
+	return opts, nil
+}
+
+// handleRouters handles the v2/routers HTTP API, including the
+// actions on a router, which are PUT requests to
+// v2/routers/<id>/<action>.
+func (n *Neutron) handleRouters(w http.ResponseWriter, r *http.Request) error {
+	var routerId, action string
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, apiRoutersV2), "/"), "/")
+	switch len(parts) {
+	case 1:
+		routerId = parts[0]
+	case 2:
+		routerId, action = parts[0], parts[1]
+	default:
+		return errNotFound
+	}
+	if action != "" && r.Method != "PUT" {
+		return errNotFound
+	}
+	switch r.Method {
+	case "GET":
+		if routerId != "" {
+			router, err := n.router(routerId)
+			if err != nil {
+				return errNotFoundJSON
+			}
+			resp := struct {
+				Router neutron.RouterV2 `json:"router"`
+			}{*router}
+			return sendJSON(http.StatusOK, resp, w, r)
+		}
+		routers := n.allRouters()
+		if len(routers) == 0 {
+			routers = []neutron.RouterV2{}
+		}
+		resp := struct {
+			Routers []neutron.RouterV2 `json:"routers"`
+		}{routers}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "POST":
+		if routerId != "" {
+			return errNotFound
+		}
+		var req struct {
+			Router routerRequest `json:"router"`
+		}
+		if err := readJSONRequest(r, &req); err != nil {
+			return err
+		}
+		opts, err := req.Router.opts()
+		if err != nil {
+			return err
+		}
+		router, err := n.createRouter(opts)
+		if err != nil {
+			return err
+		}
+		resp := struct {
+			Router neutron.RouterV2 `json:"router"`
+		}{*router}
+		return sendJSON(http.StatusCreated, resp, w, r)
+	case "PUT":
+		if routerId == "" {
+			return errNotFound
+		}
+		if _, err := n.router(routerId); err != nil {
+			return errNotFoundJSON
+		}
+		switch action {
+		case "add_router_interface", "remove_router_interface":
+			var opts neutron.RouterInterfaceOptsV2
+			if err := readJSONRequest(r, &opts); err != nil {
+				return err
+			}
+			var iface *neutron.RouterInterfaceV2
+			var err error
+			if action == "add_router_interface" {
+				iface, err = n.addRouterInterface(routerId, opts)
+			} else {
+				iface, err = n.removeRouterInterface(routerId, opts)
+			}
+			if err != nil {
+				return err
+			}
+			return sendJSON(http.StatusOK, iface, w, r)
+		}
+		var router *neutron.RouterV2
+		switch action {
+		case "":
+			var req struct {
+				Router routerRequest `json:"router"`
+			}
+			if err := readJSONRequest(r, &req); err != nil {
+				return err
+			}
+			opts, err := req.Router.opts()
+			if err != nil {
+				return err
+			}
+			if router, err = n.updateRouter(routerId, opts); err != nil {
+				return err
+			}
+		case "add_extraroutes", "remove_extraroutes":
+			var req struct {
+				Router struct {
+					Routes []neutron.HostRouteV2 `json:"routes"`
+				} `json:"router"`
+			}
+			if err := readJSONRequest(r, &req); err != nil {
+				return err
+			}
+			var err error
+			if action == "add_extraroutes" {
+				router, err = n.addRouterRoutes(routerId, req.Router.Routes)
+			} else {
+				router, err = n.removeRouterRoutes(routerId, req.Router.Routes)
+			}
+			if err != nil {
+				return err
+			}
+		default:
+			return errNotFound
+		}
+		resp := struct {
+			Router neutron.RouterV2 `json:"router"`
+		}{*router}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "DELETE":
+		if routerId == "" {
+			return errNotFound
+		}
+		if _, err := n.router(routerId); err != nil {
+			return errNotFoundJSON
+		}
+		if err := n.deleteRouter(routerId); err != nil {
+			return err
+		}
+		writeResponse(w, http.StatusNoContent, nil)
+		return nil
+	default:
+		return errNotFound
+	}
+}
+
+// readJSONRequest decodes the JSON body of a request into req.
+func readJSONRequest(r *http.Request, req interface{}) error {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil || len(body) == 0 {
+		return errBadRequestIncorrect
+	}
+	return json.Unmarshal(body, req)
+}
+
 // SetupHTTP attaches all the needed handlers to provide the HTTP API.
 func (n *Neutron) SetupHTTP(mux *http.ServeMux) {
 	handlers := map[string]http.Handler{
@@ -797,6 +1099,8 @@ func (n *Neutron) SetupHTTP(mux *http.ServeMux) {
 		"/$v/networks/":             n.handler((*Neutron).handleNetworks),
 		"/$v/subnets":               n.handler((*Neutron).handleSubnets),
 		"/$v/subnets/":              n.handler((*Neutron).handleSubnets),
+		"/$v/routers":               n.handler((*Neutron).handleRouters),
+		"/$v/routers/":              n.handler((*Neutron).handleRouters),
 	}
 	for path, h := range handlers {
 		path = strings.Replace(path, "$v", n.VersionPath, 1)
@@ -295,6 +295,11 @@ func (s *NeutronHTTPSuite) simpleTests() []SimpleTest {
 			url:    neutron.ApiNetworksV2 + "/invalid",
 			expect: errNotFound,
 		},
+		{
+			method: "POST",
+			url:    neutron.ApiNetworksV2,
+			expect: errBadRequestIncorrect,
+		},
 		{
 			method: "PUT",
 			url:    neutron.ApiNetworksV2,
@@ -303,7 +308,7 @@ func (s *NeutronHTTPSuite) simpleTests() []SimpleTest {
 		{
 			method: "PUT",
 			url:    neutron.ApiNetworksV2 + "/invalid",
-			expect: errNotFound,
+			expect: errNotFoundJSON,
 		},
 		{
 			method: "DELETE",
@@ -313,7 +318,7 @@ func (s *NeutronHTTPSuite) simpleTests() []SimpleTest {
 		{
 			method: "DELETE",
 			url:    neutron.ApiNetworksV2 + "/invalid",
-			expect: errNotFound,
+			expect: errNotFoundJSON,
 		},
 
 		{
@@ -326,6 +331,11 @@ func (s *NeutronHTTPSuite) simpleTests() []SimpleTest {
 			url:    neutron.ApiSubnetsV2 + "/invalid",
 			expect: errNotFound,
 		},
+		{
+			method: "POST",
+			url:    neutron.ApiSubnetsV2,
+			expect: errBadRequestIncorrect,
+		},
 		{
 			method: "PUT",
 			url:    neutron.ApiSubnetsV2,
@@ -334,7 +344,7 @@ func (s *NeutronHTTPSuite) simpleTests() []SimpleTest {
 		{
 			method: "PUT",
 			url:    neutron.ApiSubnetsV2 + "/invalid",
-			expect: errNotFound,
+			expect: errNotFoundJSON,
 		},
 		{
 			method: "DELETE",
@@ -344,7 +354,7 @@ func (s *NeutronHTTPSuite) simpleTests() []SimpleTest {
 		{
 			method: "DELETE",
 			url:    neutron.ApiSubnetsV2 + "/invalid",
-			expect: errNotFound,
+			expect: errNotFoundJSON,
 		},
 
 		{
@@ -372,6 +382,42 @@ func (s *NeutronHTTPSuite) simpleTests() []SimpleTest {
 			url:    neutron.ApiPortsV2 + "/42",
 			expect: errNotFoundJSONP,
 		},
+
+		{
+			method: "GET",
+			url:    neutron.ApiRoutersV2 + "/42",
+			expect: errNotFoundJSON,
+		},
+		{
+			method: "POST",
+			url:    neutron.ApiRoutersV2 + "/invalid",
+			expect: errNotFound,
+		},
+		{
+			method: "POST",
+			url:    neutron.ApiRoutersV2,
+			expect: errBadRequestIncorrect,
+		},
+		{
+			method: "PUT",
+			url:    neutron.ApiRoutersV2,
+			expect: errNotFound,
+		},
+		{
+			method: "PUT",
+			url:    neutron.ApiRoutersV2 + "/invalid/add_router_interface",
+			expect: errNotFoundJSON,
+		},
+		{
+			method: "GET",
+			url:    neutron.ApiRoutersV2 + "/invalid/add_router_interface",
+			expect: errNotFound,
+		},
+		{
+			method: "DELETE",
+			url:    neutron.ApiRoutersV2 + "/invalid",
+			expect: errNotFoundJSON,
+		},
 	}
 	return simpleTests
 }
@@ -828,6 +874,144 @@ func (s *NeutronHTTPSuite) TestGetSubnets(c *gc.C) {
 	c.Assert(expectedSubnet.Subnet, gc.DeepEquals, subnets[0])
 }
 
+func (s *NeutronHTTPSuite) TestCreateUpdateSubnet(c *gc.C) {
+	network, err := s.service.createNetwork(neutron.NetworkOptsV2{Name: "http-net"})
+	c.Assert(err, gc.IsNil)
+	defer s.service.deleteNetwork(network.Id)
+	req := map[string]interface{}{
+		"subnet": map[string]interface{}{
+			"network_id": network.Id,
+			"ip_version": 4,
+			"cidr":       "10.5.0.0/24",
+			"gateway_ip": nil,
+		},
+	}
+	resp, err := s.jsonRequest("POST", neutron.ApiSubnetsV2, req, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusCreated)
+	var expected struct {
+		Subnet neutron.SubnetV2 `json:"subnet"`
+	}
+	assertJSON(c, resp, &expected)
+	c.Assert(expected.Subnet.GatewayIP, gc.Equals, "")
+	c.Assert(expected.Subnet.Pools(), gc.DeepEquals, []neutron.AllocationPoolV2{{Start: "10.5.0.1", End: "10.5.0.254"}})
+
+	url := fmt.Sprintf("%s/%s", neutron.ApiSubnetsV2, expected.Subnet.Id)
+	req = map[string]interface{}{
+		"subnet": map[string]interface{}{"cidr": "10.6.0.0/24"},
+	}
+	resp, err = s.jsonRequest("PUT", url, req, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusBadRequest)
+
+	resp, err = s.authRequest("DELETE", url, nil, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNoContent)
+	_, err = s.service.subnet(expected.Subnet.Id)
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *NeutronHTTPSuite) TestRouters(c *gc.C) {
+	network, err := s.service.createNetwork(neutron.NetworkOptsV2{Name: "router-http-net"})
+	c.Assert(err, gc.IsNil)
+	defer s.service.deleteNetwork(network.Id)
+	subnet, err := s.service.createSubnet(neutron.SubnetOptsV2{NetworkId: network.Id, IPVersion: 4, Cidr: "10.50.0.0/24"})
+	c.Assert(err, gc.IsNil)
+	port := neutron.PortV2{
+		Id:        "router-http-port",
+		NetworkId: network.Id,
+		FixedIPs:  []neutron.PortFixedIPsV2{{IPAddress: "10.50.0.5", SubnetID: subnet.Id}},
+	}
+	err = s.service.addPort(port)
+	c.Assert(err, gc.IsNil)
+	defer s.service.removePort(port.Id)
+
+	// A floating IP cannot be associated with a port until a router
+	// connects it to the external network.
+	var fipReq struct {
+		IP neutron.FloatingIPV2 `json:"floatingip"`
+	}
+	fipReq.IP = neutron.FloatingIPV2{FloatingNetworkId: "998", PortId: port.Id}
+	resp, err := s.jsonRequest("POST", neutron.ApiFloatingIPsV2, fipReq, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNotFound)
+
+	req := map[string]interface{}{
+		"router": map[string]interface{}{
+			"name":                  "router",
+			"external_gateway_info": map[string]interface{}{"network_id": "998"},
+		},
+	}
+	resp, err = s.jsonRequest("POST", neutron.ApiRoutersV2, req, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusCreated)
+	var expected struct {
+		Router neutron.RouterV2 `json:"router"`
+	}
+	assertJSON(c, resp, &expected)
+	router := expected.Router
+	c.Assert(router.Name, gc.Equals, "router")
+	c.Assert(router.ExternalGatewayInfo.NetworkId, gc.Equals, "998")
+	url := fmt.Sprintf("%s/%s", neutron.ApiRoutersV2, router.Id)
+
+	resp, err = s.jsonRequest("PUT", url+"/add_router_interface", neutron.RouterInterfaceOptsV2{SubnetId: subnet.Id}, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	var iface neutron.RouterInterfaceV2
+	assertJSON(c, resp, &iface)
+	c.Assert(iface.Id, gc.Equals, router.Id)
+	c.Assert(iface.SubnetId, gc.Equals, subnet.Id)
+
+	resp, err = s.jsonRequest("POST", neutron.ApiFloatingIPsV2, fipReq, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusCreated)
+	var fip struct {
+		IP neutron.FloatingIPV2 `json:"floatingip"`
+	}
+	assertJSON(c, resp, &fip)
+	c.Assert(fip.IP.PortId, gc.Equals, port.Id)
+	c.Assert(fip.IP.FixedIP, gc.Equals, "10.50.0.5")
+	err = s.service.removeFloatingIP(fip.IP.Id)
+	c.Assert(err, gc.IsNil)
+
+	routes := map[string]interface{}{
+		"router": map[string]interface{}{
+			"routes": []neutron.HostRouteV2{{Destination: "192.168.0.0/16", NextHop: "10.50.0.10"}},
+		},
+	}
+	resp, err = s.jsonRequest("PUT", url+"/add_extraroutes", routes, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	assertJSON(c, resp, &expected)
+	c.Assert(expected.Router.Routes, gc.HasLen, 1)
+	resp, err = s.jsonRequest("PUT", url+"/remove_extraroutes", routes, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	assertJSON(c, resp, &expected)
+	c.Assert(expected.Router.Routes, gc.HasLen, 0)
+
+	req = map[string]interface{}{
+		"router": map[string]interface{}{"external_gateway_info": nil},
+	}
+	resp, err = s.jsonRequest("PUT", url, req, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	assertJSON(c, resp, &expected)
+	c.Assert(expected.Router.ExternalGatewayInfo, gc.IsNil)
+
+	resp, err = s.authRequest("DELETE", url, nil, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusConflict)
+	resp, err = s.jsonRequest("PUT", url+"/remove_router_interface", neutron.RouterInterfaceOptsV2{SubnetId: subnet.Id}, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	resp, err = s.authRequest("DELETE", url, nil, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNoContent)
+	_, err = s.service.router(router.Id)
+	c.Assert(err, gc.NotNil)
+}
+
 func (s *NeutronHTTPSuite) TestGetPorts(c *gc.C) {
 	// There is always a default port.
 	ports := s.service.allPorts()
@@ -619,6 +619,415 @@ func (s *NeutronSuite) TestGetSubnetV2(c *gc.C) {
 	c.Assert(*sub, gc.DeepEquals, subnet)
 }
 
+func (s *NeutronSuite) TestCreateNetworkProviderAttributes(c *gc.C) {
+	network, err := s.service.createNetwork(neutron.NetworkOptsV2{
+		Name:            "vlan-net",
+		NetworkType:     neutron.NetworkTypeVLAN,
+		PhysicalNetwork: "physnet1",
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.service.deleteNetwork(network.Id)
+	c.Assert(network.SegmentationId, gc.Equals, 1)
+	c.Assert(network.AdminStateUp, gc.Equals, true)
+	c.Assert(network.MTU, gc.Equals, 1500)
+	c.Assert(network.TenantId, gc.Equals, s.service.TenantId)
+
+	_, err = s.service.createNetwork(neutron.NetworkOptsV2{
+		NetworkType:     neutron.NetworkTypeVLAN,
+		PhysicalNetwork: "physnet1",
+		SegmentationId:  1,
+	})
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: Unable to create the network. The VLAN 1 on physical network physnet1 is in use.")
+	_, err = s.service.createNetwork(neutron.NetworkOptsV2{
+		NetworkType:     neutron.NetworkTypeVLAN,
+		PhysicalNetwork: "physnet1",
+		SegmentationId:  4095,
+	})
+	c.Assert(err, gc.ErrorMatches, `badRequest: .*segmentation_id out of range \(1 through 4094\).`)
+	_, err = s.service.createNetwork(neutron.NetworkOptsV2{NetworkType: neutron.NetworkTypeFlat})
+	c.Assert(err, gc.ErrorMatches, "badRequest: .*physical_network required for flat provider network.")
+	_, err = s.service.createNetwork(neutron.NetworkOptsV2{NetworkType: "token-ring"})
+	c.Assert(err, gc.ErrorMatches, "badRequest: .*network_type value 'token-ring' not supported.")
+	_, err = s.service.createNetwork(neutron.NetworkOptsV2{MTU: 9000})
+	c.Assert(err, gc.ErrorMatches, "badRequest: Requested MTU is too big, maximum is 1500")
+
+	_, err = s.service.updateNetwork(network.Id, neutron.NetworkOptsV2{SegmentationId: 2})
+	c.Assert(err, gc.ErrorMatches, "badRequest: .*Plugin does not support updating provider attributes.")
+	f := false
+	updated, err := s.service.updateNetwork(network.Id, neutron.NetworkOptsV2{Name: "renamed", AdminStateUp: &f, MTU: 1400})
+	c.Assert(err, gc.IsNil)
+	c.Assert(updated.Name, gc.Equals, "renamed")
+	c.Assert(updated.AdminStateUp, gc.Equals, false)
+	c.Assert(updated.MTU, gc.Equals, 1400)
+	c.Assert(updated.SegmentationId, gc.Equals, 1)
+}
+
+func (s *NeutronSuite) TestCreateSubnetValidatesCIDR(c *gc.C) {
+	network, err := s.service.createNetwork(neutron.NetworkOptsV2{Name: "cidr-net"})
+	c.Assert(err, gc.IsNil)
+	defer s.service.deleteNetwork(network.Id)
+
+	for _, test := range []struct {
+		opts neutron.SubnetOptsV2
+		err  string
+	}{{
+		opts: neutron.SubnetOptsV2{Cidr: "10.0.0.0/24"},
+		err:  `badRequest: Invalid input for ip_version. Reason: 0 is not in \[4, 6\].`,
+	}, {
+		opts: neutron.SubnetOptsV2{IPVersion: 4, Cidr: "10.0.0/24"},
+		err:  "badRequest: Invalid input for cidr. Reason: '10.0.0/24' is not a valid IP subnet.",
+	}, {
+		opts: neutron.SubnetOptsV2{IPVersion: 4, Cidr: "10.0.0.1/24"},
+		err:  "badRequest: Invalid input for cidr. Reason: '10.0.0.1/24' isn't a recognized IP subnet cidr, '10.0.0.0/24' is recommended.",
+	}, {
+		opts: neutron.SubnetOptsV2{IPVersion: 6, Cidr: "10.0.0.0/24"},
+		err:  "badRequest: .*Cidr 10.0.0.0/24 does not match ip_version 6.",
+	}, {
+		opts: neutron.SubnetOptsV2{IPVersion: 4, Cidr: "0.0.0.0/0"},
+		err:  "badRequest: .*0.0.0.0/0 is not allowed as a subnet cidr.",
+	}, {
+		opts: neutron.SubnetOptsV2{IPVersion: 4},
+		err:  "badRequest: Bad subnets request: a subnetpool must be specified in the absence of a cidr.",
+	}, {
+		opts: neutron.SubnetOptsV2{IPVersion: 4, Cidr: "10.0.0.0/24", IPv6AddressMode: neutron.IPv6ModeSLAAC},
+		err:  "badRequest: .*ipv6_ra_mode or ipv6_address_mode cannot be set when ip_version is 4.",
+	}, {
+		opts: neutron.SubnetOptsV2{IPVersion: 6, Cidr: "2001:db8::/56", IPv6AddressMode: neutron.IPv6ModeSLAAC},
+		err:  "badRequest: .*which requires the prefix to be /64.",
+	}} {
+		test.opts.NetworkId = network.Id
+		_, err := s.service.createSubnet(test.opts)
+		c.Check(err, gc.ErrorMatches, test.err)
+	}
+
+	subnet, err := s.service.createSubnet(neutron.SubnetOptsV2{NetworkId: network.Id, IPVersion: 4, Cidr: "10.0.0.0/24"})
+	c.Assert(err, gc.IsNil)
+	_, err = s.service.createSubnet(neutron.SubnetOptsV2{NetworkId: network.Id, IPVersion: 4, Cidr: "10.0.0.128/25"})
+	c.Assert(err, gc.ErrorMatches, "badRequest: .*Requested subnet with cidr: 10.0.0.128/25 for network: .* overlaps with another subnet.")
+	net, err := s.service.network(network.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(net.SubnetIds, gc.DeepEquals, []string{subnet.Id})
+}
+
+func (s *NeutronSuite) TestSubnetAddresses(c *gc.C) {
+	network, err := s.service.createNetwork(neutron.NetworkOptsV2{Name: "address-net"})
+	c.Assert(err, gc.IsNil)
+	defer s.service.deleteNetwork(network.Id)
+
+	subnet, err := s.service.createSubnet(neutron.SubnetOptsV2{NetworkId: network.Id, IPVersion: 4, Cidr: "10.1.0.0/24"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnet.GatewayIP, gc.Equals, "10.1.0.1")
+	c.Assert(subnet.EnableDHCP, gc.Equals, true)
+	c.Assert(subnet.Pools(), gc.DeepEquals, []neutron.AllocationPoolV2{{Start: "10.1.0.2", End: "10.1.0.254"}})
+
+	// A gateway in the middle of the subnet splits the default pool.
+	subnet, err = s.service.createSubnet(neutron.SubnetOptsV2{
+		NetworkId: network.Id,
+		IPVersion: 4,
+		Cidr:      "10.2.0.0/24",
+		GatewayIP: "10.2.0.100",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnet.Pools(), gc.DeepEquals, []neutron.AllocationPoolV2{
+		{Start: "10.2.0.1", End: "10.2.0.99"},
+		{Start: "10.2.0.101", End: "10.2.0.254"},
+	})
+
+	_, err = s.service.updateSubnet(subnet.Id, neutron.SubnetOptsV2{GatewayIP: "10.2.0.50"})
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: Gateway ip 10.2.0.50 conflicts with allocation pool 10.2.0.1-10.2.0.99.")
+	_, err = s.service.updateSubnet(subnet.Id, neutron.SubnetOptsV2{
+		AllocationPools: []neutron.AllocationPoolV2{{Start: "10.2.0.200", End: "10.2.1.10"}},
+	})
+	c.Assert(err, gc.ErrorMatches, "badRequest: The allocation pool 10.2.0.200-10.2.1.10 spans beyond the subnet cidr 10.2.0.0/24.")
+	_, err = s.service.updateSubnet(subnet.Id, neutron.SubnetOptsV2{
+		AllocationPools: []neutron.AllocationPoolV2{
+			{Start: "10.2.0.10", End: "10.2.0.20"},
+			{Start: "10.2.0.15", End: "10.2.0.30"},
+		},
+	})
+	c.Assert(err, gc.ErrorMatches, "badRequest: Found overlapping allocation pools: 10.2.0.10-10.2.0.20 and 10.2.0.15-10.2.0.30 for subnet 10.2.0.0/24.")
+	_, err = s.service.updateSubnet(subnet.Id, neutron.SubnetOptsV2{Cidr: "10.3.0.0/24"})
+	c.Assert(err, gc.ErrorMatches, "badRequest: Cannot update read-only attribute cidr")
+	_, err = s.service.updateSubnet(subnet.Id, neutron.SubnetOptsV2{DNSNameservers: []string{"8.8.8.8", "8.8.8.8"}})
+	c.Assert(err, gc.ErrorMatches, "badRequest: Invalid input for dns_nameservers. Reason: Duplicate nameserver '8.8.8.8'.")
+
+	subnet, err = s.service.updateSubnet(subnet.Id, neutron.SubnetOptsV2{
+		DisableGateway:  true,
+		AllocationPools: []neutron.AllocationPoolV2{{Start: "10.2.0.10", End: "10.2.0.20"}},
+		DNSNameservers:  []string{"8.8.8.8"},
+		HostRoutes:      []neutron.HostRouteV2{{Destination: "192.168.0.0/16", NextHop: "10.2.0.254"}},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(subnet.GatewayIP, gc.Equals, "")
+	c.Assert(subnet.Pools(), gc.DeepEquals, []neutron.AllocationPoolV2{{Start: "10.2.0.10", End: "10.2.0.20"}})
+	c.Assert(subnet.DNSNameservers, gc.DeepEquals, []string{"8.8.8.8"})
+	c.Assert(subnet.HostRoutes, gc.DeepEquals, []neutron.HostRouteV2{{Destination: "192.168.0.0/16", NextHop: "10.2.0.254"}})
+}
+
+func (s *NeutronSuite) TestSubnetPoolAllocation(c *gc.C) {
+	err := s.service.AddSubnetPool("pool", []string{"10.10.0.0/16"}, 24)
+	c.Assert(err, gc.IsNil)
+	network, err := s.service.createNetwork(neutron.NetworkOptsV2{Name: "pool-net"})
+	c.Assert(err, gc.IsNil)
+	defer s.service.deleteNetwork(network.Id)
+
+	first, err := s.service.createSubnet(neutron.SubnetOptsV2{NetworkId: network.Id, IPVersion: 4, SubnetPoolId: "pool"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(first.Cidr, gc.Equals, "10.10.0.0/24")
+	c.Assert(first.SubnetPoolId, gc.Equals, "pool")
+
+	// Allocations skip past the subnets already allocated from the pool,
+	// staying aligned to their prefix length.
+	other, err := s.service.createNetwork(neutron.NetworkOptsV2{Name: "pool-net-2"})
+	c.Assert(err, gc.IsNil)
+	defer s.service.deleteNetwork(other.Id)
+	second, err := s.service.createSubnet(neutron.SubnetOptsV2{NetworkId: other.Id, IPVersion: 4, SubnetPoolId: "pool", PrefixLength: 23})
+	c.Assert(err, gc.IsNil)
+	c.Assert(second.Cidr, gc.Equals, "10.10.2.0/23")
+
+	_, err = s.service.createSubnet(neutron.SubnetOptsV2{NetworkId: other.Id, IPVersion: 4, SubnetPoolId: "pool", Cidr: "10.10.3.0/24"})
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: Failed to allocate subnet: Cannot allocate requested subnet from the available set of prefixes.")
+	_, err = s.service.createSubnet(neutron.SubnetOptsV2{NetworkId: other.Id, IPVersion: 4, SubnetPoolId: "pool", PrefixLength: 8})
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: Failed to allocate subnet: Insufficient prefix space to allocate subnet size /8.")
+	_, err = s.service.createSubnet(neutron.SubnetOptsV2{NetworkId: other.Id, IPVersion: 6, SubnetPoolId: "pool"})
+	c.Assert(err, gc.ErrorMatches, "badRequest: .*ip_version 6 does not match the subnet pool pool.")
+	_, err = s.service.createSubnet(neutron.SubnetOptsV2{NetworkId: other.Id, IPVersion: 4, SubnetPoolId: "no-pool"})
+	c.Assert(err, gc.ErrorMatches, "itemNotFound: Subnet pool no-pool could not be found.")
+}
+
+func (s *NeutronSuite) TestDeleteNetworkAndSubnetInUse(c *gc.C) {
+	network, err := s.service.createNetwork(neutron.NetworkOptsV2{Name: "in-use-net"})
+	c.Assert(err, gc.IsNil)
+	subnet, err := s.service.createSubnet(neutron.SubnetOptsV2{NetworkId: network.Id, IPVersion: 4, Cidr: "10.4.0.0/24"})
+	c.Assert(err, gc.IsNil)
+	port := neutron.PortV2{
+		Id:        "in-use",
+		NetworkId: network.Id,
+		FixedIPs:  []neutron.PortFixedIPsV2{{IPAddress: "10.4.0.2", SubnetID: subnet.Id}},
+	}
+	s.createPort(c, port)
+
+	err = s.service.deleteSubnet(subnet.Id)
+	c.Assert(err, gc.ErrorMatches, fmt.Sprintf("conflictingRequest: Unable to complete operation on subnet %s: .*", subnet.Id))
+	err = s.service.deleteNetwork(network.Id)
+	c.Assert(err, gc.ErrorMatches, fmt.Sprintf("conflictingRequest: Unable to complete operation on network %s. .*", network.Id))
+
+	// Deleting the network once the port has gone deletes its subnets.
+	s.deletePort(c, port)
+	err = s.service.deleteNetwork(network.Id)
+	c.Assert(err, gc.IsNil)
+	s.ensureNoNetwork(c, *network)
+	s.ensureNoSubnet(c, *subnet)
+}
+
+// createRouterSubnet creates a network with a subnet of the given
+// CIDR, for router interfaces.
+func (s *NeutronSuite) createRouterSubnet(c *gc.C, cidr string) (*neutron.NetworkV2, *neutron.SubnetV2) {
+	network, err := s.service.createNetwork(neutron.NetworkOptsV2{Name: "router-net-" + cidr})
+	c.Assert(err, gc.IsNil)
+	subnet, err := s.service.createSubnet(neutron.SubnetOptsV2{NetworkId: network.Id, IPVersion: 4, Cidr: cidr})
+	c.Assert(err, gc.IsNil)
+	return network, subnet
+}
+
+func (s *NeutronSuite) TestRouterInterfaces(c *gc.C) {
+	router, err := s.service.createRouter(neutron.RouterOptsV2{Name: "router"})
+	c.Assert(err, gc.IsNil)
+	defer s.service.deleteRouter(router.Id)
+	c.Assert(router.TenantId, gc.Equals, s.service.TenantId)
+	c.Assert(router.AdminStateUp, gc.Equals, true)
+	c.Assert(router.ExternalGatewayInfo, gc.IsNil)
+
+	network, subnet := s.createRouterSubnet(c, "10.20.0.0/24")
+	defer s.service.deleteNetwork(network.Id)
+	iface, err := s.service.addRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{SubnetId: subnet.Id})
+	c.Assert(err, gc.IsNil)
+	c.Assert(iface.SubnetIds, gc.DeepEquals, []string{subnet.Id})
+	c.Assert(iface.NetworkId, gc.Equals, network.Id)
+	port, err := s.service.port(iface.PortId)
+	c.Assert(err, gc.IsNil)
+	c.Assert(port.DeviceId, gc.Equals, router.Id)
+	c.Assert(port.DeviceOwner, gc.Equals, neutron.DeviceOwnerRouterInterface)
+	c.Assert(port.FixedIPs, gc.DeepEquals, []neutron.PortFixedIPsV2{{IPAddress: "10.20.0.1", SubnetID: subnet.Id}})
+
+	_, err = s.service.addRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{SubnetId: subnet.Id})
+	c.Assert(err, gc.ErrorMatches, fmt.Sprintf("badRequest: Bad router request: Router already has a port on subnet %s.", subnet.Id))
+	err = s.service.deleteRouter(router.Id)
+	c.Assert(err, gc.ErrorMatches, fmt.Sprintf("conflictingRequest: Router %s still has ports", router.Id))
+	err = s.service.deleteSubnet(subnet.Id)
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: .*One or more ports have an IP allocation from this subnet.")
+
+	// An interface may be added on an existing port, whose subnet must
+	// not overlap those of the other interfaces.
+	overlapping, overlappingSubnet := s.createRouterSubnet(c, "10.20.0.0/16")
+	defer s.service.deleteNetwork(overlapping.Id)
+	_, err = s.service.addRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{SubnetId: overlappingSubnet.Id})
+	c.Assert(err, gc.ErrorMatches, "badRequest: Bad router request: Cidr 10.20.0.0/16 of subnet .* overlaps with cidr 10.20.0.0/24 of subnet .*")
+	other, otherSubnet := s.createRouterSubnet(c, "10.21.0.0/24")
+	defer s.service.deleteNetwork(other.Id)
+	otherPort := neutron.PortV2{
+		Id:        "router-port",
+		NetworkId: other.Id,
+		FixedIPs:  []neutron.PortFixedIPsV2{{IPAddress: "10.21.0.10", SubnetID: otherSubnet.Id}},
+	}
+	s.createPort(c, otherPort)
+	_, err = s.service.addRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{PortId: otherPort.Id})
+	c.Assert(err, gc.IsNil)
+	_, err = s.service.addRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{PortId: otherPort.Id})
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: .*Port already has an attached device .*")
+
+	_, err = s.service.addRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{})
+	c.Assert(err, gc.ErrorMatches, "badRequest: Bad router request: Either subnet_id or port_id must be specified.")
+	_, err = s.service.removeRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{SubnetId: overlappingSubnet.Id})
+	c.Assert(err, gc.ErrorMatches, "itemNotFound: Router .* has no interface on subnet .*")
+
+	iface, err = s.service.removeRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{PortId: otherPort.Id})
+	c.Assert(err, gc.IsNil)
+	c.Assert(iface.SubnetId, gc.Equals, otherSubnet.Id)
+	s.ensureNoPort(c, otherPort)
+	_, err = s.service.removeRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{SubnetId: subnet.Id})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.service.routerPorts(router.Id, neutron.DeviceOwnerRouterInterface), gc.HasLen, 0)
+}
+
+func (s *NeutronSuite) TestRouterGateway(c *gc.C) {
+	_, err := s.service.createRouter(neutron.RouterOptsV2{
+		ExternalGatewayInfo: &neutron.ExternalGatewayInfoV2{NetworkId: "999"},
+	})
+	c.Assert(err, gc.ErrorMatches, "badRequest: Bad router request: Network 999 is not an external network.")
+
+	// Network 998 is external.
+	router, err := s.service.createRouter(neutron.RouterOptsV2{
+		ExternalGatewayInfo: &neutron.ExternalGatewayInfoV2{NetworkId: "998"},
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.service.deleteRouter(router.Id)
+	info := router.ExternalGatewayInfo
+	c.Assert(info, gc.NotNil)
+	c.Assert(*info.EnableSNAT, gc.Equals, true)
+	c.Assert(info.ExternalFixedIPs, gc.DeepEquals, []neutron.PortFixedIPsV2{{IPAddress: "10.8.0.1", SubnetID: "998-01"}})
+	ports := s.service.routerPorts(router.Id, neutron.DeviceOwnerRouterGateway)
+	c.Assert(ports, gc.HasLen, 1)
+	c.Assert(ports[0].NetworkId, gc.Equals, "998")
+
+	// The address of the first gateway is in use.
+	_, err = s.service.createRouter(neutron.RouterOptsV2{
+		ExternalGatewayInfo: &neutron.ExternalGatewayInfoV2{
+			NetworkId:        "998",
+			ExternalFixedIPs: []neutron.PortFixedIPsV2{{IPAddress: "10.8.0.1"}},
+		},
+	})
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: .*The IP address 10.8.0.1 is in use.")
+
+	f := false
+	router, err = s.service.updateRouter(router.Id, neutron.RouterOptsV2{
+		ExternalGatewayInfo: &neutron.ExternalGatewayInfoV2{NetworkId: "998", EnableSNAT: &f},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(*router.ExternalGatewayInfo.EnableSNAT, gc.Equals, false)
+	c.Assert(router.ExternalGatewayInfo.ExternalFixedIPs, gc.DeepEquals, info.ExternalFixedIPs)
+
+	router, err = s.service.updateRouter(router.Id, neutron.RouterOptsV2{
+		ExternalGatewayInfo: &neutron.ExternalGatewayInfoV2{
+			NetworkId:        "998",
+			ExternalFixedIPs: []neutron.PortFixedIPsV2{{SubnetID: "998-01", IPAddress: "10.8.0.100"}},
+		},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(router.ExternalGatewayInfo.ExternalFixedIPs, gc.DeepEquals, []neutron.PortFixedIPsV2{{IPAddress: "10.8.0.100", SubnetID: "998-01"}})
+	c.Assert(s.service.routerPorts(router.Id, neutron.DeviceOwnerRouterGateway), gc.HasLen, 1)
+
+	router, err = s.service.updateRouter(router.Id, neutron.RouterOptsV2{ExternalGatewayInfo: &neutron.ExternalGatewayInfoV2{}})
+	c.Assert(err, gc.IsNil)
+	c.Assert(router.ExternalGatewayInfo, gc.IsNil)
+	c.Assert(s.service.routerPorts(router.Id, neutron.DeviceOwnerRouterGateway), gc.HasLen, 0)
+}
+
+func (s *NeutronSuite) TestRouterRoutes(c *gc.C) {
+	router, err := s.service.createRouter(neutron.RouterOptsV2{
+		Routes: &[]neutron.HostRouteV2{{Destination: "192.168.0.0/16", NextHop: "10.30.0.10"}},
+	})
+	c.Assert(err, gc.ErrorMatches, `badRequest: Invalid format for routes: \{'destination': '192.168.0.0/16', 'nexthop': '10.30.0.10'\}, the nexthop is not connected with router`)
+	router, err = s.service.createRouter(neutron.RouterOptsV2{})
+	c.Assert(err, gc.IsNil)
+	defer s.service.deleteRouter(router.Id)
+	network, subnet := s.createRouterSubnet(c, "10.30.0.0/24")
+	defer s.service.deleteNetwork(network.Id)
+	_, err = s.service.addRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{SubnetId: subnet.Id})
+	c.Assert(err, gc.IsNil)
+
+	route := neutron.HostRouteV2{Destination: "192.168.0.0/16", NextHop: "10.30.0.10"}
+	_, err = s.service.addRouterRoutes(router.Id, []neutron.HostRouteV2{{Destination: "192.168.0.0/16", NextHop: "10.30.0.1"}})
+	c.Assert(err, gc.ErrorMatches, "badRequest: Invalid format for routes: .*, the nexthop is used by router")
+	_, err = s.service.addRouterRoutes(router.Id, []neutron.HostRouteV2{{Destination: "192.168.0.0", NextHop: "10.30.0.10"}})
+	c.Assert(err, gc.ErrorMatches, "badRequest: Invalid format for routes: .*, the destination is not a valid CIDR")
+	router, err = s.service.addRouterRoutes(router.Id, []neutron.HostRouteV2{route, route})
+	c.Assert(err, gc.IsNil)
+	c.Assert(router.Routes, gc.DeepEquals, []neutron.HostRouteV2{route})
+
+	_, err = s.service.removeRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{SubnetId: subnet.Id})
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: Router interface for subnet .* cannot be deleted, as it is required by one or more routes.")
+
+	other := neutron.HostRouteV2{Destination: "172.16.0.0/12", NextHop: "10.30.0.20"}
+	router, err = s.service.updateRouter(router.Id, neutron.RouterOptsV2{Routes: &[]neutron.HostRouteV2{other}})
+	c.Assert(err, gc.IsNil)
+	c.Assert(router.Routes, gc.DeepEquals, []neutron.HostRouteV2{other})
+	router, err = s.service.updateRouter(router.Id, neutron.RouterOptsV2{Routes: &[]neutron.HostRouteV2{}})
+	c.Assert(err, gc.IsNil)
+	c.Assert(router.Routes, gc.HasLen, 0)
+	router, err = s.service.addRouterRoutes(router.Id, []neutron.HostRouteV2{other})
+	c.Assert(err, gc.IsNil)
+	router, err = s.service.removeRouterRoutes(router.Id, []neutron.HostRouteV2{other, route})
+	c.Assert(err, gc.IsNil)
+	c.Assert(router.Routes, gc.HasLen, 0)
+	_, err = s.service.removeRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{SubnetId: subnet.Id})
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *NeutronSuite) TestFloatingIPReachability(c *gc.C) {
+	network, subnet := s.createRouterSubnet(c, "10.40.0.0/24")
+	defer s.service.deleteNetwork(network.Id)
+	port := neutron.PortV2{
+		Id:        "fip-port",
+		NetworkId: network.Id,
+		FixedIPs:  []neutron.PortFixedIPsV2{{IPAddress: "10.40.0.5", SubnetID: subnet.Id}},
+	}
+	s.createPort(c, port)
+	defer s.deletePort(c, port)
+
+	_, err := s.service.reachableFixedIP("998", port.Id)
+	c.Assert(err, gc.ErrorMatches, fmt.Sprintf("itemNotFound: External network 998 is not reachable from subnet %s.  Therefore, cannot associate Port fip-port with a Floating IP.", subnet.Id))
+
+	router, err := s.service.createRouter(neutron.RouterOptsV2{
+		ExternalGatewayInfo: &neutron.ExternalGatewayInfoV2{NetworkId: "998"},
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.service.deleteRouter(router.Id)
+	_, err = s.service.reachableFixedIP("998", port.Id)
+	c.Assert(err, gc.NotNil)
+	_, err = s.service.addRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{SubnetId: subnet.Id})
+	c.Assert(err, gc.IsNil)
+	fixedIP, err := s.service.reachableFixedIP("998", port.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(fixedIP, gc.Equals, "10.40.0.5")
+	// The router's gateway is not on network 997.
+	_, err = s.service.reachableFixedIP("997", port.Id)
+	c.Assert(err, gc.NotNil)
+
+	fip := neutron.FloatingIPV2{Id: "reachable", IP: "10.0.0.200", FloatingNetworkId: "998", FixedIP: fixedIP, PortId: port.Id}
+	err = s.service.addFloatingIP(fip)
+	c.Assert(err, gc.IsNil)
+	_, err = s.service.removeRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{SubnetId: subnet.Id})
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: Router interface for subnet .* cannot be deleted, as it is required by one or more floating IPs.")
+	_, err = s.service.updateRouter(router.Id, neutron.RouterOptsV2{ExternalGatewayInfo: &neutron.ExternalGatewayInfoV2{}})
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: Gateway cannot be updated for router .*, since a gateway to external network 998 is required by one or more floating IPs.")
+
+	err = s.service.removeFloatingIP(fip.Id)
+	c.Assert(err, gc.IsNil)
+	_, err = s.service.removeRouterInterface(router.Id, neutron.RouterInterfaceOptsV2{SubnetId: subnet.Id})
+	c.Assert(err, gc.IsNil)
+}
+
 func (s *NeutronSuite) TestAddRemovePort(c *gc.C) {
 	port := neutron.PortV2{Id: "1"}
 	s.createPort(c, port)
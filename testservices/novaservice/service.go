@@ -3,20 +3,52 @@
 package novaservice
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"net"
 	"net/url"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-goose/goose/v5/errors"
+	"github.com/go-goose/goose/v5/neutron"
 	"github.com/go-goose/goose/v5/nova"
 	"github.com/go-goose/goose/v5/testservices"
 	"github.com/go-goose/goose/v5/testservices/identityservice"
 	"github.com/go-goose/goose/v5/testservices/neutronmodel"
 )
 
+// defaultHost is the host servers are scheduled on when no hosts
+// have been set with SetHosts.
+const defaultHost = "1"
+
+// noValidHostMessage is the fault message of servers which could not
+// be scheduled.
+const noValidHostMessage = "No valid host was found. There are not enough hosts available."
+
+// defaultQuotas holds the compute quotas of a project until a test
+// sets them with SetQuotas or the os-quota-sets API. None of them
+// are enforced, so that tests only run into quotas they ask for.
+var defaultQuotas = nova.QuotaSet{
+	Cores:                    nova.Unlimited,
+	Instances:                nova.Unlimited,
+	RAM:                      nova.Unlimited,
+	KeyPairs:                 nova.Unlimited,
+	ServerGroups:             nova.Unlimited,
+	ServerGroupMembers:       nova.Unlimited,
+	MetadataItems:            nova.Unlimited,
+	InjectedFiles:            nova.Unlimited,
+	InjectedFileContentBytes: nova.Unlimited,
+	InjectedFilePathBytes:    nova.Unlimited,
+}
+
 var _ testservices.HttpService = (*Nova)(nil)
 var _ identityservice.ServiceProvider = (*Nova)(nil)
 
@@ -26,6 +58,8 @@ type Nova struct {
 	testservices.ServiceInstance
 	neutronModel              *neutronmodel.NeutronModel
 	flavors                   map[string]nova.FlavorDetail
+	flavorExtraSpecs          map[string]map[string]string
+	flavorAccess              map[string][]string
 	servers                   map[string]nova.ServerDetail
 	groups                    map[string]nova.SecurityGroup
 	rules                     map[string]nova.SecurityGroupRule
@@ -35,16 +69,57 @@ type Nova struct {
 	serverIPs                 map[string][]string
 	availabilityZones         map[string]nova.AvailabilityZone
 	serverIdToOSInterfaces    map[string][]nova.OSInterface
+	createdPorts              map[string]bool
 	serverIdToAttachedVolumes map[string][]nova.VolumeAttachment
+	serverStatuses            map[string]string
+	serverResizes             map[string]serverResize
+	serverHosts               map[string]string
+	serverPasswords           map[string]string
+	keyPairs                  map[string]nova.KeyPair
+	osServerGroups            map[string]nova.ServerGroup
+	consoleOutputs            map[string]string
+	instanceActions           map[string][]nova.InstanceAction
+	images                    map[string]image
+	quotas                    nova.QuotaSet
+	hosts                     []string
+	computeHosts              map[string]*computeHost
+	aggregates                map[int]nova.Aggregate
+	migrations                []*migration
+	deletedServers            []deletedServer
 	nextServerId              int
 	nextGroupId               int
 	nextRuleId                int
 	nextIPId                  int
 	nextAttachmentId          int
 	nextOSInterfaceId         int
+	nextImageSeq              int
+	nextFixedIPId             int
+	nextAggregateId           int
+	nextMigrationId           int
 	useNeutronNetworking      bool
 	noValidHostZone           nova.AvailabilityZone
 	serverStatus              string
+	imageStatus               string
+	liveMigrationsHeld        bool
+}
+
+// image is an image created from a server, as shown by the compute
+// images API used by glance.Client.GetImageDetail.
+type image struct {
+	Id       string            `json:"id"`
+	Name     string            `json:"name"`
+	Created  string            `json:"created"`
+	Updated  string            `json:"updated"`
+	Progress int               `json:"progress"`
+	Status   string            `json:"status"`
+	MinRam   int               `json:"minRam"`
+	MinDisk  int               `json:"minDisk"`
+	Links    []nova.Link       `json:"links"`
+	Metadata map[string]string `json:"metadata"`
+	Server   *nova.Entity      `json:"server,omitempty"`
+
+	// seq orders images by creation, for backup rotation.
+	seq int
 }
 
 func errorJSONEncode(err error) (int, string) {
@@ -96,9 +171,9 @@ func New(hostURL, versionPath, tenantId, region string, identityService, fallbac
 	}
 	// Real openstack instances have flavours "out of the box". So we add some here.
 	defaultFlavors := []nova.FlavorDetail{
-		{Id: "1", Name: "m1.tiny", RAM: 512, VCPUs: 1, Disk: 5},
-		{Id: "2", Name: "m1.small", RAM: 2048, VCPUs: 1, Disk: 10},
-		{Id: "3", Name: "m1.medium", RAM: 4096, VCPUs: 2, Disk: 15},
+		{Id: "1", Name: "m1.tiny", RAM: 512, VCPUs: 1, Disk: 5, IsPublic: true},
+		{Id: "2", Name: "m1.small", RAM: 2048, VCPUs: 1, Disk: 10, IsPublic: true},
+		{Id: "3", Name: "m1.medium", RAM: 4096, VCPUs: 2, Disk: 15, IsPublic: true},
 	}
 	// Real openstack instances have a default security group "out of the box". So we add it here.
 	defaultSecurityGroups := []nova.SecurityGroup{
@@ -106,6 +181,8 @@ func New(hostURL, versionPath, tenantId, region string, identityService, fallbac
 	}
 	novaService := &Nova{
 		flavors:                   make(map[string]nova.FlavorDetail),
+		flavorExtraSpecs:          make(map[string]map[string]string),
+		flavorAccess:              make(map[string][]string),
 		servers:                   make(map[string]nova.ServerDetail),
 		groups:                    make(map[string]nova.SecurityGroup),
 		rules:                     make(map[string]nova.SecurityGroupRule),
@@ -115,7 +192,20 @@ func New(hostURL, versionPath, tenantId, region string, identityService, fallbac
 		serverIPs:                 make(map[string][]string),
 		availabilityZones:         make(map[string]nova.AvailabilityZone),
 		serverIdToOSInterfaces:    make(map[string][]nova.OSInterface),
+		createdPorts:              make(map[string]bool),
 		serverIdToAttachedVolumes: make(map[string][]nova.VolumeAttachment),
+		serverStatuses:            make(map[string]string),
+		serverResizes:             make(map[string]serverResize),
+		serverHosts:               make(map[string]string),
+		serverPasswords:           make(map[string]string),
+		keyPairs:                  make(map[string]nova.KeyPair),
+		osServerGroups:            make(map[string]nova.ServerGroup),
+		consoleOutputs:            make(map[string]string),
+		instanceActions:           make(map[string][]nova.InstanceAction),
+		images:                    make(map[string]image),
+		computeHosts:              make(map[string]*computeHost),
+		aggregates:                make(map[int]nova.Aggregate),
+		quotas:                    defaultQuotas,
 		useNeutronNetworking:      false,
 		ServiceInstance: testservices.ServiceInstance{
 			IdentityService:         identityService,
@@ -166,13 +256,13 @@ func (n *Nova) AddNeutronModel(neutronModel *neutronmodel.NeutronModel) {
 }
 
 // SetAvailabilityZones sets the availability zones for setting
-// availability zones.
+// availability zones. Zones defined by host aggregates are listed
+// too, but a zone set here takes precedence over an aggregate zone
+// with the same name.
 //
-// Note: this is implemented as a public method rather than as
-// an HTTP API for two reasons: availability zones are created
-// indirectly via "host aggregates", which are a cloud-provider
-// concept that we have not implemented, and because we want to
-// be able to synthesize zone state changes.
+// Note: this is implemented as a public method rather than through
+// the host aggregates HTTP API because we want to be able to
+// synthesize zone state changes.
 func (n *Nova) SetAvailabilityZones(zones ...nova.AvailabilityZone) {
 	n.availabilityZones = make(map[string]nova.AvailabilityZone)
 	for _, z := range zones {
@@ -196,6 +286,69 @@ func (n *Nova) SetAZForNoValidHosts(zone nova.AvailabilityZone) {
 	}
 }
 
+// SetHosts sets the names of the fake compute hosts which servers
+// are scheduled on. As in nova, ServerDetail.HostId is an opaque
+// hash of the tenant and the host, never the host name. Server
+// group policies are enforced against these hosts, and each host
+// has a hypervisor and a nova-compute service.
+//
+// Note: this is implemented as a public method rather than as
+// an HTTP API because nova has no API to add compute hosts.
+func (n *Nova) SetHosts(hosts ...string) {
+	n.hosts = hosts
+}
+
+// hostId returns the HostId reported for servers on the given host,
+// the hex encoded SHA-224 hash of the tenant ID and the host name,
+// or "" if there is no host.
+func (n *Nova) hostId(host string) string {
+	if host == "" {
+		return ""
+	}
+	sum := sha256.Sum224([]byte(n.TenantId + host))
+	return hex.EncodeToString(sum[:])
+}
+
+// allHosts returns the names of the fake compute hosts.
+func (n *Nova) allHosts() []string {
+	if len(n.hosts) == 0 {
+		return []string{defaultHost}
+	}
+	return n.hosts
+}
+
+// SetConsoleOutput sets the console log returned for the server
+// with the given ID.
+//
+// Note: this is implemented as a public method rather than as
+// an HTTP API, as the console log is written by the server itself
+// rather than through the OpenStack API.
+func (n *Nova) SetConsoleOutput(serverId, output string) {
+	n.consoleOutputs[serverId] = output
+}
+
+// SetQuotas changes the compute quotas enforced when creating
+// servers, keypairs and server groups. Quotas left nil in opts are
+// not changed, and quotas of nova.Unlimited are not enforced. Unlike
+// the os-quota-sets HTTP API, quotas may be set below the usage.
+//
+// Note: this is implemented as a public method as well as through
+// the os-quota-sets HTTP API, as changing quotas is usually
+// restricted to administrators.
+func (n *Nova) SetQuotas(opts nova.QuotaSetOpts) {
+	for _, q := range n.quotaUpdates(&n.quotas, opts) {
+		if q.value != nil {
+			*q.quota = *q.value
+		}
+	}
+}
+
+// ResetQuotas restores the default compute quotas, none of which are
+// enforced.
+func (n *Nova) ResetQuotas() {
+	n.quotas = defaultQuotas
+}
+
 // SetServerStatus sets the ServerDetail.Status to a new
 // value.
 //
@@ -207,6 +360,55 @@ func (n *Nova) SetServerStatus(status string) {
 	n.serverStatus = status
 }
 
+// SetImageStatus sets the status of the images subsequently created
+// from servers. If empty, images are created ACTIVE.
+//
+// Note: this is implemented as a public method rather than as
+// an HTTP API to allow tests to simulate snapshots which are
+// still being saved, or which fail.
+func (n *Nova) SetImageStatus(status string) {
+	n.imageStatus = status
+}
+
+// SetLiveMigrationsHeld sets whether live migrations are held
+// running, leaving servers MIGRATING until the migration is forced to
+// complete. Otherwise live migrations complete as soon as they start.
+//
+// Note: this is implemented as a public method rather than as
+// an HTTP API to allow tests to observe migrations in progress,
+// which nova reports but cannot be asked to slow down.
+func (n *Nova) SetLiveMigrationsHeld(held bool) {
+	n.liveMigrationsHeld = held
+}
+
+// SetServerPassword sets the admin password of the server with the
+// given ID, encrypted with the public key of the server's key pair,
+// which must be an RSA key.
+//
+// Note: this is implemented as a public method rather than as
+// an HTTP API, as the password is posted by the guest through the
+// metadata service rather than through the compute API.
+func (n *Nova) SetServerPassword(serverId, password string) error {
+	server, ok := n.servers[serverId]
+	if !ok {
+		return testservices.NewServerByIDNotFoundError(serverId)
+	}
+	keyPair, ok := n.keyPairs[server.KeyName]
+	if !ok {
+		return fmt.Errorf("server %s has no key pair", serverId)
+	}
+	publicKey, err := parseSSHRSAPublicKey(keyPair.PublicKey)
+	if err != nil {
+		return err
+	}
+	encrypted, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, []byte(password))
+	if err != nil {
+		return err
+	}
+	n.serverPasswords[serverId] = base64.StdEncoding.EncodeToString(encrypted)
+	return nil
+}
+
 // buildFlavorLinks populates the Links field of the passed
 // FlavorDetail as needed by OpenStack HTTP API. Call this
 // before addFlavor().
@@ -289,6 +491,128 @@ func (n *Nova) removeFlavor(flavorId string) error {
 		return err
 	}
 	delete(n.flavors, flavorId)
+	delete(n.flavorExtraSpecs, flavorId)
+	delete(n.flavorAccess, flavorId)
+	return nil
+}
+
+// flavorByName retrieves an existing flavor by name.
+func (n *Nova) flavorByName(name string) (*nova.FlavorDetail, error) {
+	if err := n.ProcessFunctionHook(n, name); err != nil {
+		return nil, err
+	}
+	for _, flavor := range n.flavors {
+		if flavor.Name == name {
+			return &flavor, nil
+		}
+	}
+	return nil, testservices.NewNoSuchFlavorError(name)
+}
+
+// extraSpecs returns the extra specs of an existing flavor.
+func (n *Nova) extraSpecs(flavorId string) (map[string]string, error) {
+	if err := n.ProcessFunctionHook(n, flavorId); err != nil {
+		return nil, err
+	}
+	if _, err := n.flavor(flavorId); err != nil {
+		return nil, testservices.NewFlavorNotFoundError(flavorId)
+	}
+	specs := make(map[string]string)
+	for key, value := range n.flavorExtraSpecs[flavorId] {
+		specs[key] = value
+	}
+	return specs, nil
+}
+
+// setExtraSpecs creates or updates extra specs of an existing flavor.
+func (n *Nova) setExtraSpecs(flavorId string, specs map[string]string) error {
+	if err := n.ProcessFunctionHook(n, flavorId, specs); err != nil {
+		return err
+	}
+	if _, err := n.flavor(flavorId); err != nil {
+		return testservices.NewFlavorNotFoundError(flavorId)
+	}
+	if n.flavorExtraSpecs[flavorId] == nil {
+		n.flavorExtraSpecs[flavorId] = make(map[string]string)
+	}
+	for key, value := range specs {
+		n.flavorExtraSpecs[flavorId][key] = value
+	}
+	return nil
+}
+
+// unsetExtraSpec deletes an extra spec of an existing flavor.
+func (n *Nova) unsetExtraSpec(flavorId, key string) error {
+	if err := n.ProcessFunctionHook(n, flavorId, key); err != nil {
+		return err
+	}
+	if _, err := n.flavor(flavorId); err != nil {
+		return testservices.NewFlavorNotFoundError(flavorId)
+	}
+	if _, ok := n.flavorExtraSpecs[flavorId][key]; !ok {
+		return testservices.NewFlavorExtraSpecNotFoundError(flavorId, key)
+	}
+	delete(n.flavorExtraSpecs[flavorId], key)
+	return nil
+}
+
+// allFlavorAccess returns the tenants which may use an existing
+// private flavor, sorted by ID.
+func (n *Nova) allFlavorAccess(flavorId string) ([]nova.FlavorAccess, error) {
+	if err := n.ProcessFunctionHook(n, flavorId); err != nil {
+		return nil, err
+	}
+	flavor, err := n.flavor(flavorId)
+	if err != nil {
+		return nil, testservices.NewFlavorNotFoundError(flavorId)
+	}
+	if flavor.IsPublic {
+		return nil, testservices.NewPublicFlavorAccessListError()
+	}
+	tenantIds := append([]string(nil), n.flavorAccess[flavorId]...)
+	sort.Strings(tenantIds)
+	access := []nova.FlavorAccess{}
+	for _, tenantId := range tenantIds {
+		access = append(access, nova.FlavorAccess{FlavorId: flavorId, TenantId: tenantId})
+	}
+	return access, nil
+}
+
+// addFlavorAccess allows a tenant to use an existing private flavor.
+func (n *Nova) addFlavorAccess(flavorId, tenantId string) error {
+	if err := n.ProcessFunctionHook(n, flavorId, tenantId); err != nil {
+		return err
+	}
+	flavor, err := n.flavor(flavorId)
+	if err != nil {
+		return testservices.NewFlavorNotFoundError(flavorId)
+	}
+	if flavor.IsPublic {
+		return testservices.NewPublicFlavorAccessError()
+	}
+	for _, id := range n.flavorAccess[flavorId] {
+		if id == tenantId {
+			return testservices.NewFlavorAccessExistsError(flavorId, tenantId)
+		}
+	}
+	n.flavorAccess[flavorId] = append(n.flavorAccess[flavorId], tenantId)
+	return nil
+}
+
+// removeFlavorAccess stops a tenant using an existing private flavor.
+func (n *Nova) removeFlavorAccess(flavorId, tenantId string) error {
+	if err := n.ProcessFunctionHook(n, flavorId, tenantId); err != nil {
+		return err
+	}
+	if _, err := n.flavor(flavorId); err != nil {
+		return testservices.NewFlavorNotFoundError(flavorId)
+	}
+	tenantIds := n.flavorAccess[flavorId]
+	remaining := removeString(tenantIds, tenantId)
+	if len(remaining) == len(tenantIds) {
+		return testservices.NewFlavorAccessNotFoundError(flavorId, tenantId)
+	}
+	n.flavorAccess[flavorId] = remaining
 	return nil
 }
 
@@ -352,10 +676,12 @@ func (n *Nova) server(serverId string) (*nova.ServerDetail, error) {
 		server.Status = nova.StatusError
 		server.Fault = &nova.ServerFault{
 			Code:    500,
-			Message: "No valid host was found. There are not enough hosts available.",
+			Message: noValidHostMessage,
 		}
 	} else if n.serverStatus != "" {
 		server.Status = n.serverStatus
+	} else if status, ok := n.serverStatuses[serverId]; ok {
+		server.Status = status
 	} else {
 		server.Status = nova.StatusActive
 	}
@@ -454,11 +780,209 @@ func (n *Nova) matchServers(f filter) ([]nova.ServerDetail, error) {
 		}
 		servers = matched
 	}
-	return servers, nil
+	matched := []nova.ServerDetail{}
+	for _, server := range servers {
+		if matchTags(server.Tags, f) {
+			matched = append(matched, server)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, nil
+	}
+	return matched, nil
 	// TODO(dimitern) - 2013-02-11 bug=1121690
 	// implement FilterFlavor, FilterImage, FilterMarker, FilterLimit and FilterChangesSince
 }
 
+// validateServerTags checks the tags are valid, and returns them
+// without duplicates.
+func validateServerTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, tag := range tags {
+		switch {
+		case tag == "" || len(tag) > 60:
+			return nil, testservices.NewInvalidServerTagError(fmt.Sprintf("tag %q must be between 1 and 60 characters long", tag))
+		case strings.ContainsAny(tag, "/,"):
+			return nil, testservices.NewInvalidServerTagError(fmt.Sprintf("tag %q must not contain '/' or ','", tag))
+		case seen[tag]:
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	if len(result) > nova.MaxServerTags {
+		return nil, testservices.NewTooManyServerTagsError(nova.MaxServerTags)
+	}
+	return result, nil
+}
+
+// validateBlockDeviceMappings checks the block device mappings of a
+// new server are valid, as nova does. A server booted from imageRef
+// gets an implicit boot device when none is mapped.
+func validateBlockDeviceMappings(imageRef string, mappings []nova.BlockDeviceMapping) error {
+	var bootIndexes []int
+	swaps := 0
+	for _, m := range mappings {
+		switch m.SourceType {
+		case nova.BlockDeviceSourceImage, nova.BlockDeviceSourceVolume, nova.BlockDeviceSourceSnapshot, nova.BlockDeviceSourceBlank:
+		default:
+			return testservices.NewBadRequestError(fmt.Sprintf("Invalid input for field/attribute source_type. Value: %s.", m.SourceType))
+		}
+		switch m.DestinationType {
+		case nova.BlockDeviceDestinationVolume, nova.BlockDeviceDestinationLocal:
+		default:
+			return testservices.NewBadRequestError(fmt.Sprintf("Invalid input for field/attribute destination_type. Value: %s.", m.DestinationType))
+		}
+		if m.SourceType != nova.BlockDeviceSourceBlank && m.UUID == "" {
+			return testservices.NewInvalidBlockDeviceMappingError("Missing device UUID.")
+		}
+		if m.DestinationType == nova.BlockDeviceDestinationLocal {
+			switch {
+			case m.SourceType == nova.BlockDeviceSourceVolume || m.SourceType == nova.BlockDeviceSourceSnapshot:
+				return testservices.NewInvalidBlockDeviceMappingError(fmt.Sprintf("Mapping %s to local is not supported.", m.SourceType))
+			case m.SourceType == nova.BlockDeviceSourceImage && m.BootIndex != 0:
+				return testservices.NewInvalidBlockDeviceMappingError("Mapping image to local is not supported.")
+			case m.VolumeType != "":
+				return testservices.NewInvalidBlockDeviceMappingError("Specifying a volume_type with destination_type=local is not supported.")
+			}
+		}
+		if m.VolumeSize <= 0 {
+			switch {
+			case m.SourceType == nova.BlockDeviceSourceBlank:
+				return testservices.NewInvalidBlockDeviceMappingError("Blank devices need to have a non-zero size specified")
+			case m.SourceType == nova.BlockDeviceSourceImage && m.DestinationType == nova.BlockDeviceDestinationVolume:
+				return testservices.NewInvalidBlockDeviceMappingError("Images with destination_type 'volume' need to have a non-zero size specified")
+			}
+		}
+		if m.GuestFormat == nova.GuestFormatSwap {
+			if swaps++; swaps > 1 {
+				return testservices.NewInvalidBlockDeviceMappingError("More than one swap drive requested.")
+			}
+		}
+		if m.BootIndex >= 0 {
+			bootIndexes = append(bootIndexes, m.BootIndex)
+		}
+	}
+	sort.Ints(bootIndexes)
+	if imageRef != "" && (len(bootIndexes) == 0 || bootIndexes[0] != 0) {
+		bootIndexes = append([]int{0}, bootIndexes...)
+	}
+	if len(bootIndexes) == 0 {
+		return testservices.NewInvalidBlockDeviceMappingError("Boot sequence for the instance and image/block device mapping combination is not valid.")
+	}
+	for i, index := range bootIndexes {
+		if index != i {
+			return testservices.NewInvalidBlockDeviceMappingError("Boot sequence for the instance and image/block device mapping combination is not valid.")
+		}
+	}
+	return nil
+}
+
+// setServerTags replaces the tags of an existing server.
+func (n *Nova) setServerTags(serverId string, tags []string) error {
+	if err := n.ProcessFunctionHook(n, serverId, tags); err != nil {
+		return err
+	}
+	server, ok := n.servers[serverId]
+	if !ok {
+		return testservices.NewServerByIDNotFoundError(serverId)
+	}
+	tags, err := validateServerTags(tags)
+	if err != nil {
+		return err
+	}
+	sort.Strings(tags)
+	server.Tags = tags
+	n.servers[serverId] = server
+	return nil
+}
+
+// addServerTag adds a tag to an existing server, and reports whether
+// the server did not already have it.
+func (n *Nova) addServerTag(serverId, tag string) (bool, error) {
+	if err := n.ProcessFunctionHook(n, serverId, tag); err != nil {
+		return false, err
+	}
+	server, ok := n.servers[serverId]
+	if !ok {
+		return false, testservices.NewServerByIDNotFoundError(serverId)
+	}
+	if hasString(server.Tags, tag) {
+		return false, nil
+	}
+	if err := n.setServerTags(serverId, append(append([]string(nil), server.Tags...), tag)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// removeServerTag removes a tag from an existing server.
+func (n *Nova) removeServerTag(serverId, tag string) error {
+	if err := n.ProcessFunctionHook(n, serverId, tag); err != nil {
+		return err
+	}
+	server, ok := n.servers[serverId]
+	if !ok {
+		return testservices.NewServerByIDNotFoundError(serverId)
+	}
+	if !hasString(server.Tags, tag) {
+		return testservices.NewServerTagNotFoundError(serverId, tag)
+	}
+	server.Tags = removeString(server.Tags, tag)
+	n.servers[serverId] = server
+	return nil
+}
+
+// updateServerDescription changes the description of an existing
+// server.
+func (n *Nova) updateServerDescription(serverId, description string) error {
+	if err := n.ProcessFunctionHook(n, serverId, description); err != nil {
+		return err
+	}
+	server, ok := n.servers[serverId]
+	if !ok {
+		return testservices.NewServerByIDNotFoundError(serverId)
+	}
+	server.Description = description
+	n.servers[serverId] = server
+	return nil
+}
+
+// matchTags reports whether the tags of a server satisfy the tag
+// filters, whose values are comma separated lists of tags.
+func matchTags(tags []string, f filter) bool {
+	hasAll := func(filterTags string) bool {
+		for _, tag := range strings.Split(filterTags, ",") {
+			if !hasString(tags, tag) {
+				return false
+			}
+		}
+		return true
+	}
+	hasAny := func(filterTags string) bool {
+		for _, tag := range strings.Split(filterTags, ",") {
+			if hasString(tags, tag) {
+				return true
+			}
+		}
+		return false
+	}
+	if v := f[nova.FilterTags]; v != "" && !hasAll(v) {
+		return false
+	}
+	if v := f[nova.FilterTagsAny]; v != "" && !hasAny(v) {
+		return false
+	}
+	if v := f[nova.FilterNotTags]; v != "" && hasAll(v) {
+		return false
+	}
+	if v := f[nova.FilterNotTagsAny]; v != "" && hasAny(v) {
+		return false
+	}
+	return true
+}
+
 // allServers returns a list of all existing servers.
 // Filtering is supported, see filter type for more info.
 func (n *Nova) allServers(f filter) ([]nova.ServerDetail, error) {
@@ -492,349 +1016,1765 @@ func (n *Nova) removeServer(serverId string) error {
 	if _, err := n.server(serverId); err != nil {
 		return err
 	}
+	n.deletedServers = append(n.deletedServers, deletedServer{n.servers[serverId], time.Now()})
 	delete(n.servers, serverId)
+	delete(n.serverStatuses, serverId)
+	delete(n.serverResizes, serverId)
+	delete(n.serverHosts, serverId)
+	delete(n.serverPasswords, serverId)
+	delete(n.consoleOutputs, serverId)
+	for _, m := range n.migrations {
+		if m.serverId == serverId && m.inProgress() {
+			n.setMigrationStatus(m, nova.MigrationStatusCancelled)
+		}
+	}
+	for _, osInterface := range n.serverIdToOSInterfaces[serverId] {
+		n.releasePort(serverId, osInterface.PortID)
+	}
+	delete(n.serverIdToOSInterfaces, serverId)
+	for id, group := range n.osServerGroups {
+		group.Members = removeString(group.Members, serverId)
+		n.osServerGroups[id] = group
+	}
 	return nil
 }
 
-func (n *Nova) updateSecurityGroup(group nova.SecurityGroup) error {
-	if err := n.ProcessFunctionHook(n, group); err != nil {
-		return err
-	}
-	if n.useNeutronNetworking {
-		return n.neutronModel.UpdateNovaSecurityGroup(group)
+// hasString reports whether values holds value.
+func hasString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
-	existingGroup, err := n.securityGroup(group.Id)
-	if err != nil {
-		return testservices.NewSecurityGroupByIDNotFoundError(group.Id)
+	return false
+}
+
+func removeString(values []string, value string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
 	}
-	existingGroup.Name = group.Name
-	existingGroup.Description = group.Description
-	n.groups[group.Id] = *existingGroup
-	return nil
+	return result
 }
 
-// addSecurityGroup creates a new security group.
-func (n *Nova) addSecurityGroup(group nova.SecurityGroup) error {
+// addServerGroup creates a new server group.
+func (n *Nova) addServerGroup(group nova.ServerGroup) error {
 	if err := n.ProcessFunctionHook(n, group); err != nil {
 		return err
 	}
-	if n.useNeutronNetworking {
-		return n.neutronModel.AddNovaSecurityGroup(group)
+	if _, ok := n.osServerGroups[group.Id]; ok {
+		return testservices.NewInvalidServerGroupError(fmt.Sprintf("a server group with id %q already exists", group.Id))
 	}
-	if _, err := n.securityGroup(group.Id); err == nil {
-		return testservices.NewSecurityGroupAlreadyExistsError(group.Id)
+	if overQuota(n.quotas.ServerGroups, len(n.osServerGroups), 1) {
+		return testservices.NewServerGroupQuotaExceededError()
 	}
-	group.TenantId = n.TenantId
-	if group.Rules == nil {
-		group.Rules = []nova.SecurityGroupRule{}
+	if group.Members == nil {
+		group.Members = []string{}
 	}
-	n.groups[group.Id] = group
+	n.osServerGroups[group.Id] = group
 	return nil
 }
 
-// securityGroup retrieves an existing group by ID.
-func (n *Nova) securityGroup(groupId string) (*nova.SecurityGroup, error) {
+// serverGroup retrieves an existing server group by ID.
+func (n *Nova) serverGroup(groupId string) (*nova.ServerGroup, error) {
 	if err := n.ProcessFunctionHook(n, groupId); err != nil {
 		return nil, err
 	}
-	if n.useNeutronNetworking {
-		return n.neutronModel.NovaSecurityGroup(groupId)
-	}
-	group, ok := n.groups[groupId]
+	group, ok := n.osServerGroups[groupId]
 	if !ok {
-		return nil, testservices.NewSecurityGroupByIDNotFoundError(groupId)
+		return nil, testservices.NewServerGroupNotFoundError(groupId)
 	}
 	return &group, nil
 }
 
-// securityGroupByName retrieves an existing named group.
-func (n *Nova) securityGroupByName(groupName string) (*nova.SecurityGroup, error) {
-	if err := n.ProcessFunctionHook(n, groupName); err != nil {
-		return nil, err
+// allServerGroups returns a list of all existing server groups,
+// sorted by name.
+func (n *Nova) allServerGroups() []nova.ServerGroup {
+	var groups []nova.ServerGroup
+	for _, group := range n.osServerGroups {
+		groups = append(groups, group)
 	}
-	if n.useNeutronNetworking {
-		return n.neutronModel.NovaSecurityGroupByName(groupName)
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// removeServerGroup deletes an existing server group.
+func (n *Nova) removeServerGroup(groupId string) error {
+	if err := n.ProcessFunctionHook(n, groupId); err != nil {
+		return err
 	}
-	for _, group := range n.groups {
-		if group.Name == groupName {
-			return &group, nil
-		}
+	if _, err := n.serverGroup(groupId); err != nil {
+		return err
 	}
-	return nil, testservices.NewSecurityGroupByNameNotFoundError(groupName)
+	delete(n.osServerGroups, groupId)
+	return nil
 }
 
-// allSecurityGroups returns a list of all existing groups.
-func (n *Nova) allSecurityGroups() []nova.SecurityGroup {
-	var groups []nova.SecurityGroup
-	if n.useNeutronNetworking {
-		return n.neutronModel.AllNovaSecurityGroups()
+// scheduleServer returns the host a new server in the given server
+// group and availability zone, either of which may be empty, should
+// be placed on. Only enabled hosts which are up are used, and only
+// the hosts in the zone if it is defined by host aggregates. If no
+// host can be found, or the group policy cannot be met, ok is false.
+func (n *Nova) scheduleServer(groupId, zone string) (host string, ok bool, err error) {
+	_, staticZone := n.availabilityZones[zone]
+	var hosts []string
+	for _, host := range n.allHosts() {
+		if !n.computeHost(host).schedulable() {
+			continue
+		}
+		if zone != "" && !staticZone && n.hostZone(host) != zone {
+			continue
+		}
+		hosts = append(hosts, host)
 	}
-	for _, group := range n.groups {
-		groups = append(groups, group)
+	if len(hosts) == 0 {
+		return "", false, nil
 	}
-	return groups
+	if groupId == "" {
+		return hosts[0], true, nil
+	}
+	group, err := n.serverGroup(groupId)
+	if err != nil {
+		return "", false, err
+	}
+	var memberHosts []string
+	serversPerHost := make(map[string]int)
+	for _, serverId := range group.Members {
+		if server, ok := n.servers[serverId]; ok {
+			memberHosts = append(memberHosts, n.serverHosts[server.Id])
+			serversPerHost[n.serverHosts[server.Id]]++
+		}
+	}
+	switch group.Policy {
+	case nova.ServerGroupAffinity:
+		if len(memberHosts) > 0 {
+			return memberHosts[0], hasString(hosts, memberHosts[0]), nil
+		}
+		return hosts[0], true, nil
+	case nova.ServerGroupSoftAffinity:
+		if len(memberHosts) > 0 && hasString(hosts, memberHosts[0]) {
+			return memberHosts[0], true, nil
+		}
+		return hosts[0], true, nil
+	case nova.ServerGroupAntiAffinity:
+		maxPerHost := 1
+		if group.Rules != nil && group.Rules.MaxServerPerHost > 0 {
+			maxPerHost = group.Rules.MaxServerPerHost
+		}
+		for _, host := range hosts {
+			if serversPerHost[host] < maxPerHost {
+				return host, true, nil
+			}
+		}
+		return "", false, nil
+	case nova.ServerGroupSoftAntiAffinity:
+		best := hosts[0]
+		for _, host := range hosts {
+			if serversPerHost[host] < serversPerHost[best] {
+				best = host
+			}
+		}
+		return best, true, nil
+	}
+	return "", false, fmt.Errorf("unknown server group policy %q", group.Policy)
 }
 
-// removeSecurityGroup deletes an existing group.
-func (n *Nova) removeSecurityGroup(groupId string) error {
-	if err := n.ProcessFunctionHook(n, groupId); err != nil {
+// addServerGroupMember adds a server to a server group.
+func (n *Nova) addServerGroupMember(groupId, serverId string) error {
+	if err := n.ProcessFunctionHook(n, groupId, serverId); err != nil {
 		return err
 	}
-	if n.useNeutronNetworking {
-		return n.neutronModel.RemoveSecurityGroup(groupId)
-	}
-	if _, err := n.securityGroup(groupId); err != nil {
+	group, err := n.serverGroup(groupId)
+	if err != nil {
 		return err
 	}
-	delete(n.groups, groupId)
+	group.Members = append(group.Members, serverId)
+	n.osServerGroups[groupId] = *group
 	return nil
 }
 
-// addSecurityGroupRule creates a new rule in an existing group.
-// This can be either an ingress or a group rule (see the notes
-// about nova.RuleInfo).
-func (n *Nova) addSecurityGroupRule(ruleId string, rule nova.RuleInfo) error {
-	if err := n.ProcessFunctionHook(n, ruleId, rule); err != nil {
+// addKeyPair stores a new keypair.
+func (n *Nova) addKeyPair(keyPair nova.KeyPair) error {
+	if err := n.ProcessFunctionHook(n, keyPair); err != nil {
 		return err
 	}
-	if _, err := n.securityGroupRule(ruleId); err == nil {
-		return testservices.NewSecurityGroupRuleAlreadyExistsError(ruleId)
+	if _, ok := n.keyPairs[keyPair.Name]; ok {
+		return testservices.NewKeyPairAlreadyExistsError(keyPair.Name)
 	}
-	group, err := n.securityGroup(rule.ParentGroupId)
-	if err != nil {
-		return err
+	if overQuota(n.quotas.KeyPairs, len(n.keyPairs), 1) {
+		return testservices.NewKeyPairQuotaExceededError()
 	}
-	for _, ru := range group.Rules {
-		if ru.Id == ruleId {
-			return testservices.NewCannotAddTwiceRuleToGroupError(ru.Id, group.Id)
-		}
+	// The private key is never stored by Nova.
+	keyPair.PrivateKey = ""
+	n.keyPairs[keyPair.Name] = keyPair
+	return nil
+}
+
+// keyPair retrieves an existing keypair by name.
+func (n *Nova) keyPair(name string) (*nova.KeyPair, error) {
+	if err := n.ProcessFunctionHook(n, name); err != nil {
+		return nil, err
 	}
-	var zeroSecurityGroupRef nova.SecurityGroupRef
-	newrule := nova.SecurityGroupRule{
-		ParentGroupId: rule.ParentGroupId,
-		Id:            ruleId,
-		Group:         zeroSecurityGroupRef,
+	keyPair, ok := n.keyPairs[name]
+	if !ok {
+		return nil, testservices.NewKeyPairNotFoundError(name)
 	}
-	if rule.GroupId != nil {
-		sourceGroup, err := n.securityGroup(*rule.GroupId)
-		if err != nil {
-			return testservices.NewUnknownSecurityGroupError(*rule.GroupId)
-		}
-		newrule.Group = nova.SecurityGroupRef{
-			TenantId: sourceGroup.TenantId,
-			Name:     sourceGroup.Name,
-		}
-	} else if rule.Cidr == "" {
-		// http://pad.lv/1226996
-		// It seems that if you don't supply Cidr or GroupId then
-		// Openstack treats the Cidr as 0.0.0.0/0
-		// However, since that is not clearly specified we just panic()
-		// because we don't want to rely on that behavior
-		panic(fmt.Sprintf("Neither Cidr nor GroupId are set for this SecurityGroup Rule: %v", rule))
+	return &keyPair, nil
+}
+
+// allKeyPairs returns a list of all existing keypairs, sorted by
+// name.
+func (n *Nova) allKeyPairs() []nova.KeyPair {
+	var keyPairs []nova.KeyPair
+	for _, keyPair := range n.keyPairs {
+		keyPairs = append(keyPairs, keyPair)
 	}
-	if rule.FromPort != 0 {
-		newrule.FromPort = &rule.FromPort
+	sort.Slice(keyPairs, func(i, j int) bool {
+		return keyPairs[i].Name < keyPairs[j].Name
+	})
+	return keyPairs
+}
+
+// removeKeyPair deletes an existing keypair. Servers started with
+// the keypair keep referring to it by name.
+func (n *Nova) removeKeyPair(name string) error {
+	if err := n.ProcessFunctionHook(n, name); err != nil {
+		return err
 	}
-	if rule.ToPort != 0 {
-		newrule.ToPort = &rule.ToPort
+	if _, err := n.keyPair(name); err != nil {
+		return err
 	}
-	if rule.IPProtocol != "" {
-		newrule.IPProtocol = &rule.IPProtocol
+	delete(n.keyPairs, name)
+	return nil
+}
+
+// consoleOutput returns the console log of a server. If length is
+// positive only the last length lines are returned.
+func (n *Nova) consoleOutput(serverId string, length int) (string, error) {
+	if err := n.ProcessFunctionHook(n, serverId, length); err != nil {
+		return "", err
 	}
-	if rule.Cidr != "" {
-		newrule.IPRange = make(map[string]string)
-		newrule.IPRange["cidr"] = rule.Cidr
+	if _, err := n.server(serverId); err != nil {
+		return "", err
 	}
-
-	group.Rules = append(group.Rules, newrule)
-	n.groups[group.Id] = *group
-	n.rules[newrule.Id] = newrule
-	return nil
+	output := n.consoleOutputs[serverId]
+	if length <= 0 {
+		return output, nil
+	}
+	lines := strings.SplitAfter(output, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > length {
+		lines = lines[len(lines)-length:]
+	}
+	return strings.Join(lines, ""), nil
 }
 
-// hasSecurityGroupRule returns whether the given group contains the given rule,
-// or (when groupId="-1") whether the given rule exists.
-func (n *Nova) hasSecurityGroupRule(groupId, ruleId string) bool {
-	rule, ok := n.rules[ruleId]
-	_, err := n.securityGroup(groupId)
-	return ok && (groupId == "-1" || (err == nil && rule.ParentGroupId == groupId))
+// quotaUsage holds how much of the resources limited by the compute
+// quotas is in use.
+type quotaUsage struct {
+	instances    int
+	cores        int
+	ram          int
+	keyPairs     int
+	serverGroups int
 }
 
-// securityGroupRule retrieves an existing rule by ID.
-func (n *Nova) securityGroupRule(ruleId string) (*nova.SecurityGroupRule, error) {
-	if err := n.ProcessFunctionHook(n, ruleId); err != nil {
-		return nil, err
-	}
-	rule, ok := n.rules[ruleId]
-	if !ok {
-		return nil, testservices.NewSecurityGroupRuleNotFoundError(ruleId)
+// usage returns the current usage of the resources limited by the
+// compute quotas. Cores and RAM are counted from the flavors of the
+// existing servers.
+func (n *Nova) usage() quotaUsage {
+	var usage quotaUsage
+	for _, server := range n.servers {
+		usage.instances++
+		if flavor, ok := n.flavors[server.Flavor.Id]; ok {
+			usage.cores += flavor.VCPUs
+			usage.ram += flavor.RAM
+		}
 	}
-	return &rule, nil
+	usage.keyPairs = len(n.keyPairs)
+	usage.serverGroups = len(n.osServerGroups)
+	return usage
 }
 
-// removeSecurityGroupRule deletes an existing rule from its group.
-func (n *Nova) removeSecurityGroupRule(ruleId string) error {
-	if err := n.ProcessFunctionHook(n, ruleId); err != nil {
+// overQuota reports whether requesting more of a resource with the
+// given quota and usage would exceed the quota.
+func overQuota(quota, used, requested int) bool {
+	return quota != nova.Unlimited && used+requested > quota
+}
+
+// checkServerQuotas returns an error if creating a server with the
+// given flavor in the given server group, which may be empty, would
+// exceed the compute quotas.
+func (n *Nova) checkServerQuotas(flavorId, groupId string) error {
+	if err := n.ProcessFunctionHook(n, flavorId, groupId); err != nil {
 		return err
 	}
-	rule, err := n.securityGroupRule(ruleId)
+	usage := n.usage()
+	flavor := n.flavors[flavorId]
+	switch {
+	case overQuota(n.quotas.Instances, usage.instances, 1):
+		return testservices.NewQuotaExceededError("instances", 1, usage.instances, n.quotas.Instances)
+	case overQuota(n.quotas.Cores, usage.cores, flavor.VCPUs):
+		return testservices.NewQuotaExceededError("cores", flavor.VCPUs, usage.cores, n.quotas.Cores)
+	case overQuota(n.quotas.RAM, usage.ram, flavor.RAM):
+		return testservices.NewQuotaExceededError("ram", flavor.RAM, usage.ram, n.quotas.RAM)
+	}
+	if groupId == "" {
+		return nil
+	}
+	group, err := n.serverGroup(groupId)
 	if err != nil {
 		return err
 	}
-	if group, err := n.securityGroup(rule.ParentGroupId); err == nil {
-		idx := -1
-		for ri, ru := range group.Rules {
-			if ru.Id == ruleId {
-				idx = ri
-				break
-			}
-		}
-		if idx != -1 {
-			group.Rules = append(group.Rules[:idx], group.Rules[idx+1:]...)
-			n.groups[group.Id] = *group
-		}
-		// Silently ignore missing rules...
+	if overQuota(n.quotas.ServerGroupMembers, len(group.Members), 1) {
+		return testservices.NewServerGroupMembersQuotaExceededError()
 	}
-	// ...or groups
-	delete(n.rules, ruleId)
 	return nil
 }
 
-// addServerSecurityGroup attaches an existing server to a group.
-func (n *Nova) addServerSecurityGroup(serverId string, groupId string) error {
-	if err := n.ProcessFunctionHook(n, serverId, groupId); err != nil {
+// limits returns the absolute limits of the project, derived from
+// its compute quotas and usage.
+func (n *Nova) limits() nova.AbsoluteLimits {
+	usage := n.usage()
+	return nova.AbsoluteLimits{
+		MaxTotalCores:         n.quotas.Cores,
+		MaxTotalInstances:     n.quotas.Instances,
+		MaxTotalRAMSize:       n.quotas.RAM,
+		MaxTotalKeypairs:      n.quotas.KeyPairs,
+		MaxServerGroups:       n.quotas.ServerGroups,
+		MaxServerGroupMembers: n.quotas.ServerGroupMembers,
+		MaxServerMeta:         n.quotas.MetadataItems,
+		MaxImageMeta:          n.quotas.MetadataItems,
+		MaxPersonality:        n.quotas.InjectedFiles,
+		MaxPersonalitySize:    n.quotas.InjectedFileContentBytes,
+		TotalCoresUsed:        usage.cores,
+		TotalInstancesUsed:    usage.instances,
+		TotalRAMUsed:          usage.ram,
+		TotalServerGroupsUsed: usage.serverGroups,
+	}
+}
+
+// quotaUpdate is a quota which may be changed by a
+// nova.QuotaSetOpts, with its current usage.
+type quotaUpdate struct {
+	name  string
+	value *int
+	quota *int
+	used  int
+}
+
+// quotaUpdates returns the quotas in quotas which may be changed by
+// opts, with the values given in opts.
+func (n *Nova) quotaUpdates(quotas *nova.QuotaSet, opts nova.QuotaSetOpts) []quotaUpdate {
+	usage := n.usage()
+	return []quotaUpdate{
+		{"cores", opts.Cores, &quotas.Cores, usage.cores},
+		{"instances", opts.Instances, &quotas.Instances, usage.instances},
+		{"ram", opts.RAM, &quotas.RAM, usage.ram},
+		{"key_pairs", opts.KeyPairs, &quotas.KeyPairs, usage.keyPairs},
+		{"server_groups", opts.ServerGroups, &quotas.ServerGroups, usage.serverGroups},
+		{"server_group_members", opts.ServerGroupMembers, &quotas.ServerGroupMembers, 0},
+		{"metadata_items", opts.MetadataItems, &quotas.MetadataItems, 0},
+		{"injected_files", opts.InjectedFiles, &quotas.InjectedFiles, 0},
+		{"injected_file_content_bytes", opts.InjectedFileContentBytes, &quotas.InjectedFileContentBytes, 0},
+		{"injected_file_path_bytes", opts.InjectedFilePathBytes, &quotas.InjectedFilePathBytes, 0},
+	}
+}
+
+// updateQuotas changes the compute quotas set in opts. Unless
+// opts.Force is set, quotas may not be lowered below the current
+// usage.
+func (n *Nova) updateQuotas(opts nova.QuotaSetOpts) error {
+	if err := n.ProcessFunctionHook(n, opts); err != nil {
 		return err
 	}
-	if n.useNeutronNetworking {
-		if _, err := n.neutronModel.NovaSecurityGroup(groupId); err != nil {
-			return err
+	quotas := n.quotas
+	for _, q := range n.quotaUpdates(&quotas, opts) {
+		if q.value == nil {
+			continue
 		}
-	} else {
-		if _, err := n.securityGroup(groupId); err != nil {
-			return err
+		if *q.value < nova.Unlimited {
+			return testservices.NewInvalidQuotaError(fmt.Sprintf("%s must be >= %d", q.name, nova.Unlimited))
 		}
-	}
-	if _, err := n.server(serverId); err != nil {
-		return err
-	}
-	groups, ok := n.serverGroups[serverId]
-	if ok {
-		for _, gid := range groups {
-			if gid == groupId {
-				return testservices.NewServerBelongsToGroupError(serverId, groupId)
-			}
+		if !opts.Force && *q.value != nova.Unlimited && *q.value < q.used {
+			return testservices.NewQuotaBelowUsageError(q.name, *q.value, q.used)
 		}
+		*q.quota = *q.value
 	}
-	groups = append(groups, groupId)
-	n.serverGroups[serverId] = groups
+	n.quotas = quotas
 	return nil
 }
 
-// hasServerSecurityGroup returns whether the given server belongs to the group.
-func (n *Nova) hasServerSecurityGroup(serverId string, groupId string) bool {
-	if n.useNeutronNetworking {
-		if _, err := n.neutronModel.NovaSecurityGroup(groupId); err != nil {
-			return false
-		}
-	} else {
-		if _, err := n.securityGroup(groupId); err != nil {
-			return false
+// serverResize records the flavor, status and host of a server
+// before a pending resize or cold migration, so it can be reverted.
+type serverResize struct {
+	flavor    nova.Entity
+	status    string
+	host      string
+	migration *migration
+}
+
+// checkServerAction returns the server with the given ID if it is in
+// one of the allowed statuses for the given action, and a conflict
+// error otherwise.
+func (n *Nova) checkServerAction(serverId, action string, allowed ...string) (*nova.ServerDetail, error) {
+	server, err := n.server(serverId)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range allowed {
+		if server.Status == status {
+			return server, nil
 		}
 	}
-	if _, err := n.server(serverId); err != nil {
-		return false
+	return nil, testservices.NewServerStateConflictError(action, serverId, server.Status)
+}
+
+// serverStatusTransitions holds the statuses each of the simple
+// server actions is allowed from, and the status it results in.
+var serverStatusTransitions = map[string]struct {
+	from []string
+	to   string
+}{
+	"os-stop":  {[]string{nova.StatusActive, nova.StatusError}, nova.StatusShutoff},
+	"os-start": {[]string{nova.StatusShutoff}, nova.StatusActive},
+	"pause":    {[]string{nova.StatusActive}, nova.StatusPaused},
+	"unpause":  {[]string{nova.StatusPaused}, nova.StatusActive},
+	"suspend":  {[]string{nova.StatusActive}, nova.StatusSuspended},
+	"resume":   {[]string{nova.StatusSuspended}, nova.StatusActive},
+	"shelve": {
+		[]string{nova.StatusActive, nova.StatusShutoff, nova.StatusPaused, nova.StatusSuspended},
+		// The double behaves as if shelved_offload_time is 0.
+		nova.StatusShelvedOffloaded,
+	},
+	"unshelve": {[]string{nova.StatusShelved, nova.StatusShelvedOffloaded}, nova.StatusActive},
+	"unrescue": {[]string{nova.StatusRescue}, nova.StatusActive},
+}
+
+// changeServerStatus performs one of the simple server actions (stop,
+// start, pause, unpause, suspend, resume, shelve, unshelve and
+// unrescue), moving the server to the resulting status.
+func (n *Nova) changeServerStatus(serverId, action string) error {
+	if err := n.ProcessFunctionHook(n, serverId, action); err != nil {
+		return err
 	}
-	groups, ok := n.serverGroups[serverId]
+	transition, ok := serverStatusTransitions[action]
 	if !ok {
-		return false
+		return fmt.Errorf("unknown server action %q", action)
 	}
-	for _, gid := range groups {
-		if gid == groupId {
-			return true
-		}
+	if _, err := n.checkServerAction(serverId, action, transition.from...); err != nil {
+		return err
 	}
-	return false
+	n.serverStatuses[serverId] = transition.to
+	return nil
 }
 
-// allServerSecurityGroups returns all security groups attached to the
-// given server.
-func (n *Nova) allServerSecurityGroups(serverId string) []nova.SecurityGroup {
-	var groups []nova.SecurityGroup
-	for _, gid := range n.serverGroups[serverId] {
-		group, err := n.securityGroup(gid)
-		if err != nil {
-			return nil
-		}
-		groups = append(groups, *group)
+// rebootServer reboots a server, leaving it active. Hard reboots are
+// also allowed from statuses a soft reboot cannot recover.
+func (n *Nova) rebootServer(serverId, rebootType string) error {
+	if err := n.ProcessFunctionHook(n, serverId, rebootType); err != nil {
+		return err
 	}
-	return groups
+	allowed := []string{nova.StatusActive, nova.StatusShutoff}
+	if rebootType == nova.RebootHard {
+		allowed = append(allowed, nova.StatusPaused, nova.StatusSuspended, nova.StatusError)
+	}
+	if _, err := n.checkServerAction(serverId, "reboot", allowed...); err != nil {
+		return err
+	}
+	n.serverStatuses[serverId] = nova.StatusActive
+	return nil
 }
 
-// removeServerSecurityGroup detaches an existing server from a group.
-func (n *Nova) removeServerSecurityGroup(serverId string, groupId string) error {
-	if err := n.ProcessFunctionHook(n, serverId, groupId); err != nil {
+// rebuildServer replaces the image of a server, and optionally its
+// name and metadata. A stopped server stays stopped.
+func (n *Nova) rebuildServer(serverId string, opts nova.RebuildServerOpts) error {
+	if err := n.ProcessFunctionHook(n, serverId, opts); err != nil {
 		return err
 	}
-	if n.useNeutronNetworking {
-		if _, err := n.neutronModel.NovaSecurityGroup(groupId); err != nil {
-			return err
-		}
-	} else {
-		if _, err := n.securityGroup(groupId); err != nil {
-			return err
-		}
-	}
-	if _, err := n.server(serverId); err != nil {
+	server, err := n.checkServerAction(serverId, "rebuild", nova.StatusActive, nova.StatusShutoff, nova.StatusError)
+	if err != nil {
 		return err
 	}
-	groups, ok := n.serverGroups[serverId]
-	if !ok {
-		return testservices.NewServerDoesNotBelongToGroupsError(serverId)
+	stored := n.servers[serverId]
+	stored.Image = nova.Entity{Id: opts.ImageId}
+	if opts.Name != "" {
+		stored.Name = opts.Name
 	}
-	idx := -1
-	for gi, gid := range groups {
-		if gid == groupId {
-			idx = gi
-			break
-		}
+	if opts.Metadata != nil {
+		stored.Metadata = opts.Metadata
 	}
-	if idx == -1 {
-		return testservices.NewServerDoesNotBelongToGroupError(serverId, groupId)
+	stored.Updated = time.Now().Format(time.RFC3339)
+	n.servers[serverId] = stored
+	if server.Status != nova.StatusShutoff {
+		n.serverStatuses[serverId] = nova.StatusActive
 	}
-	groups = append(groups[:idx], groups[idx+1:]...)
-	n.serverGroups[serverId] = groups
 	return nil
 }
 
-// addFloatingIP creates a new floating IP address in the pool.
-func (n *Nova) addFloatingIP(ip nova.FloatingIP) error {
-	if err := n.ProcessFunctionHook(n, ip); err != nil {
+// rescueServer boots a server from a rescue image, which is the image
+// of the server if imageRef is empty.
+func (n *Nova) rescueServer(serverId, imageRef string) error {
+	if err := n.ProcessFunctionHook(n, serverId, imageRef); err != nil {
 		return err
 	}
-	if n.useNeutronNetworking {
-		return n.neutronModel.AddNovaFloatingIP(ip)
-	}
-	if _, err := n.floatingIP(ip.Id); err == nil {
-		return testservices.NewFloatingIPExistsError(ip.Id)
+	if _, err := n.checkServerAction(serverId, "rescue", nova.StatusActive, nova.StatusShutoff, nova.StatusError); err != nil {
+		return err
 	}
-	n.floatingIPs[ip.Id] = ip
+	n.serverStatuses[serverId] = nova.StatusRescue
 	return nil
 }
 
-// hasFloatingIP returns whether the given floating IP address exists.
-func (n *Nova) hasFloatingIP(address string) bool {
-	if n.useNeutronNetworking {
-		return n.neutronModel.HasFloatingIP(address)
+// resizeServer changes the flavor of a server, leaving it waiting for
+// the resize to be confirmed or reverted.
+func (n *Nova) resizeServer(serverId, flavorId string) error {
+	if err := n.ProcessFunctionHook(n, serverId, flavorId); err != nil {
+		return err
 	}
-	if len(n.floatingIPs) == 0 {
-		return false
+	server, err := n.checkServerAction(serverId, "resize", nova.StatusActive, nova.StatusShutoff)
+	if err != nil {
+		return err
 	}
-	for _, fip := range n.floatingIPs {
-		if fip.IP == address {
-			return true
-		}
+	if server.Flavor.Id == flavorId {
+		return testservices.NewResizeSameFlavorError()
 	}
-	return false
+	if _, ok := n.flavors[flavorId]; !ok {
+		return testservices.NewFlavorNotFoundForResizeError(flavorId)
+	}
+	flavor := nova.FlavorDetail{Id: flavorId}
+	n.buildFlavorLinks(&flavor)
+	n.serverResizes[serverId] = serverResize{
+		flavor:    server.Flavor,
+		status:    server.Status,
+		host:      n.serverHosts[server.Id],
+		migration: n.addMigration(server, nova.MigrationTypeResize, n.serverHosts[server.Id], nova.MigrationStatusFinished),
+	}
+	stored := n.servers[serverId]
+	stored.Flavor = nova.Entity{Id: flavor.Id, Links: flavor.Links}
+	n.servers[serverId] = stored
+	n.serverStatuses[serverId] = nova.StatusVerifyResize
+	return nil
+}
+
+// confirmResize completes a pending resize or cold migration of a
+// server.
+func (n *Nova) confirmResize(serverId string) error {
+	if err := n.ProcessFunctionHook(n, serverId); err != nil {
+		return err
+	}
+	if _, err := n.checkServerAction(serverId, "confirmResize", nova.StatusVerifyResize); err != nil {
+		return err
+	}
+	resize := n.serverResizes[serverId]
+	if resize.migration != nil {
+		n.setMigrationStatus(resize.migration, nova.MigrationStatusConfirmed)
+	}
+	n.serverStatuses[serverId] = resize.status
+	delete(n.serverResizes, serverId)
+	return nil
+}
+
+// revertResize restores the flavor and host a server had before a
+// pending resize or cold migration.
+func (n *Nova) revertResize(serverId string) error {
+	if err := n.ProcessFunctionHook(n, serverId); err != nil {
+		return err
+	}
+	if _, err := n.checkServerAction(serverId, "revertResize", nova.StatusVerifyResize); err != nil {
+		return err
+	}
+	resize := n.serverResizes[serverId]
+	stored := n.servers[serverId]
+	stored.Flavor = resize.flavor
+	n.servers[serverId] = stored
+	if resize.migration != nil {
+		n.moveServer(serverId, resize.host)
+		n.setMigrationStatus(resize.migration, nova.MigrationStatusReverted)
+	}
+	n.serverStatuses[serverId] = resize.status
+	delete(n.serverResizes, serverId)
+	return nil
+}
+
+// migration records a move of a server between compute hosts.
+type migration struct {
+	nova.Migration
+	serverId       string
+	blockMigration bool
+
+	// serverStatus is the status of a live migrated server before
+	// the migration, restored once the migration completes.
+	serverStatus string
+}
+
+// inProgress reports whether a live migration is still in progress.
+func (m *migration) inProgress() bool {
+	if m.MigrationType != nova.MigrationTypeLive {
+		return false
+	}
+	switch m.Status {
+	case nova.MigrationStatusQueued, nova.MigrationStatusPreparing, nova.MigrationStatusRunning:
+		return true
+	}
+	return false
+}
+
+// addMigration records a new migration of a server from its current
+// host to dest.
+func (n *Nova) addMigration(server *nova.ServerDetail, migrationType, dest, status string) *migration {
+	n.nextMigrationId++
+	uuid, _ := newUUID()
+	now := time.Now().Format(time.RFC3339)
+	m := &migration{
+		Migration: nova.Migration{
+			Id:            n.nextMigrationId,
+			UUID:          uuid,
+			InstanceUUID:  server.UUID,
+			MigrationType: migrationType,
+			Status:        status,
+			SourceCompute: n.serverHosts[server.Id],
+			SourceNode:    n.serverHosts[server.Id],
+			DestCompute:   dest,
+			DestNode:      dest,
+			DestHost:      n.hypervisor(dest).HostIP,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		},
+		serverId: server.Id,
+	}
+	n.migrations = append(n.migrations, m)
+	return m
+}
+
+// setMigrationStatus changes the status of a migration.
+func (n *Nova) setMigrationStatus(m *migration, status string) {
+	m.Status = status
+	m.UpdatedAt = time.Now().Format(time.RFC3339)
+}
+
+// moveServer places a server on the given host, in the availability
+// zone of the host if zones are defined by host aggregates.
+func (n *Nova) moveServer(serverId, host string) {
+	stored := n.servers[serverId]
+	n.serverHosts[serverId] = host
+	stored.HostId = n.hostId(host)
+	if n.hasAggregateZones() {
+		stored.AvailabilityZone = n.hostZone(host)
+	}
+	stored.Updated = time.Now().Format(time.RFC3339)
+	n.servers[serverId] = stored
+}
+
+// migrationDestination returns the host a server should be moved to.
+// If host is empty, the first enabled host which is up, other than
+// the current host of the server, is chosen. Servers in an
+// availability zone defined by host aggregates stay in their zone.
+func (n *Nova) migrationDestination(server *nova.ServerDetail, host string) (string, error) {
+	if host != "" {
+		if !n.hasHost(host) {
+			return "", testservices.NewComputeHostNotFoundError(host)
+		}
+		if host == n.serverHosts[server.Id] {
+			return "", testservices.NewMigrateToSameHostError(server.Id, host)
+		}
+		if !n.computeHost(host).schedulable() {
+			return "", testservices.NewNoValidHostError()
+		}
+		return host, nil
+	}
+	zone := server.AvailabilityZone
+	_, staticZone := n.availabilityZones[zone]
+	for _, h := range n.allHosts() {
+		if h == n.serverHosts[server.Id] || !n.computeHost(h).schedulable() {
+			continue
+		}
+		if zone != "" && !staticZone && n.hostZone(h) != zone {
+			continue
+		}
+		return h, nil
+	}
+	return "", testservices.NewNoValidHostError()
+}
+
+// liveMigrateServer moves a running server to another host, chosen by
+// the scheduler if host is empty. Unless live migrations are held,
+// the migration completes immediately.
+func (n *Nova) liveMigrateServer(serverId, host string, blockMigration bool) error {
+	if err := n.ProcessFunctionHook(n, serverId, host, blockMigration); err != nil {
+		return err
+	}
+	server, err := n.checkServerAction(serverId, "os-migrateLive", nova.StatusActive, nova.StatusPaused)
+	if err != nil {
+		return err
+	}
+	dest, err := n.migrationDestination(server, host)
+	if err != nil {
+		return err
+	}
+	m := n.addMigration(server, nova.MigrationTypeLive, dest, nova.MigrationStatusRunning)
+	m.blockMigration = blockMigration
+	m.serverStatus = server.Status
+	n.serverStatuses[serverId] = nova.StatusMigrating
+	if !n.liveMigrationsHeld {
+		n.completeLiveMigration(m)
+	}
+	return nil
+}
+
+// completeLiveMigration moves a live migrated server to the
+// destination host of the migration.
+func (n *Nova) completeLiveMigration(m *migration) {
+	n.moveServer(m.serverId, m.DestCompute)
+	n.serverStatuses[m.serverId] = m.serverStatus
+	n.setMigrationStatus(m, nova.MigrationStatusCompleted)
+}
+
+// migrateServer moves a server to another host by cold migration,
+// leaving it waiting for the migration to be confirmed or reverted.
+func (n *Nova) migrateServer(serverId, host string) error {
+	if err := n.ProcessFunctionHook(n, serverId, host); err != nil {
+		return err
+	}
+	server, err := n.checkServerAction(serverId, "migrate", nova.StatusActive, nova.StatusShutoff)
+	if err != nil {
+		return err
+	}
+	dest, err := n.migrationDestination(server, host)
+	if err != nil {
+		return err
+	}
+	n.serverResizes[serverId] = serverResize{
+		flavor:    server.Flavor,
+		status:    server.Status,
+		host:      n.serverHosts[server.Id],
+		migration: n.addMigration(server, nova.MigrationTypeCold, dest, nova.MigrationStatusFinished),
+	}
+	n.moveServer(serverId, dest)
+	n.serverStatuses[serverId] = nova.StatusVerifyResize
+	return nil
+}
+
+// evacuateServer rebuilds a server on another host, chosen by the
+// scheduler if host is empty. The nova-compute service of the current
+// host of the server must be forced down.
+func (n *Nova) evacuateServer(serverId, host string) error {
+	if err := n.ProcessFunctionHook(n, serverId, host); err != nil {
+		return err
+	}
+	server, err := n.checkServerAction(serverId, "evacuate", nova.StatusActive, nova.StatusShutoff, nova.StatusError)
+	if err != nil {
+		return err
+	}
+	if !n.computeHost(n.serverHosts[server.Id]).forcedDown {
+		return testservices.NewComputeServiceInUseError(n.serverHosts[server.Id])
+	}
+	dest, err := n.migrationDestination(server, host)
+	if err != nil {
+		return err
+	}
+	n.addMigration(server, nova.MigrationTypeEvacuation, dest, nova.MigrationStatusDone)
+	n.moveServer(serverId, dest)
+	if server.Status == nova.StatusError {
+		n.serverStatuses[serverId] = nova.StatusActive
+	}
+	return nil
+}
+
+// serverMigrationById returns the migration of a server with the
+// given ID.
+func (n *Nova) serverMigrationById(serverId string, migrationId int) (*migration, error) {
+	if _, err := n.server(serverId); err != nil {
+		return nil, err
+	}
+	for _, m := range n.migrations {
+		if m.Id == migrationId && m.serverId == serverId {
+			return m, nil
+		}
+	}
+	return nil, testservices.NewMigrationNotFoundForInstanceError(migrationId, serverId)
+}
+
+// forceCompleteMigration completes a live migration of a server which
+// is held running.
+func (n *Nova) forceCompleteMigration(serverId string, migrationId int) error {
+	if err := n.ProcessFunctionHook(n, serverId, migrationId); err != nil {
+		return err
+	}
+	m, err := n.serverMigrationById(serverId, migrationId)
+	if err != nil {
+		return err
+	}
+	if m.MigrationType != nova.MigrationTypeLive || m.Status != nova.MigrationStatusRunning {
+		return testservices.NewInvalidMigrationStateError(migrationId, serverId, m.Status, "force_complete")
+	}
+	n.completeLiveMigration(m)
+	return nil
+}
+
+// allMigrations returns the migrations of all servers, most recent
+// first, filtered by host, status, instance_uuid and migration_type.
+// The host filter matches either the source or destination host.
+func (n *Nova) allMigrations(f filter) []nova.Migration {
+	migrations := []nova.Migration{}
+	for i := len(n.migrations) - 1; i >= 0; i-- {
+		m := n.migrations[i]
+		if host := f["host"]; host != "" && host != m.SourceCompute && host != m.DestCompute {
+			continue
+		}
+		if status := f["status"]; status != "" && status != m.Status {
+			continue
+		}
+		if uuid := f["instance_uuid"]; uuid != "" && uuid != m.InstanceUUID {
+			continue
+		}
+		if migrationType := f["migration_type"]; migrationType != "" && migrationType != m.MigrationType {
+			continue
+		}
+		migrations = append(migrations, m.Migration)
+	}
+	return migrations
+}
+
+// asServerMigration returns an in-progress live migration with its
+// progress, which the double reports as half way through copying the
+// memory of the server, and its disks if they are block migrated.
+func (n *Nova) asServerMigration(m *migration) nova.ServerMigration {
+	sm := nova.ServerMigration{
+		Id:            m.Id,
+		UUID:          m.UUID,
+		ServerUUID:    m.InstanceUUID,
+		Status:        m.Status,
+		SourceCompute: m.SourceCompute,
+		SourceNode:    m.SourceNode,
+		DestCompute:   m.DestCompute,
+		DestNode:      m.DestNode,
+		DestHost:      m.DestHost,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+	}
+	if flavor, ok := n.flavors[n.servers[m.serverId].Flavor.Id]; ok {
+		sm.MemoryTotalBytes = int64(flavor.RAM) << 20
+		sm.MemoryProcessedBytes = sm.MemoryTotalBytes / 2
+		sm.MemoryRemainingBytes = sm.MemoryTotalBytes - sm.MemoryProcessedBytes
+		if m.blockMigration {
+			sm.DiskTotalBytes = int64(flavor.Disk+flavor.Ephemeral) << 30
+			sm.DiskProcessedBytes = sm.DiskTotalBytes / 2
+			sm.DiskRemainingBytes = sm.DiskTotalBytes - sm.DiskProcessedBytes
+		}
+	}
+	return sm
+}
+
+// serverMigrations returns the in-progress live migrations of a
+// server.
+func (n *Nova) serverMigrations(serverId string) ([]nova.ServerMigration, error) {
+	if err := n.ProcessFunctionHook(n, serverId); err != nil {
+		return nil, err
+	}
+	if _, err := n.server(serverId); err != nil {
+		return nil, err
+	}
+	migrations := []nova.ServerMigration{}
+	for _, m := range n.migrations {
+		if m.serverId == serverId && m.inProgress() {
+			migrations = append(migrations, n.asServerMigration(m))
+		}
+	}
+	return migrations, nil
+}
+
+// serverMigration returns the in-progress live migration of a server
+// with the given ID.
+func (n *Nova) serverMigration(serverId string, migrationId int) (*nova.ServerMigration, error) {
+	if err := n.ProcessFunctionHook(n, serverId, migrationId); err != nil {
+		return nil, err
+	}
+	m, err := n.serverMigrationById(serverId, migrationId)
+	if err != nil || !m.inProgress() {
+		return nil, testservices.NewLiveMigrationNotInProgressError(migrationId, serverId)
+	}
+	sm := n.asServerMigration(m)
+	return &sm, nil
+}
+
+// instanceActionEvents holds the events nova records while carrying
+// out each of the instance actions, in order.
+var instanceActionEvents = map[string][]string{
+	nova.ActionCreate:          {"conductor_schedule_and_build_instances", "compute__do_build_and_run_instance"},
+	nova.ActionDelete:          {"compute_terminate_instance"},
+	nova.ActionReboot:          {"compute_reboot_instance"},
+	nova.ActionStop:            {"compute_stop_instance"},
+	nova.ActionStart:           {"compute_start_instance"},
+	nova.ActionPause:           {"compute_pause_instance"},
+	nova.ActionUnpause:         {"compute_unpause_instance"},
+	nova.ActionSuspend:         {"compute_suspend_instance"},
+	nova.ActionResume:          {"compute_resume_instance"},
+	nova.ActionShelve:          {"compute_shelve_instance"},
+	nova.ActionUnshelve:        {"compute_unshelve_instance"},
+	nova.ActionRebuild:         {"compute_rebuild_instance"},
+	nova.ActionResize:          {"compute_prep_resize", "compute_resize_instance", "compute_finish_resize"},
+	nova.ActionConfirmResize:   {"compute_confirm_resize"},
+	nova.ActionRevertResize:    {"compute_revert_resize", "compute_finish_revert_resize"},
+	nova.ActionAttachVolume:    {"compute_attach_volume"},
+	nova.ActionDetachVolume:    {"compute_detach_volume"},
+	nova.ActionAttachInterface: {"compute_attach_interface"},
+	nova.ActionDetachInterface: {"compute_detach_interface"},
+	nova.ActionCreateImage:     {"compute_snapshot_instance"},
+	nova.ActionCreateBackup:    {"compute_backup_instance"},
+	nova.ActionLiveMigration: {
+		"compute_check_can_live_migrate_destination",
+		"compute_live_migration",
+		"compute_post_live_migration_at_destination",
+	},
+	nova.ActionMigrate:                    {"conductor_migrate_server", "compute_prep_resize", "compute_resize_instance", "compute_finish_resize"},
+	nova.ActionEvacuate:                   {"compute_rebuild_instance"},
+	nova.ActionRescue:                     {"compute_rescue_instance"},
+	nova.ActionUnrescue:                   {"compute_unrescue_instance"},
+	nova.ActionLiveMigrationForceComplete: {"compute_live_migration_force_complete"},
+}
+
+// instanceActionTimeFormat is the format of the times of instance
+// actions and events.
+const instanceActionTimeFormat = "2006-01-02T15:04:05.000000"
+
+// recordInstanceAction records that the given request made an action
+// against a server. If fault is not nil the first event of the action
+// failed with it, and no further events were run. Actions are kept
+// after the server is deleted.
+func (n *Nova) recordInstanceAction(server *nova.ServerDetail, action, requestId, userId string, fault *nova.ServerFault) error {
+	if err := n.ProcessFunctionHook(n, server, action, requestId, userId, fault); err != nil {
+		return err
+	}
+	eventNames, ok := instanceActionEvents[action]
+	if !ok {
+		return fmt.Errorf("unknown instance action %q", action)
+	}
+	now := time.Now().UTC().Format(instanceActionTimeFormat)
+	instanceAction := nova.InstanceAction{
+		Action:       action,
+		InstanceUUID: server.UUID,
+		ProjectId:    server.TenantId,
+		RequestId:    requestId,
+		StartTime:    now,
+		UserId:       userId,
+	}
+	for _, name := range eventNames {
+		event := nova.InstanceActionEvent{
+			Event:      name,
+			StartTime:  now,
+			FinishTime: now,
+			Result:     nova.EventResultSuccess,
+			Host:       n.serverHosts[server.Id],
+			HostId:     server.HostId,
+		}
+		if fault != nil {
+			instanceAction.Message = nova.EventResultError
+			event.Result = nova.EventResultError
+			event.Traceback = fault.Details
+			if event.Traceback == "" {
+				event.Traceback = fmt.Sprintf("Traceback (most recent call last):\n%s\n", fault.Message)
+			}
+			instanceAction.Events = append(instanceAction.Events, event)
+			break
+		}
+		instanceAction.Events = append(instanceAction.Events, event)
+	}
+	n.instanceActions[server.Id] = append(n.instanceActions[server.Id], instanceAction)
+	return nil
+}
+
+// allInstanceActions returns the actions made against a server, most
+// recent first, without their events. The actions of deleted servers
+// are only returned if includeDeleted is set.
+func (n *Nova) allInstanceActions(serverId string, includeDeleted bool) ([]nova.InstanceAction, error) {
+	if err := n.ProcessFunctionHook(n, serverId, includeDeleted); err != nil {
+		return nil, err
+	}
+	if err := n.checkInstanceActionsServer(serverId, includeDeleted); err != nil {
+		return nil, err
+	}
+	recorded := n.instanceActions[serverId]
+	actions := make([]nova.InstanceAction, 0, len(recorded))
+	for i := len(recorded) - 1; i >= 0; i-- {
+		action := recorded[i]
+		action.Events = nil
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// instanceAction returns the action made against a server by the
+// given request, along with its events.
+func (n *Nova) instanceAction(serverId, requestId string, includeDeleted bool) (*nova.InstanceAction, error) {
+	if err := n.ProcessFunctionHook(n, serverId, requestId, includeDeleted); err != nil {
+		return nil, err
+	}
+	if err := n.checkInstanceActionsServer(serverId, includeDeleted); err != nil {
+		return nil, err
+	}
+	for _, action := range n.instanceActions[serverId] {
+		if action.RequestId == requestId {
+			action.Events = append([]nova.InstanceActionEvent(nil), action.Events...)
+			return &action, nil
+		}
+	}
+	return nil, testservices.NewInstanceActionNotFoundError(requestId, serverId)
+}
+
+// checkInstanceActionsServer returns an error unless the actions of
+// the given server may be shown.
+func (n *Nova) checkInstanceActionsServer(serverId string, includeDeleted bool) error {
+	_, err := n.server(serverId)
+	if _, recorded := n.instanceActions[serverId]; err != nil && recorded && includeDeleted {
+		return nil
+	}
+	return err
+}
+
+// serverPassword returns the encrypted admin password of a server,
+// which is empty if none has been set.
+func (n *Nova) serverPassword(serverId string) (string, error) {
+	if err := n.ProcessFunctionHook(n, serverId); err != nil {
+		return "", err
+	}
+	if _, err := n.server(serverId); err != nil {
+		return "", err
+	}
+	return n.serverPasswords[serverId], nil
+}
+
+// clearServerPassword removes the admin password of a server.
+func (n *Nova) clearServerPassword(serverId string) error {
+	if err := n.ProcessFunctionHook(n, serverId); err != nil {
+		return err
+	}
+	if _, err := n.server(serverId); err != nil {
+		return err
+	}
+	delete(n.serverPasswords, serverId)
+	return nil
+}
+
+// diagnosticsStates maps server statuses to the state of the server
+// reported by the hypervisor. Other statuses are reported as pending.
+var diagnosticsStates = map[string]string{
+	nova.StatusActive:       nova.DiagnosticsStateRunning,
+	nova.StatusRescue:       nova.DiagnosticsStateRunning,
+	nova.StatusMigrating:    nova.DiagnosticsStateRunning,
+	nova.StatusVerifyResize: nova.DiagnosticsStateRunning,
+	nova.StatusPaused:       nova.DiagnosticsStatePaused,
+	nova.StatusSuspended:    nova.DiagnosticsStateSuspended,
+	nova.StatusShutoff:      nova.DiagnosticsStateShutdown,
+	nova.StatusShelved:      nova.DiagnosticsStateShutdown,
+	nova.StatusError:        nova.DiagnosticsStateCrashed,
+}
+
+// serverDiagnostics returns the diagnostics of a server. The double
+// reports the resources of the server's flavor, half of them in use,
+// with a NIC per attached interface and a disk per local disk and
+// attached volume.
+func (n *Nova) serverDiagnostics(serverId string) (*nova.ServerDiagnostics, error) {
+	if err := n.ProcessFunctionHook(n, serverId); err != nil {
+		return nil, err
+	}
+	server, err := n.server(serverId)
+	if err != nil {
+		return nil, err
+	}
+	if server.Status == nova.StatusShelvedOffloaded {
+		return nil, testservices.NewServerStateConflictError("get_diagnostics", serverId, server.Status)
+	}
+	state, ok := diagnosticsStates[server.Status]
+	if !ok {
+		state = nova.DiagnosticsStatePending
+	}
+	diagnostics := &nova.ServerDiagnostics{
+		State:        state,
+		Driver:       "libvirt",
+		Hypervisor:   "qemu",
+		HypervisorOS: "linux",
+	}
+	if created, err := time.Parse(time.RFC3339, server.Created); err == nil {
+		diagnostics.Uptime = int(time.Since(created).Seconds())
+	}
+	flavor := n.flavors[server.Flavor.Id]
+	diagnostics.MemoryDetails = nova.DiagnosticsMemory{Maximum: flavor.RAM, Used: flavor.RAM / 2}
+	for i := 0; i < flavor.VCPUs; i++ {
+		diagnostics.CPUDetails = append(diagnostics.CPUDetails, nova.DiagnosticsCPU{
+			Id:          i,
+			Time:        int64(diagnostics.Uptime) * 1e9 / 2,
+			Utilisation: 50,
+		})
+	}
+	for _, osInterface := range n.serverOSInterfaces(serverId) {
+		diagnostics.NICDetails = append(diagnostics.NICDetails, nova.DiagnosticsNIC{MACAddress: osInterface.MacAddress})
+	}
+	disks := len(n.serverIdToAttachedVolumes[serverId])
+	if flavor.Disk > 0 {
+		disks++
+	}
+	if flavor.Ephemeral > 0 {
+		disks++
+	}
+	for i := 0; i < disks; i++ {
+		diagnostics.DiskDetails = append(diagnostics.DiskDetails, nova.DiagnosticsDisk{})
+	}
+	diagnostics.NumCPUs = len(diagnostics.CPUDetails)
+	diagnostics.NumNICs = len(diagnostics.NICDetails)
+	diagnostics.NumDisks = len(diagnostics.DiskDetails)
+	return diagnostics, nil
+}
+
+// deletedServer is a server which has been deleted, kept to report
+// its usage.
+type deletedServer struct {
+	server  nova.ServerDetail
+	deleted time.Time
+}
+
+// serverUsageState returns the state a server is reported in by
+// usage reports, which is that of the underlying instance rather than
+// the server status.
+func (n *Nova) serverUsageState(serverId string) string {
+	status := nova.StatusActive
+	if s, ok := n.serverStatuses[serverId]; ok {
+		status = s
+	}
+	if status == nova.StatusShutoff {
+		return "stopped"
+	}
+	return strings.ToLower(status)
+}
+
+// serverUsages returns the usage over the given period of the
+// servers of a tenant, or of all tenants if tenantId is empty,
+// ordered by creation. Deleted servers are included if they existed
+// during the period.
+func (n *Nova) serverUsages(tenantId string, start, end time.Time) []nova.ServerUsage {
+	now := time.Now()
+	servers := make([]deletedServer, 0, len(n.servers)+len(n.deletedServers))
+	for _, server := range n.servers {
+		servers = append(servers, deletedServer{server: server})
+	}
+	servers = append(servers, n.deletedServers...)
+	var usages []nova.ServerUsage
+	var started []time.Time
+	for _, s := range servers {
+		if tenantId != "" && s.server.TenantId != tenantId {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, s.server.Created)
+		if err != nil {
+			// Servers added directly to the double may have no
+			// creation time.
+			continue
+		}
+		stopped := now
+		if !s.deleted.IsZero() {
+			stopped = s.deleted
+		}
+		from, to := created, stopped
+		if start.After(from) {
+			from = start
+		}
+		if end.Before(to) {
+			to = end
+		}
+		if from.After(to) {
+			continue
+		}
+		usage := nova.ServerUsage{
+			InstanceId: s.server.Id,
+			Name:       s.server.Name,
+			TenantId:   s.server.TenantId,
+			Hours:      to.Sub(from).Hours(),
+			State:      n.serverUsageState(s.server.Id),
+			StartedAt:  created.UTC().Format(instanceActionTimeFormat),
+			Uptime:     int(stopped.Sub(created).Seconds()),
+		}
+		if !s.deleted.IsZero() {
+			usage.State = "terminated"
+			usage.EndedAt = s.deleted.UTC().Format(instanceActionTimeFormat)
+		}
+		if flavor, ok := n.flavors[s.server.Flavor.Id]; ok {
+			usage.Flavor = flavor.Name
+			usage.VCPUs = flavor.VCPUs
+			usage.MemoryMB = flavor.RAM
+			usage.LocalGB = flavor.Disk + flavor.Ephemeral
+		}
+		usages = append(usages, usage)
+		started = append(started, created)
+	}
+	sort.Sort(serverUsagesByStart{usages, started})
+	return usages
+}
+
+// serverUsagesByStart sorts server usages by the creation time of
+// the servers, then by ID.
+type serverUsagesByStart struct {
+	usages  []nova.ServerUsage
+	started []time.Time
+}
+
+func (s serverUsagesByStart) Len() int {
+	return len(s.usages)
+}
+
+func (s serverUsagesByStart) Less(i, j int) bool {
+	if !s.started[i].Equal(s.started[j]) {
+		return s.started[i].Before(s.started[j])
+	}
+	return s.usages[i].InstanceId < s.usages[j].InstanceId
+}
+
+func (s serverUsagesByStart) Swap(i, j int) {
+	s.usages[i], s.usages[j] = s.usages[j], s.usages[i]
+	s.started[i], s.started[j] = s.started[j], s.started[i]
+}
+
+// tenantUsages returns the usage over the given period of the
+// servers of a tenant, or of all tenants if tenantId is empty,
+// grouped by tenant. At most limit servers are reported on if limit
+// is positive, starting after the server with the ID given by marker
+// if it is not empty; next is the marker of the following page when
+// the limit is reached.
+func (n *Nova) tenantUsages(tenantId string, start, end time.Time, detailed bool, limit int, marker string) (usages []nova.TenantUsage, next string, err error) {
+	if err := n.ProcessFunctionHook(n, tenantId, start, end, detailed, limit, marker); err != nil {
+		return nil, "", err
+	}
+	servers := n.serverUsages(tenantId, start, end)
+	if marker != "" {
+		found := false
+		for i, server := range servers {
+			if server.InstanceId == marker {
+				servers = servers[i+1:]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, "", testservices.NewMarkerNotFoundError(marker)
+		}
+	}
+	if limit > 0 && len(servers) >= limit {
+		servers = servers[:limit]
+		next = servers[limit-1].InstanceId
+	}
+	usages = []nova.TenantUsage{}
+	for _, server := range servers {
+		i := 0
+		for i < len(usages) && usages[i].TenantId != server.TenantId {
+			i++
+		}
+		if i == len(usages) {
+			usages = append(usages, nova.TenantUsage{
+				TenantId: server.TenantId,
+				Start:    start.UTC().Format(instanceActionTimeFormat),
+				Stop:     end.UTC().Format(instanceActionTimeFormat),
+			})
+		}
+		u := &usages[i]
+		u.TotalHours += server.Hours
+		u.TotalVCPUsUsage += server.VCPUHours()
+		u.TotalMemoryMBUsage += server.MemoryMBHours()
+		u.TotalLocalGBUsage += server.LocalGBHours()
+		if detailed {
+			u.ServerUsages = append(u.ServerUsages, server)
+		}
+	}
+	return usages, next, nil
+}
+
+// imageServerStatuses holds the statuses a server may be in to have
+// an image created from it.
+var imageServerStatuses = []string{nova.StatusActive, nova.StatusShutoff, nova.StatusPaused, nova.StatusSuspended}
+
+// createImage creates a snapshot image of a server.
+func (n *Nova) createImage(serverId, name string, metadata map[string]string) (*image, error) {
+	if err := n.ProcessFunctionHook(n, serverId, name, metadata); err != nil {
+		return nil, err
+	}
+	server, err := n.checkServerAction(serverId, "createImage", imageServerStatuses...)
+	if err != nil {
+		return nil, err
+	}
+	return n.addServerImage(server, name, "snapshot", metadata)
+}
+
+// backupServer creates a backup image of a server, then deletes the
+// oldest backups of the server with the same backup type beyond
+// rotation. It returns nil if the new backup was itself rotated away.
+func (n *Nova) backupServer(serverId, name, backupType string, rotation int, metadata map[string]string) (*image, error) {
+	if err := n.ProcessFunctionHook(n, serverId, name, backupType, rotation, metadata); err != nil {
+		return nil, err
+	}
+	if rotation < 0 {
+		return nil, testservices.NewBadRequestError(fmt.Sprintf("Invalid input for field/attribute rotation. Value: %d. %d is less than the minimum of 0", rotation, rotation))
+	}
+	server, err := n.checkServerAction(serverId, "createBackup", imageServerStatuses...)
+	if err != nil {
+		return nil, err
+	}
+	meta := map[string]string{"backup_type": backupType}
+	for k, v := range metadata {
+		meta[k] = v
+	}
+	backup, err := n.addServerImage(server, name, "backup", meta)
+	if err != nil {
+		return nil, err
+	}
+	var backups []image
+	for _, img := range n.images {
+		if img.Metadata["image_type"] == "backup" &&
+			img.Metadata["instance_uuid"] == server.UUID &&
+			img.Metadata["backup_type"] == backupType {
+			backups = append(backups, img)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].seq > backups[j].seq
+	})
+	if len(backups) <= rotation {
+		return backup, nil
+	}
+	for _, img := range backups[rotation:] {
+		delete(n.images, img.Id)
+	}
+	if _, ok := n.images[backup.Id]; !ok {
+		return nil, nil
+	}
+	return backup, nil
+}
+
+// addServerImage stores a new image created from a server.
+func (n *Nova) addServerImage(server *nova.ServerDetail, name, imageType string, metadata map[string]string) (*image, error) {
+	if name == "" {
+		return nil, testservices.NewBadRequestError("Invalid input for field/attribute name. Value: . '' is too short")
+	}
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	n.nextImageSeq++
+	status := n.imageStatus
+	if status == "" {
+		status = "ACTIVE"
+	}
+	progress := 25
+	if status == "ACTIVE" {
+		progress = 100
+	}
+	meta := map[string]string{
+		"image_type":     imageType,
+		"instance_uuid":  server.UUID,
+		"base_image_ref": server.Image.Id,
+		"user_id":        server.UserId,
+	}
+	for k, v := range metadata {
+		meta[k] = v
+	}
+	now := time.Now().Format(time.RFC3339)
+	url := "/images/" + id
+	img := image{
+		Id:       id,
+		Name:     name,
+		Created:  now,
+		Updated:  now,
+		Progress: progress,
+		Status:   status,
+		Links: []nova.Link{
+			{Href: n.endpointURL(true, url), Rel: "self"},
+			{Href: n.endpointURL(false, url), Rel: "bookmark"},
+		},
+		Metadata: meta,
+		Server:   &nova.Entity{Id: server.Id, Links: server.Links},
+		seq:      n.nextImageSeq,
+	}
+	n.images[id] = img
+	return &img, nil
+}
+
+// image retrieves an existing image by ID.
+func (n *Nova) image(imageId string) (*image, error) {
+	if err := n.ProcessFunctionHook(n, imageId); err != nil {
+		return nil, err
+	}
+	img, ok := n.images[imageId]
+	if !ok {
+		return nil, testservices.NewImageNotFoundError(imageId)
+	}
+	return &img, nil
+}
+
+// allImages returns a list of all existing images, oldest first.
+func (n *Nova) allImages() []image {
+	var images []image
+	for _, img := range n.images {
+		images = append(images, img)
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].seq < images[j].seq
+	})
+	return images
+}
+
+// removeImage deletes an existing image.
+func (n *Nova) removeImage(imageId string) error {
+	if err := n.ProcessFunctionHook(n, imageId); err != nil {
+		return err
+	}
+	if _, err := n.image(imageId); err != nil {
+		return err
+	}
+	delete(n.images, imageId)
+	return nil
+}
+
+func (n *Nova) updateSecurityGroup(group nova.SecurityGroup) error {
+	if err := n.ProcessFunctionHook(n, group); err != nil {
+		return err
+	}
+	if n.useNeutronNetworking {
+		return n.neutronModel.UpdateNovaSecurityGroup(group)
+	}
+	existingGroup, err := n.securityGroup(group.Id)
+	if err != nil {
+		return testservices.NewSecurityGroupByIDNotFoundError(group.Id)
+	}
+	existingGroup.Name = group.Name
+	existingGroup.Description = group.Description
+	n.groups[group.Id] = *existingGroup
+	return nil
+}
+
+// addSecurityGroup creates a new security group.
+func (n *Nova) addSecurityGroup(group nova.SecurityGroup) error {
+	if err := n.ProcessFunctionHook(n, group); err != nil {
+		return err
+	}
+	if n.useNeutronNetworking {
+		return n.neutronModel.AddNovaSecurityGroup(group)
+	}
+	if _, err := n.securityGroup(group.Id); err == nil {
+		return testservices.NewSecurityGroupAlreadyExistsError(group.Id)
+	}
+	group.TenantId = n.TenantId
+	if group.Rules == nil {
+		group.Rules = []nova.SecurityGroupRule{}
+	}
+	n.groups[group.Id] = group
+	return nil
+}
+
+// securityGroup retrieves an existing group by ID.
+func (n *Nova) securityGroup(groupId string) (*nova.SecurityGroup, error) {
+	if err := n.ProcessFunctionHook(n, groupId); err != nil {
+		return nil, err
+	}
+	if n.useNeutronNetworking {
+		return n.neutronModel.NovaSecurityGroup(groupId)
+	}
+	group, ok := n.groups[groupId]
+	if !ok {
+		return nil, testservices.NewSecurityGroupByIDNotFoundError(groupId)
+	}
+	return &group, nil
+}
+
+// securityGroupByName retrieves an existing named group.
+func (n *Nova) securityGroupByName(groupName string) (*nova.SecurityGroup, error) {
+	if err := n.ProcessFunctionHook(n, groupName); err != nil {
+		return nil, err
+	}
+	if n.useNeutronNetworking {
+		return n.neutronModel.NovaSecurityGroupByName(groupName)
+	}
+	for _, group := range n.groups {
+		if group.Name == groupName {
+			return &group, nil
+		}
+	}
+	return nil, testservices.NewSecurityGroupByNameNotFoundError(groupName)
+}
+
+// allSecurityGroups returns a list of all existing groups.
+func (n *Nova) allSecurityGroups() []nova.SecurityGroup {
+	var groups []nova.SecurityGroup
+	if n.useNeutronNetworking {
+		return n.neutronModel.AllNovaSecurityGroups()
+	}
+	for _, group := range n.groups {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// removeSecurityGroup deletes an existing group.
+func (n *Nova) removeSecurityGroup(groupId string) error {
+	if err := n.ProcessFunctionHook(n, groupId); err != nil {
+		return err
+	}
+	if n.useNeutronNetworking {
+		return n.neutronModel.RemoveSecurityGroup(groupId)
+	}
+	if _, err := n.securityGroup(groupId); err != nil {
+		return err
+	}
+	delete(n.groups, groupId)
+	return nil
+}
+
+// addSecurityGroupRule creates a new rule in an existing group.
+// This can be either an ingress or a group rule (see the notes
+// about nova.RuleInfo).
+func (n *Nova) addSecurityGroupRule(ruleId string, rule nova.RuleInfo) error {
+	if err := n.ProcessFunctionHook(n, ruleId, rule); err != nil {
+		return err
+	}
+	if _, err := n.securityGroupRule(ruleId); err == nil {
+		return testservices.NewSecurityGroupRuleAlreadyExistsError(ruleId)
+	}
+	group, err := n.securityGroup(rule.ParentGroupId)
+	if err != nil {
+		return err
+	}
+	for _, ru := range group.Rules {
+		if ru.Id == ruleId {
+			return testservices.NewCannotAddTwiceRuleToGroupError(ru.Id, group.Id)
+		}
+	}
+	var zeroSecurityGroupRef nova.SecurityGroupRef
+	newrule := nova.SecurityGroupRule{
+		ParentGroupId: rule.ParentGroupId,
+		Id:            ruleId,
+		Group:         zeroSecurityGroupRef,
+	}
+	if rule.GroupId != nil {
+		sourceGroup, err := n.securityGroup(*rule.GroupId)
+		if err != nil {
+			return testservices.NewUnknownSecurityGroupError(*rule.GroupId)
+		}
+		newrule.Group = nova.SecurityGroupRef{
+			TenantId: sourceGroup.TenantId,
+			Name:     sourceGroup.Name,
+		}
+	} else if rule.Cidr == "" {
+		// http://pad.lv/1226996
+		// It seems that if you don't supply Cidr or GroupId then
+		// Openstack treats the Cidr as 0.0.0.0/0
+		// However, since that is not clearly specified we just panic()
+		// because we don't want to rely on that behavior
+		panic(fmt.Sprintf("Neither Cidr nor GroupId are set for this SecurityGroup Rule: %v", rule))
+	}
+	if rule.FromPort != 0 {
+		newrule.FromPort = &rule.FromPort
+	}
+	if rule.ToPort != 0 {
+		newrule.ToPort = &rule.ToPort
+	}
+	if rule.IPProtocol != "" {
+		newrule.IPProtocol = &rule.IPProtocol
+	}
+	if rule.Cidr != "" {
+		newrule.IPRange = make(map[string]string)
+		newrule.IPRange["cidr"] = rule.Cidr
+	}
+
+	group.Rules = append(group.Rules, newrule)
+	n.groups[group.Id] = *group
+	n.rules[newrule.Id] = newrule
+	return nil
+}
+
+// hasSecurityGroupRule returns whether the given group contains the given rule,
+// or (when groupId="-1") whether the given rule exists.
+func (n *Nova) hasSecurityGroupRule(groupId, ruleId string) bool {
+	rule, ok := n.rules[ruleId]
+	_, err := n.securityGroup(groupId)
+	return ok && (groupId == "-1" || (err == nil && rule.ParentGroupId == groupId))
+}
+
+// securityGroupRule retrieves an existing rule by ID.
+func (n *Nova) securityGroupRule(ruleId string) (*nova.SecurityGroupRule, error) {
+	if err := n.ProcessFunctionHook(n, ruleId); err != nil {
+		return nil, err
+	}
+	rule, ok := n.rules[ruleId]
+	if !ok {
+		return nil, testservices.NewSecurityGroupRuleNotFoundError(ruleId)
+	}
+	return &rule, nil
+}
+
+// removeSecurityGroupRule deletes an existing rule from its group.
+func (n *Nova) removeSecurityGroupRule(ruleId string) error {
+	if err := n.ProcessFunctionHook(n, ruleId); err != nil {
+		return err
+	}
+	rule, err := n.securityGroupRule(ruleId)
+	if err != nil {
+		return err
+	}
+	if group, err := n.securityGroup(rule.ParentGroupId); err == nil {
+		idx := -1
+		for ri, ru := range group.Rules {
+			if ru.Id == ruleId {
+				idx = ri
+				break
+			}
+		}
+		if idx != -1 {
+			group.Rules = append(group.Rules[:idx], group.Rules[idx+1:]...)
+			n.groups[group.Id] = *group
+		}
+		// Silently ignore missing rules...
+	}
+	// ...or groups
+	delete(n.rules, ruleId)
+	return nil
+}
+
+// addServerSecurityGroup attaches an existing server to a group.
+func (n *Nova) addServerSecurityGroup(serverId string, groupId string) error {
+	if err := n.ProcessFunctionHook(n, serverId, groupId); err != nil {
+		return err
+	}
+	if n.useNeutronNetworking {
+		if _, err := n.neutronModel.NovaSecurityGroup(groupId); err != nil {
+			return err
+		}
+	} else {
+		if _, err := n.securityGroup(groupId); err != nil {
+			return err
+		}
+	}
+	if _, err := n.server(serverId); err != nil {
+		return err
+	}
+	groups, ok := n.serverGroups[serverId]
+	if ok {
+		for _, gid := range groups {
+			if gid == groupId {
+				return testservices.NewServerBelongsToGroupError(serverId, groupId)
+			}
+		}
+	}
+	groups = append(groups, groupId)
+	n.serverGroups[serverId] = groups
+	return nil
+}
+
+// hasServerSecurityGroup returns whether the given server belongs to the group.
+func (n *Nova) hasServerSecurityGroup(serverId string, groupId string) bool {
+	if n.useNeutronNetworking {
+		if _, err := n.neutronModel.NovaSecurityGroup(groupId); err != nil {
+			return false
+		}
+	} else {
+		if _, err := n.securityGroup(groupId); err != nil {
+			return false
+		}
+	}
+	if _, err := n.server(serverId); err != nil {
+		return false
+	}
+	groups, ok := n.serverGroups[serverId]
+	if !ok {
+		return false
+	}
+	for _, gid := range groups {
+		if gid == groupId {
+			return true
+		}
+	}
+	return false
+}
+
+// allServerSecurityGroups returns all security groups attached to the
+// given server.
+func (n *Nova) allServerSecurityGroups(serverId string) []nova.SecurityGroup {
+	var groups []nova.SecurityGroup
+	for _, gid := range n.serverGroups[serverId] {
+		group, err := n.securityGroup(gid)
+		if err != nil {
+			return nil
+		}
+		groups = append(groups, *group)
+	}
+	return groups
+}
+
+// removeServerSecurityGroup detaches an existing server from a group.
+func (n *Nova) removeServerSecurityGroup(serverId string, groupId string) error {
+	if err := n.ProcessFunctionHook(n, serverId, groupId); err != nil {
+		return err
+	}
+	if n.useNeutronNetworking {
+		if _, err := n.neutronModel.NovaSecurityGroup(groupId); err != nil {
+			return err
+		}
+	} else {
+		if _, err := n.securityGroup(groupId); err != nil {
+			return err
+		}
+	}
+	if _, err := n.server(serverId); err != nil {
+		return err
+	}
+	groups, ok := n.serverGroups[serverId]
+	if !ok {
+		return testservices.NewServerDoesNotBelongToGroupsError(serverId)
+	}
+	idx := -1
+	for gi, gid := range groups {
+		if gid == groupId {
+			idx = gi
+			break
+		}
+	}
+	if idx == -1 {
+		return testservices.NewServerDoesNotBelongToGroupError(serverId, groupId)
+	}
+	groups = append(groups[:idx], groups[idx+1:]...)
+	n.serverGroups[serverId] = groups
+	return nil
+}
+
+// addFloatingIP creates a new floating IP address in the pool.
+func (n *Nova) addFloatingIP(ip nova.FloatingIP) error {
+	if err := n.ProcessFunctionHook(n, ip); err != nil {
+		return err
+	}
+	if n.useNeutronNetworking {
+		return n.neutronModel.AddNovaFloatingIP(ip)
+	}
+	if _, err := n.floatingIP(ip.Id); err == nil {
+		return testservices.NewFloatingIPExistsError(ip.Id)
+	}
+	n.floatingIPs[ip.Id] = ip
+	return nil
+}
+
+// hasFloatingIP returns whether the given floating IP address exists.
+func (n *Nova) hasFloatingIP(address string) bool {
+	if n.useNeutronNetworking {
+		return n.neutronModel.HasFloatingIP(address)
+	}
+	if len(n.floatingIPs) == 0 {
+		return false
+	}
+	for _, fip := range n.floatingIPs {
+		if fip.IP == address {
+			return true
+		}
+	}
+	return false
 }
 
 // floatingIP retrieves the floating IP by ID.
@@ -868,42 +2808,154 @@ func (n *Nova) floatingIPByAddr(address string) (*nova.FloatingIP, error) {
 	return nil, testservices.NewFloatingIPNotFoundError(address)
 }
 
-// allFloatingIPs returns a list of all created floating IPs.
-func (n *Nova) allFloatingIPs() []nova.FloatingIP {
+// allFloatingIPs returns a list of all created floating IPs.
+func (n *Nova) allFloatingIPs() []nova.FloatingIP {
+	if n.useNeutronNetworking {
+		return n.neutronModel.AllNovaFloatingIPs()
+	}
+	var fips []nova.FloatingIP
+	for _, fip := range n.floatingIPs {
+		fips = append(fips, fip)
+	}
+	return fips
+}
+
+// removeFloatingIP deletes an existing floating IP by ID.
+func (n *Nova) removeFloatingIP(ipId string) error {
+	if err := n.ProcessFunctionHook(n, ipId); err != nil {
+		return err
+	}
+	if n.useNeutronNetworking {
+		return n.neutronModel.RemoveFloatingIP(ipId)
+	}
+	if _, err := n.floatingIP(ipId); err != nil {
+		return err
+	}
+	delete(n.floatingIPs, ipId)
+	return nil
+}
+
+// addServerFloatingIP attaches an existing floating IP to a server.
+func (n *Nova) addServerFloatingIP(serverId string, ipId string) error {
+	if err := n.ProcessFunctionHook(n, serverId, ipId); err != nil {
+		return err
+	}
+	if _, err := n.server(serverId); err != nil {
+		return err
+	}
+	fixedIP := "4.3.2.1" // not important really, unused
+	var fip *nova.FloatingIP
+	var err error
+	if n.useNeutronNetworking {
+		fip, err = n.neutronModel.NovaFloatingIP(ipId)
+		if err != nil {
+			return err
+		}
+		fip.FixedIP = &fixedIP
+		if err := n.neutronModel.UpdateNovaFloatingIP(fip); err != nil {
+			return err
+		}
+	} else {
+		fip, err = n.floatingIP(ipId)
+		if err != nil {
+			return err
+		} else {
+			fip.FixedIP = &fixedIP
+			fip.InstanceId = &serverId
+			n.floatingIPs[ipId] = *fip
+		}
+	}
+	fips, ok := n.serverIPs[serverId]
+	if ok {
+		for _, fipId := range fips {
+			if fipId == ipId {
+				return testservices.NewServerHasFloatingIPError(serverId, ipId)
+			}
+		}
+	}
+	fips = append(fips, ipId)
+	n.serverIPs[serverId] = fips
+	if err := n.addFloatingIPToServerAddresses(serverId, fip.IP); err != nil {
+		return err
+	}
+	return nil
+}
+
+// addFloatingIPToServerAddresses adds a floating ip address to the servers list
+// of Addresses to facilitate juju openstack provider tests.
+func (n *Nova) addFloatingIPToServerAddresses(serverId, address string) error {
+	server, err := n.server(serverId)
+	if err != nil {
+		return err
+	}
+	newAddresses := server.Addresses["private"]
+	if strings.Contains(address, ":") {
+		newAddresses = append(newAddresses, nova.IPAddress{6, address, "floating"})
+	} else {
+		newAddresses = append(newAddresses, nova.IPAddress{4, address, "floating"})
+	}
+	server.Addresses["private"] = newAddresses
+	n.servers[serverId] = *server
+	return nil
+}
+
+// hasServerFloatingIP verifies the given floating IP belongs to a server.
+func (n *Nova) hasServerFloatingIP(serverId, address string) bool {
+	if _, err := n.server(serverId); err != nil {
+		return false
+	}
+	var fip *nova.FloatingIP
+	var err error
 	if n.useNeutronNetworking {
-		return n.neutronModel.AllNovaFloatingIPs()
+		fip, err = n.neutronModel.NovaFloatingIPByAddr(address)
+	} else {
+		fip, err = n.floatingIPByAddr(address)
 	}
-	var fips []nova.FloatingIP
-	for _, fip := range n.floatingIPs {
-		fips = append(fips, fip)
+	if err != nil {
+		return false
 	}
-	return fips
+	fips, ok := n.serverIPs[serverId]
+	if !ok {
+		return false
+	}
+	for _, fipId := range fips {
+		if fipId == fip.Id {
+			return true
+		}
+	}
+	return false
 }
 
-// removeFloatingIP deletes an existing floating IP by ID.
-func (n *Nova) removeFloatingIP(ipId string) error {
-	if err := n.ProcessFunctionHook(n, ipId); err != nil {
+// removeFloatingIPFromServerAddresses removes a floating ip address from the
+// servers list of Addresses to facilitate juju openstack provider tests.
+func (n *Nova) removeFloatingIPFromServerAddresses(serverId, address string) error {
+	server, err := n.server(serverId)
+	if err != nil {
 		return err
 	}
-	if n.useNeutronNetworking {
-		return n.neutronModel.RemoveFloatingIP(ipId)
+	serverAddresses := []nova.IPAddress{}
+	for _, serverAddress := range server.Addresses["private"] {
+		if serverAddress.Address != address {
+			serverAddresses = append(serverAddresses, serverAddress)
+		}
 	}
-	if _, err := n.floatingIP(ipId); err != nil {
-		return err
+	if len(serverAddresses) != 0 {
+		server.Addresses["private"] = serverAddresses
+	} else {
+		server.Addresses["private"] = []nova.IPAddress{}
 	}
-	delete(n.floatingIPs, ipId)
+	n.servers[serverId] = *server
 	return nil
 }
 
-// addServerFloatingIP attaches an existing floating IP to a server.
-func (n *Nova) addServerFloatingIP(serverId string, ipId string) error {
-	if err := n.ProcessFunctionHook(n, serverId, ipId); err != nil {
+// removeServerFloatingIP deletes an attached floating IP from a server.
+func (n *Nova) removeServerFloatingIP(serverId string, ipId string) error {
+	if err := n.ProcessFunctionHook(n, serverId); err != nil {
 		return err
 	}
 	if _, err := n.server(serverId); err != nil {
 		return err
 	}
-	fixedIP := "4.3.2.1" // not important really, unused
 	var fip *nova.FloatingIP
 	var err error
 	if n.useNeutronNetworking {
@@ -911,218 +2963,699 @@ func (n *Nova) addServerFloatingIP(serverId string, ipId string) error {
 		if err != nil {
 			return err
 		}
-		fip.FixedIP = &fixedIP
-		if err := n.neutronModel.UpdateNovaFloatingIP(fip); err != nil {
+		fip.FixedIP = nil
+		if err = n.neutronModel.UpdateNovaFloatingIP(fip); err != nil {
 			return err
 		}
 	} else {
-		fip, err = n.floatingIP(ipId)
-		if err != nil {
+		if fip, err = n.floatingIP(ipId); err != nil {
 			return err
 		} else {
-			fip.FixedIP = &fixedIP
-			fip.InstanceId = &serverId
+			fip.FixedIP = nil
+			fip.InstanceId = nil
 			n.floatingIPs[ipId] = *fip
 		}
 	}
+	if err := n.removeFloatingIPFromServerAddresses(serverId, fip.IP); err != nil {
+		return err
+	}
 	fips, ok := n.serverIPs[serverId]
-	if ok {
-		for _, fipId := range fips {
-			if fipId == ipId {
-				return testservices.NewServerHasFloatingIPError(serverId, ipId)
+	if !ok {
+		return testservices.NewNoFloatingIPsToRemoveError(serverId)
+	}
+	idx := -1
+	for fi, fipId := range fips {
+		if fipId == ipId {
+			idx = fi
+			break
+		}
+	}
+	if idx == -1 {
+		return testservices.NewNoFloatingIPsError(serverId, ipId)
+	}
+	fips = append(fips[:idx], fips[idx+1:]...)
+	n.serverIPs[serverId] = fips
+	return nil
+}
+
+// allNetworks returns a list of all existing networks.
+func (n *Nova) allNetworks() (networks []nova.Network) {
+	if n.useNeutronNetworking {
+		return n.neutronModel.AllNovaNetworks()
+	} else {
+		for _, net := range n.networks {
+			networks = append(networks, net)
+		}
+		return networks
+	}
+}
+
+// networks returns the named network if it exists
+func (n *Nova) network(name string) (*nova.Network, error) {
+	if n.useNeutronNetworking {
+		return n.neutronModel.NovaNetwork(name)
+	} else {
+		net, ok := n.networks[name]
+		var err error
+		if !ok {
+			err = errors.NewNotFoundf(nil, nil, "network")
+		}
+		return &net, err
+	}
+}
+
+// allAvailabilityZones returns a list of all existing availability zones,
+// sorted by name. Zones set with SetAvailabilityZones are listed along
+// with the zones defined by host aggregates.
+func (n *Nova) allAvailabilityZones() (zones []nova.AvailabilityZone) {
+	for _, zone := range n.availabilityZones {
+		zones = append(zones, zone)
+	}
+	for _, zone := range n.aggregateZones() {
+		if _, ok := n.availabilityZones[zone.Name]; !ok {
+			zones = append(zones, zone)
+		}
+	}
+	sort.Sort(azByName(zones))
+	return zones
+}
+
+// availabilityZone returns the availability zone with the given name.
+func (n *Nova) availabilityZone(name string) (nova.AvailabilityZone, bool) {
+	if zone, ok := n.availabilityZones[name]; ok {
+		return zone, true
+	}
+	for _, zone := range n.aggregateZones() {
+		if zone.Name == name {
+			return zone, true
+		}
+	}
+	return nova.AvailabilityZone{}, false
+}
+
+// aggregateZones returns the availability zones defined by host
+// aggregates. Once any are defined, the hosts outside them are in the
+// default zone. A zone is available if any of its hosts is enabled.
+func (n *Nova) aggregateZones() []nova.AvailabilityZone {
+	available := make(map[string]bool)
+	for _, aggregate := range n.aggregates {
+		if aggregate.AvailabilityZone != "" && len(aggregate.Hosts) > 0 {
+			available[aggregate.AvailabilityZone] = false
+		}
+	}
+	if len(available) == 0 {
+		return nil
+	}
+	for _, host := range n.allHosts() {
+		zone := n.hostZone(host)
+		available[zone] = available[zone] || !n.computeHost(host).disabled
+	}
+	var zones []nova.AvailabilityZone
+	for name, ok := range available {
+		zones = append(zones, nova.AvailabilityZone{
+			Name:  name,
+			State: nova.AvailabilityZoneState{Available: ok},
+		})
+	}
+	return zones
+}
+
+type azByName []nova.AvailabilityZone
+
+func (a azByName) Len() int {
+	return len(a)
+}
+
+func (a azByName) Less(i, j int) bool {
+	return a[i].Name < a[j].Name
+}
+
+func (a azByName) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+// defaultZone is the availability zone of hosts outside any
+// availability zone defined by host aggregates.
+const defaultZone = "nova"
+
+// The capacity of each fake compute host.
+const (
+	hostVCPUs    = 16
+	hostMemoryMB = 65536
+	hostLocalGB  = 1000
+)
+
+// computeHost holds the state of a fake compute host.
+type computeHost struct {
+	hypervisorId   string
+	serviceId      string
+	disabled       bool
+	disabledReason string
+	forcedDown     bool
+	updated        string
+}
+
+// schedulable reports whether new servers may be placed on the host.
+func (h *computeHost) schedulable() bool {
+	return !h.disabled && !h.forcedDown
+}
+
+// computeHost returns the state of the named compute host, creating
+// it on first use.
+func (n *Nova) computeHost(name string) *computeHost {
+	host, ok := n.computeHosts[name]
+	if !ok {
+		hypervisorId, _ := newUUID()
+		serviceId, _ := newUUID()
+		host = &computeHost{
+			hypervisorId: hypervisorId,
+			serviceId:    serviceId,
+			updated:      time.Now().Format(time.RFC3339),
+		}
+		n.computeHosts[name] = host
+	}
+	return host
+}
+
+// hasHost reports whether a compute host with the given name exists.
+func (n *Nova) hasHost(name string) bool {
+	for _, host := range n.allHosts() {
+		if host == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hostZone returns the availability zone of the given host.
+func (n *Nova) hostZone(host string) string {
+	for _, aggregate := range n.allAggregates() {
+		if aggregate.AvailabilityZone != "" && hasString(aggregate.Hosts, host) {
+			return aggregate.AvailabilityZone
+		}
+	}
+	return defaultZone
+}
+
+// hasAggregateZones reports whether any host aggregate defines an
+// availability zone.
+func (n *Nova) hasAggregateZones() bool {
+	for _, aggregate := range n.aggregates {
+		if aggregate.AvailabilityZone != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hypervisor returns the hypervisor of the given host, with the
+// resources used by the servers on it.
+func (n *Nova) hypervisor(host string) nova.Hypervisor {
+	state := n.computeHost(host)
+	hypervisor := nova.Hypervisor{
+		Id:                 state.hypervisorId,
+		HypervisorHostname: host,
+		HypervisorType:     "QEMU",
+		HypervisorVersion:  4002000,
+		State:              nova.HostStateUp,
+		Status:             nova.HostStatusEnabled,
+		Service: nova.HypervisorService{
+			Id:             state.serviceId,
+			Host:           host,
+			DisabledReason: state.disabledReason,
+		},
+		VCPUs:    hostVCPUs,
+		MemoryMB: hostMemoryMB,
+		LocalGB:  hostLocalGB,
+	}
+	for i, h := range n.allHosts() {
+		if h == host {
+			hypervisor.HostIP = fmt.Sprintf("192.168.0.%d", i+1)
+		}
+	}
+	if state.disabled {
+		hypervisor.Status = nova.HostStatusDisabled
+	}
+	if state.forcedDown {
+		hypervisor.State = nova.HostStateDown
+	}
+	for _, server := range n.servers {
+		if n.serverHosts[server.Id] != host {
+			continue
+		}
+		hypervisor.RunningVMs++
+		if flavor, ok := n.flavors[server.Flavor.Id]; ok {
+			hypervisor.VCPUsUsed += flavor.VCPUs
+			hypervisor.MemoryMBUsed += flavor.RAM
+			hypervisor.LocalGBUsed += flavor.Disk + flavor.Ephemeral
+		}
+	}
+	hypervisor.FreeRAMMB = hypervisor.MemoryMB - hypervisor.MemoryMBUsed
+	hypervisor.FreeDiskGB = hypervisor.LocalGB - hypervisor.LocalGBUsed
+	return hypervisor
+}
+
+// allHypervisors returns the hypervisors of all the compute hosts.
+func (n *Nova) allHypervisors() []nova.Hypervisor {
+	var hypervisors []nova.Hypervisor
+	for _, host := range n.allHosts() {
+		hypervisors = append(hypervisors, n.hypervisor(host))
+	}
+	return hypervisors
+}
+
+// hypervisorById returns the hypervisor with the given ID.
+func (n *Nova) hypervisorById(hypervisorId string) (*nova.Hypervisor, error) {
+	if err := n.ProcessFunctionHook(n, hypervisorId); err != nil {
+		return nil, err
+	}
+	for _, hypervisor := range n.allHypervisors() {
+		if hypervisor.Id == hypervisorId {
+			return &hypervisor, nil
+		}
+	}
+	return nil, testservices.NewHypervisorNotFoundError(hypervisorId)
+}
+
+// hypervisorStatistics adds up the capacity and usage of all the
+// hypervisors.
+func (n *Nova) hypervisorStatistics() nova.HypervisorStatistics {
+	var stats nova.HypervisorStatistics
+	for _, h := range n.allHypervisors() {
+		stats.Count++
+		stats.VCPUs += h.VCPUs
+		stats.VCPUsUsed += h.VCPUsUsed
+		stats.MemoryMB += h.MemoryMB
+		stats.MemoryMBUsed += h.MemoryMBUsed
+		stats.FreeRAMMB += h.FreeRAMMB
+		stats.LocalGB += h.LocalGB
+		stats.LocalGBUsed += h.LocalGBUsed
+		stats.FreeDiskGB += h.FreeDiskGB
+		stats.RunningVMs += h.RunningVMs
+	}
+	return stats
+}
+
+// computeService returns the nova-compute service of the given host.
+func (n *Nova) computeService(host string) nova.ComputeService {
+	state := n.computeHost(host)
+	service := nova.ComputeService{
+		Id:             state.serviceId,
+		Binary:         nova.BinaryCompute,
+		Host:           host,
+		Zone:           n.hostZone(host),
+		Status:         nova.HostStatusEnabled,
+		State:          nova.HostStateUp,
+		DisabledReason: state.disabledReason,
+		ForcedDown:     state.forcedDown,
+		UpdatedAt:      state.updated,
+	}
+	if state.disabled {
+		service.Status = nova.HostStatusDisabled
+	}
+	if state.forcedDown {
+		service.State = nova.HostStateDown
+	}
+	return service
+}
+
+// allServices returns the services of all the compute hosts, filtered
+// by binary and host if they are not empty.
+func (n *Nova) allServices(binary, host string) []nova.ComputeService {
+	services := []nova.ComputeService{}
+	for _, h := range n.allHosts() {
+		service := n.computeService(h)
+		if (binary == "" || binary == service.Binary) && (host == "" || host == service.Host) {
+			services = append(services, service)
+		}
+	}
+	return services
+}
+
+// setServiceStatus enables or disables the service with the given ID.
+// No servers are scheduled on the host of a disabled service.
+func (n *Nova) setServiceStatus(serviceId string, disabled bool, reason string) (*nova.ComputeService, error) {
+	if err := n.ProcessFunctionHook(n, serviceId, disabled, reason); err != nil {
+		return nil, err
+	}
+	for _, host := range n.allHosts() {
+		state := n.computeHost(host)
+		if state.serviceId != serviceId {
+			continue
+		}
+		state.disabled = disabled
+		state.disabledReason = ""
+		if disabled {
+			state.disabledReason = reason
+		}
+		state.updated = time.Now().Format(time.RFC3339)
+		service := n.computeService(host)
+		return &service, nil
+	}
+	return nil, testservices.NewServiceNotFoundError(serviceId)
+}
+
+// setServiceForcedDown marks the service with the given ID as down,
+// or up again. No servers are scheduled on the host of a service
+// which is down, and servers may be evacuated from it.
+func (n *Nova) setServiceForcedDown(serviceId string, down bool) (*nova.ComputeService, error) {
+	if err := n.ProcessFunctionHook(n, serviceId, down); err != nil {
+		return nil, err
+	}
+	for _, host := range n.allHosts() {
+		state := n.computeHost(host)
+		if state.serviceId != serviceId {
+			continue
+		}
+		state.forcedDown = down
+		state.updated = time.Now().Format(time.RFC3339)
+		service := n.computeService(host)
+		return &service, nil
+	}
+	return nil, testservices.NewServiceNotFoundError(serviceId)
+}
+
+// createAggregate creates a host aggregate, in the given availability
+// zone if it is not empty.
+func (n *Nova) createAggregate(name, zone string) (*nova.Aggregate, error) {
+	if err := n.ProcessFunctionHook(n, name, zone); err != nil {
+		return nil, err
+	}
+	for _, aggregate := range n.aggregates {
+		if aggregate.Name == name {
+			return nil, testservices.NewAggregateNameExistsError(name)
+		}
+	}
+	uuid, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	n.nextAggregateId++
+	now := time.Now().Format(time.RFC3339)
+	aggregate := nova.Aggregate{
+		Id:               n.nextAggregateId,
+		UUID:             uuid,
+		Name:             name,
+		AvailabilityZone: zone,
+		Hosts:            []string{},
+		Metadata:         map[string]string{},
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if zone != "" {
+		aggregate.Metadata[nova.AggregateAvailabilityZoneKey] = zone
+	}
+	n.aggregates[aggregate.Id] = aggregate
+	return &aggregate, nil
+}
+
+// aggregate returns the host aggregate with the given ID.
+func (n *Nova) aggregate(aggregateId int) (*nova.Aggregate, error) {
+	if err := n.ProcessFunctionHook(n, aggregateId); err != nil {
+		return nil, err
+	}
+	aggregate, ok := n.aggregates[aggregateId]
+	if !ok {
+		return nil, testservices.NewAggregateNotFoundError(aggregateId)
+	}
+	return &aggregate, nil
+}
+
+// allAggregates returns all the host aggregates, ordered by ID.
+func (n *Nova) allAggregates() []nova.Aggregate {
+	aggregates := []nova.Aggregate{}
+	for _, aggregate := range n.aggregates {
+		aggregates = append(aggregates, aggregate)
+	}
+	sort.Slice(aggregates, func(i, j int) bool {
+		return aggregates[i].Id < aggregates[j].Id
+	})
+	return aggregates
+}
+
+// checkAggregateZone returns an error if giving the aggregate the
+// availability zone would put any of the hosts in two zones.
+func (n *Nova) checkAggregateZone(aggregateId int, zone string, hosts []string) error {
+	if zone == "" {
+		return nil
+	}
+	var conflicts []string
+	for _, other := range n.allAggregates() {
+		if other.Id == aggregateId || other.AvailabilityZone == "" || other.AvailabilityZone == zone {
+			continue
+		}
+		for _, host := range hosts {
+			if hasString(other.Hosts, host) && !hasString(conflicts, other.AvailabilityZone) {
+				conflicts = append(conflicts, other.AvailabilityZone)
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return testservices.NewAggregateZoneConflictError(aggregateId, conflicts)
+	}
+	return nil
+}
+
+// updateAggregate changes the name and availability zone of a host
+// aggregate. Empty values are left unchanged.
+func (n *Nova) updateAggregate(aggregateId int, name, zone string) (*nova.Aggregate, error) {
+	if err := n.ProcessFunctionHook(n, aggregateId, name, zone); err != nil {
+		return nil, err
+	}
+	aggregate, err := n.aggregate(aggregateId)
+	if err != nil {
+		return nil, err
+	}
+	if name != "" && name != aggregate.Name {
+		for _, other := range n.aggregates {
+			if other.Name == name {
+				return nil, testservices.NewAggregateNameExistsError(name)
 			}
 		}
+		aggregate.Name = name
 	}
-	fips = append(fips, ipId)
-	n.serverIPs[serverId] = fips
-	if err := n.addFloatingIPToServerAddresses(serverId, fip.IP); err != nil {
-		return err
+	if zone != "" {
+		if err := n.checkAggregateZone(aggregateId, zone, aggregate.Hosts); err != nil {
+			return nil, err
+		}
+		aggregate.AvailabilityZone = zone
+		aggregate.Metadata = copyMetadata(aggregate.Metadata)
+		aggregate.Metadata[nova.AggregateAvailabilityZoneKey] = zone
 	}
-	return nil
+	aggregate.UpdatedAt = time.Now().Format(time.RFC3339)
+	n.aggregates[aggregateId] = *aggregate
+	return aggregate, nil
 }
 
-// addFloatingIPToServerAddresses adds a floating ip address to the servers list
-// of Addresses to facilitate juju openstack provider tests.
-func (n *Nova) addFloatingIPToServerAddresses(serverId, address string) error {
-	server, err := n.server(serverId)
+// deleteAggregate deletes a host aggregate with no hosts.
+func (n *Nova) deleteAggregate(aggregateId int) error {
+	if err := n.ProcessFunctionHook(n, aggregateId); err != nil {
+		return err
+	}
+	aggregate, err := n.aggregate(aggregateId)
 	if err != nil {
 		return err
 	}
-	newAddresses := server.Addresses["private"]
-	if strings.Contains(address, ":") {
-		newAddresses = append(newAddresses, nova.IPAddress{6, address, "floating"})
-	} else {
-		newAddresses = append(newAddresses, nova.IPAddress{4, address, "floating"})
+	if len(aggregate.Hosts) > 0 {
+		return testservices.NewAggregateNotEmptyError(aggregateId)
 	}
-	server.Addresses["private"] = newAddresses
-	n.servers[serverId] = *server
+	delete(n.aggregates, aggregateId)
 	return nil
 }
 
-// hasServerFloatingIP verifies the given floating IP belongs to a server.
-func (n *Nova) hasServerFloatingIP(serverId, address string) bool {
-	if _, err := n.server(serverId); err != nil {
-		return false
-	}
-	var fip *nova.FloatingIP
-	var err error
-	if n.useNeutronNetworking {
-		fip, err = n.neutronModel.NovaFloatingIPByAddr(address)
-	} else {
-		fip, err = n.floatingIPByAddr(address)
+// addAggregateHost adds a compute host to a host aggregate.
+func (n *Nova) addAggregateHost(aggregateId int, host string) (*nova.Aggregate, error) {
+	if err := n.ProcessFunctionHook(n, aggregateId, host); err != nil {
+		return nil, err
 	}
+	aggregate, err := n.aggregate(aggregateId)
 	if err != nil {
-		return false
+		return nil, err
 	}
-	fips, ok := n.serverIPs[serverId]
-	if !ok {
-		return false
+	if !n.hasHost(host) {
+		return nil, testservices.NewComputeHostNotFoundError(host)
 	}
-	for _, fipId := range fips {
-		if fipId == fip.Id {
-			return true
-		}
+	if hasString(aggregate.Hosts, host) {
+		return nil, testservices.NewAggregateHostExistsError(aggregateId, host)
 	}
-	return false
+	if err := n.checkAggregateZone(aggregateId, aggregate.AvailabilityZone, []string{host}); err != nil {
+		return nil, err
+	}
+	aggregate.Hosts = append(append([]string{}, aggregate.Hosts...), host)
+	aggregate.UpdatedAt = time.Now().Format(time.RFC3339)
+	n.aggregates[aggregateId] = *aggregate
+	return aggregate, nil
 }
 
-// removeFloatingIPFromServerAddresses removes a floating ip address from the
-// servers list of Addresses to facilitate juju openstack provider tests.
-func (n *Nova) removeFloatingIPFromServerAddresses(serverId, address string) error {
-	server, err := n.server(serverId)
+// removeAggregateHost removes a compute host from a host aggregate.
+func (n *Nova) removeAggregateHost(aggregateId int, host string) (*nova.Aggregate, error) {
+	if err := n.ProcessFunctionHook(n, aggregateId, host); err != nil {
+		return nil, err
+	}
+	aggregate, err := n.aggregate(aggregateId)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	serverAddresses := []nova.IPAddress{}
-	for _, serverAddress := range server.Addresses["private"] {
-		if serverAddress.Address != address {
-			serverAddresses = append(serverAddresses, serverAddress)
-		}
+	if !hasString(aggregate.Hosts, host) {
+		return nil, testservices.NewAggregateHostNotFoundError(aggregateId, host)
 	}
-	if len(serverAddresses) != 0 {
-		server.Addresses["private"] = serverAddresses
-	} else {
-		server.Addresses["private"] = []nova.IPAddress{}
+	hosts := []string{}
+	for _, h := range aggregate.Hosts {
+		if h != host {
+			hosts = append(hosts, h)
+		}
 	}
-	n.servers[serverId] = *server
-	return nil
+	aggregate.Hosts = hosts
+	aggregate.UpdatedAt = time.Now().Format(time.RFC3339)
+	n.aggregates[aggregateId] = *aggregate
+	return aggregate, nil
 }
 
-// removeServerFloatingIP deletes an attached floating IP from a server.
-func (n *Nova) removeServerFloatingIP(serverId string, ipId string) error {
-	if err := n.ProcessFunctionHook(n, serverId); err != nil {
-		return err
+// setAggregateMetadata changes the metadata of a host aggregate. Items
+// with a nil value are deleted. The availability_zone item sets the
+// availability zone of the aggregate.
+func (n *Nova) setAggregateMetadata(aggregateId int, metadata map[string]*string) (*nova.Aggregate, error) {
+	if err := n.ProcessFunctionHook(n, aggregateId, metadata); err != nil {
+		return nil, err
 	}
-	if _, err := n.server(serverId); err != nil {
-		return err
+	aggregate, err := n.aggregate(aggregateId)
+	if err != nil {
+		return nil, err
 	}
-	var fip *nova.FloatingIP
-	var err error
-	if n.useNeutronNetworking {
-		fip, err = n.neutronModel.NovaFloatingIP(ipId)
-		if err != nil {
-			return err
-		}
-		fip.FixedIP = nil
-		if err = n.neutronModel.UpdateNovaFloatingIP(fip); err != nil {
-			return err
-		}
-	} else {
-		if fip, err = n.floatingIP(ipId); err != nil {
-			return err
+	items := copyMetadata(aggregate.Metadata)
+	for k, v := range metadata {
+		if v == nil {
+			delete(items, k)
 		} else {
-			fip.FixedIP = nil
-			fip.InstanceId = nil
-			n.floatingIPs[ipId] = *fip
-		}
-	}
-	if err := n.removeFloatingIPFromServerAddresses(serverId, fip.IP); err != nil {
-		return err
-	}
-	fips, ok := n.serverIPs[serverId]
-	if !ok {
-		return testservices.NewNoFloatingIPsToRemoveError(serverId)
-	}
-	idx := -1
-	for fi, fipId := range fips {
-		if fipId == ipId {
-			idx = fi
-			break
+			items[k] = *v
 		}
 	}
-	if idx == -1 {
-		return testservices.NewNoFloatingIPsError(serverId, ipId)
+	zone := items[nova.AggregateAvailabilityZoneKey]
+	if err := n.checkAggregateZone(aggregateId, zone, aggregate.Hosts); err != nil {
+		return nil, err
 	}
-	fips = append(fips[:idx], fips[idx+1:]...)
-	n.serverIPs[serverId] = fips
-	return nil
+	aggregate.Metadata = items
+	aggregate.AvailabilityZone = zone
+	aggregate.UpdatedAt = time.Now().Format(time.RFC3339)
+	n.aggregates[aggregateId] = *aggregate
+	return aggregate, nil
 }
 
-// allNetworks returns a list of all existing networks.
-func (n *Nova) allNetworks() (networks []nova.Network) {
-	if n.useNeutronNetworking {
-		return n.neutronModel.AllNovaNetworks()
-	} else {
-		for _, net := range n.networks {
-			networks = append(networks, net)
-		}
-		return networks
+// copyMetadata returns a copy of the given metadata, which may be nil.
+func copyMetadata(metadata map[string]string) map[string]string {
+	result := make(map[string]string)
+	for k, v := range metadata {
+		result[k] = v
 	}
+	return result
 }
 
-// networks returns the named network if it exists
-func (n *Nova) network(name string) (*nova.Network, error) {
-	if n.useNeutronNetworking {
-		return n.neutronModel.NovaNetwork(name)
-	} else {
-		net, ok := n.networks[name]
-		var err error
-		if !ok {
-			err = errors.NewNotFoundf(nil, nil, "network")
+// Limits on the length of server metadata keys and values.
+const (
+	maxMetadataKeyLength   = 255
+	maxMetadataValueLength = 255
+)
+
+// validateMetadata checks the given server metadata against the
+// limits applied by nova, including the metadata items quota.
+func (n *Nova) validateMetadata(metadata map[string]string) error {
+	for k, v := range metadata {
+		if k == "" {
+			return testservices.NewMetadataKeyBlankError()
 		}
-		return &net, err
+		if len(k) > maxMetadataKeyLength {
+			return testservices.NewMetadataSizeError("key", maxMetadataKeyLength)
+		}
+		if len(v) > maxMetadataValueLength {
+			return testservices.NewMetadataSizeError("value", maxMetadataValueLength)
+		}
+	}
+	if limit := n.quotas.MetadataItems; limit != nova.Unlimited && len(metadata) > limit {
+		return testservices.NewMetadataLimitExceededError(limit)
 	}
+	return nil
 }
 
-// allAvailabilityZones returns a list of all existing availability zones,
-// sorted by name.
-func (n *Nova) allAvailabilityZones() (zones []nova.AvailabilityZone) {
-	for _, zone := range n.availabilityZones {
-		zones = append(zones, zone)
+// setServerMetadata sets metadata on a server, keeping any other
+// existing items.
+func (n *Nova) setServerMetadata(serverId string, metadata map[string]string) error {
+	if err := n.ProcessFunctionHook(n, serverId, metadata); err != nil {
+		return err
 	}
-	sort.Sort(azByName(zones))
-	return zones
+	return n.mergeServerMetadata(serverId, metadata)
 }
 
-type azByName []nova.AvailabilityZone
-
-func (a azByName) Len() int {
-	return len(a)
+// mergeServerMetadata adds the given items to the metadata of a
+// server, provided the result is valid.
+func (n *Nova) mergeServerMetadata(serverId string, metadata map[string]string) error {
+	server, err := n.server(serverId)
+	if err != nil {
+		return err
+	}
+	merged := make(map[string]string)
+	for k, v := range server.Metadata {
+		merged[k] = v
+	}
+	for k, v := range metadata {
+		merged[k] = v
+	}
+	if err := n.validateMetadata(merged); err != nil {
+		return err
+	}
+	server.Metadata = merged
+	n.servers[serverId] = *server
+	return nil
 }
 
-func (a azByName) Less(i, j int) bool {
-	return a[i].Name < a[j].Name
+// replaceServerMetadata replaces all the metadata of a server.
+func (n *Nova) replaceServerMetadata(serverId string, metadata map[string]string) error {
+	if err := n.ProcessFunctionHook(n, serverId, metadata); err != nil {
+		return err
+	}
+	server, err := n.server(serverId)
+	if err != nil {
+		return err
+	}
+	if err := n.validateMetadata(metadata); err != nil {
+		return err
+	}
+	replaced := make(map[string]string)
+	for k, v := range metadata {
+		replaced[k] = v
+	}
+	server.Metadata = replaced
+	n.servers[serverId] = *server
+	return nil
 }
 
-func (a azByName) Swap(i, j int) {
-	a[i], a[j] = a[j], a[i]
+// setServerMetadataItem creates or updates a single metadata item of a
+// server.
+func (n *Nova) setServerMetadataItem(serverId, key, value string) error {
+	if err := n.ProcessFunctionHook(n, serverId, key, value); err != nil {
+		return err
+	}
+	return n.mergeServerMetadata(serverId, map[string]string{key: value})
 }
 
-// setServerMetadata sets metadata on a server.
-func (n *Nova) setServerMetadata(serverId string, metadata map[string]string) error {
-	if err := n.ProcessFunctionHook(n, serverId, metadata); err != nil {
+// deleteServerMetadataItem deletes a single metadata item of a server.
+func (n *Nova) deleteServerMetadataItem(serverId, key string) error {
+	if err := n.ProcessFunctionHook(n, serverId, key); err != nil {
 		return err
 	}
 	server, err := n.server(serverId)
 	if err != nil {
 		return err
 	}
-	if server.Metadata == nil {
-		server.Metadata = make(map[string]string)
+	if _, ok := server.Metadata[key]; !ok {
+		return testservices.NewMetadataItemNotFoundError()
 	}
-	for k, v := range metadata {
-		server.Metadata[k] = v
+	remaining := make(map[string]string)
+	for k, v := range server.Metadata {
+		if k != key {
+			remaining[k] = v
+		}
 	}
+	server.Metadata = remaining
 	n.servers[serverId] = *server
 	return nil
 }
@@ -1194,3 +3727,224 @@ func (n *Nova) hasServerOSInterface(serverID string, ipAddress string) bool {
 	}
 	return false
 }
+
+// attachInterfaceStatuses holds the statuses a server may be in to
+// have interfaces attached or detached.
+var attachInterfaceStatuses = []string{nova.StatusActive, nova.StatusPaused, nova.StatusShutoff, nova.StatusSuspended, nova.StatusShelvedOffloaded}
+
+// attachOSInterface attaches a network interface to a server, either
+// by creating a port on the network netId or by using the existing
+// port portId, and adds its address to the server. Ports are shared
+// with neutron when it is used for networking.
+func (n *Nova) attachOSInterface(serverId, netId, portId, fixedIP, tag string) (*nova.OSInterface, error) {
+	if err := n.ProcessFunctionHook(n, serverId, netId, portId, fixedIP, tag); err != nil {
+		return nil, err
+	}
+	if netId != "" && portId != "" {
+		return nil, testservices.NewBadRequestError("Must not input both network_id and port_id")
+	}
+	if netId == "" && portId == "" {
+		return nil, testservices.NewBadRequestError("Must input network_id or port_id")
+	}
+	if fixedIP != "" && portId != "" {
+		return nil, testservices.NewBadRequestError("Must not input both fixed_ips and port_id")
+	}
+	if _, err := n.checkServerAction(serverId, "attach_interface", attachInterfaceStatuses...); err != nil {
+		return nil, err
+	}
+	var port neutron.PortV2
+	if portId != "" {
+		if !n.useNeutronNetworking {
+			return nil, testservices.NewPortByIDNotFoundError(portId)
+		}
+		existing, err := n.neutronModel.Port(portId)
+		if err != nil {
+			return nil, err
+		}
+		if existing.DeviceId != "" {
+			return nil, testservices.NewPortInUseError(portId)
+		}
+		port = *existing
+	} else {
+		if _, err := n.network(netId); err != nil {
+			return nil, testservices.NewNetworkNotFoundError(netId)
+		}
+		if fixedIP != "" && n.fixedIPInUse(fixedIP) {
+			return nil, testservices.NewFixedIPInUseError(fixedIP)
+		}
+		id, err := newUUID()
+		if err != nil {
+			return nil, err
+		}
+		port = neutron.PortV2{Id: id, NetworkId: netId, TenantId: n.TenantId}
+		if fixedIP != "" {
+			port.FixedIPs = []neutron.PortFixedIPsV2{{IPAddress: fixedIP}}
+		}
+	}
+	if len(port.FixedIPs) == 0 {
+		port.FixedIPs = []neutron.PortFixedIPsV2{{IPAddress: n.nextFixedIP()}}
+	}
+	if port.MACAddress == "" {
+		n.nextFixedIPId++
+		id := n.nextFixedIPId
+		port.MACAddress = fmt.Sprintf("fa:16:3e:%02x:%02x:%02x", byte(id>>16), byte(id>>8), byte(id))
+	}
+	port.DeviceId = serverId
+	port.DeviceOwner = "compute:nova"
+	port.Status = "ACTIVE"
+	if n.useNeutronNetworking {
+		var err error
+		if portId == "" {
+			err = n.neutronModel.AddPort(port)
+		} else {
+			err = n.neutronModel.UpdatePort(port)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if portId == "" {
+		n.createdPorts[port.Id] = true
+	}
+	osInterface := nova.OSInterface{
+		IPAddress:  port.FixedIPs[0].IPAddress,
+		MacAddress: port.MACAddress,
+		NetID:      port.NetworkId,
+		PortID:     port.Id,
+		PortState:  port.Status,
+		Tag:        tag,
+	}
+	for _, ip := range port.FixedIPs {
+		osInterface.FixedIPs = append(osInterface.FixedIPs, nova.PortFixedIP{IPAddress: ip.IPAddress, SubnetID: ip.SubnetID})
+	}
+	n.serverIdToOSInterfaces[serverId] = append(n.serverIdToOSInterfaces[serverId], osInterface)
+	n.addServerAddresses(serverId, port.NetworkId, osInterface.FixedIPs)
+	return &osInterface, nil
+}
+
+// detachOSInterface detaches the interface with the given port from a
+// server, and removes its address from the server. A port created by
+// attachOSInterface is deleted, and any other port is released.
+func (n *Nova) detachOSInterface(serverId, portId string) error {
+	if err := n.ProcessFunctionHook(n, serverId, portId); err != nil {
+		return err
+	}
+	if _, err := n.checkServerAction(serverId, "detach_interface", attachInterfaceStatuses...); err != nil {
+		return err
+	}
+	interfaces := n.serverIdToOSInterfaces[serverId]
+	for i, osInterface := range interfaces {
+		if osInterface.PortID != portId {
+			continue
+		}
+		n.serverIdToOSInterfaces[serverId] = append(interfaces[:i], interfaces[i+1:]...)
+		n.removeServerAddresses(serverId, osInterface.FixedIPs)
+		n.releasePort(serverId, portId)
+		return nil
+	}
+	return testservices.NewPortNotAttachedError(portId)
+}
+
+// releasePort deletes a port attached to a server by
+// attachOSInterface, or clears the device of any other port attached
+// to the server.
+func (n *Nova) releasePort(serverId, portId string) {
+	created := n.createdPorts[portId]
+	delete(n.createdPorts, portId)
+	if !n.useNeutronNetworking {
+		return
+	}
+	port, err := n.neutronModel.Port(portId)
+	if err != nil || port.DeviceId != serverId {
+		return
+	}
+	if created {
+		n.neutronModel.RemovePort(portId)
+		return
+	}
+	port.DeviceId = ""
+	port.DeviceOwner = ""
+	port.Status = "DOWN"
+	n.neutronModel.UpdatePort(*port)
+}
+
+// fixedIPInUse reports whether an address is used by the interface of
+// a server or, with neutron networking, by a port.
+func (n *Nova) fixedIPInUse(address string) bool {
+	for _, interfaces := range n.serverIdToOSInterfaces {
+		for _, osInterface := range interfaces {
+			if osInterface.IPAddress == address {
+				return true
+			}
+		}
+	}
+	if n.useNeutronNetworking {
+		for _, port := range n.neutronModel.AllPorts() {
+			for _, ip := range port.FixedIPs {
+				if ip.IPAddress == address {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// nextFixedIP allocates an unused address for an attached interface.
+func (n *Nova) nextFixedIP() string {
+	for {
+		n.nextFixedIPId++
+		id := n.nextFixedIPId
+		address := fmt.Sprintf("10.%d.%d.%d", 100+byte(id>>16), byte(id>>8), byte(id))
+		if byte(id) != 0 && !n.fixedIPInUse(address) {
+			return address
+		}
+	}
+}
+
+// addServerAddresses adds the fixed addresses of an interface on the
+// given network to a server.
+func (n *Nova) addServerAddresses(serverId, netId string, ips []nova.PortFixedIP) {
+	label := netId
+	if network, err := n.network(netId); err == nil && network.Label != "" {
+		label = network.Label
+	}
+	server := n.servers[serverId]
+	if server.Addresses == nil {
+		server.Addresses = make(map[string][]nova.IPAddress)
+	}
+	for _, ip := range ips {
+		version := 4
+		if parsed := net.ParseIP(ip.IPAddress); parsed != nil && parsed.To4() == nil {
+			version = 6
+		}
+		server.Addresses[label] = append(server.Addresses[label], nova.IPAddress{Version: version, Address: ip.IPAddress, Type: "fixed"})
+	}
+	n.servers[serverId] = server
+}
+
+// removeServerAddresses removes the fixed addresses of an interface
+// from a server.
+func (n *Nova) removeServerAddresses(serverId string, ips []nova.PortFixedIP) {
+	server := n.servers[serverId]
+	for label, addresses := range server.Addresses {
+		var kept []nova.IPAddress
+		for _, address := range addresses {
+			removed := false
+			for _, ip := range ips {
+				if address.Address == ip.IPAddress && address.Type == "fixed" {
+					removed = true
+				}
+			}
+			if !removed {
+				kept = append(kept, address)
+			}
+		}
+		if len(kept) == 0 {
+			delete(server.Addresses, label)
+		} else {
+			server.Addresses[label] = kept
+		}
+	}
+	n.servers[serverId] = server
+}
@@ -3,14 +3,26 @@
 package novaservice
 
 import (
+	"context"
+	"crypto/md5"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
+	"math/big"
 	"net/http"
+	"net/url"
 	"path"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -309,7 +321,15 @@ func (h *novaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		errNotFound.ServeHTTP(w, r)
 		return
 	}
-	err = h.method(h.n, w, r)
+	// Every request is given an ID, which is recorded against the
+	// actions it makes on servers.
+	uuid, err := newUUID()
+	if err == nil {
+		reqId := "req-" + uuid
+		w.Header().Set("X-Openstack-Request-Id", reqId)
+		r = r.WithContext(context.WithValue(r.Context(), requestIdKey{}, reqId))
+		err = h.method(h.n, w, r)
+	}
 	if err == nil {
 		return
 	}
@@ -376,8 +396,29 @@ func (n *Nova) HandleRoot(w http.ResponseWriter, r *http.Request) {
 	n.handler((*Nova).handleRoot).ServeHTTP(w, r)
 }
 
+// flavorPath splits the path of a request below flavors/<id> into
+// the flavor ID and the remaining path segments.
+func flavorPath(urlPath string) (flavorId string, rest []string) {
+	i := strings.Index(urlPath, "/flavors/")
+	if i < 0 {
+		return "", nil
+	}
+	parts := strings.Split(strings.Trim(urlPath[i+len("/flavors/"):], "/"), "/")
+	return parts[0], parts[1:]
+}
+
 // handleFlavors handles the flavors HTTP API.
 func (n *Nova) handleFlavors(w http.ResponseWriter, r *http.Request) error {
+	// Handle extra specs, access lists and actions as leaves of a flavor.
+	if strings.Contains(r.URL.Path, "/os-extra_specs") {
+		return n.handleFlavorExtraSpecs(w, r)
+	}
+	if strings.Contains(r.URL.Path, "/os-flavor-access") {
+		return n.handleFlavorAccess(w, r)
+	}
+	if flavorId, rest := flavorPath(r.URL.Path); len(rest) == 1 && rest[0] == "action" {
+		return n.handleFlavorActions(flavorId, w, r)
+	}
 	switch r.Method {
 	case "GET":
 		if flavorId := path.Base(r.URL.Path); flavorId != "flavors" {
@@ -409,7 +450,20 @@ func (n *Nova) handleFlavors(w http.ResponseWriter, r *http.Request) error {
 		if len(body) == 0 {
 			return errBadRequest2
 		}
-		return errNotImplemented
+		var req struct {
+			Flavor nova.FlavorOpts `json:"flavor"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return errBadRequest3
+		}
+		flavor, err := n.newFlavor(req.Flavor)
+		if err != nil {
+			return err
+		}
+		resp := struct {
+			Flavor nova.FlavorDetail `json:"flavor"`
+		}{*flavor}
+		return sendJSON(http.StatusOK, resp, w, r)
 	case "PUT":
 		if flavorId := path.Base(r.URL.Path); flavorId != "flavors" {
 			return errNotFoundJSON
@@ -417,13 +471,199 @@ func (n *Nova) handleFlavors(w http.ResponseWriter, r *http.Request) error {
 		return errNotFound
 	case "DELETE":
 		if flavorId := path.Base(r.URL.Path); flavorId != "flavors" {
-			return errForbidden
+			if _, err := n.flavor(flavorId); err != nil {
+				return testservices.NewFlavorNotFoundError(flavorId)
+			}
+			if err := n.removeFlavor(flavorId); err != nil {
+				return err
+			}
+			writeResponse(w, http.StatusAccepted, nil)
+			return nil
+		}
+		return errNotFound
+	}
+	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+}
+
+// newFlavor validates the options of a flavor create request, and
+// adds the requested flavor.
+func (n *Nova) newFlavor(opts nova.FlavorOpts) (*nova.FlavorDetail, error) {
+	switch {
+	case opts.Name == "" || len(opts.Name) > 255:
+		return nil, testservices.NewInvalidFlavorError("name must be between 1 and 255 characters long")
+	case opts.RAM < 1:
+		return nil, testservices.NewInvalidFlavorError("ram must be at least 1")
+	case opts.VCPUs < 1:
+		return nil, testservices.NewInvalidFlavorError("vcpus must be at least 1")
+	case opts.Disk < 0 || opts.Swap < 0 || opts.Ephemeral < 0 || opts.RxTxFactor < 0:
+		return nil, testservices.NewInvalidFlavorError("disk, swap, ephemeral and rxtx_factor must not be negative")
+	}
+	if _, err := n.flavorByName(opts.Name); err == nil {
+		return nil, testservices.NewFlavorNameExistsError(opts.Name)
+	}
+	flavor := nova.FlavorDetail{
+		Id:         opts.Id,
+		Name:       opts.Name,
+		RAM:        opts.RAM,
+		VCPUs:      opts.VCPUs,
+		Disk:       opts.Disk,
+		Swap:       opts.Swap,
+		Ephemeral:  opts.Ephemeral,
+		RxTxFactor: opts.RxTxFactor,
+		IsPublic:   opts.IsPublic == nil || *opts.IsPublic,
+	}
+	if flavor.Id == "" {
+		// Nova generates UUIDs, but the double must also work with
+		// numeric IDs.
+		for i := len(n.flavors) + 1; ; i++ {
+			if _, ok := n.flavors[strconv.Itoa(i)]; !ok {
+				flavor.Id = strconv.Itoa(i)
+				break
+			}
 		}
+	}
+	if flavor.RxTxFactor == 0 {
+		flavor.RxTxFactor = 1
+	}
+	n.buildFlavorLinks(&flavor)
+	if err := n.addFlavor(flavor); err != nil {
+		return nil, err
+	}
+	return &flavor, nil
+}
+
+// handleFlavorExtraSpecs handles the flavors/<id>/os-extra_specs HTTP
+// API.
+func (n *Nova) handleFlavorExtraSpecs(w http.ResponseWriter, r *http.Request) error {
+	flavorId, rest := flavorPath(r.URL.Path)
+	if len(rest) == 0 || len(rest) > 2 || rest[0] != "os-extra_specs" {
 		return errNotFound
 	}
+	var key string
+	if len(rest) == 2 {
+		key = rest[1]
+	}
+	type extraSpecs struct {
+		ExtraSpecs map[string]string `json:"extra_specs"`
+	}
+	switch r.Method {
+	case "GET":
+		specs, err := n.extraSpecs(flavorId)
+		if err != nil {
+			return err
+		}
+		if key == "" {
+			return sendJSON(http.StatusOK, extraSpecs{specs}, w, r)
+		}
+		value, ok := specs[key]
+		if !ok {
+			return testservices.NewFlavorExtraSpecNotFoundError(flavorId, key)
+		}
+		return sendJSON(http.StatusOK, map[string]string{key: value}, w, r)
+	case "POST", "PUT":
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil || len(body) == 0 {
+			return errBadRequest2
+		}
+		var specs map[string]string
+		if r.Method == "POST" {
+			if key != "" {
+				return errNotFound
+			}
+			var req extraSpecs
+			if err := json.Unmarshal(body, &req); err != nil {
+				return errBadRequest3
+			}
+			specs = req.ExtraSpecs
+		} else {
+			if err := json.Unmarshal(body, &specs); err != nil {
+				return errBadRequest3
+			}
+			if _, ok := specs[key]; !ok || key == "" || len(specs) != 1 {
+				return testservices.NewBadRequestError("Request body and URI mismatch")
+			}
+		}
+		if len(specs) == 0 {
+			return testservices.NewBadRequestError("No extra specs given")
+		}
+		if err := n.setExtraSpecs(flavorId, specs); err != nil {
+			return err
+		}
+		if r.Method == "PUT" {
+			return sendJSON(http.StatusOK, specs, w, r)
+		}
+		return sendJSON(http.StatusOK, extraSpecs{specs}, w, r)
+	case "DELETE":
+		if key == "" {
+			return errNotFound
+		}
+		if err := n.unsetExtraSpec(flavorId, key); err != nil {
+			return err
+		}
+		writeResponse(w, http.StatusOK, nil)
+		return nil
+	}
 	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
 }
 
+// sendFlavorAccess sends the access list of a private flavor.
+func (n *Nova) sendFlavorAccess(flavorId string, w http.ResponseWriter, r *http.Request) error {
+	access, err := n.allFlavorAccess(flavorId)
+	if err != nil {
+		return err
+	}
+	resp := struct {
+		FlavorAccess []nova.FlavorAccess `json:"flavor_access"`
+	}{access}
+	return sendJSON(http.StatusOK, resp, w, r)
+}
+
+// handleFlavorAccess handles the flavors/<id>/os-flavor-access HTTP
+// API.
+func (n *Nova) handleFlavorAccess(w http.ResponseWriter, r *http.Request) error {
+	flavorId, rest := flavorPath(r.URL.Path)
+	if len(rest) != 1 || rest[0] != "os-flavor-access" {
+		return errNotFound
+	}
+	if r.Method != "GET" {
+		return errNotFound
+	}
+	return n.sendFlavorAccess(flavorId, w, r)
+}
+
+// handleFlavorActions handles the flavors/<id>/action HTTP API.
+func (n *Nova) handleFlavorActions(flavorId string, w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return errNotFound
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil || len(body) == 0 {
+		return errBadRequest2
+	}
+	type tenantAccess struct {
+		Tenant string `json:"tenant"`
+	}
+	var action struct {
+		AddTenantAccess    *tenantAccess `json:"addTenantAccess"`
+		RemoveTenantAccess *tenantAccess `json:"removeTenantAccess"`
+	}
+	if err := json.Unmarshal(body, &action); err != nil {
+		return errBadRequest3
+	}
+	switch {
+	case action.AddTenantAccess != nil && action.AddTenantAccess.Tenant != "":
+		err = n.addFlavorAccess(flavorId, action.AddTenantAccess.Tenant)
+	case action.RemoveTenantAccess != nil && action.RemoveTenantAccess.Tenant != "":
+		err = n.removeFlavorAccess(flavorId, action.RemoveTenantAccess.Tenant)
+	default:
+		return errBadRequest3
+	}
+	if err != nil {
+		return err
+	}
+	return n.sendFlavorAccess(flavorId, w, r)
+}
+
 // handleFlavorsDetail handles the flavors/detail HTTP API.
 func (n *Nova) handleFlavorsDetail(w http.ResponseWriter, r *http.Request) error {
 	switch r.Method {
@@ -455,6 +695,39 @@ func (n *Nova) handleFlavorsDetail(w http.ResponseWriter, r *http.Request) error
 	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
 }
 
+// statusActionNames maps the simple server actions to the names of the
+// instance actions they record.
+var statusActionNames = map[string]string{
+	"os-stop":  nova.ActionStop,
+	"os-start": nova.ActionStart,
+	"pause":    nova.ActionPause,
+	"unpause":  nova.ActionUnpause,
+	"suspend":  nova.ActionSuspend,
+	"resume":   nova.ActionResume,
+	"shelve":   nova.ActionShelve,
+	"unshelve": nova.ActionUnshelve,
+	"unrescue": nova.ActionUnrescue,
+}
+
+// sendImageCreated responds to an action which created an image,
+// with the image ID in the body from microversion 2.45 and in the
+// Location header before it. A nil image was not kept.
+func sendImageCreated(img *image, w http.ResponseWriter, r *http.Request) error {
+	if img == nil {
+		writeResponse(w, http.StatusAccepted, nil)
+		return nil
+	}
+	if microversionAtLeast(r, "2.45") {
+		resp := struct {
+			ImageId string `json:"image_id"`
+		}{img.Id}
+		return sendJSON(http.StatusAccepted, resp, w, r)
+	}
+	w.Header().Set("Location", img.Links[0].Href)
+	writeResponse(w, http.StatusAccepted, nil)
+	return nil
+}
+
 // handleServerActions handles the servers/<id>/action HTTP API.
 func (n *Nova) handleServerActions(server *nova.ServerDetail, w http.ResponseWriter, r *http.Request) error {
 	if server == nil {
@@ -477,10 +750,115 @@ func (n *Nova) handleServerActions(server *nova.ServerDetail, w http.ResponseWri
 		RemoveFloatingIP *struct {
 			Address string
 		}
+		Reboot *struct {
+			Type string
+		}
+		Rebuild *nova.RebuildServerOpts
+		Resize  *struct {
+			FlavorRef string
+		}
+		GetConsoleOutput *struct {
+			Length *int
+		} `json:"os-getConsoleOutput"`
+		CreateImage  *nova.CreateImageOpts `json:"createImage"`
+		CreateBackup *struct {
+			Name       string
+			BackupType string `json:"backup_type"`
+			Rotation   *int
+			Metadata   map[string]string
+		} `json:"createBackup"`
+		MigrateLive *struct {
+			Host           *string
+			BlockMigration json.RawMessage `json:"block_migration"`
+		} `json:"os-migrateLive"`
+		Evacuate *struct {
+			Host      string
+			AdminPass string `json:"adminPass"`
+		}
 	}
 	if err := json.Unmarshal(body, &action); err != nil {
 		return err
 	}
+	// Actions without arguments are sent as {"<action>": null}, so
+	// they can only be told apart by their keys.
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(body, &keys); err != nil {
+		return err
+	}
+	for key := range keys {
+		switch key {
+		case "os-stop", "os-start", "pause", "unpause", "suspend", "resume", "shelve", "unshelve", "unrescue":
+			if err := n.changeServerStatus(server.Id, key); err != nil {
+				return err
+			}
+			if err := n.recordAction(server, statusActionNames[key], r, nil); err != nil {
+				return err
+			}
+			writeResponse(w, http.StatusAccepted, nil)
+			return nil
+		case "confirmResize":
+			if err := n.confirmResize(server.Id); err != nil {
+				return err
+			}
+			if err := n.recordAction(server, nova.ActionConfirmResize, r, nil); err != nil {
+				return err
+			}
+			writeResponse(w, http.StatusNoContent, nil)
+			return nil
+		case "revertResize":
+			if err := n.revertResize(server.Id); err != nil {
+				return err
+			}
+			if err := n.recordAction(server, nova.ActionRevertResize, r, nil); err != nil {
+				return err
+			}
+			writeResponse(w, http.StatusAccepted, nil)
+			return nil
+		case "rescue":
+			var rescue struct {
+				AdminPass      string `json:"adminPass"`
+				RescueImageRef string `json:"rescue_image_ref"`
+			}
+			if err := json.Unmarshal(keys[key], &rescue); err != nil {
+				return errBadRequest3
+			}
+			if err := n.rescueServer(server.Id, rescue.RescueImageRef); err != nil {
+				return err
+			}
+			if err := n.recordAction(server, nova.ActionRescue, r, nil); err != nil {
+				return err
+			}
+			adminPass := rescue.AdminPass
+			if adminPass == "" {
+				var err error
+				if adminPass, err = generatePassword(); err != nil {
+					return err
+				}
+			}
+			resp := struct {
+				AdminPass string `json:"adminPass"`
+			}{adminPass}
+			return sendJSON(http.StatusOK, resp, w, r)
+		case "migrate":
+			var migrate struct {
+				Host string
+			}
+			if err := json.Unmarshal(keys[key], &migrate); err != nil {
+				return errBadRequest3
+			}
+			if migrate.Host != "" && !microversionAtLeast(r, "2.56") {
+				return testservices.NewBadRequestError("Invalid input for field/attribute migrate. Value: Additional properties are not allowed ('host' was unexpected)")
+			}
+			if err := n.migrateServer(server.Id, migrate.Host); err != nil {
+				return err
+			}
+			if err := n.recordAction(server, nova.ActionMigrate, r, nil); err != nil {
+				return err
+			}
+			writeResponse(w, http.StatusAccepted, nil)
+			return nil
+		}
+	}
 	switch {
 	case action.AddSecurityGroup != nil:
 		name := action.AddSecurityGroup.Name
@@ -532,68 +910,398 @@ func (n *Nova) handleServerActions(server *nova.ServerDetail, w http.ResponseWri
 		}
 		writeResponse(w, http.StatusAccepted, nil)
 		return nil
+	case action.Reboot != nil:
+		rebootType := action.Reboot.Type
+		if rebootType != nova.RebootSoft && rebootType != nova.RebootHard {
+			return errBadRequest3
+		}
+		if err := n.rebootServer(server.Id, rebootType); err != nil {
+			return err
+		}
+		if err := n.recordAction(server, nova.ActionReboot, r, nil); err != nil {
+			return err
+		}
+		writeResponse(w, http.StatusAccepted, nil)
+		return nil
+	case action.Rebuild != nil:
+		if action.Rebuild.ImageId == "" {
+			return errBadRequestSrvImage
+		}
+		if err := n.rebuildServer(server.Id, *action.Rebuild); err != nil {
+			return err
+		}
+		if err := n.recordAction(server, nova.ActionRebuild, r, nil); err != nil {
+			return err
+		}
+		server, err := n.server(server.Id)
+		if err != nil {
+			return err
+		}
+		resp := struct {
+			Server nova.ServerDetail `json:"server"`
+		}{*server}
+		return sendJSON(http.StatusAccepted, resp, w, r)
+	case action.GetConsoleOutput != nil:
+		length := 0
+		if action.GetConsoleOutput.Length != nil {
+			length = *action.GetConsoleOutput.Length
+		}
+		output, err := n.consoleOutput(server.Id, length)
+		if err != nil {
+			return err
+		}
+		resp := struct {
+			Output string `json:"output"`
+		}{output}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case action.CreateImage != nil:
+		img, err := n.createImage(server.Id, action.CreateImage.Name, action.CreateImage.Metadata)
+		if err != nil {
+			return err
+		}
+		if err := n.recordAction(server, nova.ActionCreateImage, r, nil); err != nil {
+			return err
+		}
+		return sendImageCreated(img, w, r)
+	case action.CreateBackup != nil:
+		backup := action.CreateBackup
+		if backup.BackupType == "" {
+			return testservices.NewBadRequestError("Invalid input for field/attribute createBackup. 'backup_type' is a required property")
+		}
+		if backup.Rotation == nil {
+			return testservices.NewBadRequestError("Invalid input for field/attribute createBackup. 'rotation' is a required property")
+		}
+		img, err := n.backupServer(server.Id, backup.Name, backup.BackupType, *backup.Rotation, backup.Metadata)
+		if err != nil {
+			return err
+		}
+		if err := n.recordAction(server, nova.ActionCreateBackup, r, nil); err != nil {
+			return err
+		}
+		return sendImageCreated(img, w, r)
+	case action.MigrateLive != nil:
+		var blockMigration interface{}
+		if err := json.Unmarshal(action.MigrateLive.BlockMigration, &blockMigration); err != nil {
+			return testservices.NewBadRequestError("Invalid input for field/attribute os-migrateLive. 'block_migration' is a required property")
+		}
+		block, isBool := blockMigration.(bool)
+		if !isBool && blockMigration != "auto" {
+			return testservices.NewBadRequestError(fmt.Sprintf("Invalid input for field/attribute block_migration. Value: %v.", blockMigration))
+		}
+		host := ""
+		if action.MigrateLive.Host != nil {
+			host = *action.MigrateLive.Host
+		}
+		// The double's hosts do not share storage, so "auto" means
+		// the disks are copied.
+		if !isBool {
+			block = true
+		}
+		if err := n.liveMigrateServer(server.Id, host, block); err != nil {
+			return err
+		}
+		if err := n.recordAction(server, nova.ActionLiveMigration, r, nil); err != nil {
+			return err
+		}
+		writeResponse(w, http.StatusAccepted, nil)
+		return nil
+	case action.Evacuate != nil:
+		if err := n.evacuateServer(server.Id, action.Evacuate.Host); err != nil {
+			return err
+		}
+		if err := n.recordAction(server, nova.ActionEvacuate, r, nil); err != nil {
+			return err
+		}
+		adminPass := action.Evacuate.AdminPass
+		if adminPass == "" {
+			if adminPass, err = generatePassword(); err != nil {
+				return err
+			}
+		}
+		resp := struct {
+			AdminPass string `json:"adminPass"`
+		}{adminPass}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case action.Resize != nil:
+		if action.Resize.FlavorRef == "" {
+			return errBadRequestSrvFlavor
+		}
+		if err := n.resizeServer(server.Id, action.Resize.FlavorRef); err != nil {
+			return err
+		}
+		if err := n.recordAction(server, nova.ActionResize, r, nil); err != nil {
+			return err
+		}
+		writeResponse(w, http.StatusAccepted, nil)
+		return nil
 	}
 	return fmt.Errorf("unknown server action: %q", string(body))
 }
 
-// handleServerMetadata handles the servers/<id>/action HTTP API.
-func (n *Nova) handleServerMetadata(server *nova.ServerDetail, w http.ResponseWriter, r *http.Request) error {
+// remoteConsoleTypes holds the console types valid for each remote
+// console protocol.
+var remoteConsoleTypes = map[string][]string{
+	nova.ConsoleProtocolVNC:    {nova.ConsoleTypeNoVNC, nova.ConsoleTypeXVPVNC},
+	nova.ConsoleProtocolSpice:  {nova.ConsoleTypeSpiceHTML5},
+	nova.ConsoleProtocolRDP:    {nova.ConsoleTypeRDPHTML5},
+	nova.ConsoleProtocolSerial: {nova.ConsoleTypeSerial},
+	nova.ConsoleProtocolMKS:    {nova.ConsoleTypeWebMKS},
+}
+
+// handleRemoteConsoles handles the servers/<id>/remote-consoles HTTP API.
+func (n *Nova) handleRemoteConsoles(server *nova.ServerDetail, w http.ResponseWriter, r *http.Request) error {
 	if server == nil {
-		return errNotFound
+		return errNotFoundJSON
 	}
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil || len(body) == 0 {
-		return errNotFound
+		return errBadRequest2
 	}
 	var req struct {
-		Metadata map[string]string `json:"metadata"`
+		RemoteConsole nova.RemoteConsole `json:"remote_console"`
 	}
 	if err := json.Unmarshal(body, &req); err != nil {
-		return err
+		return errBadRequest3
 	}
-	if err := n.setServerMetadata(server.Id, req.Metadata); err != nil {
+	console := req.RemoteConsole
+	valid := false
+	for _, consoleType := range remoteConsoleTypes[console.Protocol] {
+		valid = valid || consoleType == console.Type
+	}
+	if !valid {
+		return testservices.NewInvalidRemoteConsoleError(console.Protocol, console.Type)
+	}
+	token, err := newUUID()
+	if err != nil {
 		return err
 	}
-	writeResponse(w, http.StatusOK, nil)
-	return nil
+	console.URL = fmt.Sprintf("%s://%sconsole/%s?token=%s", n.Scheme, n.Hostname, console.Type, token)
+	resp := struct {
+		RemoteConsole nova.RemoteConsole `json:"remote_console"`
+	}{console}
+	return sendJSON(http.StatusOK, resp, w, r)
 }
 
-// newUUID generates a random UUID conforming to RFC 4122.
-func newUUID() (string, error) {
-	uuid := make([]byte, 16)
-	if _, err := io.ReadFull(rand.Reader, uuid); err != nil {
-		return "", err
+// handleServerMetadata handles the servers/<id>/metadata HTTP API.
+func (n *Nova) handleServerMetadata(w http.ResponseWriter, r *http.Request) error {
+	// Keys are escaped in the path, and may contain "/".
+	urlPath := r.URL.EscapedPath()
+	i := strings.Index(urlPath, "/servers/")
+	if i < 0 {
+		return errNotFound
 	}
-	uuid[8] = uuid[8]&^0xc0 | 0x80 // variant bits; see section 4.1.1.
-	uuid[6] = uuid[6]&^0xf0 | 0x40 // version 4; see section 4.1.3.
-	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:]), nil
-}
-
-// noGroupError constructs a bad request response for an invalid group.
-func noGroupError(groupName, tenantId string) error {
-	return &errorResponse{
-		http.StatusBadRequest,
-		`{"badRequest": {"message": "Security group ` + groupName + ` not found for project ` + tenantId + `.", "code": 400}}`,
-		"application/json; charset=UTF-8",
-		"bad request URL",
-		nil,
-		nil,
+	parts := strings.Split(strings.Trim(urlPath[i+len("/servers/"):], "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "metadata" {
+		return errNotFound
 	}
-}
-
-// handleRunServer handles creating and running a server.
-func (n *Nova) handleRunServer(body []byte, w http.ResponseWriter, r *http.Request) error {
-	var req struct {
-		Server struct {
-			FlavorRef          string
-			ImageRef           string
-			Name               string
-			Metadata           map[string]string
-			SecurityGroups     []map[string]string `json:"security_groups"`
-			Networks           []map[string]string
+	serverId := parts[0]
+	var key string
+	if len(parts) == 3 {
+		var err error
+		if key, err = url.PathUnescape(parts[2]); err != nil {
+			return errBadRequest3
+		}
+	}
+	server, err := n.server(serverId)
+	if err != nil {
+		return err
+	}
+	type serverMetadata struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	type metadataItem struct {
+		Meta map[string]string `json:"meta"`
+	}
+	sendMetadata := func() error {
+		server, err := n.server(serverId)
+		if err != nil {
+			return err
+		}
+		metadata := server.Metadata
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		return sendJSON(http.StatusOK, serverMetadata{metadata}, w, r)
+	}
+	switch r.Method {
+	case "GET":
+		if key == "" {
+			return sendMetadata()
+		}
+		value, ok := server.Metadata[key]
+		if !ok {
+			return testservices.NewMetadataItemNotFoundError()
+		}
+		return sendJSON(http.StatusOK, metadataItem{map[string]string{key: value}}, w, r)
+	case "POST", "PUT":
+		if key != "" && r.Method == "POST" {
+			return errNotFound
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil || len(body) == 0 {
+			return errBadRequest2
+		}
+		if key != "" {
+			var req metadataItem
+			if err := json.Unmarshal(body, &req); err != nil || req.Meta == nil {
+				return errBadRequest3
+			}
+			if len(req.Meta) > 1 {
+				return testservices.NewBadRequestError("Request body contains too many items")
+			}
+			value, ok := req.Meta[key]
+			if !ok {
+				return testservices.NewBadRequestError("Request body and URI mismatch")
+			}
+			if err := n.setServerMetadataItem(serverId, key, value); err != nil {
+				return err
+			}
+			return sendJSON(http.StatusOK, req, w, r)
+		}
+		var req serverMetadata
+		if err := json.Unmarshal(body, &req); err != nil || req.Metadata == nil {
+			return errBadRequest3
+		}
+		if r.Method == "PUT" {
+			err = n.replaceServerMetadata(serverId, req.Metadata)
+		} else {
+			err = n.setServerMetadata(serverId, req.Metadata)
+		}
+		if err != nil {
+			return err
+		}
+		return sendMetadata()
+	case "DELETE":
+		if key == "" {
+			return errNotFound
+		}
+		if err := n.deleteServerMetadataItem(serverId, key); err != nil {
+			return err
+		}
+		writeResponse(w, http.StatusNoContent, nil)
+		return nil
+	}
+	return errNotFound
+}
+
+// microversionAtLeast reports whether the request asks for at least
+// the given compute API microversion. Requests without a microversion
+// get the minimum, 2.1.
+func microversionAtLeast(r *http.Request, version string) bool {
+	requested := r.Header.Get("X-OpenStack-Nova-API-Version")
+	if v := r.Header.Get("OpenStack-API-Version"); strings.HasPrefix(v, "compute ") {
+		requested = strings.TrimPrefix(v, "compute ")
+	}
+	if requested == "latest" {
+		return true
+	}
+	parse := func(v string) (major, minor int) {
+		parts := strings.SplitN(v, ".", 2)
+		if len(parts) != 2 {
+			return 2, 1
+		}
+		major, err1 := strconv.Atoi(parts[0])
+		minor, err2 := strconv.Atoi(parts[1])
+		if err1 != nil || err2 != nil {
+			return 2, 1
+		}
+		return major, minor
+	}
+	major, minor := parse(requested)
+	wantMajor, wantMinor := parse(version)
+	return major > wantMajor || (major == wantMajor && minor >= wantMinor)
+}
+
+// requestIdKey is the context key holding the ID of a request.
+type requestIdKey struct{}
+
+// requestId returns the ID given to a request by novaHandler.
+func requestId(r *http.Request) string {
+	id, _ := r.Context().Value(requestIdKey{}).(string)
+	return id
+}
+
+// recordAction records an action made against a server by the given
+// request. If fault is not nil the action failed with it.
+func (n *Nova) recordAction(server *nova.ServerDetail, action string, r *http.Request, fault *nova.ServerFault) error {
+	userInfo, err := userInfo(n.IdentityService, r)
+	if err != nil {
+		return err
+	}
+	return n.recordInstanceAction(server, action, requestId(r), userInfo.Id, fault)
+}
+
+// generatePassword generates a random admin password.
+func generatePassword() (string, error) {
+	uuid, err := newUUID()
+	if err != nil {
+		return "", err
+	}
+	return strings.Replace(uuid, "-", "", -1)[:12], nil
+}
+
+// newUUID generates a random UUID conforming to RFC 4122.
+func newUUID() (string, error) {
+	uuid := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, uuid); err != nil {
+		return "", err
+	}
+	uuid[8] = uuid[8]&^0xc0 | 0x80 // variant bits; see section 4.1.1.
+	uuid[6] = uuid[6]&^0xf0 | 0x40 // version 4; see section 4.1.3.
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:]), nil
+}
+
+// noGroupError constructs a bad request response for an invalid group.
+func noGroupError(groupName, tenantId string) error {
+	return &errorResponse{
+		http.StatusBadRequest,
+		`{"badRequest": {"message": "Security group ` + groupName + ` not found for project ` + tenantId + `.", "code": 400}}`,
+		"application/json; charset=UTF-8",
+		"bad request URL",
+		nil,
+		nil,
+	}
+}
+
+// parseServerNetworks returns the networks requested for a new
+// server. From microversion 2.37 networks must be given, either as a
+// list or as "auto" or "none". Servers are not attached to any
+// networks for "auto", as the double has none to allocate.
+func parseServerNetworks(data json.RawMessage, required bool) ([]map[string]string, error) {
+	if len(data) == 0 || string(data) == "null" {
+		if required {
+			return nil, testservices.NewBadRequestError("Invalid input for field/attribute server. 'networks' is a required property")
+		}
+		return nil, nil
+	}
+	var networks []map[string]string
+	if err := json.Unmarshal(data, &networks); err == nil {
+		return networks, nil
+	}
+	var auto string
+	if err := json.Unmarshal(data, &auto); err != nil || !required || (auto != "auto" && auto != "none") {
+		return nil, testservices.NewBadRequestError(fmt.Sprintf("Invalid input for field/attribute networks. Value: %s.", data))
+	}
+	return nil, nil
+}
+
+// handleRunServer handles creating and running a server.
+func (n *Nova) handleRunServer(body []byte, w http.ResponseWriter, r *http.Request) error {
+	var req struct {
+		Server struct {
+			FlavorRef          string
+			ImageRef           string
+			Name               string
+			Metadata           map[string]string
+			SecurityGroups     []map[string]string `json:"security_groups"`
+			Networks           json.RawMessage
 			AvailabilityZone   string                    `json:"availability_zone"`
 			BlockDeviceMapping []nova.BlockDeviceMapping `json:"block_device_mapping_v2,omitempty"`
+			KeyName            string                    `json:"key_name"`
+			Description        *string
+			Tags               []string
 		}
+		SchedulerHints *nova.SchedulerHints `json:"os:scheduler_hints"`
 	}
 	if err := json.Unmarshal(body, &req); err != nil {
 		return errBadRequest3
@@ -607,11 +1315,65 @@ func (n *Nova) handleRunServer(body []byte, w http.ResponseWriter, r *http.Reque
 	if req.Server.FlavorRef == "" {
 		return errBadRequestSrvFlavor
 	}
+	networks, err := parseServerNetworks(req.Server.Networks, microversionAtLeast(r, "2.37"))
+	if err != nil {
+		return err
+	}
 	if az := req.Server.AvailabilityZone; az != "" {
-		if !n.availabilityZones[az].State.Available {
+		if zone, ok := n.availabilityZone(az); !ok || !zone.State.Available {
 			return testservices.AvailabilityZoneIsNotAvailable
 		}
 	}
+	if keyName := req.Server.KeyName; keyName != "" {
+		if _, err := n.keyPair(keyName); err != nil {
+			return testservices.NewInvalidKeyNameError()
+		}
+	}
+	var description string
+	if req.Server.Description != nil {
+		if !microversionAtLeast(r, "2.19") {
+			return testservices.NewUnsupportedMicroversionFieldError("description")
+		}
+		description = *req.Server.Description
+	}
+	var tags []string
+	if req.Server.Tags != nil {
+		if !microversionAtLeast(r, "2.52") {
+			return testservices.NewUnsupportedMicroversionFieldError("tags")
+		}
+		var err error
+		if tags, err = validateServerTags(req.Server.Tags); err != nil {
+			return err
+		}
+		sort.Strings(tags)
+	}
+	if err := n.validateMetadata(req.Server.Metadata); err != nil {
+		return err
+	}
+	if req.Server.BlockDeviceMapping != nil {
+		for _, m := range req.Server.BlockDeviceMapping {
+			if m.VolumeType != "" && !microversionAtLeast(r, "2.67") {
+				return testservices.NewBadRequestError("Invalid input for field/attribute block_device_mapping_v2. Value: Additional properties are not allowed ('volume_type' was unexpected)")
+			}
+		}
+		if err := validateBlockDeviceMappings(req.Server.ImageRef, req.Server.BlockDeviceMapping); err != nil {
+			return err
+		}
+	}
+	var groupId string
+	if req.SchedulerHints != nil && req.SchedulerHints.Group != "" {
+		groupId = req.SchedulerHints.Group
+		if _, err := n.serverGroup(groupId); err != nil {
+			return testservices.NewInvalidServerGroupError(fmt.Sprintf("server group %s not found", groupId))
+		}
+	}
+	if err := n.checkServerQuotas(req.Server.FlavorRef, groupId); err != nil {
+		return err
+	}
+	host, scheduled, err := n.scheduleServer(groupId, req.Server.AvailabilityZone)
+	if err != nil {
+		return err
+	}
 	n.nextServerId++
 	id := strconv.Itoa(n.nextServerId)
 	uuid, err := newUUID()
@@ -622,7 +1384,7 @@ func (n *Nova) handleRunServer(body []byte, w http.ResponseWriter, r *http.Reque
 	// only networks with sub-nets should be used for boot
 	createSecurityGroups := true
 	networkNames := make([]string, 0)
-	for _, net := range req.Server.Networks {
+	for _, net := range networks {
 		var netPortSecurity *neutron.NetworkV2
 		var err error
 		if n.useNeutronNetworking {
@@ -671,7 +1433,7 @@ func (n *Nova) handleRunServer(body []byte, w http.ResponseWriter, r *http.Reque
 		Name:             req.Server.Name,
 		TenantId:         n.TenantId,
 		UserId:           userInfo.Id,
-		HostId:           "1",
+		HostId:           n.hostId(host),
 		Image:            image,
 		Flavor:           flavorEnt,
 		Status:           nova.StatusBuild,
@@ -680,6 +1442,12 @@ func (n *Nova) handleRunServer(body []byte, w http.ResponseWriter, r *http.Reque
 		Addresses:        make(map[string][]nova.IPAddress),
 		AvailabilityZone: req.Server.AvailabilityZone,
 		Metadata:         req.Server.Metadata,
+		KeyName:          req.Server.KeyName,
+		Description:      description,
+		Tags:             tags,
+	}
+	if server.AvailabilityZone == "" && scheduled && n.hasAggregateZones() {
+		server.AvailabilityZone = n.hostZone(host)
 	}
 	servers, err := n.allServers(nil)
 	if err != nil {
@@ -695,9 +1463,31 @@ func (n *Nova) handleRunServer(body []byte, w http.ResponseWriter, r *http.Reque
 	// set some IP addresses, expected by juju tests.
 	addr := fmt.Sprintf("127.10.0.%d", nextServer)
 	server.Addresses["public"] = []nova.IPAddress{{4, addr, "fixed"}, {6, "::dead:beef:f00d", "fixed"}}
+	if !scheduled {
+		server.Fault = &nova.ServerFault{
+			Code:    500,
+			Created: timestr,
+			Message: noValidHostMessage,
+		}
+	}
 	if err := n.addServer(server); err != nil {
 		return err
 	}
+	n.serverHosts[id] = host
+	if !scheduled {
+		n.serverStatuses[id] = nova.StatusError
+	} else if groupId != "" {
+		if err := n.addServerGroupMember(groupId, id); err != nil {
+			return err
+		}
+	}
+	created, err := n.server(id)
+	if err != nil {
+		return err
+	}
+	if err := n.recordAction(created, nova.ActionCreate, r, created.Fault); err != nil {
+		return err
+	}
 	var resp struct {
 		Server struct {
 			SecurityGroups []map[string]string `json:"security_groups"`
@@ -726,10 +1516,26 @@ func (n *Nova) handleRunServer(body []byte, w http.ResponseWriter, r *http.Reque
 	return sendJSON(http.StatusAccepted, resp, w, r)
 }
 
+// serverLeaf returns the path segment following the server ID in a
+// servers/<id>/... URL path, or "" if there is none.
+func serverLeaf(urlPath string) string {
+	i := strings.Index(urlPath, "/servers/")
+	if i < 0 {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(urlPath[i+len("/servers/"):], "/"), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
 // handleServers handles the servers HTTP API.
 func (n *Nova) handleServers(w http.ResponseWriter, r *http.Request) error {
-	// Handle os volume attachments as a leaf of a server.
-	if strings.Contains(r.URL.Path, "os-volume_attachments") {
+	// Handle the leaves of a server, named by the path segment
+	// following its ID.
+	switch serverLeaf(r.URL.Path) {
+	case "os-volume_attachments":
 		switch r.Method {
 		case "GET":
 			return n.handleListVolumes(w, r)
@@ -738,11 +1544,20 @@ func (n *Nova) handleServers(w http.ResponseWriter, r *http.Request) error {
 		case "DELETE":
 			return n.handleDetachVolumes(w, r)
 		}
-	}
-
-	// Handle os interfaces as a leaf of a server.
-	if strings.Contains(r.URL.Path, "os-interface") {
+	case "os-interface":
 		return n.handleOSInterfaces(w, r)
+	case "metadata":
+		return n.handleServerMetadata(w, r)
+	case "tags":
+		return n.handleServerTags(w, r)
+	case "os-instance-actions":
+		return n.handleInstanceActions(w, r)
+	case "migrations":
+		return n.handleServerMigrations(w, r)
+	case "os-server-password":
+		return n.handleServerPassword(w, r)
+	case "diagnostics":
+		return n.handleServerDiagnostics(w, r)
 	}
 
 	// Handle server related functionality directly.
@@ -804,11 +1619,11 @@ func (n *Nova) handleServers(w http.ResponseWriter, r *http.Request) error {
 				serverId := path.Base(strings.Replace(r.URL.Path, "/action", "", 1))
 				server, _ := n.server(serverId)
 				return n.handleServerActions(server, w, r)
-			} else if suffix == "metadata" {
-				// handle POST /servers/<id>/metadata
-				serverId := path.Base(strings.Replace(r.URL.Path, "/metadata", "", 1))
+			} else if suffix == "remote-consoles" {
+				// handle POST /servers/<id>/remote-consoles
+				serverId := path.Base(strings.Replace(r.URL.Path, "/remote-consoles", "", 1))
 				server, _ := n.server(serverId)
-				return n.handleServerMetadata(server, w, r)
+				return n.handleRemoteConsoles(server, w, r)
 			}
 			return errNotFound
 		}
@@ -828,7 +1643,8 @@ func (n *Nova) handleServers(w http.ResponseWriter, r *http.Request) error {
 
 		var req struct {
 			Server struct {
-				Name string `json:"name"`
+				Name        *string `json:"name"`
+				Description *string `json:"description"`
 			} `json:"server"`
 		}
 
@@ -839,10 +1655,19 @@ func (n *Nova) handleServers(w http.ResponseWriter, r *http.Request) error {
 		if err := json.Unmarshal(body, &req); err != nil {
 			return err
 		}
+		if req.Server.Description != nil && !microversionAtLeast(r, "2.19") {
+			return testservices.NewUnsupportedMicroversionFieldError("description")
+		}
 
-		err = n.updateServerName(serverId, req.Server.Name)
-		if err != nil {
-			return err
+		if req.Server.Name != nil {
+			if err := n.updateServerName(serverId, *req.Server.Name); err != nil {
+				return err
+			}
+		}
+		if req.Server.Description != nil {
+			if err := n.updateServerDescription(serverId, *req.Server.Description); err != nil {
+				return err
+			}
 		}
 
 		server, err := n.server(serverId)
@@ -856,12 +1681,16 @@ func (n *Nova) handleServers(w http.ResponseWriter, r *http.Request) error {
 		return sendJSON(http.StatusOK, resp, w, r)
 	case "DELETE":
 		if serverId := path.Base(r.URL.Path); serverId != "servers" {
-			if _, err := n.server(serverId); err != nil {
+			server, err := n.server(serverId)
+			if err != nil {
 				return errNotFoundJSON
 			}
 			if err := n.removeServer(serverId); err != nil {
 				return err
 			}
+			if err := n.recordAction(server, nova.ActionDelete, r, nil); err != nil {
+				return err
+			}
 			writeResponse(w, http.StatusNoContent, nil)
 			return nil
 		}
@@ -870,100 +1699,493 @@ func (n *Nova) handleServers(w http.ResponseWriter, r *http.Request) error {
 	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
 }
 
-// handleServersDetail handles the servers/detail HTTP API.
-func (n *Nova) handleServersDetail(w http.ResponseWriter, r *http.Request) error {
-	switch r.Method {
-	case "GET":
-		if serverId := path.Base(r.URL.Path); serverId != "detail" {
-			return errNotFound
-		}
-		f := make(filter)
-		if err := r.ParseForm(); err == nil && len(r.Form) > 0 {
-			for filterKey, filterValues := range r.Form {
-				for _, value := range filterValues {
-					f[filterKey] = value
-				}
-			}
-		}
-		servers, err := n.allServers(f)
+// handleInstanceActions handles the os-instance-actions HTTP API, a
+// leaf of a server. The actions of deleted servers are only shown
+// from microversion 2.21.
+func (n *Nova) handleInstanceActions(w http.ResponseWriter, r *http.Request) error {
+	i := strings.Index(r.URL.Path, "/servers/")
+	if i < 0 {
+		return errNotFound
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path[i+len("/servers/"):], "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "os-instance-actions" {
+		return errNotFound
+	}
+	if r.Method != "GET" {
+		return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+	}
+	serverId := parts[0]
+	includeDeleted := microversionAtLeast(r, "2.21")
+	if len(parts) == 2 {
+		actions, err := n.allInstanceActions(serverId, includeDeleted)
 		if err != nil {
 			return err
 		}
-		if len(servers) == 0 {
-			servers = []nova.ServerDetail{}
-		}
 		resp := struct {
-			Servers []nova.ServerDetail `json:"servers"`
-		}{servers}
+			InstanceActions []nova.InstanceAction `json:"instanceActions"`
+		}{actions}
 		return sendJSON(http.StatusOK, resp, w, r)
-	case "POST":
-		return errNotFound
-	case "PUT":
-		if serverId := path.Base(r.URL.Path); serverId != "detail" {
-			return errNotFound
-		}
-		return errBadRequest2
-	case "DELETE":
-		if serverId := path.Base(r.URL.Path); serverId != "detail" {
-			return errNotFound
-		}
-		return errNotFoundJSON
 	}
-	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
-}
-
-// processGroupId returns the group id from the given request.
-// If there was no group id specified in the path, it returns errNoGroupId
-func (n *Nova) processGroupId(w http.ResponseWriter, r *http.Request) (*nova.SecurityGroup, error) {
-	if groupId := path.Base(r.URL.Path); groupId != "os-security-groups" {
-		group, err := n.securityGroup(groupId)
-		if err != nil {
-			return nil, errNotFoundJSONSG
-		}
-		return group, nil
+	action, err := n.instanceAction(serverId, parts[2], includeDeleted)
+	if err != nil {
+		return err
 	}
-	return nil, errNoGroupId
+	resp := struct {
+		InstanceAction nova.InstanceAction `json:"instanceAction"`
+	}{*action}
+	return sendJSON(http.StatusOK, resp, w, r)
 }
 
-// handleSecurityGroups handles the os-security-groups HTTP API.
-func (n *Nova) handleSecurityGroups(w http.ResponseWriter, r *http.Request) error {
-	switch r.Method {
-	case "GET":
-		group, err := n.processGroupId(w, r)
-		if err == errNoGroupId {
-			groups := n.allSecurityGroups()
-			if len(groups) == 0 {
-				groups = []nova.SecurityGroup{}
-			}
-			resp := struct {
-				Groups []nova.SecurityGroup `json:"security_groups"`
-			}{groups}
-			return sendJSON(http.StatusOK, resp, w, r)
+// handleServerMigrations handles the servers/<id>/migrations HTTP API,
+// a leaf of a server, which shows the in-progress live migrations of
+// the server from microversion 2.23.
+func (n *Nova) handleServerMigrations(w http.ResponseWriter, r *http.Request) error {
+	if !microversionAtLeast(r, "2.23") {
+		return errNotFoundJSON
+	}
+	i := strings.Index(r.URL.Path, "/servers/")
+	if i < 0 {
+		return errNotFound
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path[i+len("/servers/"):], "/"), "/")
+	if len(parts) < 2 || len(parts) > 4 || parts[1] != "migrations" {
+		return errNotFound
+	}
+	serverId := parts[0]
+	if len(parts) == 2 {
+		if r.Method != "GET" {
+			return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
 		}
+		migrations, err := n.serverMigrations(serverId)
 		if err != nil {
 			return err
 		}
 		resp := struct {
-			Group nova.SecurityGroup `json:"security_group"`
-		}{*group}
+			Migrations []nova.ServerMigration `json:"migrations"`
+		}{migrations}
 		return sendJSON(http.StatusOK, resp, w, r)
-	case "POST":
-		if groupId := path.Base(r.URL.Path); groupId != "os-security-groups" {
-			return errNotFound
+	}
+	migrationId, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return testservices.NewLiveMigrationNotInProgressError(0, serverId)
+	}
+	switch {
+	case len(parts) == 3 && r.Method == "GET":
+		migration, err := n.serverMigration(serverId, migrationId)
+		if err != nil {
+			return err
 		}
+		resp := struct {
+			Migration nova.ServerMigration `json:"migration"`
+		}{*migration}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case len(parts) == 4 && parts[3] == "action" && r.Method == "POST":
 		body, err := ioutil.ReadAll(r.Body)
 		if err != nil || len(body) == 0 {
 			return errBadRequest2
 		}
-		var req struct {
-			Group struct {
-				Name        string
-				Description string
-			} `json:"security_group"`
+		var action map[string]json.RawMessage
+		if err := json.Unmarshal(body, &action); err != nil {
+			return errBadRequest3
 		}
-		if err := json.Unmarshal(body, &req); err != nil {
+		if _, ok := action["force_complete"]; !ok || len(action) != 1 {
+			return errBadRequest3
+		}
+		server, err := n.server(serverId)
+		if err != nil {
 			return err
-		} else {
+		}
+		if err := n.forceCompleteMigration(serverId, migrationId); err != nil {
+			return err
+		}
+		if err := n.recordAction(server, nova.ActionLiveMigrationForceComplete, r, nil); err != nil {
+			return err
+		}
+		writeResponse(w, http.StatusAccepted, nil)
+		return nil
+	}
+	return errNotFound
+}
+
+// serverLeafId returns the ID of the server whose leaf with the given
+// name is requested, or an empty string if the path is not that of
+// the leaf.
+func serverLeafId(r *http.Request, leaf string) string {
+	i := strings.Index(r.URL.Path, "/servers/")
+	if i < 0 {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path[i+len("/servers/"):], "/"), "/")
+	if len(parts) != 2 || parts[1] != leaf {
+		return ""
+	}
+	return parts[0]
+}
+
+// handleServerPassword handles the servers/<id>/os-server-password
+// HTTP API, a leaf of a server.
+func (n *Nova) handleServerPassword(w http.ResponseWriter, r *http.Request) error {
+	serverId := serverLeafId(r, "os-server-password")
+	if serverId == "" {
+		return errNotFound
+	}
+	switch r.Method {
+	case "GET":
+		password, err := n.serverPassword(serverId)
+		if err != nil {
+			return err
+		}
+		resp := struct {
+			Password string `json:"password"`
+		}{password}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "DELETE":
+		if err := n.clearServerPassword(serverId); err != nil {
+			return err
+		}
+		writeResponse(w, http.StatusNoContent, nil)
+		return nil
+	}
+	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+}
+
+// handleServerDiagnostics handles the servers/<id>/diagnostics HTTP
+// API, a leaf of a server. Before microversion 2.48 the diagnostics
+// are reported in the format of the libvirt driver.
+func (n *Nova) handleServerDiagnostics(w http.ResponseWriter, r *http.Request) error {
+	serverId := serverLeafId(r, "diagnostics")
+	if serverId == "" {
+		return errNotFound
+	}
+	if r.Method != "GET" {
+		return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+	}
+	diagnostics, err := n.serverDiagnostics(serverId)
+	if err != nil {
+		return err
+	}
+	if microversionAtLeast(r, "2.48") {
+		return sendJSON(http.StatusOK, diagnostics, w, r)
+	}
+	resp := map[string]int64{
+		"memory": int64(diagnostics.MemoryDetails.Maximum) << 10,
+	}
+	for _, cpu := range diagnostics.CPUDetails {
+		resp[fmt.Sprintf("cpu%d_time", cpu.Id)] = cpu.Time
+	}
+	for i, disk := range diagnostics.DiskDetails {
+		name := fmt.Sprintf("vd%c", 'a'+i)
+		resp[name+"_read"] = disk.ReadBytes
+		resp[name+"_read_req"] = disk.ReadRequests
+		resp[name+"_write"] = disk.WriteBytes
+		resp[name+"_write_req"] = disk.WriteRequests
+		resp[name+"_errors"] = disk.ErrorsCount
+	}
+	for i, nic := range diagnostics.NICDetails {
+		name := fmt.Sprintf("tap%d", i)
+		resp[name+"_rx"] = nic.RxOctets
+		resp[name+"_rx_packets"] = nic.RxPackets
+		resp[name+"_tx"] = nic.TxOctets
+		resp[name+"_tx_packets"] = nic.TxPackets
+	}
+	return sendJSON(http.StatusOK, resp, w, r)
+}
+
+// handleMigrations handles the os-migrations HTTP API.
+func (n *Nova) handleMigrations(w http.ResponseWriter, r *http.Request) error {
+	if path.Base(r.URL.Path) != "os-migrations" {
+		return errNotFoundJSON
+	}
+	if r.Method != "GET" {
+		return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+	}
+	query := r.URL.Query()
+	f := filter{
+		"host":           query.Get("host"),
+		"status":         query.Get("status"),
+		"instance_uuid":  query.Get("instance_uuid"),
+		"migration_type": query.Get("migration_type"),
+	}
+	resp := struct {
+		Migrations []nova.Migration `json:"migrations"`
+	}{n.allMigrations(f)}
+	return sendJSON(http.StatusOK, resp, w, r)
+}
+
+// usageTimeFormats holds the formats accepted for the start and end
+// of usage reports.
+var usageTimeFormats = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// parseUsageTime parses the named time query parameter of a usage
+// report, which defaults to now.
+func parseUsageTime(r *http.Request, name string) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Now(), nil
+	}
+	for _, format := range usageTimeFormats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, testservices.NewBadRequestError(fmt.Sprintf("Invalid input for query parameters %s. Value: %s.", name, value))
+}
+
+// handleTenantUsage handles the os-simple-tenant-usage HTTP API. The
+// reports are paginated from microversion 2.40.
+func (n *Nova) handleTenantUsage(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+	}
+	tenantId := path.Base(r.URL.Path)
+	if tenantId == "os-simple-tenant-usage" {
+		tenantId = ""
+	}
+	start, err := parseUsageTime(r, "start")
+	if err != nil {
+		return err
+	}
+	end, err := parseUsageTime(r, "end")
+	if err != nil {
+		return err
+	}
+	if start.After(end) {
+		return testservices.NewBadRequestError("Invalid start time. The start time cannot occur after the end time.")
+	}
+	query := r.URL.Query()
+	detailed := tenantId != "" || query.Get("detailed") == "1"
+	limit, marker := 0, ""
+	if microversionAtLeast(r, "2.40") {
+		if value := query.Get("limit"); value != "" {
+			if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+				return testservices.NewBadRequestError(fmt.Sprintf("Invalid input for query parameters limit. Value: %s.", value))
+			}
+		}
+		marker = query.Get("marker")
+	}
+	usages, next, err := n.tenantUsages(tenantId, start, end, detailed, limit, marker)
+	if err != nil {
+		return err
+	}
+	var links []nova.Link
+	if next != "" {
+		query.Set("marker", next)
+		apiPath := "os-simple-tenant-usage"
+		if tenantId != "" {
+			apiPath += "/" + tenantId
+		}
+		href := n.endpointURL(true, apiPath) + "?" + query.Encode()
+		links = append(links, nova.Link{Href: href, Rel: "next"})
+	}
+	if tenantId == "" {
+		resp := struct {
+			TenantUsages []nova.TenantUsage `json:"tenant_usages"`
+			Links        []nova.Link        `json:"tenant_usages_links,omitempty"`
+		}{usages, links}
+		return sendJSON(http.StatusOK, resp, w, r)
+	}
+	// A tenant without usage is reported as an empty object.
+	var usage interface{} = struct{}{}
+	if len(usages) > 0 {
+		usage = usages[0]
+	}
+	resp := struct {
+		TenantUsage interface{} `json:"tenant_usage"`
+		Links       []nova.Link `json:"tenant_usage_links,omitempty"`
+	}{usage, links}
+	return sendJSON(http.StatusOK, resp, w, r)
+}
+
+// handleServerTags handles the servers/<id>/tags HTTP API.
+func (n *Nova) handleServerTags(w http.ResponseWriter, r *http.Request) error {
+	// The tags API does not exist before microversion 2.26.
+	if !microversionAtLeast(r, "2.26") {
+		return errNotFoundJSON
+	}
+	i := strings.Index(r.URL.Path, "/servers/")
+	if i < 0 {
+		return errNotFound
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path[i+len("/servers/"):], "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "tags" {
+		return errNotFound
+	}
+	serverId := parts[0]
+	var tag string
+	if len(parts) == 3 {
+		var err error
+		if tag, err = url.PathUnescape(parts[2]); err != nil {
+			return errBadRequest3
+		}
+	}
+	server, err := n.server(serverId)
+	if err != nil {
+		return err
+	}
+	type serverTags struct {
+		Tags []string `json:"tags"`
+	}
+	switch r.Method {
+	case "GET":
+		if tag == "" {
+			tags := server.Tags
+			if tags == nil {
+				tags = []string{}
+			}
+			return sendJSON(http.StatusOK, serverTags{tags}, w, r)
+		}
+		if !hasString(server.Tags, tag) {
+			return testservices.NewServerTagNotFoundError(serverId, tag)
+		}
+		writeResponse(w, http.StatusNoContent, nil)
+		return nil
+	case "PUT":
+		if tag != "" {
+			added, err := n.addServerTag(serverId, tag)
+			if err != nil {
+				return err
+			}
+			if !added {
+				writeResponse(w, http.StatusNoContent, nil)
+				return nil
+			}
+			w.Header().Set("Location", n.endpointURL(true, fmt.Sprintf("/servers/%s/tags/%s", serverId, url.PathEscape(tag))))
+			writeResponse(w, http.StatusCreated, nil)
+			return nil
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil || len(body) == 0 {
+			return errBadRequest2
+		}
+		var req serverTags
+		if err := json.Unmarshal(body, &req); err != nil || req.Tags == nil {
+			return errBadRequest3
+		}
+		if err := n.setServerTags(serverId, req.Tags); err != nil {
+			return err
+		}
+		server, err := n.server(serverId)
+		if err != nil {
+			return err
+		}
+		tags := server.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		return sendJSON(http.StatusOK, serverTags{tags}, w, r)
+	case "DELETE":
+		if tag == "" {
+			err = n.setServerTags(serverId, nil)
+		} else {
+			err = n.removeServerTag(serverId, tag)
+		}
+		if err != nil {
+			return err
+		}
+		writeResponse(w, http.StatusNoContent, nil)
+		return nil
+	}
+	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+}
+
+// handleServersDetail handles the servers/detail HTTP API.
+func (n *Nova) handleServersDetail(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case "GET":
+		if serverId := path.Base(r.URL.Path); serverId != "detail" {
+			return errNotFound
+		}
+		f := make(filter)
+		if err := r.ParseForm(); err == nil && len(r.Form) > 0 {
+			for filterKey, filterValues := range r.Form {
+				for _, value := range filterValues {
+					f[filterKey] = value
+				}
+			}
+		}
+		servers, err := n.allServers(f)
+		if err != nil {
+			return err
+		}
+		if len(servers) == 0 {
+			servers = []nova.ServerDetail{}
+		}
+		resp := struct {
+			Servers []nova.ServerDetail `json:"servers"`
+		}{servers}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "POST":
+		return errNotFound
+	case "PUT":
+		if serverId := path.Base(r.URL.Path); serverId != "detail" {
+			return errNotFound
+		}
+		return errBadRequest2
+	case "DELETE":
+		if serverId := path.Base(r.URL.Path); serverId != "detail" {
+			return errNotFound
+		}
+		return errNotFoundJSON
+	}
+	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+}
+
+// processGroupId returns the group id from the given request.
+// If there was no group id specified in the path, it returns errNoGroupId
+func (n *Nova) processGroupId(w http.ResponseWriter, r *http.Request) (*nova.SecurityGroup, error) {
+	if groupId := path.Base(r.URL.Path); groupId != "os-security-groups" {
+		group, err := n.securityGroup(groupId)
+		if err != nil {
+			return nil, errNotFoundJSONSG
+		}
+		return group, nil
+	}
+	return nil, errNoGroupId
+}
+
+// handleSecurityGroups handles the os-security-groups HTTP API.
+func (n *Nova) handleSecurityGroups(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case "GET":
+		group, err := n.processGroupId(w, r)
+		if err == errNoGroupId {
+			groups := n.allSecurityGroups()
+			if len(groups) == 0 {
+				groups = []nova.SecurityGroup{}
+			}
+			resp := struct {
+				Groups []nova.SecurityGroup `json:"security_groups"`
+			}{groups}
+			return sendJSON(http.StatusOK, resp, w, r)
+		}
+		if err != nil {
+			return err
+		}
+		resp := struct {
+			Group nova.SecurityGroup `json:"security_group"`
+		}{*group}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "POST":
+		if groupId := path.Base(r.URL.Path); groupId != "os-security-groups" {
+			return errNotFound
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil || len(body) == 0 {
+			return errBadRequest2
+		}
+		var req struct {
+			Group struct {
+				Name        string
+				Description string
+			} `json:"security_group"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return err
+		} else {
 			_, err := n.securityGroupByName(req.Group.Name)
 			if err == nil {
 				return errBadRequestDuplicateValue
@@ -1222,32 +2444,393 @@ func (n *Nova) handleAvailabilityZones(w http.ResponseWriter, r *http.Request) e
 	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
 }
 
-// handleOSInterfaces handles the os-interfaces HTTP API.
-func (n *Nova) handleOSInterfaces(w http.ResponseWriter, r *http.Request) error {
-	switch r.Method {
-	case "GET":
-		serverId := path.Base(strings.Replace(r.URL.Path, "/os-interface", "", 1))
-
-		interfaces := n.serverOSInterfaces(serverId)
+// handleHypervisors handles the os-hypervisors HTTP API.
+func (n *Nova) handleHypervisors(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+	}
+	switch suffix := path.Base(r.URL.Path); suffix {
+	case "os-hypervisors":
+		type hypervisorSummary struct {
+			Id                 string `json:"id"`
+			HypervisorHostname string `json:"hypervisor_hostname"`
+			State              string `json:"state"`
+			Status             string `json:"status"`
+		}
+		hypervisors := []hypervisorSummary{}
+		for _, h := range n.allHypervisors() {
+			hypervisors = append(hypervisors, hypervisorSummary{h.Id, h.HypervisorHostname, h.State, h.Status})
+		}
 		resp := struct {
-			InterfaceAttachments []nova.OSInterface `json:"interfaceAttachments"`
-		}{interfaces}
+			Hypervisors []hypervisorSummary `json:"hypervisors"`
+		}{hypervisors}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "detail":
+		resp := struct {
+			Hypervisors []nova.Hypervisor `json:"hypervisors"`
+		}{n.allHypervisors()}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "statistics":
+		resp := struct {
+			Statistics nova.HypervisorStatistics `json:"hypervisor_statistics"`
+		}{n.hypervisorStatistics()}
+		return sendJSON(http.StatusOK, resp, w, r)
+	default:
+		hypervisor, err := n.hypervisorById(suffix)
+		if err != nil {
+			return err
+		}
+		resp := struct {
+			Hypervisor nova.Hypervisor `json:"hypervisor"`
+		}{*hypervisor}
 		return sendJSON(http.StatusOK, resp, w, r)
 	}
-	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
 }
 
-func (n *Nova) handleAttachVolumes(w http.ResponseWriter, r *http.Request) error {
-	serverId := path.Base(strings.Replace(r.URL.Path, "/os-volume_attachments", "", 1))
-
-	bodyBytes, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		return err
-	}
-
-	var attachment struct {
-		VolumeAttachment nova.VolumeAttachment `json:"volumeAttachment"`
-	}
+// handleServices handles the os-services HTTP API. Services can only
+// be updated by ID, which requires microversion 2.53.
+func (n *Nova) handleServices(w http.ResponseWriter, r *http.Request) error {
+	serviceId := path.Base(r.URL.Path)
+	switch r.Method {
+	case "GET":
+		if serviceId != "os-services" {
+			return errNotFoundJSON
+		}
+		resp := struct {
+			Services []nova.ComputeService `json:"services"`
+		}{n.allServices(r.URL.Query().Get("binary"), r.URL.Query().Get("host"))}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "PUT":
+		if serviceId == "os-services" || !microversionAtLeast(r, "2.53") {
+			return errNotFoundJSON
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil || len(body) == 0 {
+			return errBadRequest2
+		}
+		var req struct {
+			Status         string  `json:"status"`
+			DisabledReason *string `json:"disabled_reason"`
+			ForcedDown     *bool   `json:"forced_down"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return errBadRequest3
+		}
+		if req.ForcedDown != nil && req.Status == "" && req.DisabledReason == nil {
+			service, err := n.setServiceForcedDown(serviceId, *req.ForcedDown)
+			if err != nil {
+				return err
+			}
+			resp := struct {
+				Service nova.ComputeService `json:"service"`
+			}{*service}
+			return sendJSON(http.StatusOK, resp, w, r)
+		}
+		var reason string
+		switch req.Status {
+		case nova.HostStatusEnabled:
+			if req.DisabledReason != nil {
+				return testservices.NewBadRequestError("Specifying a disabled reason is not supported when the status is enabled.")
+			}
+		case nova.HostStatusDisabled:
+			if req.DisabledReason != nil {
+				reason = *req.DisabledReason
+			}
+		default:
+			return testservices.NewBadRequestError(fmt.Sprintf("Invalid input for field/attribute status. Value: %s.", req.Status))
+		}
+		service, err := n.setServiceStatus(serviceId, req.Status == nova.HostStatusDisabled, reason)
+		if err != nil {
+			return err
+		}
+		if req.ForcedDown != nil {
+			if service, err = n.setServiceForcedDown(serviceId, *req.ForcedDown); err != nil {
+				return err
+			}
+		}
+		resp := struct {
+			Service nova.ComputeService `json:"service"`
+		}{*service}
+		return sendJSON(http.StatusOK, resp, w, r)
+	}
+	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+}
+
+// handleAggregates handles the os-aggregates HTTP API.
+func (n *Nova) handleAggregates(w http.ResponseWriter, r *http.Request) error {
+	i := strings.Index(r.URL.Path, "/os-aggregates")
+	if i < 0 {
+		return errNotFound
+	}
+	var parts []string
+	if rest := strings.Trim(r.URL.Path[i+len("/os-aggregates"):], "/"); rest != "" {
+		parts = strings.Split(rest, "/")
+	}
+	type aggregateResponse struct {
+		Aggregate nova.Aggregate `json:"aggregate"`
+	}
+	sendAggregate := func(aggregate *nova.Aggregate, err error) error {
+		if err != nil {
+			return err
+		}
+		return sendJSON(http.StatusOK, aggregateResponse{*aggregate}, w, r)
+	}
+	if len(parts) == 0 {
+		switch r.Method {
+		case "GET":
+			resp := struct {
+				Aggregates []nova.Aggregate `json:"aggregates"`
+			}{n.allAggregates()}
+			return sendJSON(http.StatusOK, resp, w, r)
+		case "POST":
+			var req struct {
+				Aggregate nova.AggregateOpts `json:"aggregate"`
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil || len(body) == 0 {
+				return errBadRequest2
+			}
+			if err := json.Unmarshal(body, &req); err != nil {
+				return errBadRequest3
+			}
+			if req.Aggregate.Name == "" {
+				return testservices.NewBadRequestError("Invalid input for field/attribute aggregate. Value: name is a required property")
+			}
+			return sendAggregate(n.createAggregate(req.Aggregate.Name, req.Aggregate.AvailabilityZone))
+		}
+		return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+	}
+	if len(parts) > 2 || len(parts) == 2 && parts[1] != "action" {
+		return errNotFoundJSON
+	}
+	aggregateId, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return testservices.NewBadRequestError(fmt.Sprintf("Invalid aggregate id %s.", parts[0]))
+	}
+	var body []byte
+	if r.Method == "POST" || r.Method == "PUT" {
+		if body, err = ioutil.ReadAll(r.Body); err != nil || len(body) == 0 {
+			return errBadRequest2
+		}
+	}
+	if len(parts) == 2 {
+		if r.Method != "POST" {
+			return errNotFoundJSON
+		}
+		var req struct {
+			AddHost *struct {
+				Host string `json:"host"`
+			} `json:"add_host"`
+			RemoveHost *struct {
+				Host string `json:"host"`
+			} `json:"remove_host"`
+			SetMetadata *struct {
+				Metadata map[string]*string `json:"metadata"`
+			} `json:"set_metadata"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return errBadRequest3
+		}
+		switch {
+		case req.AddHost != nil:
+			return sendAggregate(n.addAggregateHost(aggregateId, req.AddHost.Host))
+		case req.RemoveHost != nil:
+			return sendAggregate(n.removeAggregateHost(aggregateId, req.RemoveHost.Host))
+		case req.SetMetadata != nil:
+			return sendAggregate(n.setAggregateMetadata(aggregateId, req.SetMetadata.Metadata))
+		}
+		return errBadRequest3
+	}
+	switch r.Method {
+	case "GET":
+		return sendAggregate(n.aggregate(aggregateId))
+	case "PUT":
+		var req struct {
+			Aggregate nova.AggregateOpts `json:"aggregate"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return errBadRequest3
+		}
+		return sendAggregate(n.updateAggregate(aggregateId, req.Aggregate.Name, req.Aggregate.AvailabilityZone))
+	case "DELETE":
+		if err := n.deleteAggregate(aggregateId); err != nil {
+			return err
+		}
+		writeResponse(w, http.StatusOK, nil)
+		return nil
+	}
+	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+}
+
+// handleImages handles the images HTTP API, through which the images
+// created from servers are shown.
+func (n *Nova) handleImages(w http.ResponseWriter, r *http.Request) error {
+	imageId := path.Base(r.URL.Path)
+	switch r.Method {
+	case "GET":
+		if imageId == "images" {
+			images := n.allImages()
+			entities := make([]nova.Entity, len(images))
+			for i, img := range images {
+				entities[i] = nova.Entity{Id: img.Id, Name: img.Name, Links: img.Links}
+			}
+			resp := struct {
+				Images []nova.Entity `json:"images"`
+			}{entities}
+			return sendJSON(http.StatusOK, resp, w, r)
+		}
+		img, err := n.image(imageId)
+		if err != nil {
+			return err
+		}
+		resp := struct {
+			Image image `json:"image"`
+		}{*img}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "DELETE":
+		if imageId == "images" {
+			return errNotFound
+		}
+		if err := n.removeImage(imageId); err != nil {
+			return err
+		}
+		writeResponse(w, http.StatusNoContent, nil)
+		return nil
+	}
+	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+}
+
+// handleImagesDetail handles the images/detail HTTP API.
+func (n *Nova) handleImagesDetail(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+	}
+	if imageId := path.Base(r.URL.Path); imageId != "detail" {
+		return errNotFound
+	}
+	images := n.allImages()
+	if images == nil {
+		images = []image{}
+	}
+	resp := struct {
+		Images []image `json:"images"`
+	}{images}
+	return sendJSON(http.StatusOK, resp, w, r)
+}
+
+// handleOSInterfaces handles the os-interfaces HTTP API.
+func (n *Nova) handleOSInterfaces(w http.ResponseWriter, r *http.Request) error {
+	i := strings.Index(r.URL.Path, "/servers/")
+	if i < 0 {
+		return errNotFound
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path[i+len("/servers/"):], "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "os-interface" {
+		return errNotFound
+	}
+	serverId := parts[0]
+	var portId string
+	if len(parts) == 3 {
+		portId = parts[2]
+	}
+	type interfaceAttachment struct {
+		InterfaceAttachment nova.OSInterface `json:"interfaceAttachment"`
+	}
+	switch r.Method {
+	case "GET":
+		if portId != "" {
+			for _, osInterface := range n.serverOSInterfaces(serverId) {
+				if osInterface.PortID == portId {
+					return sendJSON(http.StatusOK, interfaceAttachment{osInterface}, w, r)
+				}
+			}
+			return testservices.NewPortNotAttachedError(portId)
+		}
+		interfaces := n.serverOSInterfaces(serverId)
+		resp := struct {
+			InterfaceAttachments []nova.OSInterface `json:"interfaceAttachments"`
+		}{interfaces}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "POST":
+		if portId != "" {
+			return errNotFound
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil || len(body) == 0 {
+			return errBadRequest2
+		}
+		var req struct {
+			InterfaceAttachment struct {
+				NetId    string `json:"net_id"`
+				PortId   string `json:"port_id"`
+				FixedIPs []struct {
+					IPAddress string `json:"ip_address"`
+				} `json:"fixed_ips"`
+				Tag *string `json:"tag"`
+			} `json:"interfaceAttachment"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return errBadRequest2
+		}
+		attachment := req.InterfaceAttachment
+		var fixedIP string
+		switch len(attachment.FixedIPs) {
+		case 0:
+		case 1:
+			fixedIP = attachment.FixedIPs[0].IPAddress
+		default:
+			return testservices.NewBadRequestError("Invalid input for field/attribute fixed_ips. Value is too long")
+		}
+		var tag string
+		if attachment.Tag != nil {
+			if !microversionAtLeast(r, "2.49") {
+				return testservices.NewBadRequestError("Invalid input for field/attribute interfaceAttachment. Additional properties are not allowed ('tag' was unexpected)")
+			}
+			tag = *attachment.Tag
+		}
+		osInterface, err := n.attachOSInterface(serverId, attachment.NetId, attachment.PortId, fixedIP, tag)
+		if err != nil {
+			return err
+		}
+		server, err := n.server(serverId)
+		if err != nil {
+			return err
+		}
+		if err := n.recordAction(server, nova.ActionAttachInterface, r, nil); err != nil {
+			return err
+		}
+		return sendJSON(http.StatusOK, interfaceAttachment{*osInterface}, w, r)
+	case "DELETE":
+		if portId == "" {
+			return errNotFound
+		}
+		if err := n.detachOSInterface(serverId, portId); err != nil {
+			return err
+		}
+		server, err := n.server(serverId)
+		if err != nil {
+			return err
+		}
+		if err := n.recordAction(server, nova.ActionDetachInterface, r, nil); err != nil {
+			return err
+		}
+		writeResponse(w, http.StatusAccepted, nil)
+		return nil
+	}
+	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+}
+
+func (n *Nova) handleAttachVolumes(w http.ResponseWriter, r *http.Request) error {
+	serverId := path.Base(strings.Replace(r.URL.Path, "/os-volume_attachments", "", 1))
+
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	var attachment struct {
+		VolumeAttachment nova.VolumeAttachment `json:"volumeAttachment"`
+	}
 	if err := json.Unmarshal(bodyBytes, &attachment); err != nil {
 		return err
 	}
@@ -1295,6 +2878,11 @@ func (n *Nova) handleAttachVolumes(w http.ResponseWriter, r *http.Request) error
 	serverVols := n.serverIdToAttachedVolumes[serverId]
 	serverVols = append(serverVols, attachment.VolumeAttachment)
 	n.serverIdToAttachedVolumes[serverId] = serverVols
+	if server, err := n.server(serverId); err == nil {
+		if err := n.recordAction(server, nova.ActionAttachVolume, r, nil); err != nil {
+			return err
+		}
+	}
 
 	// Echo the request back with an attachment ID.
 	resp, err := json.Marshal(&attachment)
@@ -1314,6 +2902,11 @@ func (n *Nova) handleDetachVolumes(w http.ResponseWriter, r *http.Request) error
 		if vol.Id == attachId {
 			serverVols = append(serverVols[:volIdx], serverVols[volIdx+1:]...)
 			n.serverIdToAttachedVolumes[serverId] = serverVols
+			if server, err := n.server(serverId); err == nil {
+				if err := n.recordAction(server, nova.ActionDetachVolume, r, nil); err != nil {
+					return err
+				}
+			}
 			writeResponse(w, http.StatusAccepted, nil)
 			return nil
 		}
@@ -1338,6 +2931,345 @@ func (n *Nova) handleListVolumes(w http.ResponseWriter, r *http.Request) error {
 	return err
 }
 
+// sshFingerprint returns the MD5 fingerprint of an SSH public key
+// in authorized_keys format, as computed by Nova.
+func sshFingerprint(publicKey string) (string, error) {
+	fields := strings.Fields(publicKey)
+	if len(fields) < 2 {
+		return "", fmt.Errorf("failed to generate fingerprint")
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("failed to generate fingerprint")
+	}
+	return colonHex(md5.Sum(blob)), nil
+}
+
+// x509Fingerprint returns the SHA1 fingerprint of a PEM encoded
+// certificate, as computed by Nova.
+func x509Fingerprint(publicKey string) (string, error) {
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", fmt.Errorf("failed to load x509 certificate")
+	}
+	return colonHex(sha1.Sum(block.Bytes)), nil
+}
+
+func colonHex(sum interface{}) string {
+	hex := fmt.Sprintf("% x", sum)
+	return strings.Replace(hex, " ", ":", -1)
+}
+
+// generateSSHKeyPair generates a new 2048 bit RSA SSH keypair, as
+// Nova does, returning the public key in authorized_keys format and
+// the PEM encoded private key.
+func generateSSHKeyPair() (string, string, error) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+	// The key is the key type, the exponent and the modulus, each
+	// preceded by its length. The exponent and modulus are mpints,
+	// which need a leading zero if their top bit is set.
+	mpint := func(i *big.Int) []byte {
+		b := i.Bytes()
+		if len(b) > 0 && b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+		return b
+	}
+	var blob []byte
+	for _, part := range [][]byte{[]byte("ssh-rsa"), mpint(big.NewInt(int64(private.E))), mpint(private.N)} {
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(part)))
+		blob = append(blob, length[:]...)
+		blob = append(blob, part...)
+	}
+	der := x509.MarshalPKCS1PrivateKey(private)
+	publicKey := "ssh-rsa " + base64.StdEncoding.EncodeToString(blob) + " Generated-by-Nova"
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der}))
+	return publicKey, privateKey, nil
+}
+
+// parseSSHRSAPublicKey parses an RSA public key in the OpenSSH
+// authorized_keys format.
+func parseSSHRSAPublicKey(publicKey string) (*rsa.PublicKey, error) {
+	fields := strings.Fields(publicKey)
+	if len(fields) < 2 || fields[0] != "ssh-rsa" {
+		return nil, fmt.Errorf("not an RSA public key")
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid RSA public key: %v", err)
+	}
+	// The key is the key type, the exponent and the modulus, each
+	// preceded by its length.
+	var parts [][]byte
+	for len(blob) > 0 && len(parts) < 3 {
+		if len(blob) < 4 {
+			break
+		}
+		length := binary.BigEndian.Uint32(blob)
+		if uint32(len(blob)-4) < length {
+			break
+		}
+		parts = append(parts, blob[4:4+length])
+		blob = blob[4+length:]
+	}
+	if len(parts) != 3 || string(parts[0]) != "ssh-rsa" {
+		return nil, fmt.Errorf("invalid RSA public key")
+	}
+	e := new(big.Int).SetBytes(parts[1])
+	if !e.IsInt64() || e.Int64() > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid RSA public key exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(parts[2]), E: int(e.Int64())}, nil
+}
+
+// handleKeyPairs handles the os-keypairs HTTP API.
+func (n *Nova) handleKeyPairs(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case "GET":
+		if name := path.Base(r.URL.Path); name != "os-keypairs" {
+			keyPair, err := n.keyPair(name)
+			if err != nil {
+				return err
+			}
+			resp := struct {
+				KeyPair nova.KeyPair `json:"keypair"`
+			}{*keyPair}
+			return sendJSON(http.StatusOK, resp, w, r)
+		}
+		type listItem struct {
+			KeyPair nova.KeyPair `json:"keypair"`
+		}
+		items := []listItem{}
+		for _, keyPair := range n.allKeyPairs() {
+			items = append(items, listItem{keyPair})
+		}
+		resp := struct {
+			KeyPairs []listItem `json:"keypairs"`
+		}{items}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "POST":
+		if name := path.Base(r.URL.Path); name != "os-keypairs" {
+			return errNotFound
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil || len(body) == 0 {
+			return errBadRequest2
+		}
+		var req struct {
+			KeyPair nova.KeyPair `json:"keypair"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return errBadRequest3
+		}
+		keyPair := req.KeyPair
+		if keyPair.Name == "" || len(keyPair.Name) > 255 {
+			return testservices.NewInvalidKeyPairError("Keypair name must be string and between 1 and 255 characters long")
+		}
+		if keyPair.Type == "" {
+			keyPair.Type = nova.KeyTypeSSH
+		}
+		switch {
+		case keyPair.Type == nova.KeyTypeSSH && keyPair.PublicKey == "":
+			keyPair.PublicKey, keyPair.PrivateKey, err = generateSSHKeyPair()
+			if err != nil {
+				return err
+			}
+			keyPair.Fingerprint, err = sshFingerprint(keyPair.PublicKey)
+		case keyPair.Type == nova.KeyTypeSSH:
+			keyPair.Fingerprint, err = sshFingerprint(keyPair.PublicKey)
+		case keyPair.Type == nova.KeyTypeX509 && keyPair.PublicKey != "":
+			keyPair.Fingerprint, err = x509Fingerprint(keyPair.PublicKey)
+		case keyPair.Type == nova.KeyTypeX509:
+			// Generating certificates is not supported by the double.
+			return errNotImplemented
+		default:
+			return testservices.NewInvalidKeyPairError(fmt.Sprintf("invalid keypair type %q", keyPair.Type))
+		}
+		if err != nil {
+			return testservices.NewInvalidKeyPairError(err.Error())
+		}
+		if userInfo, err := userInfo(n.IdentityService, r); err == nil {
+			keyPair.UserId = userInfo.Id
+		}
+		keyPair.Created = time.Now().Format(time.RFC3339)
+		if err := n.addKeyPair(keyPair); err != nil {
+			return err
+		}
+		resp := struct {
+			KeyPair nova.KeyPair `json:"keypair"`
+		}{keyPair}
+		return sendJSON(http.StatusCreated, resp, w, r)
+	case "DELETE":
+		if name := path.Base(r.URL.Path); name != "os-keypairs" {
+			if err := n.removeKeyPair(name); err != nil {
+				return err
+			}
+			writeResponse(w, http.StatusNoContent, nil)
+			return nil
+		}
+		return errNotFound
+	}
+	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+}
+
+// handleServerGroups handles the os-server-groups HTTP API.
+func (n *Nova) handleServerGroups(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case "GET":
+		if groupId := path.Base(r.URL.Path); groupId != "os-server-groups" {
+			group, err := n.serverGroup(groupId)
+			if err != nil {
+				return err
+			}
+			resp := struct {
+				ServerGroup nova.ServerGroup `json:"server_group"`
+			}{*group}
+			return sendJSON(http.StatusOK, resp, w, r)
+		}
+		groups := n.allServerGroups()
+		if len(groups) == 0 {
+			groups = []nova.ServerGroup{}
+		}
+		resp := struct {
+			ServerGroups []nova.ServerGroup `json:"server_groups"`
+		}{groups}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "POST":
+		if groupId := path.Base(r.URL.Path); groupId != "os-server-groups" {
+			return errNotFound
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil || len(body) == 0 {
+			return errBadRequest2
+		}
+		var req struct {
+			ServerGroup nova.ServerGroupOpts `json:"server_group"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return errBadRequest3
+		}
+		opts := req.ServerGroup
+		if opts.Name == "" {
+			return testservices.NewInvalidServerGroupError("name must be given")
+		}
+		switch opts.Policy {
+		case nova.ServerGroupAffinity, nova.ServerGroupSoftAffinity, nova.ServerGroupSoftAntiAffinity:
+			if opts.Rules != nil {
+				return testservices.NewInvalidServerGroupError("rules are only allowed with the anti-affinity policy")
+			}
+		case nova.ServerGroupAntiAffinity:
+			if opts.Rules != nil && opts.Rules.MaxServerPerHost < 1 {
+				return testservices.NewInvalidServerGroupError("max_server_per_host must be at least 1")
+			}
+		default:
+			return testservices.NewInvalidServerGroupError(fmt.Sprintf("invalid policy %q", opts.Policy))
+		}
+		id, err := newUUID()
+		if err != nil {
+			return err
+		}
+		group := nova.ServerGroup{
+			Id:        id,
+			Name:      opts.Name,
+			Policy:    opts.Policy,
+			Rules:     opts.Rules,
+			Members:   []string{},
+			ProjectId: n.TenantId,
+		}
+		if userInfo, err := userInfo(n.IdentityService, r); err == nil {
+			group.UserId = userInfo.Id
+		}
+		if err := n.addServerGroup(group); err != nil {
+			return err
+		}
+		resp := struct {
+			ServerGroup nova.ServerGroup `json:"server_group"`
+		}{group}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "DELETE":
+		if groupId := path.Base(r.URL.Path); groupId != "os-server-groups" {
+			if err := n.removeServerGroup(groupId); err != nil {
+				return err
+			}
+			writeResponse(w, http.StatusNoContent, nil)
+			return nil
+		}
+		return errNotFound
+	}
+	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+}
+
+// handleLimits handles the limits HTTP API.
+func (n *Nova) handleLimits(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case "GET":
+		if name := path.Base(r.URL.Path); name != "limits" {
+			return errNotFound
+		}
+		resp := struct {
+			Limits nova.Limits `json:"limits"`
+		}{nova.Limits{Absolute: n.limits(), Rate: []nova.RateLimit{}}}
+		return sendJSON(http.StatusOK, resp, w, r)
+	}
+	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+}
+
+// handleQuotaSets handles the os-quota-sets HTTP API. Only the
+// quotas of the double's own tenant can be changed; other tenants
+// have the default quotas.
+func (n *Nova) handleQuotaSets(w http.ResponseWriter, r *http.Request) error {
+	var parts []string
+	if i := strings.Index(r.URL.Path, "/os-quota-sets/"); i >= 0 {
+		parts = strings.Split(strings.Trim(r.URL.Path[i+len("/os-quota-sets/"):], "/"), "/")
+	}
+	if len(parts) == 0 || parts[0] == "" || len(parts) > 2 || (len(parts) == 2 && parts[1] != "defaults") {
+		return errNotFound
+	}
+	tenantId := parts[0]
+	switch r.Method {
+	case "GET":
+		quotas := defaultQuotas
+		if len(parts) == 1 && tenantId == n.TenantId {
+			quotas = n.quotas
+		}
+		quotas.Id = tenantId
+		resp := struct {
+			QuotaSet nova.QuotaSet `json:"quota_set"`
+		}{quotas}
+		return sendJSON(http.StatusOK, resp, w, r)
+	case "PUT":
+		if len(parts) != 1 {
+			return errNotFound
+		}
+		if tenantId != n.TenantId {
+			return testservices.NewInvalidQuotaError(fmt.Sprintf("unknown project %s", tenantId))
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil || len(body) == 0 {
+			return errBadRequest2
+		}
+		var req struct {
+			QuotaSet nova.QuotaSetOpts `json:"quota_set"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return errBadRequest3
+		}
+		if err := n.updateQuotas(req.QuotaSet); err != nil {
+			return err
+		}
+		// The response to an update holds no id.
+		resp := struct {
+			QuotaSet nova.QuotaSet `json:"quota_set"`
+		}{n.quotas}
+		return sendJSON(http.StatusOK, resp, w, r)
+	}
+	return fmt.Errorf("unknown request method %q for %s", r.Method, r.URL.Path)
+}
+
 // SetupHTTP attaches all the needed handlers to provide the HTTP API.
 //
 // TODO (stickupkid): The following needs re-working for version 3 of goose.
@@ -1353,13 +3285,24 @@ func (n *Nova) handleListVolumes(w http.ResponseWriter, r *http.Request) error {
 // }
 func (n *Nova) SetupHTTP(mux *http.ServeMux) {
 	handlers := map[string]http.Handler{
-		"/$v/":                        errBadRequest,
-		"/$v/$t/":                     errNotFound,
-		"/$v/$t/flavors":              n.handler((*Nova).handleFlavors),
-		"/$v/$t/flavors/detail":       n.handler((*Nova).handleFlavorsDetail),
-		"/$v/$t/servers":              n.handler((*Nova).handleServers),
-		"/$v/$t/servers/detail":       n.handler((*Nova).handleServersDetail),
-		"/$v/$t/os-availability-zone": n.handler((*Nova).handleAvailabilityZones),
+		"/$v/":                          errBadRequest,
+		"/$v/$t/":                       errNotFound,
+		"/$v/$t/flavors":                n.handler((*Nova).handleFlavors),
+		"/$v/$t/flavors/detail":         n.handler((*Nova).handleFlavorsDetail),
+		"/$v/$t/servers":                n.handler((*Nova).handleServers),
+		"/$v/$t/servers/detail":         n.handler((*Nova).handleServersDetail),
+		"/$v/$t/os-availability-zone":   n.handler((*Nova).handleAvailabilityZones),
+		"/$v/$t/os-keypairs":            n.handler((*Nova).handleKeyPairs),
+		"/$v/$t/os-server-groups":       n.handler((*Nova).handleServerGroups),
+		"/$v/$t/limits":                 n.handler((*Nova).handleLimits),
+		"/$v/$t/images":                 n.handler((*Nova).handleImages),
+		"/$v/$t/images/detail":          n.handler((*Nova).handleImagesDetail),
+		"/$v/$t/os-quota-sets":          n.handler((*Nova).handleQuotaSets),
+		"/$v/$t/os-hypervisors":         n.handler((*Nova).handleHypervisors),
+		"/$v/$t/os-services":            n.handler((*Nova).handleServices),
+		"/$v/$t/os-aggregates":          n.handler((*Nova).handleAggregates),
+		"/$v/$t/os-migrations":          n.handler((*Nova).handleMigrations),
+		"/$v/$t/os-simple-tenant-usage": n.handler((*Nova).handleTenantUsage),
 	}
 	if !n.useNeutronNetworking {
 		handlers["/$v/$t/os-security-groups"] = n.handler((*Nova).handleSecurityGroups)
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
@@ -229,7 +230,7 @@ func (s *NovaHTTPSuite) simpleTests() []SimpleTest {
 		{
 			method: "DELETE",
 			url:    "/flavors/invalid",
-			expect: errForbidden,
+			expect: &errorResponse{code: 404, body: "{\"itemNotFound\":{\"message\":\"Flavor invalid could not be found.\", \"code\":404}}"},
 		},
 		{
 			method: "GET",
@@ -757,6 +758,39 @@ func (s *NovaHTTPSuite) TestRunServer(c *gc.C) {
 	s.service.removeServer(srv.Id)
 }
 
+func (s *NovaHTTPSuite) TestRunServerRequiresNetworks(c *gc.C) {
+	req := map[string]interface{}{"server": map[string]interface{}{
+		"name":      "srv1",
+		"imageRef":  "image",
+		"flavorRef": "flavor",
+	}}
+	microversion := setHeader("OpenStack-API-Version", "compute 2.37")
+	resp, err := s.jsonRequest("POST", "/servers", req, microversion)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusBadRequest)
+
+	for _, networks := range []string{"auto", "none"} {
+		req["server"].(map[string]interface{})["networks"] = networks
+		resp, err = s.jsonRequest("POST", "/servers", req, nil)
+		c.Assert(err, gc.IsNil)
+		c.Assert(resp.StatusCode, gc.Equals, http.StatusBadRequest)
+		resp, err = s.jsonRequest("POST", "/servers", req, microversion)
+		c.Assert(err, gc.IsNil)
+		c.Assert(resp.StatusCode, gc.Equals, http.StatusAccepted)
+		var created struct {
+			Server struct {
+				Id string `json:"id"`
+			} `json:"server"`
+		}
+		assertJSON(c, resp, &created)
+		s.service.removeServer(created.Server.Id)
+	}
+	req["server"].(map[string]interface{})["networks"] = "some"
+	resp, err = s.jsonRequest("POST", "/servers", req, microversion)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusBadRequest)
+}
+
 func (s *NovaHTTPSuite) TestDeleteServer(c *gc.C) {
 	server := nova.ServerDetail{Id: "sr1"}
 	_, err := s.service.server(server.Id)
@@ -1380,6 +1414,222 @@ func (s *NovaHTTPSuite) TestSetServerMetadata(c *gc.C) {
 	c.Assert(server.Metadata, gc.DeepEquals, req.Metadata)
 }
 
+func (s *NovaHTTPSuite) TestServerMetadataItem(c *gc.C) {
+	const serverId = "sr1"
+
+	err := s.service.addServer(nova.ServerDetail{Id: serverId, Metadata: map[string]string{"k1": "v1"}})
+	c.Assert(err, gc.IsNil)
+	defer s.service.removeServer(serverId)
+
+	var item struct {
+		Meta map[string]string `json:"meta"`
+	}
+	resp, err := s.authRequest("GET", "/servers/"+serverId+"/metadata/k1", nil, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	assertJSON(c, resp, &item)
+	c.Assert(item.Meta, gc.DeepEquals, map[string]string{"k1": "v1"})
+
+	item.Meta = map[string]string{"k2": "v2"}
+	resp, err = s.jsonRequest("PUT", "/servers/"+serverId+"/metadata/k1", item, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusBadRequest)
+	resp, err = s.jsonRequest("PUT", "/servers/"+serverId+"/metadata/k2", item, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+
+	resp, err = s.authRequest("DELETE", "/servers/"+serverId+"/metadata/k1", nil, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNoContent)
+	resp, err = s.authRequest("GET", "/servers/"+serverId+"/metadata/k1", nil, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNotFound)
+
+	var metadata struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	resp, err = s.authRequest("GET", "/servers/"+serverId+"/metadata", nil, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	assertJSON(c, resp, &metadata)
+	c.Assert(metadata.Metadata, gc.DeepEquals, map[string]string{"k2": "v2"})
+
+	// Keys are escaped in the path, and may name other leaves of
+	// a server.
+	for _, key := range []string{"os-interface", "a/b%c"} {
+		item.Meta = map[string]string{key: "v"}
+		resp, err = s.jsonRequest("PUT", "/servers/"+serverId+"/metadata/"+url.PathEscape(key), item, nil)
+		c.Assert(err, gc.IsNil)
+		c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+		resp, err = s.authRequest("GET", "/servers/"+serverId+"/metadata/"+url.PathEscape(key), nil, nil)
+		c.Assert(err, gc.IsNil)
+		c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+		assertJSON(c, resp, &item)
+		c.Assert(item.Meta, gc.DeepEquals, map[string]string{key: "v"})
+	}
+}
+
+func (s *NovaHTTPSuite) TestUpdateServiceRequiresMicroversion(c *gc.C) {
+	service := s.service.computeService(defaultHost)
+	req := struct {
+		Status         string `json:"status"`
+		DisabledReason string `json:"disabled_reason"`
+	}{"enabled", "maintenance"}
+	resp, err := s.jsonRequest("PUT", "/os-services/"+service.Id, req, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNotFound)
+	resp, err = s.jsonRequest("PUT", "/os-services/"+service.Id, req, setHeader("OpenStack-API-Version", "compute 2.53"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusBadRequest)
+
+	req.Status = "disabled"
+	resp, err = s.jsonRequest("PUT", "/os-services/"+service.Id, req, setHeader("OpenStack-API-Version", "compute 2.53"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	defer s.service.setServiceStatus(service.Id, false, "")
+	var updated struct {
+		Service nova.ComputeService `json:"service"`
+	}
+	assertJSON(c, resp, &updated)
+	c.Assert(updated.Service.Status, gc.Equals, "disabled")
+	c.Assert(updated.Service.DisabledReason, gc.Equals, "maintenance")
+}
+
+func (s *NovaHTTPSuite) TestMigrationsRequireMicroversion(c *gc.C) {
+	s.service.SetHosts("host1", "host2")
+	defer s.service.SetHosts()
+	server := nova.ServerDetail{Id: "sr1", HostId: s.service.hostId("host1")}
+	err := s.service.addServer(server)
+	c.Assert(err, gc.IsNil)
+	defer s.service.removeServer(server.Id)
+	s.service.serverHosts[server.Id] = "host1"
+
+	resp, err := s.authRequest("GET", "/servers/sr1/migrations", nil, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNotFound)
+	resp, err = s.authRequest("GET", "/servers/sr1/migrations", nil, setHeader("OpenStack-API-Version", "compute 2.23"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	var migrations struct {
+		Migrations []nova.ServerMigration `json:"migrations"`
+	}
+	assertJSON(c, resp, &migrations)
+	c.Assert(migrations.Migrations, gc.HasLen, 0)
+
+	// A target host for a cold migration needs microversion 2.56.
+	req := map[string]interface{}{"migrate": map[string]string{"host": "host2"}}
+	resp, err = s.jsonRequest("POST", "/servers/sr1/action", req, setHeader("OpenStack-API-Version", "compute 2.55"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusBadRequest)
+	resp, err = s.jsonRequest("POST", "/servers/sr1/action", req, setHeader("OpenStack-API-Version", "compute 2.56"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusAccepted)
+
+	live := map[string]interface{}{"os-migrateLive": map[string]interface{}{"host": nil, "block_migration": "yes"}}
+	resp, err = s.jsonRequest("POST", "/servers/sr1/action", live, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusBadRequest)
+}
+
+func (s *NovaHTTPSuite) TestServerDiagnosticsFormats(c *gc.C) {
+	flavor := nova.FlavorDetail{Id: "fl1", VCPUs: 2, RAM: 512}
+	err := s.service.addFlavor(flavor)
+	c.Assert(err, gc.IsNil)
+	defer s.service.removeFlavor(flavor.Id)
+	server := nova.ServerDetail{Id: "sr1", Status: nova.StatusActive, Flavor: nova.Entity{Id: flavor.Id}}
+	err = s.service.addServer(server)
+	c.Assert(err, gc.IsNil)
+	defer s.service.removeServer(server.Id)
+
+	// Before microversion 2.48 the diagnostics are those reported by
+	// the hypervisor driver, here libvirt.
+	resp, err := s.authRequest("GET", "/servers/sr1/diagnostics", nil, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	var flat map[string]int64
+	assertJSON(c, resp, &flat)
+	c.Check(flat["memory"], gc.Equals, int64(512*1024))
+	c.Check(flat, gc.HasLen, 3)
+
+	resp, err = s.authRequest("GET", "/servers/sr1/diagnostics", nil, setHeader("OpenStack-API-Version", "compute 2.48"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	var diagnostics nova.ServerDiagnostics
+	assertJSON(c, resp, &diagnostics)
+	c.Check(diagnostics.State, gc.Equals, nova.DiagnosticsStateRunning)
+	c.Check(diagnostics.NumCPUs, gc.Equals, 2)
+	c.Check(diagnostics.MemoryDetails.Maximum, gc.Equals, 512)
+}
+
+func (s *NovaHTTPSuite) TestServerTagsRequireMicroversion(c *gc.C) {
+	const serverId = "sr1"
+
+	err := s.service.addServer(nova.ServerDetail{Id: serverId})
+	c.Assert(err, gc.IsNil)
+	defer s.service.removeServer(serverId)
+
+	resp, err := s.authRequest("PUT", "/servers/"+serverId+"/tags/web", nil, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNotFound)
+	resp, err = s.authRequest("PUT", "/servers/"+serverId+"/tags/web", nil, setHeader("OpenStack-API-Version", "compute 2.26"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusCreated)
+	c.Assert(resp.Header.Get("Location"), gc.Matches, ".*/servers/sr1/tags/web")
+	resp, err = s.authRequest("PUT", "/servers/"+serverId+"/tags/web", nil, setHeader("X-OpenStack-Nova-API-Version", "2.30"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNoContent)
+	for _, tag := range []string{"metadata-owner", "os-interface", "os-volume_attachments"} {
+		resp, err = s.authRequest("PUT", "/servers/"+serverId+"/tags/"+tag, nil, setHeader("OpenStack-API-Version", "compute 2.26"))
+		c.Assert(err, gc.IsNil)
+		c.Assert(resp.StatusCode, gc.Equals, http.StatusCreated)
+	}
+
+	var req struct {
+		Server struct {
+			Description string `json:"description"`
+		} `json:"server"`
+	}
+	req.Server.Description = "described"
+	resp, err = s.jsonRequest("PUT", "/servers/"+serverId, req, setHeader("OpenStack-API-Version", "compute 2.18"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusBadRequest)
+	resp, err = s.jsonRequest("PUT", "/servers/"+serverId, req, setHeader("OpenStack-API-Version", "compute 2.19"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+
+	server, err := s.service.server(serverId)
+	c.Assert(err, gc.IsNil)
+	c.Assert(server.Tags, gc.DeepEquals, []string{"metadata-owner", "os-interface", "os-volume_attachments", "web"})
+	c.Assert(server.Description, gc.Equals, "described")
+}
+
+func (s *NovaHTTPSuite) TestCreateImageMicroversion(c *gc.C) {
+	const serverId = "sr1"
+
+	err := s.service.addServer(nova.ServerDetail{Id: serverId})
+	c.Assert(err, gc.IsNil)
+	defer s.service.removeServer(serverId)
+
+	req := struct {
+		CreateImage nova.CreateImageOpts `json:"createImage"`
+	}{nova.CreateImageOpts{Name: "snap"}}
+	resp, err := s.jsonRequest("POST", "/servers/"+serverId+"/action", req, nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusAccepted)
+	c.Assert(resp.Header.Get("Location"), gc.Matches, ".*/images/.*")
+
+	resp, err = s.jsonRequest("POST", "/servers/"+serverId+"/action", req, setHeader("OpenStack-API-Version", "compute 2.45"))
+	c.Assert(err, gc.IsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusAccepted)
+	c.Assert(resp.Header.Get("Location"), gc.Equals, "")
+	var created struct {
+		ImageId string `json:"image_id"`
+	}
+	assertJSON(c, resp, &created)
+	_, err = s.service.image(created.ImageId)
+	c.Assert(err, gc.IsNil)
+}
+
 func (s *NovaHTTPSuite) TestAttachVolumeBlankDeviceName(c *gc.C) {
 	var req struct {
 		VolumeAttachment struct {
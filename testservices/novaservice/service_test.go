@@ -4,9 +4,12 @@ package novaservice
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	gc "gopkg.in/check.v1"
 
+	"github.com/go-goose/goose/v5/neutron"
 	"github.com/go-goose/goose/v5/nova"
 	"github.com/go-goose/goose/v5/testservices/hook"
 	"github.com/go-goose/goose/v5/testservices/neutronmodel"
@@ -605,6 +608,612 @@ func (s *NovaSuite) TestGetServerByName(c *gc.C) {
 	}
 }
 
+func (s *NovaSuite) TestChangeServerStatus(c *gc.C) {
+	server := nova.ServerDetail{Id: "test"}
+	s.createServer(c, server)
+	defer s.deleteServer(c, server)
+	for _, t := range []struct {
+		action string
+		status string
+	}{
+		{"os-stop", nova.StatusShutoff},
+		{"os-start", nova.StatusActive},
+		{"suspend", nova.StatusSuspended},
+		{"resume", nova.StatusActive},
+		{"shelve", nova.StatusShelvedOffloaded},
+		{"unshelve", nova.StatusActive},
+	} {
+		err := s.service.changeServerStatus(server.Id, t.action)
+		c.Assert(err, gc.IsNil)
+		sr, err := s.service.server(server.Id)
+		c.Assert(err, gc.IsNil)
+		c.Assert(sr.Status, gc.Equals, t.status)
+	}
+	err := s.service.changeServerStatus(server.Id, "resume")
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: Cannot 'resume' instance test while it is in status ACTIVE")
+}
+
+func (s *NovaSuite) TestResizeAndRevertServer(c *gc.C) {
+	server := nova.ServerDetail{Id: "test", Flavor: nova.Entity{Id: "1"}}
+	s.createServer(c, server)
+	defer s.deleteServer(c, server)
+	err := s.service.resizeServer(server.Id, "no-such-flavor")
+	c.Assert(err, gc.ErrorMatches, `badRequest: Unable to find flavor "no-such-flavor"`)
+	err = s.service.changeServerStatus(server.Id, "os-stop")
+	c.Assert(err, gc.IsNil)
+	err = s.service.resizeServer(server.Id, "2")
+	c.Assert(err, gc.IsNil)
+	sr, _ := s.service.server(server.Id)
+	c.Assert(sr.Status, gc.Equals, nova.StatusVerifyResize)
+	c.Assert(sr.Flavor.Id, gc.Equals, "2")
+	err = s.service.revertResize(server.Id)
+	c.Assert(err, gc.IsNil)
+	sr, _ = s.service.server(server.Id)
+	c.Assert(sr.Status, gc.Equals, nova.StatusShutoff)
+	c.Assert(sr.Flavor, gc.DeepEquals, server.Flavor)
+}
+
+func (s *NovaSuite) TestAddRemoveKeyPair(c *gc.C) {
+	keyPair := nova.KeyPair{Name: "key", PublicKey: "ssh-rsa AAAA", PrivateKey: "secret"}
+	err := s.service.addKeyPair(keyPair)
+	c.Assert(err, gc.IsNil)
+	err = s.service.addKeyPair(keyPair)
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: Key pair 'key' already exists.")
+	kp, err := s.service.keyPair("key")
+	c.Assert(err, gc.IsNil)
+	c.Assert(kp.PrivateKey, gc.Equals, "")
+	c.Assert(s.service.allKeyPairs(), gc.HasLen, 1)
+	err = s.service.removeKeyPair("key")
+	c.Assert(err, gc.IsNil)
+	err = s.service.removeKeyPair("key")
+	c.Assert(err, gc.ErrorMatches, "itemNotFound: Keypair key not found")
+}
+
+func (s *NovaSuite) TestScheduleServerAntiAffinity(c *gc.C) {
+	s.service.SetHosts("h1", "h2")
+	defer s.service.SetHosts()
+	group := nova.ServerGroup{Id: "g1", Policy: nova.ServerGroupAntiAffinity}
+	err := s.service.addServerGroup(group)
+	c.Assert(err, gc.IsNil)
+	defer s.service.removeServerGroup(group.Id)
+	for i, expected := range []string{"h1", "h2"} {
+		host, ok, err := s.service.scheduleServer(group.Id, "")
+		c.Assert(err, gc.IsNil)
+		c.Assert(ok, gc.Equals, true)
+		c.Assert(host, gc.Equals, expected)
+		server := nova.ServerDetail{Id: fmt.Sprint("sr", i), HostId: s.service.hostId(host)}
+		s.createServer(c, server)
+		s.service.serverHosts[server.Id] = host
+		err = s.service.addServerGroupMember(group.Id, server.Id)
+		c.Assert(err, gc.IsNil)
+	}
+	_, ok, err := s.service.scheduleServer(group.Id, "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(ok, gc.Equals, false)
+
+	// Removing a server frees its host.
+	s.deleteServer(c, nova.ServerDetail{Id: "sr0"})
+	sg, err := s.service.serverGroup(group.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(sg.Members, gc.DeepEquals, []string{"sr1"})
+	host, ok, err := s.service.scheduleServer(group.Id, "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(host, gc.Equals, "h1")
+	s.deleteServer(c, nova.ServerDetail{Id: "sr1"})
+}
+
+func (s *NovaSuite) TestConsoleOutput(c *gc.C) {
+	server := nova.ServerDetail{Id: "test"}
+	s.createServer(c, server)
+	defer s.deleteServer(c, server)
+	output, err := s.service.consoleOutput(server.Id, 0)
+	c.Assert(err, gc.IsNil)
+	c.Assert(output, gc.Equals, "")
+	s.service.SetConsoleOutput(server.Id, "one\ntwo\nthree")
+	for length, expected := range map[int]string{
+		-1: "one\ntwo\nthree",
+		1:  "three",
+		2:  "two\nthree",
+		5:  "one\ntwo\nthree",
+	} {
+		output, err := s.service.consoleOutput(server.Id, length)
+		c.Assert(err, gc.IsNil)
+		c.Check(output, gc.Equals, expected)
+	}
+}
+
+func (s *NovaSuite) TestCheckServerQuotas(c *gc.C) {
+	defer s.service.ResetQuotas()
+	server := nova.ServerDetail{Id: "test", Flavor: nova.Entity{Id: "3"}}
+	s.createServer(c, server)
+	defer s.deleteServer(c, server)
+	limits := s.service.limits()
+	c.Assert(limits.TotalInstancesUsed, gc.Equals, 1)
+	c.Assert(limits.TotalCoresUsed, gc.Equals, 2)
+	c.Assert(limits.TotalRAMUsed, gc.Equals, 4096)
+	c.Assert(limits.MaxTotalInstances, gc.Equals, nova.Unlimited)
+	c.Assert(limits.MaxTotalKeypairs, gc.Equals, nova.Unlimited)
+
+	c.Assert(s.service.checkServerQuotas("3", ""), gc.IsNil)
+	one, three, unlimited := 1, 3, nova.Unlimited
+	s.service.SetQuotas(nova.QuotaSetOpts{Instances: &one})
+	c.Assert(s.service.limits().MaxTotalCores, gc.Equals, nova.Unlimited)
+	err := s.service.checkServerQuotas("3", "")
+	c.Assert(err, gc.ErrorMatches, "forbidden: Quota exceeded for instances: Requested 1, but already used 1 of 1 instances")
+	s.service.SetQuotas(nova.QuotaSetOpts{Instances: &unlimited, Cores: &three})
+	err = s.service.checkServerQuotas("3", "")
+	c.Assert(err, gc.ErrorMatches, "forbidden: Quota exceeded for cores: Requested 2, but already used 2 of 3 cores")
+	c.Assert(s.service.checkServerQuotas("1", ""), gc.IsNil)
+
+	cores := 1
+	err = s.service.updateQuotas(nova.QuotaSetOpts{Cores: &cores})
+	c.Assert(err, gc.ErrorMatches, "badRequest: Quota limit 1 for cores must be greater than or equal to already used and reserved 2.")
+	err = s.service.updateQuotas(nova.QuotaSetOpts{Cores: &cores, Force: true})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.service.limits().MaxTotalCores, gc.Equals, 1)
+}
+
+func (s *NovaSuite) TestFlavorExtraSpecsAndAccess(c *gc.C) {
+	flavor := nova.FlavorDetail{Id: "test", Name: "private"}
+	s.createFlavor(c, flavor)
+	err := s.service.setExtraSpecs(flavor.Id, map[string]string{"a": "1", "b": "2"})
+	c.Assert(err, gc.IsNil)
+	err = s.service.unsetExtraSpec(flavor.Id, "a")
+	c.Assert(err, gc.IsNil)
+	specs, err := s.service.extraSpecs(flavor.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(specs, gc.DeepEquals, map[string]string{"b": "2"})
+	err = s.service.unsetExtraSpec(flavor.Id, "a")
+	c.Assert(err, gc.ErrorMatches, "itemNotFound: Flavor test has no extra specs with key a.")
+
+	err = s.service.addFlavorAccess(flavor.Id, "tenant")
+	c.Assert(err, gc.IsNil)
+	access, err := s.service.allFlavorAccess(flavor.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(access, gc.DeepEquals, []nova.FlavorAccess{{FlavorId: "test", TenantId: "tenant"}})
+
+	// Removing the flavor removes its extra specs and access list.
+	s.deleteFlavor(c, flavor)
+	c.Assert(s.service.flavorExtraSpecs, gc.HasLen, 0)
+	c.Assert(s.service.flavorAccess, gc.HasLen, 0)
+	_, err = s.service.extraSpecs(flavor.Id)
+	c.Assert(err, gc.ErrorMatches, "itemNotFound: Flavor test could not be found.")
+}
+
+func (s *NovaSuite) TestServerTags(c *gc.C) {
+	server := nova.ServerDetail{Id: "test"}
+	s.createServer(c, server)
+	defer s.deleteServer(c, server)
+	err := s.service.setServerTags(server.Id, []string{"b", "a", "b"})
+	c.Assert(err, gc.IsNil)
+	added, err := s.service.addServerTag(server.Id, "c")
+	c.Assert(err, gc.IsNil)
+	c.Assert(added, gc.Equals, true)
+	added, err = s.service.addServerTag(server.Id, "a")
+	c.Assert(err, gc.IsNil)
+	c.Assert(added, gc.Equals, false)
+	err = s.service.removeServerTag(server.Id, "b")
+	c.Assert(err, gc.IsNil)
+	sr, err := s.service.server(server.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(sr.Tags, gc.DeepEquals, []string{"a", "c"})
+
+	err = s.service.removeServerTag(server.Id, "b")
+	c.Assert(err, gc.ErrorMatches, "itemNotFound: Instance test has no tag 'b'")
+	err = s.service.setServerTags(server.Id, []string{"a,b"})
+	c.Assert(err, gc.ErrorMatches, "badRequest: .* must not contain '/' or ','")
+	tooMany := make([]string, nova.MaxServerTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprint(i)
+	}
+	err = s.service.setServerTags(server.Id, tooMany)
+	c.Assert(err, gc.ErrorMatches, "badRequest: The number of tags exceeded the per-server limit 50")
+}
+
+func (s *NovaSuite) TestInstanceActions(c *gc.C) {
+	server := nova.ServerDetail{Id: "test", UUID: "test-uuid", TenantId: "tenant"}
+	s.createServer(c, server)
+	err := s.service.recordInstanceAction(&server, nova.ActionCreate, "req-1", "user", nil)
+	c.Assert(err, gc.IsNil)
+	fault := &nova.ServerFault{Code: 500, Message: "boom"}
+	err = s.service.recordInstanceAction(&server, nova.ActionResize, "req-2", "user", fault)
+	c.Assert(err, gc.IsNil)
+	err = s.service.recordInstanceAction(&server, "frobnicate", "req-3", "user", nil)
+	c.Assert(err, gc.ErrorMatches, `unknown instance action "frobnicate"`)
+
+	actions, err := s.service.allInstanceActions(server.Id, false)
+	c.Assert(err, gc.IsNil)
+	c.Assert(actions, gc.HasLen, 2)
+	c.Assert(actions[0].RequestId, gc.Equals, "req-2")
+	c.Assert(actions[0].Message, gc.Equals, "Error")
+	c.Assert(actions[0].Events, gc.IsNil)
+	c.Assert(actions[1].RequestId, gc.Equals, "req-1")
+	c.Assert(actions[1].InstanceUUID, gc.Equals, "test-uuid")
+	c.Assert(actions[1].ProjectId, gc.Equals, "tenant")
+
+	action, err := s.service.instanceAction(server.Id, "req-2", false)
+	c.Assert(err, gc.IsNil)
+	c.Assert(action.Events, gc.HasLen, 1)
+	c.Assert(action.Events[0].Event, gc.Equals, "compute_prep_resize")
+	c.Assert(action.Events[0].Result, gc.Equals, nova.EventResultError)
+	c.Assert(action.Events[0].Traceback, gc.Equals, "Traceback (most recent call last):\nboom\n")
+	_, err = s.service.instanceAction(server.Id, "req-3", false)
+	c.Assert(err, gc.ErrorMatches, "itemNotFound: Action req-3 on instance test could not be found")
+
+	s.deleteServer(c, server)
+	_, err = s.service.allInstanceActions(server.Id, false)
+	c.Assert(err, gc.ErrorMatches, "itemNotFound: .*")
+	actions, err = s.service.allInstanceActions(server.Id, true)
+	c.Assert(err, gc.IsNil)
+	c.Assert(actions, gc.HasLen, 2)
+}
+
+func (s *NovaSuite) TestServerImages(c *gc.C) {
+	server := nova.ServerDetail{Id: "test", UUID: "test-uuid", Image: nova.Entity{Id: "base"}}
+	s.createServer(c, server)
+	defer s.deleteServer(c, server)
+
+	snapshot, err := s.service.createImage(server.Id, "snap", map[string]string{"key": "value"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(snapshot.Status, gc.Equals, "ACTIVE")
+	c.Assert(snapshot.Metadata["image_type"], gc.Equals, "snapshot")
+	c.Assert(snapshot.Metadata["base_image_ref"], gc.Equals, "base")
+	c.Assert(snapshot.Metadata["key"], gc.Equals, "value")
+
+	first, err := s.service.backupServer(server.Id, "first", "daily", 1, nil)
+	c.Assert(err, gc.IsNil)
+	second, err := s.service.backupServer(server.Id, "second", "daily", 1, nil)
+	c.Assert(err, gc.IsNil)
+	_, err = s.service.image(first.Id)
+	c.Assert(err, gc.ErrorMatches, "itemNotFound: Image .* could not be found.")
+	images := s.service.allImages()
+	c.Assert(images, gc.HasLen, 2)
+	c.Assert(images[0].Id, gc.Equals, snapshot.Id)
+	c.Assert(images[1].Id, gc.Equals, second.Id)
+
+	_, err = s.service.backupServer(server.Id, "bad", "daily", -1, nil)
+	c.Assert(err, gc.ErrorMatches, "badRequest: .* -1 is less than the minimum of 0")
+	c.Assert(s.service.removeImage(snapshot.Id), gc.IsNil)
+	c.Assert(s.service.removeImage(snapshot.Id), gc.ErrorMatches, "itemNotFound: .*")
+}
+
+func (s *NovaSuite) TestAttachDetachOSInterface(c *gc.C) {
+	server := nova.ServerDetail{Id: "test"}
+	s.createServer(c, server)
+	defer s.deleteServer(c, server)
+
+	_, err := s.service.attachOSInterface(server.Id, "", "", "", "")
+	c.Assert(err, gc.ErrorMatches, "badRequest: Must input network_id or port_id")
+	_, err = s.service.attachOSInterface(server.Id, "missing", "", "", "")
+	c.Assert(err, gc.ErrorMatches, `itemNotFound: No such network "missing"`)
+
+	osInterface, err := s.service.attachOSInterface(server.Id, "1", "", "", "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(osInterface.NetID, gc.Equals, "1")
+	c.Assert(osInterface.IPAddress, gc.Not(gc.Equals), "")
+	c.Assert(s.service.serverOSInterfaces(server.Id), gc.DeepEquals, []nova.OSInterface{*osInterface})
+	sr, err := s.service.server(server.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(sr.Addresses["net"], gc.DeepEquals, []nova.IPAddress{{Version: 4, Address: osInterface.IPAddress, Type: "fixed"}})
+
+	err = s.service.detachOSInterface(server.Id, osInterface.PortID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.service.serverOSInterfaces(server.Id), gc.HasLen, 0)
+	sr, err = s.service.server(server.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(sr.Addresses, gc.HasLen, 0)
+	err = s.service.detachOSInterface(server.Id, osInterface.PortID)
+	c.Assert(err, gc.ErrorMatches, "itemNotFound: Port .* is not attached")
+}
+
+func (s *NovaSuite) TestAttachOSInterfaceNeutronPorts(c *gc.C) {
+	if !s.useNeutronNetworking {
+		c.Skip("ports are only shared with neutron")
+	}
+	server := nova.ServerDetail{Id: "test"}
+	s.createServer(c, server)
+	defer s.deleteServer(c, server)
+	other := nova.ServerDetail{Id: "other"}
+	s.createServer(c, other)
+	defer s.deleteServer(c, other)
+
+	// A port created on attach is deleted on detach.
+	created, err := s.service.attachOSInterface(server.Id, "1", "", "10.20.0.5", "")
+	c.Assert(err, gc.IsNil)
+	port, err := s.service.neutronModel.Port(created.PortID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(port.DeviceId, gc.Equals, server.Id)
+	c.Assert(port.FixedIPs, gc.DeepEquals, []neutron.PortFixedIPsV2{{IPAddress: "10.20.0.5"}})
+	_, err = s.service.attachOSInterface(other.Id, "1", "", "10.20.0.5", "")
+	c.Assert(err, gc.ErrorMatches, "badRequest: Fixed IP address 10.20.0.5 is already in use.")
+	err = s.service.detachOSInterface(server.Id, created.PortID)
+	c.Assert(err, gc.IsNil)
+	_, err = s.service.neutronModel.Port(created.PortID)
+	c.Assert(err, gc.ErrorMatches, "itemNotFound: No such port .*")
+
+	// An existing port is kept, and released on detach.
+	err = s.service.neutronModel.AddPort(neutron.PortV2{
+		Id:        "storage",
+		NetworkId: "1",
+		FixedIPs:  []neutron.PortFixedIPsV2{{IPAddress: "10.30.0.7"}},
+	})
+	c.Assert(err, gc.IsNil)
+	defer s.service.neutronModel.RemovePort("storage")
+	attached, err := s.service.attachOSInterface(server.Id, "", "storage", "", "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(attached.IPAddress, gc.Equals, "10.30.0.7")
+	_, err = s.service.attachOSInterface(other.Id, "", "storage", "", "")
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: Port storage is still in use.")
+	err = s.service.detachOSInterface(server.Id, "storage")
+	c.Assert(err, gc.IsNil)
+	port, err = s.service.neutronModel.Port("storage")
+	c.Assert(err, gc.IsNil)
+	c.Assert(port.DeviceId, gc.Equals, "")
+
+	// Deleting a server releases its ports.
+	_, err = s.service.attachOSInterface(other.Id, "", "storage", "", "")
+	c.Assert(err, gc.IsNil)
+	s.deleteServer(c, other)
+	s.createServer(c, other)
+	port, err = s.service.neutronModel.Port("storage")
+	c.Assert(err, gc.IsNil)
+	c.Assert(port.DeviceId, gc.Equals, "")
+}
+
+func (s *NovaSuite) TestServerMetadataItems(c *gc.C) {
+	server := nova.ServerDetail{Id: "test", Metadata: map[string]string{"k1": "v1"}}
+	s.createServer(c, server)
+	defer s.deleteServer(c, server)
+
+	err := s.service.setServerMetadataItem(server.Id, "k2", "v2")
+	c.Assert(err, gc.IsNil)
+	err = s.service.deleteServerMetadataItem(server.Id, "k1")
+	c.Assert(err, gc.IsNil)
+	err = s.service.deleteServerMetadataItem(server.Id, "k1")
+	c.Assert(err, gc.ErrorMatches, "itemNotFound: Metadata item was not found")
+	sr, err := s.service.server(server.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(sr.Metadata, gc.DeepEquals, map[string]string{"k2": "v2"})
+
+	err = s.service.setServerMetadataItem(server.Id, "", "v")
+	c.Assert(err, gc.ErrorMatches, "badRequest: Metadata property key blank.")
+	err = s.service.setServerMetadataItem(server.Id, strings.Repeat("k", 256), "v")
+	c.Assert(err, gc.ErrorMatches, "badRequest: Metadata property key greater than 255 characters.")
+	err = s.service.replaceServerMetadata(server.Id, map[string]string{"k": strings.Repeat("v", 256)})
+	c.Assert(err, gc.ErrorMatches, "badRequest: Metadata property value greater than 255 characters.")
+
+	one := 1
+	s.service.SetQuotas(nova.QuotaSetOpts{MetadataItems: &one})
+	defer s.service.ResetQuotas()
+	err = s.service.setServerMetadata(server.Id, map[string]string{"k3": "v3"})
+	c.Assert(err, gc.ErrorMatches, "forbidden: Maximum number of metadata items exceeds 1")
+	err = s.service.replaceServerMetadata(server.Id, map[string]string{"k3": "v3"})
+	c.Assert(err, gc.IsNil)
+	sr, err = s.service.server(server.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(sr.Metadata, gc.DeepEquals, map[string]string{"k3": "v3"})
+}
+
+func (s *NovaSuite) TestValidateBlockDeviceMappings(c *gc.C) {
+	swap := nova.BlockDeviceMapping{BootIndex: -1, SourceType: "blank", DestinationType: "local", VolumeSize: 1, GuestFormat: "swap"}
+	data := nova.BlockDeviceMapping{BootIndex: 1, SourceType: "blank", DestinationType: "volume", VolumeSize: 10}
+	bootVolume := nova.BlockDeviceMapping{BootIndex: 0, SourceType: "volume", DestinationType: "volume", UUID: "volume-id"}
+
+	// A server booted from an image has an implicit boot device.
+	c.Assert(validateBlockDeviceMappings("image-id", []nova.BlockDeviceMapping{data, swap}), gc.IsNil)
+	c.Assert(validateBlockDeviceMappings("", []nova.BlockDeviceMapping{bootVolume, data, swap}), gc.IsNil)
+
+	for _, test := range []struct {
+		imageRef string
+		mappings []nova.BlockDeviceMapping
+		err      string
+	}{
+		{"", []nova.BlockDeviceMapping{data}, "Boot sequence .* is not valid."},
+		{"", []nova.BlockDeviceMapping{bootVolume, bootVolume}, "Boot sequence .* is not valid."},
+		{"image-id", []nova.BlockDeviceMapping{swap, swap}, "More than one swap drive requested."},
+		{"image-id", []nova.BlockDeviceMapping{{BootIndex: -1, SourceType: "blank", DestinationType: "local", VolumeType: "ssd", VolumeSize: 1}},
+			"Specifying a volume_type with destination_type=local is not supported."},
+		{"image-id", []nova.BlockDeviceMapping{{BootIndex: -1, SourceType: "image", DestinationType: "local", UUID: "image-id"}},
+			"Mapping image to local is not supported."},
+		{"image-id", []nova.BlockDeviceMapping{{BootIndex: -1, SourceType: "blank", DestinationType: "volume"}},
+			"Blank devices need to have a non-zero size specified"},
+	} {
+		err := validateBlockDeviceMappings(test.imageRef, test.mappings)
+		c.Check(err, gc.ErrorMatches, "badRequest: Block Device Mapping is Invalid: "+test.err)
+	}
+	err := validateBlockDeviceMappings("", []nova.BlockDeviceMapping{{SourceType: "disk", DestinationType: "volume"}})
+	c.Check(err, gc.ErrorMatches, "badRequest: Invalid input for field/attribute source_type. Value: disk.")
+}
+
+func (s *NovaSuite) TestScheduleServerHostsAndZones(c *gc.C) {
+	s.service.SetHosts("host1", "host2", "host3")
+	defer s.service.SetHosts()
+	aggregate, err := s.service.createAggregate("rack", "zone-a")
+	c.Assert(err, gc.IsNil)
+	defer s.service.deleteAggregate(aggregate.Id)
+	_, err = s.service.addAggregateHost(aggregate.Id, "host3")
+	c.Assert(err, gc.IsNil)
+	defer s.service.removeAggregateHost(aggregate.Id, "host3")
+	c.Assert(s.service.hostZone("host3"), gc.Equals, "zone-a")
+	c.Assert(s.service.hostZone("host1"), gc.Equals, "nova")
+
+	host, ok, err := s.service.scheduleServer("", "zone-a")
+	c.Assert(err, gc.IsNil)
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(host, gc.Equals, "host3")
+
+	// Disabled hosts are skipped, and a zone with no enabled hosts
+	// is unavailable.
+	service := s.service.computeService("host3")
+	_, err = s.service.setServiceStatus(service.Id, true, "")
+	c.Assert(err, gc.IsNil)
+	defer s.service.setServiceStatus(service.Id, false, "")
+	_, ok, err = s.service.scheduleServer("", "zone-a")
+	c.Assert(err, gc.IsNil)
+	c.Assert(ok, gc.Equals, false)
+	zone, found := s.service.availabilityZone("zone-a")
+	c.Assert(found, gc.Equals, true)
+	c.Assert(zone.State.Available, gc.Equals, false)
+	host, ok, err = s.service.scheduleServer("", "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(host, gc.Equals, "host1")
+
+	// Changing the zone through the metadata is checked too.
+	other, err := s.service.createAggregate("other", "")
+	c.Assert(err, gc.IsNil)
+	defer s.service.deleteAggregate(other.Id)
+	_, err = s.service.addAggregateHost(other.Id, "host3")
+	c.Assert(err, gc.IsNil)
+	defer s.service.removeAggregateHost(other.Id, "host3")
+	zoneB := "zone-b"
+	_, err = s.service.setAggregateMetadata(other.Id, map[string]*string{"availability_zone": &zoneB})
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: .* already in availability zone\\(s\\) \\[zone-a\\].")
+}
+
+func (s *NovaSuite) TestMigrationStates(c *gc.C) {
+	s.service.SetHosts("host1", "host2", "host3")
+	defer s.service.SetHosts()
+	server := nova.ServerDetail{Id: "sr1", UUID: "uuid1", HostId: s.service.hostId("host1"), Flavor: nova.Entity{Id: "1"}}
+	s.createServer(c, server)
+	defer s.deleteServer(c, server)
+	s.service.serverHosts[server.Id] = "host1"
+
+	// A held live migration leaves the server migrating on its
+	// source host until it is forced to complete.
+	s.service.SetLiveMigrationsHeld(true)
+	defer s.service.SetLiveMigrationsHeld(false)
+	err := s.service.liveMigrateServer(server.Id, "", true)
+	c.Assert(err, gc.IsNil)
+	sr, _ := s.service.server(server.Id)
+	c.Assert(sr.Status, gc.Equals, nova.StatusMigrating)
+	c.Assert(sr.HostId, gc.Equals, s.service.hostId("host1"))
+	c.Assert(s.service.serverHosts[server.Id], gc.Equals, "host1")
+	inProgress, err := s.service.serverMigrations(server.Id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(inProgress, gc.HasLen, 1)
+	c.Assert(inProgress[0].DestCompute, gc.Equals, "host2")
+	c.Assert(inProgress[0].Status, gc.Equals, nova.MigrationStatusRunning)
+	c.Assert(inProgress[0].MemoryTotalBytes, gc.Equals, int64(512)<<20)
+	c.Assert(inProgress[0].DiskTotalBytes, gc.Equals, int64(5)<<30)
+	err = s.service.migrateServer(server.Id, "")
+	c.Assert(err, gc.ErrorMatches, "conflictingRequest: .*MIGRATING.*")
+	err = s.service.forceCompleteMigration(server.Id, inProgress[0].Id)
+	c.Assert(err, gc.IsNil)
+	sr, _ = s.service.server(server.Id)
+	c.Assert(sr.Status, gc.Equals, nova.StatusActive)
+	c.Assert(sr.HostId, gc.Equals, s.service.hostId("host2"))
+	c.Assert(s.service.serverHosts[server.Id], gc.Equals, "host2")
+	err = s.service.forceCompleteMigration(server.Id, inProgress[0].Id)
+	c.Assert(err, gc.ErrorMatches, "badRequest: Migration .* is completed. Cannot force_complete .*")
+
+	// A cold migration waits to be confirmed on the destination,
+	// and goes back to the source host if reverted.
+	err = s.service.migrateServer(server.Id, "host2")
+	c.Assert(err, gc.ErrorMatches, "badRequest: Unable to migrate instance \\(sr1\\) to current host \\(host2\\).")
+	err = s.service.migrateServer(server.Id, "host3")
+	c.Assert(err, gc.IsNil)
+	sr, _ = s.service.server(server.Id)
+	c.Assert(sr.Status, gc.Equals, nova.StatusVerifyResize)
+	c.Assert(sr.HostId, gc.Equals, s.service.hostId("host3"))
+	c.Assert(s.service.serverHosts[server.Id], gc.Equals, "host3")
+	err = s.service.revertResize(server.Id)
+	c.Assert(err, gc.IsNil)
+	sr, _ = s.service.server(server.Id)
+	c.Assert(sr.HostId, gc.Equals, s.service.hostId("host2"))
+	c.Assert(s.service.serverHosts[server.Id], gc.Equals, "host2")
+
+	// Evacuation needs the source host to be down, and skips hosts
+	// which are down.
+	err = s.service.evacuateServer(server.Id, "")
+	c.Assert(err, gc.ErrorMatches, "badRequest: Compute service of host2 is still in use.")
+	host1 := s.service.computeService("host1")
+	host2 := s.service.computeService("host2")
+	_, err = s.service.setServiceForcedDown(host1.Id, true)
+	c.Assert(err, gc.IsNil)
+	defer s.service.setServiceForcedDown(host1.Id, false)
+	_, err = s.service.setServiceForcedDown(host2.Id, true)
+	c.Assert(err, gc.IsNil)
+	defer s.service.setServiceForcedDown(host2.Id, false)
+	err = s.service.evacuateServer(server.Id, "host1")
+	c.Assert(err, gc.ErrorMatches, "badRequest: No valid host was found. .*")
+	err = s.service.evacuateServer(server.Id, "")
+	c.Assert(err, gc.IsNil)
+	sr, _ = s.service.server(server.Id)
+	c.Assert(sr.HostId, gc.Equals, s.service.hostId("host3"))
+	c.Assert(s.service.serverHosts[server.Id], gc.Equals, "host3")
+
+	var history []string
+	for _, m := range s.service.allMigrations(filter{"instance_uuid": "uuid1"}) {
+		history = append(history, m.MigrationType+" "+m.SourceCompute+"->"+m.DestCompute+" "+m.Status)
+	}
+	c.Assert(history, gc.DeepEquals, []string{
+		"evacuation host2->host3 done",
+		"migration host2->host3 reverted",
+		"live-migration host1->host2 completed",
+	})
+	c.Assert(s.service.allMigrations(filter{"host": "host1", "instance_uuid": "uuid1"}), gc.HasLen, 1)
+}
+
+func (s *NovaSuite) TestTenantUsages(c *gc.C) {
+	now := time.Now()
+	created := now.Add(-2 * time.Hour).Format(time.RFC3339)
+	servers := []nova.ServerDetail{
+		{Id: "sr1", Name: "a", TenantId: "t1", Created: created, Flavor: nova.Entity{Id: "1"}},
+		{Id: "sr2", Name: "b", TenantId: "t2", Created: created, Flavor: nova.Entity{Id: "2"}},
+		{Id: "sr3", Name: "c", TenantId: "t1", Created: now.Add(time.Hour).Format(time.RFC3339)},
+	}
+	for _, server := range servers {
+		s.createServer(c, server)
+	}
+	defer s.deleteServer(c, servers[1])
+	defer s.deleteServer(c, servers[2])
+
+	// Only the time within the period is counted, and servers
+	// created after it are left out.
+	usages, next, err := s.service.tenantUsages("", now.Add(-time.Hour), now, true, 0, "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(next, gc.Equals, "")
+	c.Assert(usages, gc.HasLen, 2)
+	c.Assert(usages[0].TenantId, gc.Equals, "t1")
+	c.Assert(usages[0].ServerUsages, gc.HasLen, 1)
+	usage := usages[0].ServerUsages[0]
+	c.Assert(usage.InstanceId, gc.Equals, "sr1")
+	c.Assert(usage.Flavor, gc.Equals, "m1.tiny")
+	c.Assert(usage.State, gc.Equals, "active")
+	c.Assert(usage.Hours > 0.99 && usage.Hours < 1.01, gc.Equals, true)
+	c.Assert(usages[0].TotalMemoryMBUsage, gc.Equals, usage.Hours*512)
+	c.Assert(usages[0].TotalLocalGBUsage, gc.Equals, usage.Hours*5)
+	c.Assert(usages[1].TotalVCPUsUsage, gc.Equals, usages[1].TotalHours)
+
+	usages, next, err = s.service.tenantUsages("", now.Add(-time.Hour), now, false, 1, "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(next, gc.Equals, "sr1")
+	c.Assert(usages, gc.HasLen, 1)
+	c.Assert(usages[0].ServerUsages, gc.IsNil)
+	usages, next, err = s.service.tenantUsages("", now.Add(-time.Hour), now, false, 1, next)
+	c.Assert(err, gc.IsNil)
+	c.Assert(next, gc.Equals, "sr2")
+	c.Assert(usages[0].TenantId, gc.Equals, "t2")
+	usages, next, err = s.service.tenantUsages("", now.Add(-time.Hour), now, false, 1, next)
+	c.Assert(err, gc.IsNil)
+	c.Assert(next, gc.Equals, "")
+	c.Assert(usages, gc.HasLen, 0)
+	_, _, err = s.service.tenantUsages("", now.Add(-time.Hour), now, false, 1, "no-such-server")
+	c.Assert(err, gc.ErrorMatches, "badRequest: marker \\[no-such-server\\] not found")
+
+	// Deleted servers are still reported.
+	err = s.service.removeServer(servers[0].Id)
+	c.Assert(err, gc.IsNil)
+	usages, _, err = s.service.tenantUsages("t1", now.Add(-time.Hour), time.Now(), true, 0, "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(usages, gc.HasLen, 1)
+	c.Assert(usages[0].ServerUsages, gc.HasLen, 1)
+	c.Assert(usages[0].ServerUsages[0].State, gc.Equals, "terminated")
+	c.Assert(usages[0].ServerUsages[0].EndedAt, gc.Not(gc.Equals), "")
+}
+
 func (s *NovaSuite) TestAddRemoveSecurityGroup(c *gc.C) {
 	if s.service.useNeutronNetworking {
 		c.Skip("skipped in novaservice when using Neutron Model")
@@ -11,6 +11,8 @@ import (
 
 	gc "gopkg.in/check.v1"
 
+	"github.com/go-goose/goose/v5/client"
+	"github.com/go-goose/goose/v5/identity"
 	"github.com/go-goose/goose/v5/swift"
 	"github.com/go-goose/goose/v5/testing/httpsuite"
 	"github.com/go-goose/goose/v5/testservices/identityservice"
@@ -389,3 +391,70 @@ func (s *SwiftHTTPSSuite) TestHasHTTPSServiceURL(c *gc.C) {
 	endpoints := s.service.Endpoints()
 	c.Assert(endpoints[0].PublicURL[:8], gc.Equals, "https://")
 }
+
+// SwiftV1AuthSuite runs the Swift double behind Swift v1 (TempAuth)
+// authentication, as a standalone Swift cluster would be.
+type SwiftV1AuthSuite struct {
+	httpsuite.HTTPSuite
+	identity *identityservice.SwiftV1
+	service  *Swift
+}
+
+var _ = gc.Suite(&SwiftV1AuthSuite{})
+
+func (s *SwiftV1AuthSuite) SetUpSuite(c *gc.C) {
+	s.HTTPSuite.SetUpSuite(c)
+	s.identity = identityservice.NewSwiftV1()
+	s.identity.AddUser("test:tester", "testing", "", "")
+	s.service = New(s.Server.URL, versionPath, tenantId, region, s.identity, nil)
+}
+
+func (s *SwiftV1AuthSuite) SetUpTest(c *gc.C) {
+	s.HTTPSuite.SetUpTest(c)
+	s.identity.SetupHTTP(s.Mux)
+	s.service.SetupHTTP(s.Mux)
+}
+
+func (s *SwiftV1AuthSuite) TestSwiftClient(c *gc.C) {
+	cred := &identity.Credentials{
+		URL:     s.Server.URL,
+		User:    "test:tester",
+		Secrets: "testing",
+	}
+	cl := client.NewClient(cred, identity.AuthSwiftV1, nil)
+	c.Assert(cl.Authenticate(), gc.IsNil)
+	swiftURL, err := cl.MakeServiceURL("object-store", "", nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(swiftURL, gc.Equals, s.service.endpointURL(""))
+
+	swiftClient := swift.New(cl)
+	c.Assert(swiftClient.CreateContainer("v1-container", swift.Private), gc.IsNil)
+	c.Assert(swiftClient.PutObject("v1-container", "object", []byte("data")), gc.IsNil)
+	data, err := swiftClient.GetObject("v1-container", "object")
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(data), gc.Equals, "data")
+}
+
+func (s *SwiftV1AuthSuite) TestSwiftClientWithRegion(c *gc.C) {
+	cred := &identity.Credentials{
+		URL:     s.Server.URL,
+		User:    "test:tester",
+		Secrets: "testing",
+		Region:  "RegionOne",
+	}
+	cl := client.NewClient(cred, identity.AuthSwiftV1, nil)
+	c.Assert(cl.Authenticate(), gc.IsNil)
+	swiftURL, err := cl.MakeServiceURL("object-store", "", nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(swiftURL, gc.Equals, s.service.endpointURL(""))
+}
+
+func (s *SwiftV1AuthSuite) TestBadKey(c *gc.C) {
+	cred := &identity.Credentials{
+		URL:     s.Server.URL + "/auth/v1.0",
+		User:    "test:tester",
+		Secrets: "wrong",
+	}
+	cl := client.NewClient(cred, identity.AuthSwiftV1, nil)
+	c.Assert(cl.Authenticate(), gc.ErrorMatches, `(?s)authentication failed.*invalid Swift credentials.*`)
+}